@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/celrule"
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/plugin"
+	"github.com/marek-kar/kube-slowwhy/pkg/render"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+	"github.com/marek-kar/kube-slowwhy/pkg/suppress"
+)
+
+func newAnalyzeCmd() *cobra.Command {
+	af := &analyzerFlags{}
+	var format string
+	var showSuppressed bool
+
+	cmd := &cobra.Command{
+		Use:   "analyze SNAPSHOT|BUNDLE|RECORDING",
+		Short: "Run the analysis rules against a saved snapshot, bundle or recording",
+		Long: `Run the analysis rules against a saved snapshot. Given a bundle directory
+written by collect --contexts, analyse every cluster in it and print a fleet
+report showing which findings are common to all clusters, shared by several,
+or unique to one. Given a recording written by record, analyse every frame and
+print when each finding first appeared, peaked and cleared.
+
+Rule thresholds, enabled rules and the namespaces each rule sees are read
+from the config file, .slowwhy.yaml in the current directory unless --config
+says otherwise; see kube-slowwhy rules config.
+
+Findings matched by the suppression file, .slowwhy-ignore.yaml in the current
+directory unless --ignore-file says otherwise, are left out of the report and
+counted; --show-suppressed lists them with their reasons.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := render.Format(format)
+			if f != render.FormatTable && f != render.FormatJSON {
+				return fmt.Errorf("invalid --output %q: must be table or json", format)
+			}
+			a, err := af.load()
+			if err != nil {
+				return err
+			}
+
+			if snapshot.IsBundle(args[0]) {
+				fleet, err := analyzeBundle(args[0], a)
+				if err != nil {
+					return err
+				}
+				return render.NewFleet(f).RenderFleet(cmd.OutOrStdout(), fleet)
+			}
+
+			if snapshot.IsRecording(args[0]) {
+				timeline, err := analyzeRecording(args[0], a)
+				if err != nil {
+					return err
+				}
+				return render.NewTimeline(f).RenderTimeline(cmd.OutOrStdout(), timeline)
+			}
+
+			snap, err := snapshot.Load(args[0])
+			if err != nil {
+				return err
+			}
+			report := a.snapshot(snap)
+			if !showSuppressed {
+				report.Suppressed = nil
+			}
+			return render.New(f).Render(cmd.OutOrStdout(), report)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", string(render.FormatTable), "output format: table or json")
+	cmd.Flags().BoolVar(&showSuppressed, "show-suppressed", false, "list suppressed findings and the reasons they were suppressed")
+	af.addFlags(cmd.Flags())
+	return cmd
+}
+
+// analyzer runs the default rules, configured by the config file if any,
+// correlates their findings and hides the ones matched by the suppression
+// file, if any.
+type analyzer struct {
+	config analysis.Config
+	engine *analysis.Engine
+	ignore *suppress.File
+}
+
+func (a analyzer) snapshot(snap *collector.Snapshot) model.Report {
+	return a.frame(nil, snap)
+}
+
+// frame is snapshot for a frame of a recording, with the previous frame, if
+// any, available to trend rules.
+func (a analyzer) frame(prev, snap *collector.Snapshot) model.Report {
+	report := a.engine.AnalyzeFrame(prev, snap)
+	report.Findings = analysis.NewCorrelator().Correlate(report.Findings)
+	return a.ignore.Apply(report, time.Now())
+}
+
+// configFlags select the rule config file.
+type configFlags struct {
+	path string
+}
+
+func (f *configFlags) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.path, "config", analysis.DefaultConfigFile, "rule config file with thresholds and enabled rules (empty = none)")
+}
+
+// load reads the config file. The default file is optional; a file named
+// with --config is not.
+func (f *configFlags) load() (analysis.Config, error) {
+	if f.path == "" {
+		return analysis.Config{}, nil
+	}
+	if _, err := os.Stat(f.path); os.IsNotExist(err) && f.path == analysis.DefaultConfigFile {
+		return analysis.Config{}, nil
+	}
+	return analysis.LoadConfig(f.path)
+}
+
+// engine returns the default engine with the rules from the config file's
+// rule files and the exec plugins, configured by the config file.
+func (f *configFlags) engine() (*analysis.Engine, analysis.Config, error) {
+	cfg, err := f.load()
+	if err != nil {
+		return nil, cfg, err
+	}
+	engine := analysis.DefaultEngine()
+	known := make(map[string]bool)
+	for _, r := range engine.Rules(nil) {
+		known[r.Name] = true
+	}
+	for _, path := range cfg.RuleFiles {
+		rules, err := celrule.Load(path)
+		if err != nil {
+			return nil, cfg, err
+		}
+		for _, r := range rules {
+			if known[r.Name()] {
+				return nil, cfg, fmt.Errorf("rule file %s: rule %s is already defined", path, r.Name())
+			}
+			known[r.Name()] = true
+			engine.Register(r)
+		}
+	}
+	plugins, err := discoverPlugins(cfg.Plugins)
+	if err != nil {
+		return nil, cfg, err
+	}
+	for _, p := range plugins {
+		if known[p.Name()] {
+			return nil, cfg, fmt.Errorf("plugin %s: rule %s is already defined", p.Path(), p.Name())
+		}
+		known[p.Name()] = true
+		engine.Register(p)
+	}
+	if err := engine.Configure(cfg); err != nil {
+		return nil, cfg, fmt.Errorf("config file %s: %w", f.path, err)
+	}
+	return engine, cfg, nil
+}
+
+// discoverPlugins finds the exec plugins in the configured dirs and on PATH.
+func discoverPlugins(cfg *analysis.PluginConfig) ([]*plugin.Plugin, error) {
+	var dirs []string
+	var opts plugin.Options
+	if cfg != nil {
+		dirs = cfg.Dirs
+		if cfg.Timeout != nil {
+			opts.Timeout = cfg.Timeout.Duration
+		}
+		if cfg.MaxOutput != nil {
+			opts.MaxOutput = cfg.MaxOutput.Value()
+		}
+	}
+	plugins, warnings, err := plugin.Discover(dirs, os.Getenv("PATH"), opts)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	return plugins, err
+}
+
+// analyzerFlags select the config and suppression files.
+type analyzerFlags struct {
+	config     configFlags
+	ignorePath string
+}
+
+func (f *analyzerFlags) addFlags(fs *pflag.FlagSet) {
+	f.config.addFlags(fs)
+	fs.StringVar(&f.ignorePath, "ignore-file", suppress.DefaultFile, "suppression file for known findings (empty = none)")
+}
+
+// load reads the config and suppression files and warns about expired
+// suppressions. The default files are optional; files named with --config or
+// --ignore-file are not.
+func (f *analyzerFlags) load() (analyzer, error) {
+	engine, cfg, err := f.config.engine()
+	if err != nil {
+		return analyzer{}, err
+	}
+	a := analyzer{config: cfg, engine: engine}
+	if f.ignorePath == "" {
+		return a, nil
+	}
+	if _, err := os.Stat(f.ignorePath); os.IsNotExist(err) && f.ignorePath == suppress.DefaultFile {
+		return a, nil
+	}
+	file, err := suppress.Load(f.ignorePath)
+	if err != nil {
+		return analyzer{}, err
+	}
+	now := time.Now()
+	for _, s := range file.Expired(now) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: suppression %s expired on %s (%s)\n", f.ignorePath, s, s.Expires, s.Reason)
+	}
+	a.ignore = file
+	return a, nil
+}
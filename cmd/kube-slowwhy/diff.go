@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/diff"
+	"github.com/marek-kar/kube-slowwhy/pkg/render"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+func newDiffCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "diff BEFORE AFTER",
+		Short: "Show what changed between two snapshots of a cluster",
+		Long: `Compare two snapshots of the same cluster: nodes added or removed, node
+condition transitions, cordons and kubelet upgrades, pods that changed phase
+or restarted, Warning event reasons that are new in AFTER, PVC phase changes
+and kube-system DaemonSet readiness. Objects are matched by name, so redacted
+snapshots must share a redaction key.`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := render.Format(format)
+			if f != render.FormatTable && f != render.FormatJSON {
+				return fmt.Errorf("invalid --output %q: must be table or json", format)
+			}
+
+			before, err := snapshot.Load(args[0])
+			if err != nil {
+				return err
+			}
+			after, err := snapshot.Load(args[1])
+			if err != nil {
+				return err
+			}
+			return render.NewDiff(f).RenderDiff(cmd.OutOrStdout(), diff.Snapshots(before, after))
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", string(render.FormatTable), "output format: table or json")
+	return cmd
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+const defaultBundleDir = "bundle"
+
+// fleetFlags select the contexts collected into a bundle.
+type fleetFlags struct {
+	contexts    []string
+	all         bool
+	concurrency int
+	compression string
+}
+
+func (f *fleetFlags) addFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&f.contexts, "contexts", nil, "collect from these kubeconfig contexts concurrently into a bundle directory")
+	fs.BoolVar(&f.all, "all-contexts", false, "collect from every context in the kubeconfig into a bundle directory")
+	fs.IntVar(&f.concurrency, "context-concurrency", f.concurrency, "maximum number of clusters collected in parallel")
+	fs.StringVar(&f.compression, "bundle-compression", f.compression, "compression of the snapshots in a bundle: none, gzip or zstd")
+}
+
+func (f *fleetFlags) enabled() bool {
+	return f.all || len(f.contexts) > 0
+}
+
+func (f *fleetFlags) extension() (string, error) {
+	switch snapshot.Compression(f.compression) {
+	case snapshot.CompressionNone:
+		return ".json", nil
+	case snapshot.CompressionGzip:
+		return ".json.gz", nil
+	case snapshot.CompressionZstd:
+		return ".json.zst", nil
+	default:
+		return "", fmt.Errorf("invalid --bundle-compression %q: must be none, gzip or zstd", f.compression)
+	}
+}
+
+// collectBundle collects every selected context into the bundle directory
+// opts.Output. A context that fails is recorded in the manifest and does not
+// stop the others; the command fails only if no context could be collected.
+func collectBundle(ctx context.Context, kube *clusterFlags, fleet *fleetFlags, opts collector.Options, post postProcess) error {
+	if kube.context != "" {
+		return fmt.Errorf("--context cannot be combined with --contexts or --all-contexts")
+	}
+	ext, err := fleet.extension()
+	if err != nil {
+		return err
+	}
+	contexts := fleet.contexts
+	if fleet.all {
+		if contexts, err = kube.allContexts(); err != nil {
+			return err
+		}
+	}
+	if len(contexts) == 0 {
+		return fmt.Errorf("no kubeconfig contexts to collect")
+	}
+
+	dir := opts.Output
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create bundle directory: %w", err)
+	}
+
+	manifest := snapshot.Manifest{
+		CreatedAt: time.Now().UTC(),
+		Clusters:  make([]snapshot.BundleEntry, len(contexts)),
+	}
+	files := bundleFileNames(contexts, ext)
+
+	fmt.Fprintf(os.Stderr, "Collecting %d cluster snapshots (since %s)...\n", len(contexts), opts.Since)
+
+	concurrency := fleet.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg     sync.WaitGroup
+		stderr sync.Mutex
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, name := range contexts {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			logf := func(format string, args ...interface{}) {
+				stderr.Lock()
+				defer stderr.Unlock()
+				fmt.Fprintf(os.Stderr, "[%s] "+format+"\n", append([]interface{}{name}, args...)...)
+			}
+
+			entry := snapshot.BundleEntry{Context: name}
+			snap, err := collectSnapshot(ctx, kube.forContext(name), opts, post, func(err error) {
+				logf("Warning: %v", err)
+			})
+			if err == nil {
+				err = snapshot.Save(filepath.Join(dir, files[i]), snap)
+			}
+			if err != nil {
+				logf("Error: %v", err)
+				entry.Error = err.Error()
+			} else {
+				entry.File = files[i]
+				if snap.Cluster != nil {
+					entry.ClusterID = snap.Cluster.ID
+				}
+				logf("Snapshot written to %s", filepath.Join(dir, files[i]))
+			}
+			manifest.Clusters[i] = entry
+		}(i, name)
+	}
+	wg.Wait()
+
+	if err := snapshot.WriteManifest(dir, manifest); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, e := range manifest.Clusters {
+		if e.Error != "" {
+			failed++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Bundle written to %s (%d of %d clusters collected)\n", dir, len(contexts)-failed, len(contexts))
+	if failed == len(contexts) {
+		return fmt.Errorf("no cluster could be collected")
+	}
+	return nil
+}
+
+// bundleFileNames returns a distinct file name for every context.
+func bundleFileNames(contexts []string, ext string) []string {
+	used := make(map[string]bool, len(contexts))
+	names := make([]string, len(contexts))
+	for i, c := range contexts {
+		name := snapshot.BundleFileName(c, ext)
+		for n := 2; used[name]; n++ {
+			name = snapshot.BundleFileName(c+"-"+strconv.Itoa(n), ext)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// analyzeBundle analyses every snapshot in the bundle directory and combines
+// the results. Contexts whose collection failed, or whose snapshot cannot be
+// read, are reported as errors in the fleet report.
+func analyzeBundle(dir string, a analyzer) (model.FleetReport, error) {
+	manifest, err := snapshot.LoadManifest(dir)
+	if err != nil {
+		return model.FleetReport{}, err
+	}
+
+	clusters := make([]analysis.ClusterReport, 0, len(manifest.Clusters))
+	for _, e := range manifest.Clusters {
+		c := analysis.ClusterReport{Context: e.Context}
+		switch {
+		case e.Error != "":
+			c.Err = fmt.Errorf("%s", e.Error)
+		default:
+			snap, err := snapshot.Load(filepath.Join(dir, e.File))
+			if err != nil {
+				c.Err = err
+			} else {
+				c.Report = a.snapshot(snap)
+			}
+		}
+		clusters = append(clusters, c)
+	}
+	return analysis.Fleet(clusters), nil
+}
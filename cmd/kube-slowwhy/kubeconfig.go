@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// inClusterContext names the context of snapshots collected with the pod's
+// service account rather than a kubeconfig.
+const inClusterContext = "in-cluster"
+
+// clusterFlags are the kubectl-style connection flags shared by every command
+// that talks to a cluster.
+type clusterFlags struct {
+	kubeconfig     string
+	context        string
+	cluster        string
+	user           string
+	as             string
+	asGroups       []string
+	requestTimeout string
+	qps            float32
+	burst          int
+}
+
+func newClusterFlags() *clusterFlags {
+	return &clusterFlags{requestTimeout: "0", qps: 50, burst: 100}
+}
+
+func (f *clusterFlags) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "path to the kubeconfig file (default $KUBECONFIG or ~/.kube/config; in-cluster config when run as a pod)")
+	fs.StringVar(&f.context, "context", "", "kubeconfig context to use (default the current context)")
+	fs.StringVar(&f.cluster, "cluster", "", "kubeconfig cluster to use")
+	fs.StringVar(&f.user, "user", "", "kubeconfig user to use")
+	fs.StringVar(&f.as, "as", "", "username to impersonate")
+	fs.StringArrayVar(&f.asGroups, "as-group", nil, "group to impersonate; repeat for several groups")
+	fs.StringVar(&f.requestTimeout, "request-timeout", f.requestTimeout, "timeout for a single API request, e.g. 30s (0 = no timeout)")
+	fs.Float32Var(&f.qps, "qps", f.qps, "maximum queries per second to the apiserver")
+	fs.IntVar(&f.burst, "burst", f.burst, "maximum burst of queries to the apiserver")
+}
+
+// clusterTarget is what the kubeconfig says about the cluster being collected.
+type clusterTarget struct {
+	context string
+	host    string
+}
+
+func (f *clusterFlags) loader() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: f.context,
+		Context: clientcmdapi.Context{
+			Cluster:  f.cluster,
+			AuthInfo: f.user,
+		},
+		AuthInfo: clientcmdapi.AuthInfo{
+			Impersonate:       f.as,
+			ImpersonateGroups: f.asGroups,
+		},
+		Timeout: f.requestTimeout,
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// restConfig resolves the flags into a client config. Without a kubeconfig
+// file, a process running in a pod uses its service account.
+func (f *clusterFlags) restConfig() (*rest.Config, clusterTarget, error) {
+	if f.qps <= 0 || f.burst <= 0 {
+		return nil, clusterTarget{}, fmt.Errorf("--qps and --burst must be positive")
+	}
+	if len(f.asGroups) > 0 && f.as == "" {
+		return nil, clusterTarget{}, fmt.Errorf("--as-group requires --as")
+	}
+
+	loader := f.loader()
+	config, err := loader.ClientConfig()
+	if err != nil {
+		return nil, clusterTarget{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	config.QPS = f.qps
+	config.Burst = f.burst
+	// The in-cluster config ignores these overrides, so apply them again.
+	if f.as != "" {
+		config.Impersonate = rest.ImpersonationConfig{UserName: f.as, Groups: f.asGroups}
+	}
+	timeout, err := clientcmd.ParseTimeout(f.requestTimeout)
+	if err != nil {
+		return nil, clusterTarget{}, fmt.Errorf("invalid --request-timeout: %w", err)
+	}
+	config.Timeout = timeout
+
+	var target clusterTarget
+	raw, err := loader.RawConfig()
+	switch {
+	case f.context != "":
+		target.context = f.context
+	case err == nil && len(raw.Contexts) > 0:
+		target.context = raw.CurrentContext
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		target.context = inClusterContext
+	}
+
+	target.host = config.Host
+	if u, err := url.Parse(config.Host); err == nil && u.Host != "" {
+		target.host = u.Host
+	}
+	return config, target, nil
+}
+
+// buildClient returns a client for the selected context along with that
+// context's name and apiserver host.
+func (f *clusterFlags) buildClient() (kubernetes.Interface, clusterTarget, error) {
+	config, target, err := f.restConfig()
+	if err != nil {
+		return nil, clusterTarget{}, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, clusterTarget{}, fmt.Errorf("create client: %w", err)
+	}
+	return client, target, nil
+}
+
+// forContext returns a copy of the flags selecting another context.
+func (f *clusterFlags) forContext(name string) *clusterFlags {
+	c := *f
+	c.context = name
+	return &c
+}
+
+// allContexts lists the contexts of the selected kubeconfig in name order.
+func (f *clusterFlags) allContexts() ([]string, error) {
+	raw, err := f.loader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	names := make([]string, 0, len(raw.Contexts))
+	for name := range raw.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
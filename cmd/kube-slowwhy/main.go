@@ -2,26 +2,31 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/ed25519"
 	"fmt"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
+	"github.com/spf13/pflag"
 
 	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/redact"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	root := &cobra.Command{
-		Use:   "kube-slowwhy",
-		Short: "Diagnose slow Kubernetes clusters",
+		Use:     "kube-slowwhy",
+		Short:   "Diagnose slow Kubernetes clusters",
+		Version: version,
 	}
 
-	root.AddCommand(newCollectCmd())
+	root.AddCommand(newCollectCmd(), newAnalyzeCmd(), newDiffCmd(), newPreflightCmd(), newRBACCmd(), newRecordCmd(), newReportCmd(), newRulesCmd(), newSnapshotCmd(), newWatchCmd())
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -30,6 +35,10 @@ func main() {
 
 func newCollectCmd() *cobra.Command {
 	opts := collector.DefaultOptions()
+	kube := newClusterFlags()
+	fleet := &fleetFlags{concurrency: 4, compression: string(snapshot.CompressionNone)}
+	pp := &postProcessFlags{}
+	config := &configFlags{}
 	var since string
 
 	cmd := &cobra.Command{
@@ -41,31 +50,42 @@ func newCollectCmd() *cobra.Command {
 				return fmt.Errorf("invalid --since value: %w", err)
 			}
 			opts.Since = d
+			if opts.NodeConcurrency < 1 {
+				return fmt.Errorf("invalid --node-concurrency value: must be at least 1")
+			}
+			if opts.NodeTimeout <= 0 {
+				return fmt.Errorf("invalid --node-timeout value: must be positive")
+			}
 
-			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-			defer cancel()
-
-			client, err := buildClient()
+			post, err := pp.load()
 			if err != nil {
 				return err
 			}
-
-			fmt.Fprintf(os.Stderr, "Collecting cluster snapshot (since %s)...\n", opts.Since)
-			snap, err := collector.Collect(ctx, client, opts)
+			cfg, err := config.load()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return err
 			}
-			if snap == nil {
-				return fmt.Errorf("snapshot is nil")
+			opts.SystemPodSelectors = cfg.SystemPodSelectors
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+
+			if fleet.enabled() {
+				if !cmd.Flags().Changed("out") {
+					opts.Output = defaultBundleDir
+				}
+				return collectBundle(ctx, kube, fleet, opts, post)
 			}
 
-			data, err := json.MarshalIndent(snap, "", "  ")
+			fmt.Fprintf(os.Stderr, "Collecting cluster snapshot (since %s)...\n", opts.Since)
+			snap, err := collectSnapshot(ctx, kube, opts, post, func(err error) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			})
 			if err != nil {
-				return fmt.Errorf("marshal snapshot: %w", err)
+				return err
 			}
-
-			if err := os.WriteFile(opts.Output, data, 0o644); err != nil {
-				return fmt.Errorf("write file: %w", err)
+			if err := snapshot.Save(opts.Output, snap); err != nil {
+				return err
 			}
 
 			fmt.Fprintf(os.Stderr, "Snapshot written to %s\n", opts.Output)
@@ -75,23 +95,110 @@ func newCollectCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&since, "since", "30m", "look-back duration for events")
 	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "filter by namespace (empty = all)")
-	cmd.Flags().StringVarP(&opts.Output, "out", "o", opts.Output, "output file path")
+	cmd.Flags().StringVarP(&opts.Output, "out", "o", opts.Output, "output file path; a .gz or .zst extension compresses the snapshot (bundle directory with --contexts, default \""+defaultBundleDir+"\")")
+	addCollectorFlags(cmd.Flags(), &opts)
+	cmd.Flags().IntVar(&opts.NodeConcurrency, "node-concurrency", opts.NodeConcurrency, "maximum number of nodes queried in parallel")
+	cmd.Flags().DurationVar(&opts.NodeTimeout, "node-timeout", opts.NodeTimeout, "timeout for each per-node request")
+	cmd.Flags().Int64Var(&opts.LogTailLines, "log-tail", opts.LogTailLines, "number of log lines to fetch per container")
+	cmd.Flags().Int64Var(&opts.LogLimitBytes, "log-limit-bytes", opts.LogLimitBytes, "maximum bytes of log to fetch per container")
+	pp.addFlags(cmd.Flags())
+	config.addFlags(cmd.Flags())
+	fleet.addFlags(cmd.Flags())
+	kube.addFlags(cmd.Flags())
 
 	return cmd
 }
 
-func buildClient() (kubernetes.Interface, error) {
-	rules := clientcmd.NewDefaultClientConfigLoadingRules()
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		rules, &clientcmd.ConfigOverrides{},
-	).ClientConfig()
+// postProcess is applied to every collected snapshot before it is written.
+type postProcess struct {
+	redaction *redaction
+	signKey   ed25519.PrivateKey
+}
+
+// postProcessFlags select the redaction and signing of collected snapshots.
+type postProcessFlags struct {
+	redactProfile string
+	redactKeyFile string
+	signKeyFile   string
+}
+
+func (f *postProcessFlags) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.redactProfile, "redact", "", "redact the snapshot with a built-in profile (minimal, standard, strict) or a profile file")
+	fs.Lookup("redact").NoOptDefVal = redact.DefaultProfile
+	fs.StringVar(&f.signKeyFile, "sign-key", "", "ed25519 private key (PEM) used to sign the snapshot; see snapshot keygen")
+	fs.StringVar(&f.redactKeyFile, "redact-key-file", "", "file holding the key used to hash names (default $"+redactKeyEnv+", else a random key)")
+}
+
+// load reads the redaction profile and key and the signing key once, so every
+// snapshot of a run is hashed with the same key.
+func (f *postProcessFlags) load() (postProcess, error) {
+	var post postProcess
+	var err error
+	if f.redactProfile != "" {
+		if post.redaction, err = loadRedaction(f.redactProfile, f.redactKeyFile); err != nil {
+			return postProcess{}, err
+		}
+	}
+	if f.signKeyFile != "" {
+		if post.signKey, err = snapshot.LoadPrivateKey(f.signKeyFile); err != nil {
+			return postProcess{}, err
+		}
+	}
+	return post, nil
+}
+
+// collectSnapshot collects one cluster, fills in the header fields only the
+// CLI knows, and redacts and signs the result. Collector failures that leave
+// a usable snapshot are passed to warn.
+func collectSnapshot(ctx context.Context, kube *clusterFlags, opts collector.Options, post postProcess, warn func(error)) (*collector.Snapshot, error) {
+	client, target, err := kube.buildClient()
 	if err != nil {
-		return nil, fmt.Errorf("load kubeconfig: %w", err)
+		return nil, err
 	}
 
-	client, err := kubernetes.NewForConfig(config)
+	snap, err := collector.Collect(ctx, client, opts)
 	if err != nil {
-		return nil, fmt.Errorf("create client: %w", err)
+		warn(err)
 	}
-	return client, nil
+	if snap == nil {
+		return nil, fmt.Errorf("snapshot is nil")
+	}
+	if snap.Cluster == nil {
+		snap.Cluster = &collector.ClusterInfo{}
+	}
+	snap.Cluster.Context = target.context
+	snap.Cluster.APIServerHost = target.host
+	if snap.Collection != nil {
+		snap.Collection.CollectorVersion = version
+		if n := len(snap.Collection.RBACDenials); n > 0 {
+			warn(fmt.Errorf("%d request(s) denied by RBAC; run kube-slowwhy preflight with the same flags to see which permissions are missing", n))
+		}
+	}
+
+	if post.redaction != nil {
+		redactor, err := post.redaction.newRedactor()
+		if err != nil {
+			return nil, err
+		}
+		if err := redactor.Apply(snap); err != nil {
+			return nil, fmt.Errorf("redact snapshot: %w", err)
+		}
+	}
+
+	if post.signKey != nil {
+		if err := snapshot.Sign(snap, post.signKey); err != nil {
+			return nil, fmt.Errorf("sign snapshot: %w", err)
+		}
+	}
+	return snap, nil
+}
+
+// addCollectorFlags registers the opt-in collectors, which also decide the
+// permissions checked by preflight and granted by rbac.
+func addCollectorFlags(fs *pflag.FlagSet, opts *collector.Options) {
+	fs.BoolVar(&opts.APIServerMetrics, "apiserver-metrics", false, "scrape apiserver /metrics for request latency and flow control data")
+	fs.BoolVar(&opts.KubeletStats, "kubelet-stats", false, "fetch kubelet summary stats from every node through the apiserver proxy")
+	fs.BoolVar(&opts.CadvisorMetrics, "cadvisor-metrics", false, "fetch cAdvisor CPU throttling counters from every node through the apiserver proxy")
+	fs.BoolVar(&opts.ResourceMetrics, "resource-metrics", false, "read node and pod usage from metrics.k8s.io when metrics-server is installed")
+	fs.BoolVar(&opts.Logs, "logs", false, "tail logs from CoreDNS, CNI, CSI and crashlooping containers")
 }
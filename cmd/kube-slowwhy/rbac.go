@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/rbac"
+	"github.com/marek-kar/kube-slowwhy/pkg/watch"
+)
+
+func newPreflightCmd() *cobra.Command {
+	opts := collector.DefaultOptions()
+	kube := newClusterFlags()
+	var format string
+	var watching bool
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that you have the permissions collect needs",
+		Long: `Ask the apiserver, with SelfSubjectAccessReviews, whether the current
+identity holds every permission needed by the selected collectors. Pass the
+same collector flags as collect. Exits non-zero if any permission is missing.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid --format %q: must be text or json", format)
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+
+			client, _, err := kube.buildClient()
+			if err != nil {
+				return err
+			}
+			results, err := rbac.Check(ctx, client, requiredPermissions(opts, watching))
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if format == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+			} else {
+				tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintf(tw, "COLLECTOR\tPERMISSION\tNAMESPACE\tRESULT\n")
+				for _, r := range results {
+					result := "ok"
+					if !r.Allowed {
+						result = "DENIED"
+						if r.Reason != "" {
+							result += " (" + r.Reason + ")"
+						}
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Collector, r.Permission, orAll(r.Namespace), result)
+				}
+				if err := tw.Flush(); err != nil {
+					return err
+				}
+			}
+
+			if denied := rbac.Denied(results); len(denied) > 0 {
+				return fmt.Errorf("%d of %d permission(s) denied; kube-slowwhy rbac prints a ClusterRole that grants them", len(denied), len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "check namespaced permissions in this namespace only (empty = all)")
+	addCollectorFlags(cmd.Flags(), &opts)
+	addWatchFlag(cmd.Flags(), &watching)
+	cmd.Flags().StringVar(&format, "format", "text", "output format: text or json")
+	kube.addFlags(cmd.Flags())
+	return cmd
+}
+
+func newRBACCmd() *cobra.Command {
+	opts := collector.DefaultOptions()
+	var name string
+	var all, watching bool
+
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Print the minimal ClusterRole for the selected collectors",
+		Long: `Print a ClusterRole granting exactly the permissions collect needs with
+the given collector flags. Pass --all to include every opt-in collector and
+--watch to add what the watch command needs.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all {
+				opts.APIServerMetrics = true
+				opts.KubeletStats = true
+				opts.CadvisorMetrics = true
+				opts.ResourceMetrics = true
+				opts.Logs = true
+			}
+			data, err := rbac.ClusterRoleYAML(name, requiredPermissions(opts, watching))
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+
+	addCollectorFlags(cmd.Flags(), &opts)
+	cmd.Flags().BoolVar(&all, "all", false, "include the permissions of every opt-in collector")
+	addWatchFlag(cmd.Flags(), &watching)
+	cmd.Flags().StringVar(&name, "name", rbac.DefaultRoleName, "name of the ClusterRole")
+	return cmd
+}
+
+func addWatchFlag(fs *pflag.FlagSet, watching *bool) {
+	fs.BoolVar(watching, "watch", false, "include the list and watch permissions of the watch command")
+}
+
+// requiredPermissions is collect's permissions for opts, plus watch's when
+// watching is set.
+func requiredPermissions(opts collector.Options, watching bool) []collector.Permission {
+	perms := collector.RequiredPermissions(opts)
+	if watching {
+		perms = append(perms, watch.RequiredPermissions(opts.Namespace)...)
+	}
+	return perms
+}
+
+func orAll(ns string) string {
+	if ns == "" {
+		return "*"
+	}
+	return ns
+}
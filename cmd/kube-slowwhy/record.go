@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+const defaultRecordingFile = "recording.json.zst"
+
+func newRecordCmd() *cobra.Command {
+	opts := collector.DefaultOptions()
+	opts.Output = defaultRecordingFile
+	kube := newClusterFlags()
+	pp := &postProcessFlags{}
+	config := &configFlags{}
+	var since string
+	var interval, duration time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Collect a snapshot at a fixed interval into one recording",
+		Long: `Collect a snapshot every --interval for --duration and write them as the
+frames of a single recording file. analyze on the recording evaluates the
+rules on every frame, with the previous frame available to trend rules, and
+reports when each finding first appeared, peaked and cleared.
+
+Stop early with Ctrl-C; the frames collected so far are kept.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := time.ParseDuration(since)
+			if err != nil {
+				return fmt.Errorf("invalid --since value: %w", err)
+			}
+			opts.Since = d
+			if interval <= 0 {
+				return fmt.Errorf("invalid --interval value: must be positive")
+			}
+			if duration < 0 {
+				return fmt.Errorf("invalid --duration value: must not be negative")
+			}
+
+			post, err := pp.load()
+			if err != nil {
+				return err
+			}
+			cfg, err := config.load()
+			if err != nil {
+				return err
+			}
+			opts.SystemPodSelectors = cfg.SystemPodSelectors
+			_, target, err := kube.restConfig()
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+
+			start := time.Now()
+			rec, err := snapshot.CreateRecording(opts.Output, snapshot.RecordingHeader{
+				StartedAt:        start.UTC(),
+				Interval:         interval.String(),
+				Context:          target.context,
+				CollectorVersion: version,
+			})
+			if err != nil {
+				return err
+			}
+
+			frames, err := record(ctx, rec, kube, opts, post, interval, start.Add(duration), duration > 0)
+			if cerr := rec.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Recording of %d frame(s) written to %s\n", frames, opts.Output)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "time between frames")
+	cmd.Flags().DurationVar(&duration, "duration", time.Hour, "how long to record (0 = until interrupted)")
+	cmd.Flags().StringVar(&since, "since", "30m", "look-back duration for events in each frame")
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "filter by namespace (empty = all)")
+	cmd.Flags().StringVarP(&opts.Output, "out", "o", opts.Output, "output file path; a .gz or .zst extension compresses the recording")
+	addCollectorFlags(cmd.Flags(), &opts)
+	pp.addFlags(cmd.Flags())
+	config.addFlags(cmd.Flags())
+	kube.addFlags(cmd.Flags())
+	return cmd
+}
+
+// record collects a frame now and then on every tick until end, when bounded,
+// or until ctx is cancelled. A frame interrupted by cancellation is dropped; a
+// frame that fails to collect is skipped with a warning.
+func record(ctx context.Context, rec *snapshot.RecordingWriter, kube *clusterFlags, opts collector.Options, post postProcess, interval time.Duration, end time.Time, bounded bool) (int, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	frames := 0
+	for {
+		snap, err := collectSnapshot(ctx, kube, opts, post, func(err error) {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Warning: frame %d: %v\n", frames+1, err)
+			}
+		})
+		switch {
+		case ctx.Err() != nil:
+			return frames, nil
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: frame %d skipped: %v\n", frames+1, err)
+		default:
+			if err := rec.Add(snap); err != nil {
+				return frames, err
+			}
+			frames++
+			fmt.Fprintf(os.Stderr, "Frame %d collected at %s\n", frames, snap.CollectedAt.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return frames, nil
+		case now := <-ticker.C:
+			if bounded && !now.Before(end) {
+				return frames, nil
+			}
+		}
+	}
+}
+
+// analyzeRecording replays a recording through the default rules. A
+// recording cut short mid-frame is analysed up to the last complete frame.
+func analyzeRecording(path string, a analyzer) (model.Timeline, error) {
+	r, err := snapshot.OpenRecording(path)
+	if err != nil {
+		return model.Timeline{}, err
+	}
+	defer r.Close()
+
+	timeline, err := analysis.Replay(r.Next, a.frame)
+	timeline.Context, timeline.Interval = r.Header.Context, r.Header.Interval
+	if err != nil {
+		if len(timeline.Frames) == 0 {
+			return timeline, fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v; analysed the first %d frame(s)\n", path, err, len(timeline.Frames))
+	}
+	return timeline, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/redact"
+)
+
+const redactKeyEnv = "KUBE_SLOWWHY_REDACT_KEY"
+
+// redaction is a loaded profile and hashing key. A Redactor is not safe for
+// concurrent use, so each snapshot gets its own from newRedactor.
+type redaction struct {
+	profile redact.Profile
+	key     []byte
+}
+
+// newRedactor loads the named or file-based profile and the hashing key and
+// returns a redactor for a single snapshot.
+func newRedactor(profile, keyFile string) (*redact.Redactor, error) {
+	r, err := loadRedaction(profile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return r.newRedactor()
+}
+
+// loadRedaction loads the named or file-based profile and the hashing key. The
+// key comes from keyFile, then $KUBE_SLOWWHY_REDACT_KEY, and is otherwise
+// random, in which case hashed names only correlate between the snapshots of
+// one run.
+func loadRedaction(profile, keyFile string) (*redaction, error) {
+	p, err := redact.LoadProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	switch {
+	case keyFile != "":
+		key, err = redact.LoadKey(keyFile)
+	case os.Getenv(redactKeyEnv) != "":
+		key = []byte(os.Getenv(redactKeyEnv))
+	default:
+		key, err = redact.NewKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := &redaction{profile: p, key: key}
+	if _, err := r.newRedactor(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *redaction) newRedactor() (*redact.Redactor, error) {
+	red, err := redact.New(r.profile, r.key)
+	if err != nil {
+		return nil, fmt.Errorf("redaction profile %s: %w", r.profile.Name, err)
+	}
+	return red, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/render"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+func newReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Work with reports written by analyze -o json",
+	}
+	cmd.AddCommand(newReportDiffCmd())
+	return cmd
+}
+
+func newReportDiffCmd() *cobra.Command {
+	af := &analyzerFlags{}
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "diff BEFORE AFTER",
+		Short: "Show which findings are new, escalated, de-escalated, resolved or unchanged",
+		Long: `Compare the findings of two reports of the same cluster. Findings are matched
+by fingerprint, which is derived from the rule, category and the objects a
+finding is about, so a finding whose ID or wording changed between versions
+still matches. Each argument is a report written by analyze -o json or a
+snapshot, which is analysed first. Findings matched by the suppression file
+are left out of both sides.`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := render.Format(format)
+			if f != render.FormatTable && f != render.FormatJSON {
+				return fmt.Errorf("invalid --output %q: must be table or json", format)
+			}
+
+			a, err := af.load()
+			if err != nil {
+				return err
+			}
+			before, err := loadReport(args[0], a)
+			if err != nil {
+				return err
+			}
+			after, err := loadReport(args[1], a)
+			if err != nil {
+				return err
+			}
+			return render.NewReportDiff(f).RenderReportDiff(cmd.OutOrStdout(), analysis.DiffReports(before, after))
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", string(render.FormatTable), "output format: table or json")
+	af.addFlags(cmd.Flags())
+	return cmd
+}
+
+// loadReport reads a JSON report, or analyses path if it is a snapshot, and
+// applies a's suppressions.
+func loadReport(path string, a analyzer) (model.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return model.Report{}, err
+	}
+	var probe struct {
+		Findings json.RawMessage `json:"findings"`
+	}
+	if json.Unmarshal(data, &probe) == nil && probe.Findings != nil {
+		var report model.Report
+		if err := json.Unmarshal(data, &report); err != nil {
+			return model.Report{}, fmt.Errorf("%s: %w", path, err)
+		}
+		return a.ignore.Apply(report, time.Now()), nil
+	}
+
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		return model.Report{}, err
+	}
+	return a.snapshot(snap), nil
+}
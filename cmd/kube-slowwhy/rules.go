@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/render"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+func newRulesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Inspect the analysis rules and their configuration",
+	}
+	cmd.AddCommand(newRulesListCmd(), newRulesDescribeCmd(), newRulesConfigCmd())
+	return cmd
+}
+
+func newRulesListCmd() *cobra.Command {
+	config := &configFlags{}
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list [SNAPSHOT]",
+		Short: "List the analysis rules",
+		Long: `List every analysis rule with its status under the config file. Given a
+snapshot, also show which rules analyze would skip because a collector they
+require was not enabled, failed or was denied by RBAC.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := render.Format(format)
+			if f != render.FormatTable && f != render.FormatJSON {
+				return fmt.Errorf("invalid --output %q: must be table or json", format)
+			}
+			rules, err := describeRules(config, args)
+			if err != nil {
+				return err
+			}
+			return render.NewRules(f).RenderRules(cmd.OutOrStdout(), rules)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", string(render.FormatTable), "output format: table or json")
+	config.addFlags(cmd.Flags())
+	return cmd
+}
+
+func newRulesDescribeCmd() *cobra.Command {
+	config := &configFlags{}
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "describe RULE [SNAPSHOT]",
+		Short: "Describe one analysis rule and its parameters",
+		Long: `Describe a rule: what it looks for, the collectors it requires and uses, the
+severities it reports, its documentation and its effective parameters under
+the config file. Given a snapshot, also say whether analyze would skip it.`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := render.Format(format)
+			if f != render.FormatTable && f != render.FormatJSON {
+				return fmt.Errorf("invalid --output %q: must be table or json", format)
+			}
+			rules, err := describeRules(config, args[1:])
+			if err != nil {
+				return err
+			}
+			var names []string
+			for _, r := range rules {
+				if r.Name == args[0] {
+					return render.NewRules(f).RenderRule(cmd.OutOrStdout(), r)
+				}
+				names = append(names, r.Name)
+			}
+			return fmt.Errorf("unknown rule %q (known rules: %s)", args[0], strings.Join(names, ", "))
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", string(render.FormatTable), "output format: table or json")
+	config.addFlags(cmd.Flags())
+	return cmd
+}
+
+// describeRules describes the configured rules, against the snapshot in
+// args if there is one.
+func describeRules(config *configFlags, args []string) ([]model.RuleInfo, error) {
+	engine, _, err := config.engine()
+	if err != nil {
+		return nil, err
+	}
+	var snap *collector.Snapshot
+	if len(args) > 0 {
+		if snap, err = snapshot.Load(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	return engine.Rules(snap), nil
+}
+
+func newRulesConfigCmd() *cobra.Command {
+	config := &configFlags{}
+	var defaults bool
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective rule configuration",
+		Long: `Print the configuration the rules run with: the config file, .slowwhy.yaml in
+the current directory unless --config says otherwise, merged over the
+defaults, with every parameter of every rule. With --defaults the config file
+is ignored, so the output is a complete starting point for a new file.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if defaults {
+				config.path = ""
+			}
+			engine, cfg, err := config.engine()
+			if err != nil {
+				return err
+			}
+			effective := engine.Config()
+			effective.SystemPodSelectors = cfg.SystemPodSelectors
+			effective.RuleFiles = cfg.RuleFiles
+			effective.Plugins = cfg.Plugins
+			if len(effective.SystemPodSelectors) == 0 {
+				effective.SystemPodSelectors = collector.DefaultSystemPodSelectors()
+			}
+			data, err := yaml.Marshal(effective)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&defaults, "defaults", false, "print the built-in defaults, ignoring the config file")
+	config.addFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/redact"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Inspect and validate snapshot files",
+	}
+	cmd.AddCommand(newSnapshotValidateCmd(), newSnapshotSchemaCmd(), newSnapshotRedactCmd(),
+		newSnapshotVerifyCmd(), newSnapshotKeygenCmd())
+	return cmd
+}
+
+func newSnapshotValidateCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "validate FILE...",
+		Short: "Check that snapshot files can be read by this version",
+		Long: `Check that each snapshot file has a supported schema version, contains no
+unknown fields and is internally consistent. Older schema versions are
+accepted if they can be migrated. Exits non-zero if any file is invalid.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid --format %q: must be text or json", format)
+			}
+
+			results := make([]snapshot.ValidationResult, 0, len(args))
+			invalid := 0
+			for _, path := range args {
+				result, err := snapshot.ValidateFile(path)
+				if err != nil {
+					return err
+				}
+				if !result.Valid() {
+					invalid++
+				}
+				results = append(results, result)
+			}
+
+			out := cmd.OutOrStdout()
+			if format == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+			} else {
+				for _, r := range results {
+					switch {
+					case !r.Valid():
+						fmt.Fprintf(out, "%s: invalid\n", r.Path)
+						for _, p := range r.Problems {
+							fmt.Fprintf(out, "  - %s\n", p)
+						}
+					case r.Migrated:
+						fmt.Fprintf(out, "%s: ok (schema %s, readable as %s)\n", r.Path, r.SchemaVersion, collector.SnapshotSchemaVersion)
+					default:
+						fmt.Fprintf(out, "%s: ok (schema %s)\n", r.Path, r.SchemaVersion)
+					}
+				}
+			}
+
+			if invalid > 0 {
+				return fmt.Errorf("%d of %d snapshot(s) invalid", invalid, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "text", "output format: text or json")
+	return cmd
+}
+
+func newSnapshotSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for the current snapshot format",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := snapshot.Schema()
+			if err != nil {
+				return fmt.Errorf("generate schema: %w", err)
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+}
+
+func newSnapshotRedactCmd() *cobra.Command {
+	var profile, keyFile string
+
+	cmd := &cobra.Command{
+		Use:   "redact IN OUT",
+		Short: "Write a redacted copy of a snapshot",
+		Long: `Write a copy of the snapshot IN to OUT with names hashed and identifiers
+scrubbed according to a redaction profile. OUT is compressed according to its
+extension, like collect --out.`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := newRedactor(profile, keyFile)
+			if err != nil {
+				return err
+			}
+			snap, err := snapshot.Load(args[0])
+			if err != nil {
+				return err
+			}
+			if err := r.Apply(snap); err != nil {
+				return fmt.Errorf("redact %s: %w", args[0], err)
+			}
+			if err := snapshot.Save(args[1], snap); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Redacted snapshot (profile %s) written to %s\n", snap.Redaction.Profile, args[1])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&profile, "profile", redact.DefaultProfile, "built-in profile (minimal, standard, strict) or profile file")
+	cmd.Flags().StringVar(&keyFile, "redact-key-file", "", "file holding the key used to hash names (default $"+redactKeyEnv+", else a random key)")
+	return cmd
+}
+
+func newSnapshotVerifyCmd() *cobra.Command {
+	var keyFile string
+
+	cmd := &cobra.Command{
+		Use:   "verify FILE...",
+		Short: "Check snapshot signatures and content digests",
+		Long: `Check that each snapshot is signed and unmodified since signing. With --key
+the signature must also come from that public key; without it the key embedded
+in the snapshot is used, which proves integrity but not who signed it.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var trusted ed25519.PublicKey
+			if keyFile != "" {
+				var err error
+				if trusted, err = snapshot.LoadPublicKey(keyFile); err != nil {
+					return err
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			failed := 0
+			for _, path := range args {
+				v, err := snapshot.VerifyFile(path, trusted)
+				if err != nil {
+					fmt.Fprintf(out, "FAILED %v\n", err)
+					failed++
+					continue
+				}
+				sig := v.Signature
+				fmt.Fprintf(out, "OK %s: signed by key %s at %s\n", path, sig.KeyID, sig.SignedAt.Format(time.RFC3339))
+				fmt.Fprintf(out, "   collector %s, cluster %s, context %s\n",
+					orUnknown(sig.CollectorVersion), orUnknown(sig.ClusterID), orUnknown(sig.Context))
+				if !v.Trusted {
+					fmt.Fprintf(out, "   warning: checked against the key embedded in the snapshot; pass --key to require a trusted signer\n")
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d snapshot(s) failed verification", failed, len(args))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFile, "key", "", "trusted ed25519 public key (PEM) the snapshots must be signed with")
+	return cmd
+}
+
+func newSnapshotKeygenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "keygen NAME",
+		Short: "Generate an ed25519 key pair for signing snapshots",
+		Long: `Write NAME.key (private, mode 0600) and NAME.pub. Pass NAME.key to
+collect --sign-key and share NAME.pub with whoever verifies the snapshots.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			privPath, pubPath := args[0]+".key", args[0]+".pub"
+			pub, err := snapshot.GenerateKey(privPath, pubPath)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s and %s (key ID %s)\n", privPath, pubPath, snapshot.PublicKeyID(pub))
+			return nil
+		},
+	}
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/watch"
+)
+
+func newWatchCmd() *cobra.Command {
+	opts := watch.DefaultOptions()
+	kube := newClusterFlags()
+	af := &analyzerFlags{}
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Keep analysing a live cluster and print findings as they change",
+		Long: `Keep a live snapshot of nodes, pods, events, PVCs, PVs and kube-system
+health from watches, re-run the rules a short while after every change, and
+print only findings that are new, escalated or resolved. The first analysis
+prints every current finding. Stop with Ctrl-C.
+
+watch needs list and watch access in addition to collect's permissions; see
+kube-slowwhy rbac --watch.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid --output %q: must be text or json", format)
+			}
+			if opts.Since <= 0 {
+				return fmt.Errorf("invalid --since value: must be positive")
+			}
+
+			a, err := af.load()
+			if err != nil {
+				return err
+			}
+			if len(a.config.SystemPodSelectors) > 0 {
+				opts.SystemPodSelectors = a.config.SystemPodSelectors
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+
+			client, target, err := kube.buildClient()
+			if err != nil {
+				return err
+			}
+			w, err := watch.New(ctx, client, opts, a.snapshot)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Watching %s (events since %s, debounce %s)...\n", orDefault(target.context, target.host), opts.Since, opts.Debounce)
+			out := cmd.OutOrStdout()
+			var writeErr error
+			err = w.Run(ctx, func(u watch.Update) {
+				if writeErr != nil {
+					return
+				}
+				if format == "json" {
+					writeErr = writeUpdateJSON(out, u)
+				} else {
+					writeErr = writeUpdateText(out, u)
+				}
+				if writeErr != nil {
+					cancel()
+				}
+			})
+			if writeErr != nil {
+				return writeErr
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "watch pods and PVCs in this namespace only (empty = all)")
+	cmd.Flags().DurationVar(&opts.Since, "since", opts.Since, "only consider events seen within this window")
+	cmd.Flags().DurationVar(&opts.Debounce, "debounce", opts.Debounce, "wait this long after a change before re-analysing")
+	cmd.Flags().DurationVar(&opts.Resync, "resync", opts.Resync, "re-analyse at least this often so old events age out")
+	cmd.Flags().StringVarP(&format, "output", "o", "text", "output format: text, or json for one change per line")
+	af.addFlags(cmd.Flags())
+	kube.addFlags(cmd.Flags())
+	return cmd
+}
+
+func writeUpdateText(w io.Writer, u watch.Update) error {
+	ts := u.Time.Format("15:04:05")
+	if u.Initial {
+		if _, err := fmt.Fprintf(w, "%s %d finding(s)\n", ts, len(u.Report.Findings)); err != nil {
+			return err
+		}
+	}
+	for _, c := range u.Changes {
+		severity := strings.ToUpper(string(c.Finding.Severity))
+		if c.Type == model.FindingEscalated {
+			severity = strings.ToUpper(string(c.Previous)) + "->" + severity
+		}
+		if _, err := fmt.Fprintf(w, "%s %-9s %-14s %s  %s\n", ts, strings.ToUpper(string(c.Type)), severity, c.Finding.ID, c.Finding.Title); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type watchLine struct {
+	Time time.Time `json:"time"`
+	model.FindingChange
+}
+
+func writeUpdateJSON(w io.Writer, u watch.Update) error {
+	enc := json.NewEncoder(w)
+	for _, c := range u.Changes {
+		if err := enc.Encode(watchLine{Time: u.Time.UTC(), FindingChange: c}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,422 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type APIServerRule struct {
+	params *APIServerParams
+}
+
+func (r *APIServerRule) Name() string { return "apiserver-latency" }
+
+func (r *APIServerRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Slow API requests and etcd operations, and API Priority and Fairness rejections and saturation, from apiserver metrics.",
+		Categories:  []string{"control-plane"},
+		Requires:    []string{"apiserver-metrics"},
+		MinSeverity: model.SeverityLow,
+		MaxSeverity: model.SeverityCritical,
+		DocURL:      docBaseURL + "api-server-latency",
+	}
+}
+
+type APIServerParams struct {
+	// MinSamples is the number of requests a latency series needs before
+	// its p99 is trusted.
+	MinSamples uint64 `json:"minSamples"`
+	// The latency thresholds are p99 values in seconds.
+	SlowRequestSeconds float64 `json:"slowRequestSeconds"`
+	SlowListSeconds    float64 `json:"slowListSeconds"`
+	SlowEtcdSeconds    float64 `json:"slowEtcdSeconds"`
+	// HighRejections is the number of APF rejections that makes the finding
+	// high.
+	HighRejections uint64 `json:"highRejections"`
+}
+
+func DefaultAPIServerParams() APIServerParams {
+	return APIServerParams{
+		MinSamples:         20,
+		SlowRequestSeconds: 1.0,
+		SlowListSeconds:    5.0,
+		SlowEtcdSeconds:    0.5,
+		HighRejections:     100,
+	}
+}
+
+func (p *APIServerParams) Validate() error {
+	return firstError(
+		atLeast("minSamples", p.MinSamples, 1),
+		positive("slowRequestSeconds", p.SlowRequestSeconds),
+		notBelow("slowListSeconds", p.SlowListSeconds, "slowRequestSeconds", p.SlowRequestSeconds),
+		positive("slowEtcdSeconds", p.SlowEtcdSeconds),
+		atLeast("highRejections", p.HighRejections, 1),
+	)
+}
+
+func (r *APIServerRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *APIServerRule) SetParams(p Params) { r.params = p.(*APIServerParams) }
+
+func (r *APIServerRule) current() APIServerParams {
+	if r.params == nil {
+		return DefaultAPIServerParams()
+	}
+	return *r.params
+}
+
+const maxMetricEvidence = 10
+
+var longRunningVerbs = []string{
+	"WATCH",
+	"WATCHLIST",
+	"CONNECT",
+}
+
+var criticalPriorityLevels = []string{
+	"system",
+	"leader-election",
+	"node-high",
+}
+
+type slowSeries struct {
+	ref     string
+	message string
+	data    map[string]string
+	ratio   float64
+}
+
+func (r *APIServerRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	if snap.APIServer == nil {
+		return nil
+	}
+
+	params := r.current()
+	var findings []model.Finding
+	if f, ok := slowRequestsFinding(snap.APIServer, params); ok {
+		findings = append(findings, f)
+	}
+	if f, ok := apfRejectionsFinding(snap.APIServer, params); ok {
+		findings = append(findings, f)
+	}
+	if f, ok := apfSaturationFinding(snap.APIServer); ok {
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func slowRequestsFinding(m *collector.APIServerMetrics, params APIServerParams) (model.Finding, bool) {
+	var slow []slowSeries
+	for _, rl := range m.RequestLatencies {
+		if isLongRunningVerb(rl.Verb) || rl.Latency.Count < params.MinSamples {
+			continue
+		}
+		threshold := params.SlowRequestSeconds
+		if strings.EqualFold(rl.Verb, "LIST") {
+			threshold = params.SlowListSeconds
+		}
+		p99 := histogramQuantile(0.99, rl.Latency)
+		if p99 <= threshold {
+			continue
+		}
+		resource := rl.Resource
+		if rl.Subresource != "" {
+			resource += "/" + rl.Subresource
+		}
+		slow = append(slow, slowSeries{
+			ref:     seriesRef("apiserver_request_duration_seconds", "verb", rl.Verb, "resource", rl.Resource, "subresource", rl.Subresource),
+			message: fmt.Sprintf("p99 latency for %s %s is %s (threshold %s)", rl.Verb, resource, formatSeconds(p99), formatSeconds(threshold)),
+			data: map[string]string{
+				"verb":     rl.Verb,
+				"resource": resource,
+				"p99":      fmt.Sprintf("%.3f", p99),
+				"count":    fmt.Sprintf("%d", rl.Latency.Count),
+			},
+			ratio: p99 / threshold,
+		})
+	}
+
+	var slowEtcd []slowSeries
+	for _, el := range m.EtcdLatencies {
+		if el.Latency.Count < params.MinSamples {
+			continue
+		}
+		p99 := histogramQuantile(0.99, el.Latency)
+		if p99 <= params.SlowEtcdSeconds {
+			continue
+		}
+		slowEtcd = append(slowEtcd, slowSeries{
+			ref:     seriesRef("etcd_request_duration_seconds", "operation", el.Operation, "type", el.Type),
+			message: fmt.Sprintf("etcd p99 latency for %s %s is %s", el.Operation, el.Type, formatSeconds(p99)),
+			data: map[string]string{
+				"operation": el.Operation,
+				"type":      el.Type,
+				"p99":       fmt.Sprintf("%.3f", p99),
+				"count":     fmt.Sprintf("%d", el.Latency.Count),
+			},
+			ratio: p99 / params.SlowEtcdSeconds,
+		})
+	}
+
+	if len(slow) == 0 && len(slowEtcd) == 0 {
+		return model.Finding{}, false
+	}
+
+	sortSlowSeries(slow)
+	sortSlowSeries(slowEtcd)
+
+	evidence := slowSeriesEvidence(slow)
+	evidence = append(evidence, slowSeriesEvidence(slowEtcd)...)
+
+	worst := 0.0
+	for _, s := range slow {
+		if s.ratio > worst {
+			worst = s.ratio
+		}
+	}
+
+	return model.Finding{
+		SchemaVersion: model.SchemaVersion,
+		ID:            "apiserver-slow-requests",
+		Title:         "Slow API server requests",
+		Category:      "control-plane",
+		Severity:      slowRequestSeverity(worst, len(slow), len(slowEtcd)),
+		Confidence:    slowRequestConfidence(len(slow), len(slowEtcd)),
+		Summary:       slowRequestSummary(slow, slowEtcd),
+		Evidence:      evidence,
+		NextSteps:     slowRequestNextSteps(len(slowEtcd) > 0),
+		Timestamp:     time.Now().UTC(),
+	}, true
+}
+
+func apfRejectionsFinding(m *collector.APIServerMetrics, params APIServerParams) (model.Finding, bool) {
+	var total uint64
+	levels := make(map[string]bool)
+	critical := false
+	var evidence []model.Evidence
+
+	for _, rej := range m.FlowControlRejections {
+		if rej.Count == 0 {
+			continue
+		}
+		total += rej.Count
+		levels[rej.PriorityLevel] = true
+		if isCriticalPriorityLevel(rej.PriorityLevel) {
+			critical = true
+		}
+		evidence = append(evidence, model.Evidence{
+			Type:    model.EvidenceMetric,
+			Ref:     seriesRef("apiserver_flowcontrol_rejected_requests_total", "priority_level", rej.PriorityLevel, "flow_schema", rej.FlowSchema, "reason", rej.Reason),
+			Message: fmt.Sprintf("%d request(s) rejected at priority level %s (flow schema %s, reason %s)", rej.Count, rej.PriorityLevel, rej.FlowSchema, rej.Reason),
+			Data: map[string]string{
+				"priorityLevel": rej.PriorityLevel,
+				"flowSchema":    rej.FlowSchema,
+				"reason":        rej.Reason,
+				"count":         fmt.Sprintf("%d", rej.Count),
+			},
+		})
+	}
+
+	if total == 0 {
+		return model.Finding{}, false
+	}
+
+	severity := model.SeverityMedium
+	if critical {
+		severity = model.SeverityCritical
+	} else if total >= params.HighRejections {
+		severity = model.SeverityHigh
+	}
+
+	return model.Finding{
+		SchemaVersion: model.SchemaVersion,
+		ID:            "apiserver-apf-rejections",
+		Title:         "API Priority and Fairness is rejecting requests",
+		Category:      "control-plane",
+		Severity:      severity,
+		Confidence:    0.8,
+		Summary: fmt.Sprintf("%d request(s) rejected by API Priority and Fairness across %d priority level(s) since the apiserver started.",
+			total, len(levels)),
+		Evidence: evidence,
+		NextSteps: []string{
+			"Identify the clients mapped to the rejecting flow schemas",
+			"Check for controllers or scripts issuing request storms",
+			"Review PriorityLevelConfiguration shares and queue lengths",
+			"Scale or tune the apiserver if all priority levels are saturated",
+		},
+		Timestamp: time.Now().UTC(),
+	}, true
+}
+
+func apfSaturationFinding(m *collector.APIServerMetrics) (model.Finding, bool) {
+	var evidence []model.Evidence
+	queued := false
+
+	for _, pl := range m.PriorityLevels {
+		if pl.Name == "exempt" || pl.ConcurrencyLimit <= 0 {
+			continue
+		}
+		if pl.Executing < pl.ConcurrencyLimit && pl.InQueue == 0 {
+			continue
+		}
+		if pl.InQueue > pl.ConcurrencyLimit {
+			queued = true
+		}
+		evidence = append(evidence, model.Evidence{
+			Type:    model.EvidenceMetric,
+			Ref:     seriesRef("apiserver_flowcontrol_current_executing_seats", "priority_level", pl.Name),
+			Message: fmt.Sprintf("Priority level %s is executing %.0f of %.0f seats with %.0f request(s) queued", pl.Name, pl.Executing, pl.ConcurrencyLimit, pl.InQueue),
+			Data: map[string]string{
+				"priorityLevel":    pl.Name,
+				"executing":        fmt.Sprintf("%.0f", pl.Executing),
+				"concurrencyLimit": fmt.Sprintf("%.0f", pl.ConcurrencyLimit),
+				"inQueue":          fmt.Sprintf("%.0f", pl.InQueue),
+			},
+		})
+	}
+
+	if len(evidence) == 0 {
+		return model.Finding{}, false
+	}
+
+	for _, in := range m.InflightRequests {
+		evidence = append(evidence, model.Evidence{
+			Type:    model.EvidenceMetric,
+			Ref:     seriesRef("apiserver_current_inflight_requests", "request_kind", in.RequestKind),
+			Message: fmt.Sprintf("%.0f %s request(s) in flight", in.Current, in.RequestKind),
+		})
+	}
+
+	severity := model.SeverityMedium
+	if queued {
+		severity = model.SeverityHigh
+	}
+
+	return model.Finding{
+		SchemaVersion: model.SchemaVersion,
+		ID:            "apiserver-apf-saturation",
+		Title:         "API server priority levels are saturated",
+		Category:      "control-plane",
+		Severity:      severity,
+		Confidence:    0.6,
+		Summary:       "One or more API Priority and Fairness priority levels are at their concurrency limit; requests in these levels are queued and will be slow.",
+		Evidence:      evidence,
+		NextSteps: []string{
+			"Check which flow schemas map to the saturated priority levels",
+			"Look for clients issuing expensive LIST calls without pagination",
+			"Consider raising nominalConcurrencyShares for the affected level",
+		},
+		Timestamp: time.Now().UTC(),
+	}, true
+}
+
+func isLongRunningVerb(verb string) bool {
+	for _, v := range longRunningVerbs {
+		if strings.EqualFold(verb, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCriticalPriorityLevel(name string) bool {
+	for _, pl := range criticalPriorityLevels {
+		if name == pl {
+			return true
+		}
+	}
+	return false
+}
+
+func sortSlowSeries(s []slowSeries) {
+	sort.SliceStable(s, func(i, j int) bool {
+		if s[i].ratio != s[j].ratio {
+			return s[i].ratio > s[j].ratio
+		}
+		return s[i].ref < s[j].ref
+	})
+}
+
+func slowSeriesEvidence(series []slowSeries) []model.Evidence {
+	if len(series) > maxMetricEvidence {
+		series = series[:maxMetricEvidence]
+	}
+	evidence := make([]model.Evidence, 0, len(series))
+	for _, s := range series {
+		evidence = append(evidence, model.Evidence{
+			Type:    model.EvidenceMetric,
+			Ref:     s.ref,
+			Message: s.message,
+			Data:    s.data,
+		})
+	}
+	return evidence
+}
+
+func slowRequestSeverity(worstRatio float64, slowCount, etcdCount int) model.Severity {
+	if worstRatio >= 10 {
+		return model.SeverityCritical
+	}
+	if worstRatio >= 4 || slowCount >= 5 {
+		return model.SeverityHigh
+	}
+	if slowCount > 0 {
+		return model.SeverityMedium
+	}
+	if etcdCount > 0 {
+		return model.SeverityMedium
+	}
+	return model.SeverityLow
+}
+
+func slowRequestConfidence(slowCount, etcdCount int) float64 {
+	base := 0.6
+	if slowCount > 0 && etcdCount > 0 {
+		base += 0.15
+	}
+	if slowCount >= 3 {
+		base += 0.10
+	}
+	if base > 1.0 {
+		base = 1.0
+	}
+	return base
+}
+
+func slowRequestSummary(slow, slowEtcd []slowSeries) string {
+	var parts []string
+	if len(slow) > 0 {
+		parts = append(parts, fmt.Sprintf("%d verb/resource combination(s) exceed the latency threshold; worst: %s.", len(slow), slow[0].message))
+	}
+	if len(slowEtcd) > 0 {
+		parts = append(parts, fmt.Sprintf("%d etcd operation(s) are slow, which points at storage-bound latency.", len(slowEtcd)))
+	}
+	parts = append(parts, "Latencies are cumulative since the apiserver started.")
+	return strings.Join(parts, " ")
+}
+
+func slowRequestNextSteps(etcdSlow bool) []string {
+	steps := []string{
+		"Check apiserver CPU and memory usage",
+		"Find clients issuing expensive LIST calls using audit logs or apiserver_request_total",
+		"Prefer informers and paginated LIST calls in controllers",
+	}
+	if etcdSlow {
+		steps = append(steps,
+			"Check etcd disk latency (etcd_disk_wal_fsync_duration_seconds)",
+			"Defragment etcd and review its database size",
+		)
+	}
+	return steps
+}
@@ -0,0 +1,205 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func latencyHistogram(count uint64, bounds []float64, cumulative []uint64) collector.Histogram {
+	h := collector.Histogram{Count: count}
+	for i, b := range bounds {
+		h.Buckets = append(h.Buckets, collector.HistogramBucket{UpperBound: b, Count: cumulative[i]})
+	}
+	return h
+}
+
+func TestAPIServerRule_NoMetrics(t *testing.T) {
+	rule := &APIServerRule{}
+	findings := rule.Evaluate(&collector.Snapshot{})
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d", len(findings))
+	}
+}
+
+func TestAPIServerRule_HealthyLatency(t *testing.T) {
+	snap := &collector.Snapshot{
+		APIServer: &collector.APIServerMetrics{
+			RequestLatencies: []collector.RequestLatency{
+				{
+					Verb: "GET", Resource: "pods",
+					Latency: latencyHistogram(1000, []float64{0.1, 0.5, 1}, []uint64{995, 1000, 1000}),
+				},
+			},
+		},
+	}
+
+	rule := &APIServerRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d", len(findings))
+	}
+}
+
+func TestAPIServerRule_SlowVerbs(t *testing.T) {
+	snap := &collector.Snapshot{
+		APIServer: &collector.APIServerMetrics{
+			RequestLatencies: []collector.RequestLatency{
+				{
+					Verb: "LIST", Resource: "pods",
+					Latency: latencyHistogram(100, []float64{1, 5, 10, 30}, []uint64{50, 80, 90, 100}),
+				},
+				{
+					Verb: "WATCH", Resource: "pods",
+					Latency: latencyHistogram(100, []float64{1, 5, 10, 30}, []uint64{0, 0, 0, 0}),
+				},
+				{
+					Verb: "POST", Resource: "events",
+					Latency: latencyHistogram(5, []float64{1, 5}, []uint64{0, 5}),
+				},
+			},
+			EtcdLatencies: []collector.EtcdLatency{
+				{
+					Operation: "list", Type: "/registry/pods",
+					Latency: latencyHistogram(100, []float64{0.1, 1, 2}, []uint64{10, 50, 100}),
+				},
+			},
+		},
+	}
+
+	rule := &APIServerRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+
+	f := findings[0]
+	if f.ID != "apiserver-slow-requests" {
+		t.Errorf("id: got %q, want %q", f.ID, "apiserver-slow-requests")
+	}
+	if f.Category != "control-plane" {
+		t.Errorf("category: got %q, want %q", f.Category, "control-plane")
+	}
+	if f.Severity != model.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityHigh)
+	}
+	if len(f.Evidence) != 2 {
+		t.Fatalf("evidence count: got %d, want 2 (LIST pods + etcd)", len(f.Evidence))
+	}
+	for _, e := range f.Evidence {
+		if e.Type != model.EvidenceMetric {
+			t.Errorf("evidence type: got %q, want %q", e.Type, model.EvidenceMetric)
+		}
+	}
+	want := `apiserver_request_duration_seconds{verb="LIST",resource="pods"}`
+	if f.Evidence[0].Ref != want {
+		t.Errorf("evidence ref: got %q, want %q", f.Evidence[0].Ref, want)
+	}
+}
+
+func TestAPIServerRule_APFRejections(t *testing.T) {
+	snap := &collector.Snapshot{
+		APIServer: &collector.APIServerMetrics{
+			FlowControlRejections: []collector.FlowControlRejection{
+				{PriorityLevel: "workload-low", FlowSchema: "service-accounts", Reason: "queue-full", Count: 150},
+				{PriorityLevel: "global-default", FlowSchema: "global-default", Reason: "time-out", Count: 0},
+			},
+		},
+	}
+
+	rule := &APIServerRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.ID != "apiserver-apf-rejections" {
+		t.Errorf("id: got %q, want %q", f.ID, "apiserver-apf-rejections")
+	}
+	if f.Severity != model.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityHigh)
+	}
+	if len(f.Evidence) != 1 {
+		t.Errorf("evidence count: got %d, want 1", len(f.Evidence))
+	}
+}
+
+func TestAPIServerRule_CriticalPriorityLevelRejections(t *testing.T) {
+	snap := &collector.Snapshot{
+		APIServer: &collector.APIServerMetrics{
+			FlowControlRejections: []collector.FlowControlRejection{
+				{PriorityLevel: "leader-election", FlowSchema: "kube-controller-manager", Reason: "time-out", Count: 2},
+			},
+		},
+	}
+
+	rule := &APIServerRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].Severity != model.SeverityCritical {
+		t.Errorf("severity: got %q, want %q", findings[0].Severity, model.SeverityCritical)
+	}
+}
+
+func TestAPIServerRule_SaturatedPriorityLevel(t *testing.T) {
+	snap := &collector.Snapshot{
+		APIServer: &collector.APIServerMetrics{
+			PriorityLevels: []collector.PriorityLevelInfo{
+				{Name: "exempt", Executing: 50, ConcurrencyLimit: 0},
+				{Name: "global-default", Executing: 3, ConcurrencyLimit: 49},
+				{Name: "workload-low", Executing: 245, InQueue: 300, ConcurrencyLimit: 245},
+			},
+			InflightRequests: []collector.InflightRequests{
+				{RequestKind: "readOnly", Current: 260},
+			},
+		},
+	}
+
+	rule := &APIServerRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.ID != "apiserver-apf-saturation" {
+		t.Errorf("id: got %q, want %q", f.ID, "apiserver-apf-saturation")
+	}
+	if f.Severity != model.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityHigh)
+	}
+	if len(f.Evidence) != 2 {
+		t.Errorf("evidence count: got %d, want 2 (priority level + inflight)", len(f.Evidence))
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := latencyHistogram(100, []float64{1, 2, 4}, []uint64{50, 90, 100})
+
+	if got := histogramQuantile(0.5, h); got != 1 {
+		t.Errorf("p50: got %f, want 1", got)
+	}
+	if got := histogramQuantile(0.7, h); got != 1.5 {
+		t.Errorf("p70: got %f, want 1.5", got)
+	}
+	if got := histogramQuantile(0.99, h); got < 3.5 || got > 4 {
+		t.Errorf("p99: got %f, want between 3.5 and 4", got)
+	}
+}
+
+func TestAPIServerRule_Name(t *testing.T) {
+	rule := &APIServerRule{}
+	if rule.Name() != "apiserver-latency" {
+		t.Errorf("name: got %q, want %q", rule.Name(), "apiserver-latency")
+	}
+}
+
+var _ Rule = (*APIServerRule)(nil)
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// DiffReports classifies the findings of two reports of the same cluster as
+// new, escalated, de-escalated, resolved or unchanged, matching them by
+// fingerprint. Findings from reports written before fingerprints existed are
+// fingerprinted here.
+func DiffReports(before, after model.Report) model.ReportDiff {
+	d := model.ReportDiff{
+		SchemaVersion: model.SchemaVersion,
+		Before:        before.Snapshot,
+		After:         after.Snapshot,
+		Findings:      diffFindings(before.Findings, after.Findings),
+	}
+	if b, a := before.Snapshot, after.Snapshot; b != nil && a != nil {
+		if b.ClusterID != "" && a.ClusterID != "" && b.ClusterID != a.ClusterID {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("reports are from different clusters (%s and %s)", b.ClusterID, a.ClusterID))
+		}
+		if a.CollectedAt.Before(b.CollectedAt) {
+			d.Warnings = append(d.Warnings, "the after report was produced from an earlier snapshot than the before report")
+		}
+	}
+	return d
+}
+
+// Changes lists the findings that appeared, got more severe or disappeared
+// between two analyses. Findings that only lost severity or changed wording
+// are not reported.
+func Changes(prev, cur []model.Finding) []model.FindingChange {
+	var changes []model.FindingChange
+	for _, c := range diffFindings(prev, cur) {
+		switch c.Type {
+		case model.FindingNew, model.FindingEscalated, model.FindingResolved:
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func diffFindings(prev, cur []model.Finding) []model.FindingChange {
+	before := byFingerprint(prev)
+	after := byFingerprint(cur)
+
+	changes := []model.FindingChange{}
+	for fp, f := range after {
+		old, ok := before[fp]
+		switch {
+		case !ok:
+			changes = append(changes, model.FindingChange{Type: model.FindingNew, Finding: f})
+		case severityRank(f.Severity) > severityRank(old.Severity):
+			changes = append(changes, model.FindingChange{Type: model.FindingEscalated, Previous: old.Severity, Finding: f})
+		case severityRank(f.Severity) < severityRank(old.Severity):
+			changes = append(changes, model.FindingChange{Type: model.FindingDeescalated, Previous: old.Severity, Finding: f})
+		default:
+			changes = append(changes, model.FindingChange{Type: model.FindingUnchanged, Finding: f})
+		}
+	}
+	for fp, f := range before {
+		if _, ok := after[fp]; !ok {
+			changes = append(changes, model.FindingChange{Type: model.FindingResolved, Previous: f.Severity, Finding: f})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		ri, rj := severityRank(changes[i].Finding.Severity), severityRank(changes[j].Finding.Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		if changes[i].Finding.ID != changes[j].Finding.ID {
+			return changes[i].Finding.ID < changes[j].Finding.ID
+		}
+		return changes[i].Finding.Fingerprint < changes[j].Finding.Fingerprint
+	})
+	return changes
+}
+
+// byFingerprint indexes findings by fingerprint. Uncorrelated findings can
+// share one, in which case the most severe is kept.
+func byFingerprint(findings []model.Finding) map[string]model.Finding {
+	m := make(map[string]model.Finding, len(findings))
+	for _, f := range findings {
+		if f.Fingerprint == "" {
+			f.Fingerprint = Fingerprint(f)
+		}
+		if old, ok := m[f.Fingerprint]; !ok || severityRank(f.Severity) > severityRank(old.Severity) {
+			m[f.Fingerprint] = f
+		}
+	}
+	return m
+}
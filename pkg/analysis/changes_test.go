@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func TestChanges(t *testing.T) {
+	dns := model.Finding{ID: "dns-instability", Severity: model.SeverityHigh}
+	storage := model.Finding{ID: "storage-issue", Severity: model.SeverityLow}
+	prev := []model.Finding{dns, storage, nodeFinding("a", model.SeverityMedium), nodeFinding("b", model.SeverityHigh)}
+	cur := []model.Finding{dns, nodeFinding("a", model.SeverityCritical), nodeFinding("b", model.SeverityLow), nodeFinding("c", model.SeverityMedium)}
+
+	changes := Changes(prev, cur)
+
+	want := []struct {
+		typ model.FindingChangeType
+		id  string
+	}{
+		{model.FindingEscalated, "node-pressure-a-memorypressure"},
+		{model.FindingNew, "node-pressure-c-memorypressure"},
+		{model.FindingResolved, "storage-issue"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].Type != w.typ || changes[i].Finding.ID != w.id {
+			t.Errorf("change %d: got %s %s, want %s %s", i, changes[i].Type, changes[i].Finding.ID, w.typ, w.id)
+		}
+	}
+	if changes[0].Previous != model.SeverityMedium {
+		t.Errorf("escalation should record the previous severity, got %q", changes[0].Previous)
+	}
+	if len(Changes(cur, cur)) != 0 {
+		t.Error("identical finding sets should have no changes")
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	dns := model.Finding{ID: "dns-instability", Rule: "dns-instability", Category: "dns", Severity: model.SeverityHigh}
+	// A reworded ID with the same rule, category and objects is the same
+	// finding.
+	renamed := nodeFinding("b", model.SeverityLow)
+	renamed.ID = "node-pressure-b-memorypressure-approaching"
+	before := model.Report{
+		Snapshot: &model.SnapshotInfo{CollectedAt: time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC), ClusterID: "uid-1"},
+		Findings: []model.Finding{dns, nodeFinding("a", model.SeverityMedium), nodeFinding("b", model.SeverityHigh), nodeFinding("d", model.SeverityLow)},
+	}
+	after := model.Report{
+		Snapshot: &model.SnapshotInfo{CollectedAt: time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), ClusterID: "uid-1"},
+		Findings: []model.Finding{dns, nodeFinding("a", model.SeverityCritical), renamed, nodeFinding("c", model.SeverityMedium)},
+	}
+
+	d := DiffReports(before, after)
+
+	if len(d.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", d.Warnings)
+	}
+	want := []struct {
+		typ model.FindingChangeType
+		id  string
+	}{
+		{model.FindingEscalated, "node-pressure-a-memorypressure"},
+		{model.FindingUnchanged, "dns-instability"},
+		{model.FindingNew, "node-pressure-c-memorypressure"},
+		{model.FindingDeescalated, "node-pressure-b-memorypressure-approaching"},
+		{model.FindingResolved, "node-pressure-d-memorypressure"},
+	}
+	if len(d.Findings) != len(want) {
+		t.Fatalf("expected %d findings, got %+v", len(want), d.Findings)
+	}
+	for i, w := range want {
+		if d.Findings[i].Type != w.typ || d.Findings[i].Finding.ID != w.id {
+			t.Errorf("finding %d: got %s %s, want %s %s", i, d.Findings[i].Type, d.Findings[i].Finding.ID, w.typ, w.id)
+		}
+	}
+	if d.Findings[3].Previous != model.SeverityHigh {
+		t.Errorf("de-escalation should record the previous severity, got %q", d.Findings[3].Previous)
+	}
+	if d.Count(model.FindingUnchanged) != 1 {
+		t.Errorf("expected 1 unchanged finding, got %d", d.Count(model.FindingUnchanged))
+	}
+
+	after.Snapshot.ClusterID = "uid-2"
+	if d := DiffReports(before, after); len(d.Warnings) != 1 {
+		t.Errorf("expected a different-cluster warning, got %v", d.Warnings)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	pod := func(uid string) model.Finding {
+		return model.Finding{
+			ID: "restart-trend-default-web-0-app", Rule: "restart-trend", Category: "pod-health",
+			Evidence: []model.Evidence{
+				model.ObjectEvidence(model.EvidenceResource, model.PodRef("default", "web-0", uid), "restarted", nil),
+				{Type: model.EvidenceMetric, Ref: "restarts", Message: "3 restarts"},
+			},
+		}
+	}
+	a, b := pod("u1"), pod("u2")
+	b.ID = "something-else"
+	b.Evidence = append(b.Evidence, model.Evidence{Type: model.EvidenceResource, Ref: "Pod/default/web-0"})
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Error("fingerprint should ignore the ID, UIDs, duplicate refs and non-resource evidence")
+	}
+
+	other := pod("u1")
+	other.Rule = "cpu-throttling"
+	if Fingerprint(a) == Fingerprint(other) {
+		t.Error("findings from different rules should not share a fingerprint")
+	}
+
+	slow := model.Finding{ID: "apiserver-slow-requests", Rule: "apiserver-latency", Category: "control-plane"}
+	rejected := model.Finding{ID: "apiserver-apf-rejections", Rule: "apiserver-latency", Category: "control-plane"}
+	if Fingerprint(slow) == Fingerprint(rejected) {
+		t.Error("findings without resource evidence should fall back to their ID")
+	}
+}
+
+func TestFingerprint_NodePressureConditions(t *testing.T) {
+	node := collector.NodeInfo{
+		Name: "worker-5",
+		Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue},
+			{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+		},
+	}
+	fingerprints := func(snap *collector.Snapshot) map[string]string {
+		m := make(map[string]string)
+		for _, f := range (&NodePressureRule{}).Evaluate(snap) {
+			f.Rule = "node-pressure"
+			m[f.ID] = Fingerprint(f)
+		}
+		return m
+	}
+
+	active := fingerprints(&collector.Snapshot{Nodes: []collector.NodeInfo{node}})
+	memory, disk := active["node-pressure-worker-5-memorypressure"], active["node-pressure-worker-5-diskpressure"]
+	if memory == "" || disk == "" || memory == disk {
+		t.Fatalf("conditions of one node should have their own fingerprints: %v", active)
+	}
+
+	node.Conditions = nil
+	node.Capacity = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")}
+	approaching := fingerprints(&collector.Snapshot{
+		Nodes: []collector.NodeInfo{node},
+		KubeletStats: []collector.NodeStats{{
+			NodeName:              "worker-5",
+			MemoryAvailableBytes:  600 << 20,
+			MemoryWorkingSetBytes: 7 << 30,
+		}},
+	})
+	if got := approaching["node-pressure-worker-5-memorypressure-approaching"]; got != memory {
+		t.Errorf("approaching MemoryPressure should match MemoryPressure: %v vs %v", approaching, active)
+	}
+}
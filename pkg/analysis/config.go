@@ -0,0 +1,240 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// DefaultConfigFile is read from the working directory when no file is named.
+const DefaultConfigFile = ".slowwhy.yaml"
+
+// Config tunes the rules for a cluster:
+//
+//	systemPodSelectors:
+//	  - k8s-app in (kube-dns, kube-proxy)
+//	ruleFiles:
+//	  - team-rules.yaml
+//	plugins:
+//	  dirs: [plugins]
+//	  timeout: 10s
+//	rules:
+//	  pending-pods:
+//	    params:
+//	      criticalCount: 20
+//	  cpu-throttling:
+//	    namespaces: [payments, checkout]
+//	  admission-webhooks:
+//	    enabled: false
+type Config struct {
+	// SystemPodSelectors are the label selectors picking the kube-system
+	// pods that are collected and watched. Empty means the built-in list.
+	SystemPodSelectors []string `json:"systemPodSelectors,omitempty"`
+	// RuleFiles are files of declarative rules to load alongside the
+	// built-in ones. Relative paths are relative to the config file.
+	RuleFiles []string              `json:"ruleFiles,omitempty"`
+	Plugins   *PluginConfig         `json:"plugins,omitempty"`
+	Rules     map[string]RuleConfig `json:"rules,omitempty"`
+}
+
+// PluginConfig says where exec plugins are found and limits their runs.
+// Plugins are also looked up on PATH.
+type PluginConfig struct {
+	// Dirs are searched before PATH. Relative paths are relative to the
+	// config file.
+	Dirs      []string           `json:"dirs,omitempty"`
+	Timeout   *metav1.Duration   `json:"timeout,omitempty"`
+	MaxOutput *resource.Quantity `json:"maxOutput,omitempty"`
+}
+
+// RuleConfig configures one rule. Params only needs the parameters that
+// differ from the defaults.
+type RuleConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	// Namespaces limits the rule to the namespaced objects in these
+	// namespaces. Cluster-scoped objects such as nodes are always visible.
+	Namespaces []string        `json:"namespaces,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+}
+
+// LoadConfig reads the config file at path. Rule names and params are
+// checked by Engine.Configure.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config file: %w", err)
+	}
+	return parseConfig(data, path)
+}
+
+func parseConfig(data []byte, source string) (Config, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse config file %s: %w", source, err)
+	}
+	if err := collector.ValidateSystemPodSelectors(cfg.SystemPodSelectors); err != nil {
+		return Config{}, fmt.Errorf("config file %s: %w", source, err)
+	}
+	relativeTo(cfg.RuleFiles, source)
+	if p := cfg.Plugins; p != nil {
+		relativeTo(p.Dirs, source)
+		if p.Timeout != nil && p.Timeout.Duration <= 0 {
+			return Config{}, fmt.Errorf("config file %s: plugins.timeout must be greater than 0, got %s", source, p.Timeout.Duration)
+		}
+		if p.MaxOutput != nil && p.MaxOutput.Sign() <= 0 {
+			return Config{}, fmt.Errorf("config file %s: plugins.maxOutput must be greater than 0, got %s", source, p.MaxOutput)
+		}
+	}
+	return cfg, nil
+}
+
+// relativeTo makes the relative paths in paths relative to the directory of
+// the file source.
+func relativeTo(paths []string, source string) {
+	for i, p := range paths {
+		if !filepath.IsAbs(p) {
+			paths[i] = filepath.Join(filepath.Dir(source), p)
+		}
+	}
+}
+
+type ruleSettings struct {
+	disabled   bool
+	namespaces []string
+}
+
+// Configure applies cfg to the engine's rules. Rules not named in cfg keep
+// their current settings.
+func (e *Engine) Configure(cfg Config) error {
+	byName := make(map[string]Rule, len(e.rules))
+	for _, r := range e.rules {
+		byName[r.Name()] = r
+	}
+	names := make([]string, 0, len(cfg.Rules))
+	for name := range cfg.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rc := cfg.Rules[name]
+		r, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown rule %q (known rules: %s)", name, strings.Join(e.ruleNames(), ", "))
+		}
+		for _, ns := range rc.Namespaces {
+			if strings.TrimSpace(ns) == "" {
+				return fmt.Errorf("rule %s: empty namespace", name)
+			}
+		}
+		if len(rc.Params) > 0 && string(rc.Params) != "null" {
+			cr, ok := r.(ConfigurableRule)
+			if !ok {
+				return fmt.Errorf("rule %s has no params", name)
+			}
+			p := cr.Params()
+			dec := json.NewDecoder(bytes.NewReader(rc.Params))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(p); err != nil {
+				return fmt.Errorf("rule %s: params: %w", name, err)
+			}
+			if err := p.Validate(); err != nil {
+				return fmt.Errorf("rule %s: params: %w", name, err)
+			}
+			cr.SetParams(p)
+		}
+
+		if e.settings == nil {
+			e.settings = make(map[string]ruleSettings)
+		}
+		s := e.settings[name]
+		if rc.Enabled != nil {
+			s.disabled = !*rc.Enabled
+		}
+		if rc.Namespaces != nil {
+			s.namespaces = rc.Namespaces
+		}
+		e.settings[name] = s
+	}
+	return nil
+}
+
+// Config returns the effective configuration of every rule, with all of its
+// parameters.
+func (e *Engine) Config() Config {
+	cfg := Config{Rules: make(map[string]RuleConfig, len(e.rules))}
+	for _, r := range e.rules {
+		s := e.settings[r.Name()]
+		enabled := !s.disabled
+		rc := RuleConfig{Enabled: &enabled, Namespaces: s.namespaces}
+		if cr, ok := r.(ConfigurableRule); ok {
+			data, err := json.Marshal(cr.Params())
+			if err == nil {
+				rc.Params = data
+			}
+		}
+		cfg.Rules[r.Name()] = rc
+	}
+	return cfg
+}
+
+func (e *Engine) ruleNames() []string {
+	names := make([]string, 0, len(e.rules))
+	for _, r := range e.rules {
+		names = append(names, r.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scopeSnapshot returns a copy of snap holding only the namespaced objects
+// and per-pod stats in namespaces. Events about cluster-scoped objects are
+// kept.
+func scopeSnapshot(snap *collector.Snapshot, namespaces []string) *collector.Snapshot {
+	if snap == nil || len(namespaces) == 0 {
+		return snap
+	}
+	in := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		in[ns] = true
+	}
+
+	scoped := *snap
+	scoped.ScopedNamespaces = namespaces
+	scoped.Pods = filter(snap.Pods, func(p collector.PodInfo) bool { return in[p.Namespace] })
+	scoped.KubeSystem.Pods = filter(snap.KubeSystem.Pods, func(p collector.PodInfo) bool { return in[p.Namespace] })
+	scoped.Events = filter(snap.Events, func(e collector.EventInfo) bool {
+		return e.InvolvedObject.Namespace == "" || in[e.InvolvedObject.Namespace]
+	})
+	scoped.PVCs = filter(snap.PVCs, func(p collector.PVCInfo) bool { return in[p.Namespace] })
+	scoped.Endpoints = filter(snap.Endpoints, func(e collector.ServiceEndpointsInfo) bool { return in[e.Namespace] })
+	scoped.ContainerCPU = filter(snap.ContainerCPU, func(c collector.ContainerCPUStats) bool { return in[c.Namespace] })
+	scoped.Logs = filter(snap.Logs, func(l collector.ContainerLog) bool { return in[l.Namespace] })
+	// Node stats stay, as nodes do, but only list the pods in namespaces.
+	scoped.KubeletStats = make([]collector.NodeStats, len(snap.KubeletStats))
+	for i, ns := range snap.KubeletStats {
+		ns.Pods = filter(ns.Pods, func(p collector.PodStats) bool { return in[p.Namespace] })
+		scoped.KubeletStats[i] = ns
+	}
+	return &scoped
+}
+
+func filter[T any](items []T, keep func(T) bool) []T {
+	var kept []T
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func unschedulable(ns, name string) collector.PodInfo {
+	return collector.PodInfo{
+		Name: name, Namespace: ns, Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable",
+			Message: "0/3 nodes are available: 3 Insufficient cpu.",
+		}},
+	}
+}
+
+func pendingSnapshot() *collector.Snapshot {
+	snap := &collector.Snapshot{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		snap.Pods = append(snap.Pods, unschedulable("shop", name))
+	}
+	snap.Pods = append(snap.Pods, unschedulable("batch", "f"))
+	return snap
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+systemPodSelectors: ["k8s-app in (kube-dns, kube-proxy)"]
+rules:
+  pending-pods:
+    namespaces: [shop]
+    params:
+      criticalCount: 10
+  node-pressure:
+    enabled: false
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.SystemPodSelectors) != 1 || len(cfg.Rules) != 2 {
+		t.Fatalf("got %+v", cfg)
+	}
+	if rc := cfg.Rules["node-pressure"]; rc.Enabled == nil || *rc.Enabled {
+		t.Errorf("node-pressure: got %+v", rc)
+	}
+
+	cfg, err = parseConfig([]byte("ruleFiles: [team.yaml, /etc/rules.yaml]\nplugins:\n  dirs: [plugins]\n  timeout: 10s\n  maxOutput: 1Mi\n"), "conf/.slowwhy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RuleFiles[0] != "conf/team.yaml" || cfg.RuleFiles[1] != "/etc/rules.yaml" || cfg.Plugins.Dirs[0] != "conf/plugins" {
+		t.Errorf("paths should be relative to the config file: got %+v %+v", cfg.RuleFiles, cfg.Plugins.Dirs)
+	}
+	if cfg.Plugins.Timeout.Duration != 10*time.Second || cfg.Plugins.MaxOutput.Value() != 1<<20 {
+		t.Errorf("plugins: got %+v", cfg.Plugins)
+	}
+
+	for _, bad := range []string{
+		"rule:\n  pending-pods: {}\n",
+		"systemPodSelectors: ['k8s-app in (']\n",
+		"rules:\n  pending-pods:\n    disabled: true\n",
+		"plugins:\n  timeout: 0s\n",
+		"plugins:\n  maxOutput: -1Mi\n",
+	} {
+		if _, err := parseConfig([]byte(bad), "test"); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestEngineConfigure(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+rules:
+  pending-pods:
+    params:
+      criticalCount: 10
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := DefaultEngine()
+	if err := e.Configure(cfg); err != nil {
+		t.Fatal(err)
+	}
+	report := e.Analyze(pendingSnapshot())
+	if len(report.Findings) != 1 || report.Findings[0].Severity != model.SeverityHigh {
+		t.Fatalf("expected one high finding below criticalCount, got %+v", report.Findings)
+	}
+
+	var params PendingPodsParams
+	if err := json.Unmarshal(e.Config().Rules["pending-pods"].Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params != (PendingPodsParams{CriticalCount: 10, HighCount: 2}) {
+		t.Errorf("effective params: got %+v", params)
+	}
+}
+
+func TestEngineConfigure_Errors(t *testing.T) {
+	for name, rules := range map[string]string{
+		"unknown rule":  `{"no-such-rule": {}}`,
+		"unknown param": `{"pending-pods": {"params": {"criticalCuont": 3}}}`,
+		"invalid param": `{"pending-pods": {"params": {"criticalCount": 1, "highCount": 2}}}`,
+		"wrong type":    `{"dns-instability": {"params": {"restartThreshold": "3"}}}`,
+		"empty ns":      `{"dns-instability": {"namespaces": [""]}}`,
+	} {
+		var cfg Config
+		if err := json.Unmarshal([]byte(`{"rules": `+rules+`}`), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		err := DefaultEngine().Configure(cfg)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if name == "unknown rule" && !strings.Contains(err.Error(), "pending-pods") {
+			t.Errorf("%s: error should list the known rules, got %v", name, err)
+		}
+	}
+}
+
+func TestEngineConfigure_DisabledAndScoped(t *testing.T) {
+	disabled := false
+	e := DefaultEngine()
+	if err := e.Configure(Config{Rules: map[string]RuleConfig{
+		"pending-pods": {Enabled: &disabled},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if report := e.Analyze(pendingSnapshot()); len(report.Findings) != 0 {
+		t.Errorf("expected no findings from a disabled rule, got %+v", report.Findings)
+	}
+
+	e = DefaultEngine()
+	if err := e.Configure(Config{Rules: map[string]RuleConfig{
+		"pending-pods": {Namespaces: []string{"batch"}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	report := e.Analyze(pendingSnapshot())
+	if len(report.Findings) != 1 || report.Findings[0].Severity != model.SeverityMedium {
+		t.Fatalf("expected one medium finding for the batch pod, got %+v", report.Findings)
+	}
+	for _, ev := range report.Findings[0].Evidence {
+		if ref, ok := ev.ObjectRef(); ok && ref.Namespace == "shop" {
+			t.Errorf("evidence from outside the rule's namespaces: %+v", ev)
+		}
+	}
+}
+
+func TestEngineConfigure_ScopedKubeletStats(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{{Name: "w1", Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue},
+		}}},
+		KubeletStats: []collector.NodeStats{{NodeName: "w1", Pods: []collector.PodStats{
+			{Namespace: "shop", Name: "cart-0", MemoryWorkingSetBytes: 2 << 30},
+			{Namespace: "batch", Name: "etl-0", MemoryWorkingSetBytes: 6 << 30},
+		}}},
+	}
+	e := DefaultEngine()
+	if err := e.Configure(Config{Rules: map[string]RuleConfig{
+		"node-pressure": {Namespaces: []string{"shop"}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	report := e.Analyze(snap)
+	if len(report.Findings) != 1 {
+		t.Fatalf("expected the MemoryPressure finding, got %+v", report.Findings)
+	}
+	var pods []string
+	for _, ev := range report.Findings[0].Evidence {
+		if ref, ok := ev.ObjectRef(); ok && ref.Kind == "Pod" {
+			pods = append(pods, ref.Namespace+"/"+ref.Name)
+		}
+	}
+	if len(pods) != 1 || pods[0] != "shop/cart-0" {
+		t.Errorf("expected only the shop pod as a top consumer, got %v", pods)
+	}
+	if len(snap.KubeletStats[0].Pods) != 2 {
+		t.Error("scoping modified the snapshot")
+	}
+}
+
+func TestDefaultParamsAreValid(t *testing.T) {
+	for _, r := range DefaultEngine().rules {
+		cr, ok := r.(ConfigurableRule)
+		if !ok {
+			continue
+		}
+		if err := cr.Params().Validate(); err != nil {
+			t.Errorf("%s: %v", r.Name(), err)
+		}
+	}
+}
@@ -25,17 +25,28 @@ func (c *Correlator) Correlate(findings []model.Finding) []model.Finding {
 	return merged
 }
 
+// rootCauseKey groups findings about the same objects. Findings with no
+// resource evidence, such as API server metrics, have nothing to agree on and
+// only merge with findings of the same ID.
 func rootCauseKey(f model.Finding) string {
-	parts := []string{f.Category}
-	refs := make(map[string]bool)
-	for _, e := range f.Evidence {
-		if e.Type == model.EvidenceResource && !refs[e.Ref] {
-			refs[e.Ref] = true
-			parts = append(parts, e.Ref)
+	refs := resourceRefs(f)
+	if len(refs) == 0 {
+		return f.Category + "|id:" + f.ID
+	}
+	return strings.Join(append([]string{f.Category}, refs...), "|")
+}
+
+// evidenceRefKey returns the canonical form of the object an evidence item
+// points at, so "Pod/ns/x" from an older plugin and "pod/ns/x" merge. Parts
+// of one object, such as the webhooks of a configuration, stay apart.
+func evidenceRefKey(e model.Evidence) string {
+	if ref, ok := e.ObjectRef(); ok {
+		if ref.FieldPath != "" {
+			return ref.String() + "#" + ref.FieldPath
 		}
+		return ref.String()
 	}
-	sort.Strings(parts[1:])
-	return strings.Join(parts, "|")
+	return e.Ref
 }
 
 func mergeFindings(findings []model.Finding) []model.Finding {
@@ -79,10 +90,10 @@ func mergePair(primary, secondary model.Finding) model.Finding {
 
 	existingRefs := make(map[string]bool)
 	for _, e := range primary.Evidence {
-		existingRefs[string(e.Type)+":"+e.Ref+":"+e.Message] = true
+		existingRefs[string(e.Type)+":"+evidenceRefKey(e)+":"+e.Message] = true
 	}
 	for _, e := range secondary.Evidence {
-		key := string(e.Type) + ":" + e.Ref + ":" + e.Message
+		key := string(e.Type) + ":" + evidenceRefKey(e) + ":" + e.Message
 		if !existingRefs[key] {
 			primary.Evidence = append(primary.Evidence, e)
 			existingRefs[key] = true
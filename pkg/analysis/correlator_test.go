@@ -312,3 +312,58 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+func TestCorrelator_MergesEquivalentRefs(t *testing.T) {
+	c := NewCorrelator()
+	pod := model.PodRef("default", "web-0", "uid-1")
+	input := []model.Finding{
+		{
+			ID: "a", Category: "pod-health", Severity: model.SeverityMedium, Confidence: 0.6,
+			Evidence: []model.Evidence{
+				model.ObjectEvidence(model.EvidenceResource, pod, "crashloop", nil),
+			},
+			Timestamp: testTS,
+		},
+		{
+			ID: "b", Category: "pod-health", Severity: model.SeverityHigh, Confidence: 0.7,
+			Evidence: []model.Evidence{
+				{Type: model.EvidenceResource, Ref: "Pod/default/web-0", Message: "crashloop"},
+			},
+			Timestamp: testTS,
+		},
+	}
+
+	result := c.Correlate(input)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 merged finding, got %d", len(result))
+	}
+	if len(result[0].Evidence) != 1 {
+		t.Errorf("expected duplicate evidence to collapse, got %d items", len(result[0].Evidence))
+	}
+}
+
+func TestCorrelator_FindingsWithoutResourcesNotMerged(t *testing.T) {
+	c := NewCorrelator()
+	input := []model.Finding{
+		{
+			ID: "apiserver-slow-requests", Category: "control-plane", Severity: model.SeverityHigh, Confidence: 0.7,
+			Title:     "Slow API server requests",
+			Evidence:  []model.Evidence{{Type: model.EvidenceMetric, Ref: "apiserver_request_duration_seconds", Message: "LIST pods p99 6s"}},
+			Timestamp: testTS,
+		},
+		{
+			ID: "apiserver-apf-rejections", Category: "control-plane", Severity: model.SeverityCritical, Confidence: 0.8,
+			Title:     "API Priority and Fairness rejecting requests",
+			Evidence:  []model.Evidence{{Type: model.EvidenceMetric, Ref: "apiserver_flowcontrol_rejected_requests_total", Message: "workload-low rejected 40"}},
+			Timestamp: testTS,
+		},
+	}
+
+	result := c.Correlate(input)
+	if len(result) != 2 {
+		t.Fatalf("expected both apiserver findings, got %+v", result)
+	}
+	if result[0].ID != "apiserver-apf-rejections" || result[1].ID != "apiserver-slow-requests" {
+		t.Errorf("got %s and %s", result[0].ID, result[1].ID)
+	}
+}
@@ -0,0 +1,277 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type CPUThrottlingRule struct {
+	params *CPUThrottlingParams
+}
+
+func (r *CPUThrottlingRule) Name() string { return "cpu-throttling" }
+
+func (r *CPUThrottlingRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Containers throttled by their CPU limit in a large share of CFS periods, with a limit recommendation.",
+		Categories:  []string{"resource-pressure"},
+		Requires:    []string{"cadvisor-metrics"},
+		Uses:        []string{"pods", "kube-system"},
+		MinSeverity: model.SeverityMedium,
+		MaxSeverity: model.SeverityHigh,
+		DocURL:      docBaseURL + "cpu-throttling",
+	}
+}
+
+type CPUThrottlingParams struct {
+	// MinPeriods is the number of CFS periods a container must have run
+	// before its throttle ratio is trusted.
+	MinPeriods uint64 `json:"minPeriods"`
+	// ThrottleRatio is the fraction of throttled periods that is reported,
+	// and HighThrottleRatio the one that makes the finding high.
+	ThrottleRatio     float64 `json:"throttleRatio"`
+	HighThrottleRatio float64 `json:"highThrottleRatio"`
+	// LowCPULimit is the CPU limit at or below which the limit is called
+	// out as the likely cause.
+	LowCPULimit resource.Quantity `json:"lowCPULimit"`
+	// LatencySensitiveKeywords mark a container as latency sensitive, and
+	// its throttling as high, when found in its pod or container name.
+	LatencySensitiveKeywords []string `json:"latencySensitiveKeywords"`
+}
+
+func DefaultCPUThrottlingParams() CPUThrottlingParams {
+	return CPUThrottlingParams{
+		MinPeriods:        100,
+		ThrottleRatio:     0.25,
+		HighThrottleRatio: 0.50,
+		LowCPULimit:       resource.MustParse("500m"),
+		LatencySensitiveKeywords: []string{
+			"api", "web", "frontend", "gateway", "ingress", "proxy", "envoy", "nginx", "haproxy", "dns", "grpc", "http",
+		},
+	}
+}
+
+func (p *CPUThrottlingParams) Validate() error {
+	return firstError(
+		atLeast("minPeriods", p.MinPeriods, 1),
+		fraction("throttleRatio", p.ThrottleRatio),
+		fraction("highThrottleRatio", p.HighThrottleRatio),
+		notBelow("highThrottleRatio", p.HighThrottleRatio, "throttleRatio", p.ThrottleRatio),
+		positive("lowCPULimit", float64(p.LowCPULimit.MilliValue())),
+	)
+}
+
+func (r *CPUThrottlingRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *CPUThrottlingRule) SetParams(p Params) { r.params = p.(*CPUThrottlingParams) }
+
+func (r *CPUThrottlingRule) current() CPUThrottlingParams {
+	if r.params == nil {
+		return DefaultCPUThrottlingParams()
+	}
+	return *r.params
+}
+
+func (r *CPUThrottlingRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	params := r.current()
+	var findings []model.Finding
+
+	stats := make([]collector.ContainerCPUStats, len(snap.ContainerCPU))
+	copy(stats, snap.ContainerCPU)
+	sort.SliceStable(stats, func(i, j int) bool {
+		return throttleRatio(stats[i]) > throttleRatio(stats[j])
+	})
+
+	for _, st := range stats {
+		if st.Periods < params.MinPeriods {
+			continue
+		}
+		ratio := throttleRatio(st)
+		if ratio < params.ThrottleRatio {
+			continue
+		}
+
+		pod, container := findContainer(snap, st.Namespace, st.Pod, st.Container)
+		limit, hasLimit := int64(0), false
+		if container != nil {
+			limit, hasLimit = quantityValue(container.Resources.Limits, corev1.ResourceCPU)
+		}
+		lowLimit := hasLimit && limit <= params.LowCPULimit.MilliValue()
+		latencySensitive := isLatencySensitive(st, pod, params)
+
+		evidence := []model.Evidence{
+			{
+				Type: model.EvidenceMetric,
+				Ref: seriesRef("container_cpu_cfs_throttled_periods_total",
+					"namespace", st.Namespace, "pod", st.Pod, "container", st.Container),
+				Message: fmt.Sprintf("CPU throttle ratio at %.0f%% (%d of %d CFS periods throttled)",
+					ratio*100, st.ThrottledPeriods, st.Periods),
+				Data: map[string]string{
+					"node":             st.NodeName,
+					"ratio":            fmt.Sprintf("%.3f", ratio),
+					"throttledSeconds": fmt.Sprintf("%.1f", st.ThrottledSeconds),
+				},
+			},
+		}
+		if hasLimit {
+			msg := fmt.Sprintf("Container %s has a CPU limit of %s", st.Container, formatResource(corev1.ResourceCPU, limit))
+			if lowLimit {
+				msg += fmt.Sprintf(" (at or below %s)", formatResource(corev1.ResourceCPU, params.LowCPULimit.MilliValue()))
+			}
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				model.PodRef(st.Namespace, st.Pod, podUID(pod)),
+				msg,
+				map[string]string{
+					"container": st.Container,
+					"cpuLimit":  formatResource(corev1.ResourceCPU, limit),
+				},
+			))
+		}
+		if container != nil {
+			if usage, ok := quantityValue(container.Usage, corev1.ResourceCPU); ok && hasLimit {
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceMetric,
+					model.PodRef(st.Namespace, st.Pod, podUID(pod)),
+					fmt.Sprintf("Container %s uses %s of its %s CPU limit", st.Container, formatResource(corev1.ResourceCPU, usage), formatResource(corev1.ResourceCPU, limit)),
+					nil,
+				))
+			}
+		}
+
+		findings = append(findings, model.Finding{
+			SchemaVersion: model.SchemaVersion,
+			ID:            fmt.Sprintf("cpu-throttling-%s-%s-%s", st.Namespace, st.Pod, st.Container),
+			Title:         "CPU Throttling Detected",
+			Category:      "resource-pressure",
+			Severity:      throttleSeverity(ratio, latencySensitive, params),
+			Confidence:    throttleConfidence(st, hasLimit, lowLimit, params),
+			Summary:       throttleSummary(st, ratio, latencySensitive),
+			Evidence:      evidence,
+			NextSteps:     throttleNextSteps(ratio, limit, hasLimit, latencySensitive),
+			Timestamp:     time.Now().UTC(),
+		})
+	}
+
+	return findings
+}
+
+func throttleRatio(st collector.ContainerCPUStats) float64 {
+	if st.Periods == 0 {
+		return 0
+	}
+	return float64(st.ThrottledPeriods) / float64(st.Periods)
+}
+
+func findContainer(snap *collector.Snapshot, namespace, podName, containerName string) (*collector.PodInfo, *collector.ContainerInfo) {
+	for _, pods := range [][]collector.PodInfo{snap.Pods, snap.KubeSystem.Pods} {
+		for i := range pods {
+			p := &pods[i]
+			if p.Namespace != namespace || p.Name != podName {
+				continue
+			}
+			for j := range p.Containers {
+				if p.Containers[j].Name == containerName {
+					return p, &p.Containers[j]
+				}
+			}
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func podUID(pod *collector.PodInfo) string {
+	if pod == nil {
+		return ""
+	}
+	return pod.UID
+}
+
+func isLatencySensitive(st collector.ContainerCPUStats, pod *collector.PodInfo, params CPUThrottlingParams) bool {
+	if st.Namespace == "kube-system" {
+		return true
+	}
+	if pod != nil && pod.QOSClass == corev1.PodQOSGuaranteed {
+		return true
+	}
+	name := strings.ToLower(st.Pod + " " + st.Container)
+	for _, kw := range params.LatencySensitiveKeywords {
+		if strings.Contains(name, strings.ToLower(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+func throttleSeverity(ratio float64, latencySensitive bool, params CPUThrottlingParams) model.Severity {
+	if ratio >= params.HighThrottleRatio || latencySensitive {
+		return model.SeverityHigh
+	}
+	return model.SeverityMedium
+}
+
+func throttleConfidence(st collector.ContainerCPUStats, hasLimit, lowLimit bool, params CPUThrottlingParams) float64 {
+	base := 0.6
+	if st.Periods >= 10*params.MinPeriods {
+		base += 0.1
+	}
+	if hasLimit {
+		base += 0.1
+	}
+	if lowLimit {
+		base += 0.1
+	}
+	if base > 1.0 {
+		base = 1.0
+	}
+	return base
+}
+
+func throttleSummary(st collector.ContainerCPUStats, ratio float64, latencySensitive bool) string {
+	s := fmt.Sprintf("Container %s in pod %s/%s is being CPU throttled in %.0f%% of scheduling periods.",
+		st.Container, st.Namespace, st.Pod, ratio*100)
+	if latencySensitive {
+		s += " The workload looks latency-sensitive, so throttling shows up directly as tail latency."
+	}
+	return s
+}
+
+// recommendedCPULimit sizes a limit so that the observed demand would have fit,
+// rounded up to the next 100m.
+func recommendedCPULimit(limit int64, ratio float64) int64 {
+	if ratio >= 0.9 {
+		ratio = 0.9
+	}
+	want := float64(limit) / (1 - ratio)
+	return int64(math.Ceil(want/100-1e-9) * 100)
+}
+
+func throttleNextSteps(ratio float64, limit int64, hasLimit, latencySensitive bool) []string {
+	var steps []string
+	if hasLimit {
+		steps = append(steps, fmt.Sprintf("Increase the CPU limit from %s to at least %s",
+			formatResource(corev1.ResourceCPU, limit), formatResource(corev1.ResourceCPU, recommendedCPULimit(limit, ratio))))
+	} else {
+		steps = append(steps, "Check the CPU limit set on the container or its LimitRange defaults")
+	}
+	if latencySensitive {
+		steps = append(steps, "Consider removing the CPU limit and relying on requests for latency-sensitive workloads")
+	}
+	steps = append(steps, "Check whether the application's thread pool or GOMAXPROCS exceeds the CPU limit")
+	return steps
+}
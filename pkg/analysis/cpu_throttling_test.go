@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func limitedPod(ns, name, container, cpuLimit string) collector.PodInfo {
+	return collector.PodInfo{
+		Namespace: ns,
+		Name:      name,
+		Phase:     corev1.PodRunning,
+		QOSClass:  corev1.PodQOSBurstable,
+		Containers: []collector.ContainerInfo{
+			{
+				Name: container,
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpuLimit)},
+				},
+			},
+		},
+	}
+}
+
+func TestCPUThrottlingRule_NoStats(t *testing.T) {
+	rule := &CPUThrottlingRule{}
+	findings := rule.Evaluate(&collector.Snapshot{})
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d", len(findings))
+	}
+}
+
+func TestCPUThrottlingRule_BelowThreshold(t *testing.T) {
+	snap := &collector.Snapshot{
+		ContainerCPU: []collector.ContainerCPUStats{
+			{NodeName: "worker-1", Namespace: "batch", Pod: "report-0", Container: "report", Periods: 1000, ThrottledPeriods: 100},
+			{NodeName: "worker-1", Namespace: "batch", Pod: "report-1", Container: "report", Periods: 20, ThrottledPeriods: 20},
+		},
+	}
+
+	rule := &CPUThrottlingRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d", len(findings))
+	}
+}
+
+func TestCPUThrottlingRule_LatencySensitiveLowLimit(t *testing.T) {
+	snap := &collector.Snapshot{
+		Pods: []collector.PodInfo{limitedPod("default", "frontend-xyz", "web", "200m")},
+		ContainerCPU: []collector.ContainerCPUStats{
+			{NodeName: "worker-1", Namespace: "default", Pod: "frontend-xyz", Container: "web", Periods: 1000, ThrottledPeriods: 450},
+		},
+	}
+
+	rule := &CPUThrottlingRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.ID != "cpu-throttling-default-frontend-xyz-web" {
+		t.Errorf("id: got %q", f.ID)
+	}
+	if f.Title != "CPU Throttling Detected" {
+		t.Errorf("title: got %q", f.Title)
+	}
+	if f.Severity != model.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityHigh)
+	}
+	if f.Confidence < 0.89 {
+		t.Errorf("confidence: got %.2f, want >= 0.9", f.Confidence)
+	}
+	if len(f.Evidence) != 2 || f.Evidence[0].Type != model.EvidenceMetric || f.Evidence[1].Type != model.EvidenceResource {
+		t.Errorf("expected metric + resource evidence, got %+v", f.Evidence)
+	}
+	if !strings.Contains(f.NextSteps[0], "from 200m to at least 400m") {
+		t.Errorf("limit recommendation: got %q", f.NextSteps[0])
+	}
+}
+
+func TestCPUThrottlingRule_BatchWorkload(t *testing.T) {
+	snap := &collector.Snapshot{
+		Pods: []collector.PodInfo{limitedPod("batch", "report-0", "report", "2")},
+		ContainerCPU: []collector.ContainerCPUStats{
+			{NodeName: "worker-1", Namespace: "batch", Pod: "report-0", Container: "report", Periods: 500, ThrottledPeriods: 150},
+		},
+	}
+
+	rule := &CPUThrottlingRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].Severity != model.SeverityMedium {
+		t.Errorf("severity: got %q, want %q", findings[0].Severity, model.SeverityMedium)
+	}
+}
+
+func TestRecommendedCPULimit(t *testing.T) {
+	tests := []struct {
+		limit int64
+		ratio float64
+		want  int64
+	}{
+		{200, 0.45, 400},
+		{1000, 0.25, 1400},
+		{500, 0.99, 5000},
+	}
+	for _, tt := range tests {
+		if got := recommendedCPULimit(tt.limit, tt.ratio); got != tt.want {
+			t.Errorf("recommendedCPULimit(%d, %.2f) = %d, want %d", tt.limit, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestCPUThrottlingRule_Name(t *testing.T) {
+	rule := &CPUThrottlingRule{}
+	if rule.Name() != "cpu-throttling" {
+		t.Errorf("name: got %q, want %q", rule.Name(), "cpu-throttling")
+	}
+}
+
+var _ Rule = (*CPUThrottlingRule)(nil)
@@ -11,14 +11,52 @@ import (
 	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
-type DNSRule struct{}
+type DNSRule struct {
+	params *DNSParams
+}
 
 func (r *DNSRule) Name() string { return "dns-instability" }
 
-const (
-	maxLogLineLen    = 256
-	restartThreshold = 3
-)
+func (r *DNSRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "CoreDNS crashloops and restarts, and DNS failures in events and CoreDNS logs.",
+		Categories:  []string{"dns"},
+		Uses:        []string{"kube-system", "pods", "events", "logs"},
+		MinSeverity: model.SeverityLow,
+		MaxSeverity: model.SeverityCritical,
+		DocURL:      docBaseURL + "dns-instability",
+	}
+}
+
+type DNSParams struct {
+	// RestartThreshold is the restart count from which a CoreDNS container
+	// counts as unstable.
+	RestartThreshold int32 `json:"restartThreshold"`
+}
+
+func DefaultDNSParams() DNSParams {
+	return DNSParams{RestartThreshold: 3}
+}
+
+func (p *DNSParams) Validate() error {
+	return atLeast("restartThreshold", p.RestartThreshold, 1)
+}
+
+func (r *DNSRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *DNSRule) SetParams(p Params) { r.params = p.(*DNSParams) }
+
+func (r *DNSRule) current() DNSParams {
+	if r.params == nil {
+		return DefaultDNSParams()
+	}
+	return *r.params
+}
 
 var coreDNSLabels = []string{
 	"coredns",
@@ -43,6 +81,7 @@ var dnsEventReasons = []string{
 }
 
 func (r *DNSRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	params := r.current()
 	dnsPods := findCoreDNSPods(snap)
 	if len(dnsPods) == 0 {
 		return nil
@@ -55,52 +94,56 @@ func (r *DNSRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 		for _, c := range pod.Containers {
 			if c.State.Waiting != nil && c.State.Waiting.Reason == "CrashLoopBackOff" {
 				crashlooping++
-				evidence = append(evidence, model.Evidence{
-					Type:    model.EvidenceResource,
-					Ref:     fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name),
-					Message: fmt.Sprintf("Container %s is in CrashLoopBackOff", c.Name),
-					Data: map[string]string{
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceResource,
+					model.PodRef(pod.Namespace, pod.Name, pod.UID),
+					fmt.Sprintf("Container %s is in CrashLoopBackOff", c.Name),
+					map[string]string{
 						"container":    c.Name,
 						"restartCount": fmt.Sprintf("%d", c.RestartCount),
 					},
-				})
-			} else if c.RestartCount >= restartThreshold {
+				))
+			} else if c.RestartCount >= params.RestartThreshold {
 				highRestarts++
-				evidence = append(evidence, model.Evidence{
-					Type:    model.EvidenceResource,
-					Ref:     fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name),
-					Message: fmt.Sprintf("Container %s has %d restarts", c.Name, c.RestartCount),
-					Data: map[string]string{
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceResource,
+					model.PodRef(pod.Namespace, pod.Name, pod.UID),
+					fmt.Sprintf("Container %s has %d restarts", c.Name, c.RestartCount),
+					map[string]string{
 						"container":    c.Name,
 						"restartCount": fmt.Sprintf("%d", c.RestartCount),
 					},
-				})
+				))
 			}
 		}
 
 		if pod.Phase != corev1.PodRunning {
-			evidence = append(evidence, model.Evidence{
-				Type:    model.EvidenceResource,
-				Ref:     fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name),
-				Message: fmt.Sprintf("CoreDNS pod is in %s phase", pod.Phase),
-			})
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				model.PodRef(pod.Namespace, pod.Name, pod.UID),
+				fmt.Sprintf("CoreDNS pod is in %s phase", pod.Phase),
+				nil,
+			))
 		}
 	}
 
 	dnsEvents := findDNSEvents(snap.Events, dnsPods)
 	for _, ev := range dnsEvents {
-		evidence = append(evidence, model.Evidence{
-			Type:    model.EvidenceEvent,
-			Ref:     ev.InvolvedObject,
-			Message: truncate(ev.Message, maxLogLineLen),
-			Data: map[string]string{
+		evidence = append(evidence, model.ObjectEvidence(
+			model.EvidenceEvent,
+			ev.InvolvedObject,
+			collector.TruncateLogLine(ev.Message),
+			map[string]string{
 				"reason": ev.Reason,
 				"count":  fmt.Sprintf("%d", ev.Count),
 			},
-		})
+		))
 	}
 
-	logEvidence := findDNSLogPatternEvidence(snap.Events)
+	logEvidence := findDNSLogPatternEvidence(snap.Events, dnsPods)
+	logEvidence = append(logEvidence, logPatternEvidence(snap.Logs, func(l collector.ContainerLog) bool {
+		return podInSlice(dnsPods, l.Pod, l.Namespace)
+	}, logCategoryDNS, logCategoryCrash)...)
 	evidence = append(evidence, logEvidence...)
 
 	if len(evidence) == 0 {
@@ -118,7 +161,7 @@ func (r *DNSRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 			Category:      "dns",
 			Severity:      severity,
 			Confidence:    confidence,
-			Summary:       dnsSummary(dnsPods, crashlooping, highRestarts, len(dnsEvents)),
+			Summary:       dnsSummary(dnsPods, crashlooping, highRestarts, len(dnsEvents), len(logEvidence)),
 			Evidence:      evidence,
 			NextSteps:     dnsNextSteps(),
 			Timestamp:     time.Now().UTC(),
@@ -166,14 +209,9 @@ func podInSlice(pods []collector.PodInfo, name, ns string) bool {
 }
 
 func findDNSEvents(events []collector.EventInfo, dnsPods []collector.PodInfo) []collector.EventInfo {
-	podRefs := make(map[string]bool)
-	for _, p := range dnsPods {
-		podRefs[fmt.Sprintf("Pod/%s/%s", p.Namespace, p.Name)] = true
-	}
-
 	var matched []collector.EventInfo
 	for _, ev := range events {
-		if !podRefs[ev.InvolvedObject] {
+		if !eventInvolvesAnyPod(ev, dnsPods) {
 			continue
 		}
 		for _, reason := range dnsEventReasons {
@@ -186,23 +224,31 @@ func findDNSEvents(events []collector.EventInfo, dnsPods []collector.PodInfo) []
 	return matched
 }
 
-func findDNSLogPatternEvidence(events []collector.EventInfo) []model.Evidence {
+func eventInvolvesAnyPod(ev collector.EventInfo, pods []collector.PodInfo) bool {
+	for _, p := range pods {
+		if eventInvolvesPod(ev, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func findDNSLogPatternEvidence(events []collector.EventInfo, dnsPods []collector.PodInfo) []model.Evidence {
 	var evidence []model.Evidence
 	for _, ev := range events {
-		if !strings.Contains(strings.ToLower(ev.InvolvedObject), "coredns") &&
-			!strings.Contains(strings.ToLower(ev.InvolvedObject), "kube-dns") {
+		if !eventInvolvesAnyPod(ev, dnsPods) {
 			continue
 		}
 		for _, pattern := range dnsLogPatterns {
 			if strings.Contains(strings.ToLower(ev.Message), strings.ToLower(pattern)) {
-				evidence = append(evidence, model.Evidence{
-					Type:    model.EvidenceLog,
-					Ref:     ev.InvolvedObject,
-					Message: truncate(ev.Message, maxLogLineLen),
-					Data: map[string]string{
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceLog,
+					ev.InvolvedObject,
+					collector.TruncateLogLine(ev.Message),
+					map[string]string{
 						"pattern": pattern,
 					},
-				})
+				))
 				break
 			}
 		}
@@ -210,13 +256,6 @@ func findDNSLogPatternEvidence(events []collector.EventInfo) []model.Evidence {
 	return evidence
 }
 
-func truncate(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
-	return s[:max-3] + "..."
-}
-
 func dnsConfidence(crashlooping, highRestarts, eventCount, logPatterns int) float64 {
 	base := 0.5
 	if crashlooping > 0 {
@@ -250,7 +289,7 @@ func dnsSeverity(crashlooping, highRestarts, totalDNSPods int) model.Severity {
 	return model.SeverityLow
 }
 
-func dnsSummary(pods []collector.PodInfo, crashlooping, highRestarts, eventCount int) string {
+func dnsSummary(pods []collector.PodInfo, crashlooping, highRestarts, eventCount, logLines int) string {
 	parts := []string{fmt.Sprintf("%d CoreDNS pod(s) inspected.", len(pods))}
 	if crashlooping > 0 {
 		parts = append(parts, fmt.Sprintf("%d crashlooping.", crashlooping))
@@ -261,6 +300,9 @@ func dnsSummary(pods []collector.PodInfo, crashlooping, highRestarts, eventCount
 	if eventCount > 0 {
 		parts = append(parts, fmt.Sprintf("%d warning event(s).", eventCount))
 	}
+	if logLines > 0 {
+		parts = append(parts, fmt.Sprintf("%d error pattern(s) in logs.", logLines))
+	}
 	return strings.Join(parts, " ")
 }
 
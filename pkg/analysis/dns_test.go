@@ -88,7 +88,7 @@ func TestDNSRule_CrashLooping(t *testing.T) {
 				Reason:         "BackOff",
 				Message:        "Back-off restarting failed container",
 				Type:           "Warning",
-				InvolvedObject: "Pod/kube-system/coredns-abc123",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "kube-system", Name: "coredns-abc123"},
 				Count:          10,
 			},
 		},
@@ -218,7 +218,7 @@ func TestDNSRule_LogPatterns(t *testing.T) {
 				Reason:         "Unhealthy",
 				Message:        "Liveness probe failed: SERVFAIL for upstream dns query",
 				Type:           "Warning",
-				InvolvedObject: "Pod/kube-system/coredns-abc",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "kube-system", Name: "coredns-abc"},
 				Count:          3,
 			},
 		},
@@ -243,6 +243,62 @@ func TestDNSRule_LogPatterns(t *testing.T) {
 	}
 }
 
+func TestDNSRule_CollectedLogs(t *testing.T) {
+	snap := &collector.Snapshot{
+		KubeSystem: collector.KubeSystemHealth{
+			Pods: []collector.PodInfo{
+				{
+					Name: "coredns-abc", Namespace: "kube-system", Phase: corev1.PodRunning,
+					Containers: []collector.ContainerInfo{
+						{Name: "coredns", Ready: true, RestartCount: 1},
+					},
+				},
+			},
+		},
+		Logs: []collector.ContainerLog{
+			{
+				Namespace: "kube-system", Pod: "coredns-abc", Container: "coredns",
+				Lines: []string{
+					"[INFO] plugin/reload: Running configuration SHA512 = 591cf3",
+					`[ERROR] plugin/errors: 2 example.com. A: read udp 10.244.0.5:53012->10.0.0.2:53: i/o timeout`,
+				},
+			},
+			{
+				Namespace: "kube-system", Pod: "coredns-abc", Container: "coredns", Previous: true,
+				Lines: []string{
+					`[FATAL] plugin/loop: Loop (127.0.0.1:55953 -> :53) detected for zone ".", see https://coredns.io/plugins/loop#troubleshooting`,
+				},
+			},
+			{
+				Namespace: "default", Pod: "web-0", Container: "web",
+				Lines: []string{"panic: runtime error: invalid memory address"},
+			},
+		},
+	}
+
+	rule := &DNSRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding from logs alone, got %d", len(findings))
+	}
+
+	patterns := make(map[string]bool)
+	for _, e := range findings[0].Evidence {
+		if e.Type != model.EvidenceLog {
+			t.Errorf("unexpected evidence: %+v", e)
+			continue
+		}
+		if e.Ref != "pod/kube-system/coredns-abc" {
+			t.Errorf("ref: got %q", e.Ref)
+		}
+		patterns[e.Data["pattern"]] = true
+	}
+	if len(patterns) != 2 || !patterns["upstream-timeout"] || !patterns["forward-loop"] {
+		t.Errorf("patterns: got %v, want upstream-timeout and forward-loop", patterns)
+	}
+}
+
 func TestDNSRule_TruncatesLongMessages(t *testing.T) {
 	longMsg := ""
 	for i := 0; i < 300; i++ {
@@ -267,7 +323,7 @@ func TestDNSRule_TruncatesLongMessages(t *testing.T) {
 				Reason:         "BackOff",
 				Message:        longMsg,
 				Type:           "Warning",
-				InvolvedObject: "Pod/kube-system/coredns-abc",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "kube-system", Name: "coredns-abc"},
 				Count:          1,
 			},
 		},
@@ -281,8 +337,8 @@ func TestDNSRule_TruncatesLongMessages(t *testing.T) {
 	}
 
 	for _, e := range findings[0].Evidence {
-		if len(e.Message) > maxLogLineLen {
-			t.Errorf("message not truncated: len %d > %d", len(e.Message), maxLogLineLen)
+		if len(e.Message) > collector.MaxLogLineLen {
+			t.Errorf("message not truncated: len %d > %d", len(e.Message), collector.MaxLogLineLen)
 		}
 	}
 }
@@ -6,7 +6,8 @@ import (
 )
 
 type Engine struct {
-	rules []Rule
+	rules    []Rule
+	settings map[string]ruleSettings
 }
 
 func NewEngine(rules ...Rule) *Engine {
@@ -19,6 +20,11 @@ func DefaultEngine() *Engine {
 		&PendingPodsRule{},
 		&DNSRule{},
 		&StorageRule{},
+		&APIServerRule{},
+		&WebhookRule{},
+		&ResourceUsageRule{},
+		&CPUThrottlingRule{},
+		&RestartTrendRule{},
 	)
 }
 
@@ -27,9 +33,49 @@ func (e *Engine) Register(r Rule) {
 }
 
 func (e *Engine) Analyze(snap *collector.Snapshot) model.Report {
+	return e.AnalyzeFrame(nil, snap)
+}
+
+// AnalyzeFrame analyses snap with prev, the previous frame of a recording,
+// available to trend rules. prev may be nil.
+func (e *Engine) AnalyzeFrame(prev, snap *collector.Snapshot) model.Report {
 	var findings []model.Finding
+	var skipped []model.SkippedRule
+	var failed []model.RuleError
 	for _, r := range e.rules {
-		findings = append(findings, r.Evaluate(snap)...)
+		s := e.settings[r.Name()]
+		if s.disabled {
+			continue
+		}
+		if reason := skipReason(r, snap); reason != "" {
+			skipped = append(skipped, model.SkippedRule{Rule: r.Name(), Reason: reason})
+			continue
+		}
+		cur := scopeSnapshot(snap, s.namespaces)
+		var found []model.Finding
+		if tr, ok := r.(TrendRule); ok && prev != nil {
+			found = tr.EvaluateTrend(scopeSnapshot(prev, s.namespaces), cur)
+		} else if fr, ok := r.(FallibleRule); ok {
+			var err error
+			if found, err = fr.Run(cur); err != nil {
+				failed = append(failed, model.RuleError{Rule: r.Name(), Error: err.Error()})
+			}
+		} else {
+			found = r.Evaluate(cur)
+		}
+		for _, f := range found {
+			if f.Rule == "" {
+				f.Rule = r.Name()
+			}
+			if f.Fingerprint == "" {
+				f.Fingerprint = Fingerprint(f)
+			}
+			findings = append(findings, f)
+		}
 	}
-	return model.NewReport(findings)
+	report := model.NewReport(findings)
+	report.Snapshot = snapshotInfo(snap)
+	report.SkippedRules = skipped
+	report.RuleErrors = failed
+	return report
 }
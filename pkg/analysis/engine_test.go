@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type fallibleRule struct {
+	name string
+	err  error
+	// partial returns a finding along with err.
+	partial bool
+}
+
+func (r fallibleRule) Name() string { return r.name }
+
+func (r fallibleRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	findings, _ := r.Run(snap)
+	return findings
+}
+
+func (r fallibleRule) Run(*collector.Snapshot) ([]model.Finding, error) {
+	findings := []model.Finding{{ID: r.name + "-finding", Category: "test", Severity: model.SeverityLow}}
+	if r.err != nil && !r.partial {
+		return nil, r.err
+	}
+	return findings, r.err
+}
+
+func TestEngineRecordsRuleErrors(t *testing.T) {
+	e := NewEngine(fallibleRule{name: "works"}, fallibleRule{name: "broken", err: errors.New("timed out after 30s")})
+	report := e.Analyze(&collector.Snapshot{})
+
+	if len(report.Findings) != 1 || report.Findings[0].Rule != "works" {
+		t.Errorf("findings: got %+v", report.Findings)
+	}
+	want := []model.RuleError{{Rule: "broken", Error: "timed out after 30s"}}
+	if len(report.RuleErrors) != 1 || report.RuleErrors[0] != want[0] {
+		t.Errorf("rule errors: got %+v, want %+v", report.RuleErrors, want)
+	}
+}
+
+func TestEngineKeepsFindingsOfPartlyFailedRules(t *testing.T) {
+	e := NewEngine(fallibleRule{name: "partial", err: errors.New("pod/default/web-0: match: no such key"), partial: true})
+	report := e.Analyze(&collector.Snapshot{})
+
+	if len(report.Findings) != 1 || report.Findings[0].ID != "partial-finding" {
+		t.Errorf("findings: got %+v", report.Findings)
+	}
+	if len(report.RuleErrors) != 1 || report.RuleErrors[0].Rule != "partial" {
+		t.Errorf("rule errors: got %+v", report.RuleErrors)
+	}
+}
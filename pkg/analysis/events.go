@@ -0,0 +1,48 @@
+package analysis
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// eventInvolves reports whether ev is about the given object. The UID is
+// authoritative when both sides carry one, so events about an earlier object
+// with the same name are not attributed to its replacement.
+func eventInvolves(ev collector.EventInfo, obj model.ObjectRef) bool {
+	return ev.InvolvedObject.Matches(obj)
+}
+
+func eventInvolvesPod(ev collector.EventInfo, pod collector.PodInfo) bool {
+	return eventInvolves(ev, model.PodRef(pod.Namespace, pod.Name, pod.UID))
+}
+
+// mentionsName reports whether s contains name as a whole token, so that
+// "worker-1" does not match "worker-10".
+func mentionsName(s, name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; ; {
+		idx := strings.Index(s[i:], name)
+		if idx < 0 {
+			return false
+		}
+		start := i + idx
+		end := start + len(name)
+		if !isNameChar(s, start-1) && !isNameChar(s, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isNameChar(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	r := rune(s[i])
+	return r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func TestEventInvolves(t *testing.T) {
+	pod := collector.PodInfo{Namespace: "default", Name: "web-0", UID: "uid-new"}
+
+	tests := []struct {
+		name string
+		ev   collector.EventInfo
+		want bool
+	}{
+		{"same uid", collector.EventInfo{InvolvedObject: model.PodRef("default", "web-0", "uid-new")}, true},
+		{"previous incarnation", collector.EventInfo{InvolvedObject: model.PodRef("default", "web-0", "uid-old")}, false},
+		{"no uid falls back to ref", collector.EventInfo{InvolvedObject: model.PodRef("default", "web-0", "")}, true},
+		{"prefix of another pod", collector.EventInfo{InvolvedObject: model.PodRef("default", "web-01", "")}, false},
+	}
+	for _, tt := range tests {
+		if got := eventInvolvesPod(tt.ev, pod); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMentionsName(t *testing.T) {
+	tests := []struct {
+		s, name string
+		want    bool
+	}{
+		{"The node worker-1 was low on resource: memory.", "worker-1", true},
+		{"The node worker-10 was low on resource: memory.", "worker-1", false},
+		{"evicted from worker-1", "worker-1", true},
+		{"my-worker-1 is fine, worker-1.", "worker-1", true},
+		{"anything", "", false},
+	}
+	for _, tt := range tests {
+		if got := mentionsName(tt.s, tt.name); got != tt.want {
+			t.Errorf("mentionsName(%q, %q) = %v, want %v", tt.s, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// Fingerprint identifies a finding across runs by its rule, category and the
+// canonical refs of the objects it is about, so it survives ID and wording
+// changes and a pod being recreated with a new UID. Findings with no resource
+// evidence, such as API server metrics, fall back to their ID. The condition
+// a finding reports on its object keeps, for example, a node's MemoryPressure
+// and DiskPressure apart, while approaching a condition and reaching it match.
+func Fingerprint(f model.Finding) string {
+	parts := append([]string{f.Rule, f.Category}, resourceRefs(f)...)
+	if len(parts) == 2 {
+		parts = append(parts, f.ID)
+	}
+	parts = append(parts, resourceConditions(f)...)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// resourceRefs returns the sorted, distinct canonical refs of f's resource
+// evidence.
+func resourceRefs(f model.Finding) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, e := range f.Evidence {
+		if e.Type != model.EvidenceResource {
+			continue
+		}
+		if ref := evidenceRefKey(e); !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// resourceConditions returns the sorted, distinct conditions named by f's
+// resource evidence.
+func resourceConditions(f model.Finding) []string {
+	var conds []string
+	seen := make(map[string]bool)
+	for _, e := range f.Evidence {
+		if e.Type != model.EvidenceResource {
+			continue
+		}
+		if c := e.Data["condition"]; c != "" && !seen[c] {
+			seen[c] = true
+			conds = append(conds, "condition:"+c)
+		}
+	}
+	sort.Strings(conds)
+	return conds
+}
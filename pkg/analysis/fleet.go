@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// ClusterReport is the analysis of one cluster in a fleet. Err is set, and
+// Report is ignored, when the cluster could not be collected or analysed.
+type ClusterReport struct {
+	Context string
+	Report  model.Report
+	Err     error
+}
+
+// Fleet combines per-cluster reports, grouping findings of the same kind and
+// classifying each group as common to all clusters, shared by several, or
+// unique to one. Groups are ordered by how many clusters they affect, then by
+// severity.
+func Fleet(clusters []ClusterReport) model.FleetReport {
+	fleet := model.FleetReport{
+		SchemaVersion: model.SchemaVersion,
+		Clusters:      make([]model.FleetCluster, 0, len(clusters)),
+	}
+
+	groups := make(map[string]*model.FleetFinding)
+	var order []string
+	analysed := 0
+
+	for _, c := range clusters {
+		fc := model.FleetCluster{Context: c.Context}
+		if c.Err != nil {
+			fc.Error = c.Err.Error()
+			fleet.Clusters = append(fleet.Clusters, fc)
+			continue
+		}
+		analysed++
+		fc.Snapshot = c.Report.Snapshot
+		fc.Findings = len(c.Report.Findings)
+		fleet.Clusters = append(fleet.Clusters, fc)
+
+		for _, f := range c.Report.Findings {
+			key := fleetKey(f)
+			g, ok := groups[key]
+			if !ok {
+				g = &model.FleetFinding{Key: key, Rule: f.Rule, Category: f.Category, Title: f.Title}
+				groups[key] = g
+				order = append(order, key)
+			}
+			if severityRank(f.Severity) > severityRank(g.Severity) {
+				g.Severity = f.Severity
+			}
+
+			n := len(g.Clusters)
+			if n == 0 || g.Clusters[n-1].Context != c.Context {
+				g.Clusters = append(g.Clusters, model.FleetOccurrence{Context: c.Context})
+				n++
+			}
+			occ := &g.Clusters[n-1]
+			occ.IDs = append(occ.IDs, f.ID)
+			if severityRank(f.Severity) > severityRank(occ.Severity) {
+				occ.Severity = f.Severity
+			}
+		}
+	}
+
+	fleet.Findings = make([]model.FleetFinding, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		switch {
+		case len(g.Clusters) == analysed:
+			g.Scope = model.FleetScopeCommon
+		case len(g.Clusters) > 1:
+			g.Scope = model.FleetScopeShared
+		default:
+			g.Scope = model.FleetScopeUnique
+		}
+		fleet.Findings = append(fleet.Findings, *g)
+	}
+	sort.SliceStable(fleet.Findings, func(i, j int) bool {
+		a, b := fleet.Findings[i], fleet.Findings[j]
+		if len(a.Clusters) != len(b.Clusters) {
+			return len(a.Clusters) > len(b.Clusters)
+		}
+		if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		return a.Key < b.Key
+	})
+	return fleet
+}
+
+// fleetKey is the finding's ID with the names and namespaces of the objects
+// in its evidence removed, so the same problem on differently named objects
+// in different clusters produces the same key. IDs that do not start with the
+// rule name are qualified by it.
+func fleetKey(f model.Finding) string {
+	var names []string
+	for _, e := range f.Evidence {
+		if e.Object == nil {
+			continue
+		}
+		for _, n := range []string{e.Object.Name, e.Object.Namespace} {
+			if n != "" {
+				names = append(names, n)
+			}
+		}
+	}
+	// Longer names first, so "web-0" is not left behind as "-0" after "web".
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	// Names are only removed as whole dash-separated segments.
+	id := "-" + f.ID + "-"
+	for _, n := range names {
+		seg := "-" + n + "-"
+		for strings.Contains(id, seg) {
+			id = strings.ReplaceAll(id, seg, "-")
+		}
+	}
+	id = strings.Trim(id, "-")
+	if f.Rule == "" || strings.HasPrefix(id, f.Rule) {
+		return id
+	}
+	return f.Rule + "/" + id
+}
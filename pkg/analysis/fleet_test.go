@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func nodeFinding(node string, sev model.Severity) model.Finding {
+	return model.Finding{
+		ID:       "node-pressure-" + node + "-memorypressure",
+		Rule:     "node-pressure",
+		Category: "node-health",
+		Title:    "Node " + node + " has MemoryPressure",
+		Severity: sev,
+		Evidence: []model.Evidence{model.ObjectEvidence(model.EvidenceResource, model.NodeRef(node, ""), "MemoryPressure", nil)},
+	}
+}
+
+func TestFleet(t *testing.T) {
+	dns := model.Finding{ID: "dns-instability", Rule: "dns-instability", Category: "dns", Title: "DNS instability", Severity: model.SeverityHigh}
+	clusters := []ClusterReport{
+		{Context: "eu-1", Report: model.NewReport([]model.Finding{dns, nodeFinding("ip-10-0-1-2", model.SeverityMedium), nodeFinding("a", model.SeverityCritical)})},
+		{Context: "eu-2", Report: model.NewReport([]model.Finding{dns, nodeFinding("worker-7", model.SeverityHigh)})},
+		{Context: "us-1", Report: model.NewReport([]model.Finding{dns, {ID: "storage-issue", Rule: "storage-issues", Category: "storage", Severity: model.SeverityLow}})},
+		{Context: "ap-1", Err: errors.New("dial tcp: i/o timeout")},
+	}
+
+	fleet := Fleet(clusters)
+
+	if len(fleet.Clusters) != 4 || fleet.Clusters[3].Error == "" || fleet.Clusters[0].Findings != 3 {
+		t.Errorf("clusters: got %+v", fleet.Clusters)
+	}
+	if len(fleet.Findings) != 3 {
+		t.Fatalf("expected 3 fleet findings, got %+v", fleet.Findings)
+	}
+
+	common := fleet.Findings[0]
+	if common.Key != "dns-instability" || common.Scope != model.FleetScopeCommon || len(common.Clusters) != 3 {
+		t.Errorf("common finding: got %+v", common)
+	}
+
+	shared := fleet.Findings[1]
+	if shared.Key != "node-pressure-memorypressure" || shared.Scope != model.FleetScopeShared {
+		t.Errorf("shared finding: got %+v", shared)
+	}
+	if shared.Severity != model.SeverityCritical || len(shared.Clusters) != 2 || len(shared.Clusters[0].IDs) != 2 {
+		t.Errorf("shared finding occurrences: got %+v", shared)
+	}
+
+	unique := fleet.Findings[2]
+	if unique.Scope != model.FleetScopeUnique || unique.Clusters[0].Context != "us-1" {
+		t.Errorf("unique finding: got %+v", unique)
+	}
+}
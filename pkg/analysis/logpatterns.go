@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const (
+	maxLogEvidencePerContainer = 3
+	maxLogEvidence             = 10
+)
+
+type logCategory string
+
+const (
+	logCategoryDNS     logCategory = "dns"
+	logCategoryStorage logCategory = "storage"
+	logCategoryNetwork logCategory = "network"
+	logCategoryCrash   logCategory = "crash"
+)
+
+type logPattern struct {
+	name     string
+	category logCategory
+	re       *regexp.Regexp
+}
+
+var logPatterns = []logPattern{
+	{"servfail", logCategoryDNS, regexp.MustCompile(`\bSERVFAIL\b`)},
+	{"upstream-timeout", logCategoryDNS, regexp.MustCompile(`(?i)i/o timeout|read udp .*timeout`)},
+	{"forward-loop", logCategoryDNS, regexp.MustCompile(`plugin/loop: Loop .* detected`)},
+	{"no-healthy-upstream", logCategoryDNS, regexp.MustCompile(`(?i)no healthy upstream|plugin/forward: no (healthy )?upstream`)},
+	{"apiserver-unreachable", logCategoryDNS, regexp.MustCompile(`plugin/kubernetes: .*(connection refused|i/o timeout|Failed to watch)`)},
+
+	{"csi-rpc-error", logCategoryStorage, regexp.MustCompile(`rpc error: code = (DeadlineExceeded|Internal|Unavailable|ResourceExhausted|Aborted)`)},
+	{"attach-failed", logCategoryStorage, regexp.MustCompile(`(?i)(attach|detach)(volume)? (failed|error)|failed to (attach|detach)`)},
+	{"mount-failed", logCategoryStorage, regexp.MustCompile(`(?i)mount(ing)? (failed|error)|failed to mount|NodeStageVolume failed|NodePublishVolume failed`)},
+	{"volume-limit", logCategoryStorage, regexp.MustCompile(`(?i)maximum (number of )?(volumes|attachments)|AttachmentLimitExceeded`)},
+
+	{"ip-exhausted", logCategoryNetwork, regexp.MustCompile(`(?i)no (more )?(free )?ip addresses available|failed to allocate (an )?ip|ipam.*exhausted`)},
+	{"cni-setup-failed", logCategoryNetwork, regexp.MustCompile(`(?i)failed to (setup|set up|add) network|cni plugin not initialized`)},
+
+	{"panic", logCategoryCrash, regexp.MustCompile(`^panic: |\bfatal error: `)},
+	{"out-of-memory", logCategoryCrash, regexp.MustCompile(`(?i)out of memory|OOMKilled|cannot allocate memory`)},
+	{"segfault", logCategoryCrash, regexp.MustCompile(`(?i)segmentation fault|SIGSEGV`)},
+}
+
+// logPatternEvidence turns log lines matching patterns in the given categories
+// into log evidence, for the containers accepted by match.
+func logPatternEvidence(logs []collector.ContainerLog, match func(collector.ContainerLog) bool, categories ...logCategory) []model.Evidence {
+	wanted := make(map[logCategory]bool, len(categories))
+	for _, c := range categories {
+		wanted[c] = true
+	}
+
+	var evidence []model.Evidence
+	for _, l := range logs {
+		if !match(l) {
+			continue
+		}
+		perContainer := 0
+		for _, line := range l.Lines {
+			if perContainer >= maxLogEvidencePerContainer || len(evidence) >= maxLogEvidence {
+				break
+			}
+			p, ok := matchLogLine(line, wanted)
+			if !ok {
+				continue
+			}
+			perContainer++
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceLog,
+				model.PodRef(l.Namespace, l.Pod, ""),
+				collector.TruncateLogLine(line),
+				map[string]string{
+					"container": l.Container,
+					"pattern":   p.name,
+					"previous":  fmt.Sprintf("%t", l.Previous),
+				},
+			))
+		}
+	}
+	return evidence
+}
+
+func matchLogLine(line string, categories map[logCategory]bool) (logPattern, bool) {
+	for _, p := range logPatterns {
+		if categories[p.category] && p.re.MatchString(line) {
+			return p, true
+		}
+	}
+	return logPattern{}, false
+}
@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+func TestMatchLogLine(t *testing.T) {
+	all := map[logCategory]bool{
+		logCategoryDNS: true, logCategoryStorage: true, logCategoryNetwork: true, logCategoryCrash: true,
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`[INFO] 10.244.1.3:41120 - 5 "A IN api.example.com. udp" SERVFAIL qr,rd 42 0.5s`, "servfail"},
+		{`[ERROR] plugin/kubernetes: Failed to watch *v1.Service: connection refused`, "apiserver-unreachable"},
+		{`NodeStageVolume failed for volume vol-123: timed out`, "mount-failed"},
+		{`failed to allocate IP for pod default/web-0: no more free IP addresses available`, "ip-exhausted"},
+		{`panic: assignment to entry in nil map`, "panic"},
+		{`[INFO] plugin/reload: Running configuration`, ""},
+	}
+	for _, tt := range tests {
+		p, ok := matchLogLine(tt.line, all)
+		got := ""
+		if ok {
+			got = p.name
+		}
+		if got != tt.want {
+			t.Errorf("matchLogLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLogPatternEvidence_Caps(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = "panic: " + strings.Repeat("x", 300)
+	}
+	logs := []collector.ContainerLog{
+		{Namespace: "default", Pod: "a", Container: "app", Lines: lines},
+		{Namespace: "default", Pod: "b", Container: "app", Lines: lines},
+		{Namespace: "default", Pod: "c", Container: "app", Lines: lines},
+		{Namespace: "default", Pod: "d", Container: "app", Lines: lines},
+	}
+
+	evidence := logPatternEvidence(logs, func(collector.ContainerLog) bool { return true }, logCategoryCrash)
+
+	if len(evidence) != maxLogEvidence {
+		t.Errorf("evidence: got %d, want %d", len(evidence), maxLogEvidence)
+	}
+	for _, e := range evidence {
+		if len(e.Message) > collector.MaxLogLineLen {
+			t.Errorf("message not truncated: %d chars", len(e.Message))
+		}
+	}
+}
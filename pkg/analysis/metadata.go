@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const docBaseURL = "https://github.com/marek-kar/kube-slowwhy/blob/main/docs/quickstart.md#"
+
+// optInCollectors are off unless enabled with the flag of the same name.
+var optInCollectors = map[string]func(collector.CollectionOptions) bool{
+	"apiserver-metrics": func(o collector.CollectionOptions) bool { return o.APIServerMetrics },
+	"kubelet-stats":     func(o collector.CollectionOptions) bool { return o.KubeletStats },
+	"cadvisor-metrics":  func(o collector.CollectionOptions) bool { return o.CadvisorMetrics },
+	"resource-metrics":  func(o collector.CollectionOptions) bool { return o.ResourceMetrics },
+	"logs":              func(o collector.CollectionOptions) bool { return o.Logs },
+}
+
+// hasData reports whether snap holds anything from the named collector.
+func hasData(snap *collector.Snapshot, name string) bool {
+	switch name {
+	case "nodes":
+		return len(snap.Nodes) > 0
+	case "pods":
+		return len(snap.Pods) > 0
+	case "events":
+		return len(snap.Events) > 0
+	case "pvcs":
+		return len(snap.PVCs) > 0
+	case "pvs":
+		return len(snap.PVs) > 0
+	case "kube-system":
+		return len(snap.KubeSystem.Pods) > 0 || len(snap.KubeSystem.DaemonSets) > 0
+	case "webhooks":
+		return len(snap.Webhooks) > 0
+	case "endpoints":
+		return len(snap.Endpoints) > 0
+	case "apiserver-metrics":
+		return snap.APIServer != nil
+	case "kubelet-stats":
+		return len(snap.KubeletStats) > 0
+	case "cadvisor-metrics":
+		return len(snap.ContainerCPU) > 0
+	case "resource-metrics":
+		for _, n := range snap.Nodes {
+			if len(n.Usage) > 0 {
+				return true
+			}
+		}
+		return false
+	case "logs":
+		return len(snap.Logs) > 0
+	}
+	return false
+}
+
+// missingCollector explains why the named collector's data is absent from
+// snap, or returns "" if it is present or was collected and found nothing.
+// Snapshots written before collection options were recorded only count an
+// opt-in collector as collected if it holds data.
+func missingCollector(snap *collector.Snapshot, name string) string {
+	if hasData(snap, name) {
+		return ""
+	}
+	if enabled, ok := optInCollectors[name]; ok && (snap.Collection == nil || !enabled(snap.Collection.Options)) {
+		return fmt.Sprintf("%s not collected (collect with --%s)", name, name)
+	}
+	if snap.Collection == nil {
+		return ""
+	}
+	for _, d := range snap.Collection.RBACDenials {
+		if d.Collector == name {
+			return fmt.Sprintf("%s denied by RBAC: %s", name, d.Message)
+		}
+	}
+	for _, e := range snap.Collection.Errors {
+		if e.Collector == name {
+			return fmt.Sprintf("%s failed: %s", name, e.Message)
+		}
+	}
+	return ""
+}
+
+// skipReason returns why r cannot run on snap, or "".
+func skipReason(r Rule, snap *collector.Snapshot) string {
+	dr, ok := r.(DescribedRule)
+	if !ok {
+		return ""
+	}
+	for _, name := range dr.Metadata().Requires {
+		if reason := missingCollector(snap, name); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// Rules describes the engine's rules with their configuration. Given a
+// snapshot, it also says which enabled rules would be skipped for it.
+func (e *Engine) Rules(snap *collector.Snapshot) []model.RuleInfo {
+	infos := make([]model.RuleInfo, 0, len(e.rules))
+	for _, r := range e.rules {
+		info := model.RuleInfo{RuleMetadata: model.RuleMetadata{Name: r.Name()}}
+		if dr, ok := r.(DescribedRule); ok {
+			info.RuleMetadata = dr.Metadata()
+		}
+		s := e.settings[r.Name()]
+		info.Enabled = !s.disabled
+		info.Namespaces = s.namespaces
+		if cr, ok := r.(ConfigurableRule); ok {
+			if data, err := json.Marshal(cr.Params()); err == nil {
+				info.Params = data
+			}
+		}
+		if snap != nil && info.Enabled {
+			info.Skipped = skipReason(r, snap)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
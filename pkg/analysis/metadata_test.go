@@ -0,0 +1,106 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+func TestMissingCollector(t *testing.T) {
+	collected := &collector.Snapshot{Collection: &collector.CollectionInfo{
+		Options:     collector.CollectionOptions{CadvisorMetrics: true},
+		RBACDenials: []collector.RBACDenial{{Collector: "webhooks", Message: "forbidden"}},
+		Errors:      []collector.CollectorError{{Collector: "pods", Message: "timeout"}},
+	}}
+
+	for _, tc := range []struct {
+		name      string
+		snap      *collector.Snapshot
+		collector string
+		want      string
+	}{
+		{"opt-in not enabled", collected, "apiserver-metrics", "collect with --apiserver-metrics"},
+		{"opt-in enabled but empty", collected, "cadvisor-metrics", ""},
+		{"denied", collected, "webhooks", "denied by RBAC"},
+		{"failed", collected, "pods", "pods failed: timeout"},
+		{"core collector empty", collected, "nodes", ""},
+		{"old snapshot with data", &collector.Snapshot{APIServer: &collector.APIServerMetrics{}}, "apiserver-metrics", ""},
+		{"old snapshot without data", &collector.Snapshot{}, "logs", "not collected"},
+	} {
+		got := missingCollector(tc.snap, tc.collector)
+		if (tc.want == "") != (got == "") || !strings.Contains(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEngineSkipsRulesWithoutData(t *testing.T) {
+	e := DefaultEngine()
+	report := e.Analyze(pendingSnapshot())
+
+	skipped := make(map[string]bool)
+	for _, s := range report.SkippedRules {
+		skipped[s.Rule] = true
+	}
+	for _, name := range []string{"apiserver-latency", "cpu-throttling", "resource-usage"} {
+		if !skipped[name] {
+			t.Errorf("expected %s to be skipped, got %+v", name, report.SkippedRules)
+		}
+	}
+	if skipped["pending-pods"] || len(report.Findings) == 0 {
+		t.Errorf("pending-pods should run, got skipped %+v and findings %+v", report.SkippedRules, report.Findings)
+	}
+
+	disabled := false
+	if err := e.Configure(Config{Rules: map[string]RuleConfig{"apiserver-latency": {Enabled: &disabled}}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range e.Analyze(pendingSnapshot()).SkippedRules {
+		if s.Rule == "apiserver-latency" {
+			t.Errorf("a disabled rule should not be reported as skipped")
+		}
+	}
+}
+
+func TestEngineRules(t *testing.T) {
+	rules := DefaultEngine().Rules(pendingSnapshot())
+	if len(rules) != len(DefaultEngine().rules) {
+		t.Fatalf("got %d rules", len(rules))
+	}
+	for _, r := range rules {
+		if r.Description == "" || r.Version == "" || len(r.Categories) == 0 || r.MaxSeverity == "" || r.DocURL == "" {
+			t.Errorf("%s: incomplete metadata %+v", r.Name, r.RuleMetadata)
+		}
+		if !r.Enabled || len(r.Params) == 0 {
+			t.Errorf("%s: expected enabled with params, got %+v", r.Name, r)
+		}
+		for _, name := range append(r.Requires, r.Uses...) {
+			if _, ok := optInCollectors[name]; !ok && !hasDataKnown(name) {
+				t.Errorf("%s: unknown collector %q", r.Name, name)
+			}
+		}
+		if (r.Name == "cpu-throttling") != strings.Contains(r.Skipped, "cadvisor-metrics") {
+			t.Errorf("%s: skipped %q", r.Name, r.Skipped)
+		}
+	}
+}
+
+// hasDataKnown reports whether hasData knows the collector, by checking it
+// finds data in a snapshot that has some of everything.
+func hasDataKnown(name string) bool {
+	full := &collector.Snapshot{
+		Nodes:        []collector.NodeInfo{{}},
+		Pods:         []collector.PodInfo{{}},
+		Events:       []collector.EventInfo{{}},
+		PVCs:         []collector.PVCInfo{{}},
+		PVs:          []collector.PVInfo{{}},
+		KubeSystem:   collector.KubeSystemHealth{Pods: []collector.PodInfo{{}}},
+		Webhooks:     []collector.WebhookInfo{{}},
+		Endpoints:    []collector.ServiceEndpointsInfo{{}},
+		KubeletStats: []collector.NodeStats{{}},
+		ContainerCPU: []collector.ContainerCPUStats{{}},
+		Logs:         []collector.ContainerLog{{}},
+	}
+	return hasData(full, name)
+}
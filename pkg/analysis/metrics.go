@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// histogramQuantile estimates the q-quantile of a cumulative histogram the same
+// way PromQL's histogram_quantile does, interpolating linearly inside a bucket.
+func histogramQuantile(q float64, h collector.Histogram) float64 {
+	if h.Count == 0 || len(h.Buckets) == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.Count)
+	var prevBound float64
+	var prevCount uint64
+	for _, b := range h.Buckets {
+		if float64(b.Count) >= rank {
+			if b.Count == prevCount {
+				return b.UpperBound
+			}
+			return prevBound + (b.UpperBound-prevBound)*(rank-float64(prevCount))/float64(b.Count-prevCount)
+		}
+		prevBound = b.UpperBound
+		prevCount = b.Count
+	}
+	return h.Buckets[len(h.Buckets)-1].UpperBound
+}
+
+func seriesRef(metric string, labels ...string) string {
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i+1] == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	if len(parts) == 0 {
+		return metric
+	}
+	return metric + "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatSeconds(s float64) string {
+	if s < 1 {
+		return fmt.Sprintf("%.0fms", s*1000)
+	}
+	return fmt.Sprintf("%.2fs", s)
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -2,6 +2,7 @@ package analysis
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,61 +12,138 @@ import (
 	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
-type NodePressureRule struct{}
+type NodePressureRule struct {
+	params *NodePressureParams
+}
 
 func (r *NodePressureRule) Name() string { return "node-pressure" }
 
+func (r *NodePressureRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Nodes under disk, memory or PID pressure or approaching it, with evictions and the heaviest pods.",
+		Categories:  []string{"node-health"},
+		Requires:    []string{"nodes"},
+		Uses:        []string{"events", "pods", "kubelet-stats"},
+		MinSeverity: model.SeverityMedium,
+		MaxSeverity: model.SeverityCritical,
+		DocURL:      docBaseURL + "node-pressure",
+	}
+}
+
+type NodePressureParams struct {
+	// EvictionKeywords mark an event as eviction-related when found,
+	// case-insensitively, in its reason or message.
+	EvictionKeywords []string `json:"evictionKeywords"`
+	// The *WarnRatio parameters are the fraction of a resource left below
+	// which a node is reported as approaching pressure.
+	MemoryWarnRatio  float64 `json:"memoryWarnRatio"`
+	NodefsWarnRatio  float64 `json:"nodefsWarnRatio"`
+	ImagefsWarnRatio float64 `json:"imagefsWarnRatio"`
+	PIDWarnRatio     float64 `json:"pidWarnRatio"`
+	// TopPods is how many of the heaviest pods on the node are listed.
+	TopPods int `json:"topPods"`
+}
+
+func DefaultNodePressureParams() NodePressureParams {
+	return NodePressureParams{
+		EvictionKeywords: []string{
+			"Evicted",
+			"eviction",
+			"NodeHasDiskPressure",
+			"NodeHasMemoryPressure",
+			"NodeHasPIDPressure",
+			"OOMKilling",
+			"SystemOOM",
+		},
+		MemoryWarnRatio:  0.10,
+		NodefsWarnRatio:  0.15,
+		ImagefsWarnRatio: 0.20,
+		PIDWarnRatio:     0.15,
+		TopPods:          3,
+	}
+}
+
+func (p *NodePressureParams) Validate() error {
+	return firstError(
+		nonEmpty("evictionKeywords", p.EvictionKeywords),
+		fraction("memoryWarnRatio", p.MemoryWarnRatio),
+		fraction("nodefsWarnRatio", p.NodefsWarnRatio),
+		fraction("imagefsWarnRatio", p.ImagefsWarnRatio),
+		fraction("pidWarnRatio", p.PIDWarnRatio),
+		atLeast("topPods", p.TopPods, 0),
+	)
+}
+
+func (r *NodePressureRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *NodePressureRule) SetParams(p Params) { r.params = p.(*NodePressureParams) }
+
+func (r *NodePressureRule) current() NodePressureParams {
+	if r.params == nil {
+		return DefaultNodePressureParams()
+	}
+	return *r.params
+}
+
 var pressureConditions = []corev1.NodeConditionType{
 	corev1.NodeDiskPressure,
 	corev1.NodeMemoryPressure,
 	corev1.NodePIDPressure,
 }
 
-var evictionReasons = []string{
-	"Evicted",
-	"eviction",
-	"NodeHasDiskPressure",
-	"NodeHasMemoryPressure",
-	"NodeHasPIDPressure",
-	"OOMKilling",
-	"SystemOOM",
+type pressureSignal struct {
+	condition corev1.NodeConditionType
+	signal    string
+	remaining float64
+	warnAt    float64
+	message   string
 }
 
 func (r *NodePressureRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	params := r.current()
 	var findings []model.Finding
 
 	for _, node := range snap.Nodes {
+		stats := findNodeStats(snap.KubeletStats, node.Name)
+
 		for _, cond := range node.Conditions {
 			if !isPressureCondition(cond.Type) || cond.Status != corev1.ConditionTrue {
 				continue
 			}
 
 			evidence := []model.Evidence{
-				{
-					Type:    model.EvidenceResource,
-					Ref:     fmt.Sprintf("node/%s", node.Name),
-					Message: fmt.Sprintf("Condition %s is True: %s", cond.Type, cond.Message),
-					Data: map[string]string{
+				model.ObjectEvidence(
+					model.EvidenceResource,
+					model.NodeRef(node.Name, node.UID),
+					fmt.Sprintf("Condition %s is True: %s", cond.Type, cond.Message),
+					map[string]string{
 						"condition": string(cond.Type),
 						"status":    string(cond.Status),
 						"reason":    cond.Reason,
 					},
-				},
+				),
 			}
 
-			relatedEvents := findEvictionEvents(snap.Events, node.Name)
+			relatedEvents := findEvictionEvents(snap.Events, snap.Pods, node, params.EvictionKeywords)
 			for _, ev := range relatedEvents {
-				evidence = append(evidence, model.Evidence{
-					Type:    model.EvidenceEvent,
-					Ref:     ev.InvolvedObject,
-					Message: ev.Message,
-					Data: map[string]string{
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceEvent,
+					ev.InvolvedObject,
+					ev.Message,
+					map[string]string{
 						"reason": ev.Reason,
 						"count":  fmt.Sprintf("%d", ev.Count),
 					},
-				})
+				))
 			}
 
+			evidence = append(evidence, topPodEvidence(stats, cond.Type, params.TopPods)...)
+
 			confidence := pressureConfidence(cond, len(relatedEvents))
 			severity := pressureSeverity(cond.Type, len(relatedEvents))
 
@@ -82,6 +160,8 @@ func (r *NodePressureRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 				Timestamp:     time.Now().UTC(),
 			})
 		}
+
+		findings = append(findings, approachingPressureFindings(node, stats, params)...)
 	}
 
 	return findings
@@ -96,22 +176,31 @@ func isPressureCondition(ct corev1.NodeConditionType) bool {
 	return false
 }
 
-func findEvictionEvents(events []collector.EventInfo, nodeName string) []collector.EventInfo {
+func findEvictionEvents(events []collector.EventInfo, pods []collector.PodInfo, node collector.NodeInfo, keywords []string) []collector.EventInfo {
+	var onNode []collector.PodInfo
+	for _, p := range pods {
+		if p.NodeName == node.Name {
+			onNode = append(onNode, p)
+		}
+	}
+
 	var matched []collector.EventInfo
 	for _, ev := range events {
-		if !isEvictionRelated(ev.Reason, ev.Message) {
+		if !isEvictionRelated(ev.Reason, ev.Message, keywords) {
 			continue
 		}
-		if strings.Contains(ev.InvolvedObject, nodeName) || strings.Contains(ev.Message, nodeName) {
+		if eventInvolves(ev, model.NodeRef(node.Name, node.UID)) ||
+			eventInvolvesAnyPod(ev, onNode) ||
+			mentionsName(ev.Message, node.Name) {
 			matched = append(matched, ev)
 		}
 	}
 	return matched
 }
 
-func isEvictionRelated(reason, message string) bool {
+func isEvictionRelated(reason, message string, keywords []string) bool {
 	combined := reason + " " + message
-	for _, keyword := range evictionReasons {
+	for _, keyword := range keywords {
 		if strings.Contains(strings.ToLower(combined), strings.ToLower(keyword)) {
 			return true
 		}
@@ -181,3 +270,192 @@ func pressureNextSteps(ct corev1.NodeConditionType) []string {
 func conditionSlug(ct corev1.NodeConditionType) string {
 	return strings.ToLower(strings.ReplaceAll(string(ct), " ", "-"))
 }
+
+func findNodeStats(stats []collector.NodeStats, nodeName string) *collector.NodeStats {
+	for i := range stats {
+		if stats[i].NodeName == nodeName {
+			return &stats[i]
+		}
+	}
+	return nil
+}
+
+func pressureSignals(node collector.NodeInfo, stats *collector.NodeStats, params NodePressureParams) []pressureSignal {
+	var signals []pressureSignal
+
+	memCapacity := float64(node.Capacity.Memory().Value())
+	if memCapacity == 0 {
+		memCapacity = float64(stats.MemoryAvailableBytes + stats.MemoryWorkingSetBytes)
+	}
+	if memCapacity > 0 && (stats.MemoryAvailableBytes > 0 || stats.MemoryWorkingSetBytes > 0) {
+		signals = append(signals, pressureSignal{
+			condition: corev1.NodeMemoryPressure,
+			signal:    "memory.available",
+			remaining: float64(stats.MemoryAvailableBytes) / memCapacity,
+			warnAt:    params.MemoryWarnRatio,
+			message: fmt.Sprintf("%s of %s memory available",
+				formatBytes(stats.MemoryAvailableBytes), formatBytes(uint64(memCapacity))),
+		})
+	}
+
+	if stats.FS != nil && stats.FS.CapacityBytes > 0 {
+		signals = append(signals, pressureSignal{
+			condition: corev1.NodeDiskPressure,
+			signal:    "nodefs.available",
+			remaining: float64(stats.FS.AvailableBytes) / float64(stats.FS.CapacityBytes),
+			warnAt:    params.NodefsWarnRatio,
+			message: fmt.Sprintf("%s of %s node filesystem available",
+				formatBytes(stats.FS.AvailableBytes), formatBytes(stats.FS.CapacityBytes)),
+		})
+	}
+
+	if stats.ImageFS != nil && stats.ImageFS.CapacityBytes > 0 {
+		signals = append(signals, pressureSignal{
+			condition: corev1.NodeDiskPressure,
+			signal:    "imagefs.available",
+			remaining: float64(stats.ImageFS.AvailableBytes) / float64(stats.ImageFS.CapacityBytes),
+			warnAt:    params.ImagefsWarnRatio,
+			message: fmt.Sprintf("%s of %s image filesystem available",
+				formatBytes(stats.ImageFS.AvailableBytes), formatBytes(stats.ImageFS.CapacityBytes)),
+		})
+	}
+
+	if stats.MaxPID > 0 {
+		signals = append(signals, pressureSignal{
+			condition: corev1.NodePIDPressure,
+			signal:    "pid.available",
+			remaining: 1 - float64(stats.RunningProcesses)/float64(stats.MaxPID),
+			warnAt:    params.PIDWarnRatio,
+			message:   fmt.Sprintf("%d of %d PIDs in use", stats.RunningProcesses, stats.MaxPID),
+		})
+	}
+
+	return signals
+}
+
+func approachingPressureFindings(node collector.NodeInfo, stats *collector.NodeStats, params NodePressureParams) []model.Finding {
+	if stats == nil {
+		return nil
+	}
+
+	active := make(map[corev1.NodeConditionType]bool)
+	for _, cond := range node.Conditions {
+		if cond.Status == corev1.ConditionTrue {
+			active[cond.Type] = true
+		}
+	}
+
+	byCondition := make(map[corev1.NodeConditionType][]pressureSignal)
+	for _, sig := range pressureSignals(node, stats, params) {
+		if active[sig.condition] || sig.remaining >= sig.warnAt {
+			continue
+		}
+		byCondition[sig.condition] = append(byCondition[sig.condition], sig)
+	}
+
+	var findings []model.Finding
+	for _, ct := range pressureConditions {
+		signals := byCondition[ct]
+		if len(signals) == 0 {
+			continue
+		}
+
+		evidence := []model.Evidence{
+			model.ObjectEvidence(
+				model.EvidenceResource,
+				model.NodeRef(node.Name, node.UID),
+				fmt.Sprintf("Condition %s is not yet True", ct),
+				map[string]string{
+					"condition": string(ct),
+				},
+			),
+		}
+
+		severity := model.SeverityMedium
+		var messages []string
+		for _, sig := range signals {
+			if sig.remaining < sig.warnAt/2 {
+				severity = model.SeverityHigh
+			}
+			messages = append(messages, sig.message)
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceMetric,
+				model.NodeRef(node.Name, node.UID),
+				fmt.Sprintf("%s (%.0f%% remaining, warning below %.0f%%)", sig.message, sig.remaining*100, sig.warnAt*100),
+				map[string]string{
+					"signal":    sig.signal,
+					"remaining": fmt.Sprintf("%.3f", sig.remaining),
+				},
+			))
+		}
+		evidence = append(evidence, topPodEvidence(stats, ct, params.TopPods)...)
+
+		findings = append(findings, model.Finding{
+			SchemaVersion: model.SchemaVersion,
+			ID:            fmt.Sprintf("node-pressure-%s-%s-approaching", node.Name, conditionSlug(ct)),
+			Title:         fmt.Sprintf("Node %s is approaching %s", node.Name, ct),
+			Category:      "node-health",
+			Severity:      severity,
+			Confidence:    0.6,
+			Summary: fmt.Sprintf("Node %s is close to the kubelet eviction threshold for %s: %s.",
+				node.Name, ct, strings.Join(messages, "; ")),
+			Evidence:  evidence,
+			NextSteps: pressureNextSteps(ct),
+			Timestamp: time.Now().UTC(),
+		})
+	}
+
+	return findings
+}
+
+func topPodEvidence(stats *collector.NodeStats, ct corev1.NodeConditionType, top int) []model.Evidence {
+	if stats == nil || len(stats.Pods) == 0 {
+		return nil
+	}
+
+	var usage func(p collector.PodStats) uint64
+	var describe func(p collector.PodStats) string
+	switch ct {
+	case corev1.NodeMemoryPressure:
+		usage = func(p collector.PodStats) uint64 { return p.MemoryWorkingSetBytes }
+		describe = func(p collector.PodStats) string {
+			return fmt.Sprintf("%s memory working set", formatBytes(p.MemoryWorkingSetBytes))
+		}
+	case corev1.NodeDiskPressure:
+		usage = func(p collector.PodStats) uint64 { return p.EphemeralStorageBytes }
+		describe = func(p collector.PodStats) string {
+			return fmt.Sprintf("%s ephemeral storage", formatBytes(p.EphemeralStorageBytes))
+		}
+	case corev1.NodePIDPressure:
+		usage = func(p collector.PodStats) uint64 { return p.Processes }
+		describe = func(p collector.PodStats) string {
+			return fmt.Sprintf("%d processes", p.Processes)
+		}
+	default:
+		return nil
+	}
+
+	pods := make([]collector.PodStats, 0, len(stats.Pods))
+	for _, p := range stats.Pods {
+		if usage(p) > 0 {
+			pods = append(pods, p)
+		}
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		return usage(pods[i]) > usage(pods[j])
+	})
+	if len(pods) > top {
+		pods = pods[:top]
+	}
+
+	evidence := make([]model.Evidence, 0, len(pods))
+	for _, p := range pods {
+		evidence = append(evidence, model.ObjectEvidence(
+			model.EvidenceMetric,
+			model.PodRef(p.Namespace, p.Name, ""),
+			fmt.Sprintf("Top consumer on %s: pod %s/%s uses %s", stats.NodeName, p.Namespace, p.Name, describe(p)),
+			nil,
+		))
+	}
+	return evidence
+}
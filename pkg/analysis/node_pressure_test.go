@@ -5,6 +5,7 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/marek-kar/kube-slowwhy/pkg/collector"
@@ -98,7 +99,7 @@ func TestNodePressureRule_MemoryPressureWithEvictions(t *testing.T) {
 				Reason:         "Evicted",
 				Message:        "The node worker-2 was low on resource: memory.",
 				Type:           "Warning",
-				InvolvedObject: "Pod/default/app-pod",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "app-pod"},
 				Count:          3,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
@@ -109,7 +110,7 @@ func TestNodePressureRule_MemoryPressureWithEvictions(t *testing.T) {
 				Reason:         "OOMKilling",
 				Message:        "Memory cgroup out of memory on worker-2",
 				Type:           "Warning",
-				InvolvedObject: "Pod/monitoring/prometheus-0",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "monitoring", Name: "prometheus-0"},
 				Count:          1,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC),
@@ -120,7 +121,7 @@ func TestNodePressureRule_MemoryPressureWithEvictions(t *testing.T) {
 				Reason:         "Scheduled",
 				Message:        "Successfully assigned default/other-pod to worker-3",
 				Type:           "Normal",
-				InvolvedObject: "Pod/default/other-pod",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "other-pod"},
 				Count:          1,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
@@ -179,6 +180,110 @@ func TestNodePressureRule_MultiplePressures(t *testing.T) {
 	}
 }
 
+func TestNodePressureRule_ApproachingMemoryPressure(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{
+			{
+				Name: "worker-4",
+				Conditions: []corev1.NodeCondition{
+					{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+					{Type: corev1.NodeDiskPressure, Status: corev1.ConditionFalse},
+				},
+				Capacity: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		},
+		KubeletStats: []collector.NodeStats{
+			{
+				NodeName:              "worker-4",
+				MemoryAvailableBytes:  600 << 20,
+				MemoryWorkingSetBytes: 7 << 30,
+				FS:                    &collector.FSStats{AvailableBytes: 50 << 30, CapacityBytes: 100 << 30},
+				Pods: []collector.PodStats{
+					{Namespace: "default", Name: "small", MemoryWorkingSetBytes: 100 << 20},
+					{Namespace: "default", Name: "cache", MemoryWorkingSetBytes: 4 << 30},
+					{Namespace: "batch", Name: "job", MemoryWorkingSetBytes: 2 << 30},
+					{Namespace: "default", Name: "tiny", MemoryWorkingSetBytes: 10 << 20},
+				},
+			},
+		},
+	}
+
+	rule := &NodePressureRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+
+	f := findings[0]
+	if f.ID != "node-pressure-worker-4-memorypressure-approaching" {
+		t.Errorf("id: got %q", f.ID)
+	}
+	if f.Severity != model.SeverityMedium {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityMedium)
+	}
+
+	var topPods []string
+	for _, e := range f.Evidence {
+		if e.Type == model.EvidenceMetric && e.Ref != "node/worker-4" {
+			topPods = append(topPods, e.Ref)
+		}
+	}
+	want := []string{"pod/default/cache", "pod/batch/job", "pod/default/small"}
+	if len(topPods) != len(want) {
+		t.Fatalf("top pods: got %v, want %v", topPods, want)
+	}
+	for i := range want {
+		if topPods[i] != want[i] {
+			t.Errorf("top pod %d: got %q, want %q", i, topPods[i], want[i])
+		}
+	}
+}
+
+func TestNodePressureRule_ActivePressureNotReportedAsApproaching(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{
+			{
+				Name: "worker-5",
+				Conditions: []corev1.NodeCondition{
+					{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, Reason: "KubeletHasDiskPressure"},
+				},
+			},
+		},
+		KubeletStats: []collector.NodeStats{
+			{
+				NodeName: "worker-5",
+				FS:       &collector.FSStats{AvailableBytes: 5 << 30, CapacityBytes: 100 << 30},
+				Pods: []collector.PodStats{
+					{Namespace: "logs", Name: "fluentd", EphemeralStorageBytes: 20 << 30},
+				},
+			},
+		},
+	}
+
+	rule := &NodePressureRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].ID != "node-pressure-worker-5-diskpressure" {
+		t.Errorf("id: got %q", findings[0].ID)
+	}
+
+	hasTopPod := false
+	for _, e := range findings[0].Evidence {
+		if e.Ref == "pod/logs/fluentd" {
+			hasTopPod = true
+		}
+	}
+	if !hasTopPod {
+		t.Error("expected top consuming pod evidence")
+	}
+}
+
 func TestNodePressureRule_Name(t *testing.T) {
 	rule := &NodePressureRule{}
 	if rule.Name() != "node-pressure" {
@@ -191,3 +296,56 @@ var _ Rule = (*NodePressureRule)(nil)
 func init() {
 	_ = metav1.Now()
 }
+
+func TestNodePressureRule_EvictionEventsMatchedByPodNode(t *testing.T) {
+	pressure := []corev1.NodeCondition{
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue, Reason: "KubeletHasInsufficientMemory"},
+	}
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{
+			{Name: "worker-1", Conditions: pressure},
+			{Name: "worker-10", Conditions: pressure},
+		},
+		Pods: []collector.PodInfo{
+			{Namespace: "default", Name: "app-pod", UID: "uid-app", NodeName: "worker-1"},
+		},
+		Events: []collector.EventInfo{
+			{
+				Namespace:      "default",
+				Name:           "app-pod.17a",
+				Reason:         "Evicted",
+				Message:        "The node was low on resource: memory.",
+				Type:           "Warning",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "app-pod", UID: "uid-app"},
+				Count:          1,
+			},
+			{
+				Namespace:      "default",
+				Name:           "worker-10.oom",
+				Reason:         "SystemOOM",
+				Message:        "System OOM encountered on worker-10",
+				Type:           "Warning",
+				InvolvedObject: model.ObjectRef{Kind: "Node", Name: "worker-10"},
+				Count:          1,
+			},
+		},
+	}
+
+	rule := &NodePressureRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d", len(findings))
+	}
+	for _, f := range findings {
+		events := 0
+		for _, e := range f.Evidence {
+			if e.Type == model.EvidenceEvent {
+				events++
+			}
+		}
+		if events != 1 {
+			t.Errorf("%s: event evidence got %d, want 1", f.ID, events)
+		}
+	}
+}
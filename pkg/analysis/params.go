@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// atLeast and the helpers below validate one parameter each, naming it by
+// its config file key.
+func atLeast[T cmp.Ordered](name string, v, min T) error {
+	if v < min {
+		return fmt.Errorf("%s must be at least %v, got %v", name, min, v)
+	}
+	return nil
+}
+
+func notBelow[T cmp.Ordered](name string, v T, otherName string, other T) error {
+	if v < other {
+		return fmt.Errorf("%s (%v) must not be below %s (%v)", name, v, otherName, other)
+	}
+	return nil
+}
+
+func positive(name string, v float64) error {
+	if v <= 0 {
+		return fmt.Errorf("%s must be greater than 0, got %v", name, v)
+	}
+	return nil
+}
+
+// fraction checks that v is in (0, 1].
+func fraction(name string, v float64) error {
+	if v <= 0 || v > 1 {
+		return fmt.Errorf("%s must be greater than 0 and at most 1, got %v", name, v)
+	}
+	return nil
+}
+
+func nonEmpty(name string, v []string) error {
+	if len(v) == 0 {
+		return fmt.Errorf("%s must not be empty", name)
+	}
+	return nil
+}
+
+// firstError returns the first non-nil error.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,16 +11,69 @@ import (
 	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
-type PendingPodsRule struct{}
+type PendingPodsRule struct {
+	params *PendingPodsParams
+}
 
 func (r *PendingPodsRule) Name() string { return "pending-pods" }
 
+func (r *PendingPodsRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Pending pods grouped by why the scheduler cannot place them or their sandbox cannot get a network.",
+		Categories:  []string{"scheduling"},
+		Requires:    []string{"pods"},
+		Uses:        []string{"events", "logs"},
+		MinSeverity: model.SeverityLow,
+		MaxSeverity: model.SeverityCritical,
+		DocURL:      docBaseURL + "pending-pods",
+	}
+}
+
+type PendingPodsParams struct {
+	// CriticalCount is the number of pods Pending for one reason that makes
+	// the finding critical, whatever the reason.
+	CriticalCount int `json:"criticalCount"`
+	// HighCount is the number of pods Pending for lack of CPU, memory or a
+	// pod network that makes the finding high.
+	HighCount int `json:"highCount"`
+}
+
+func DefaultPendingPodsParams() PendingPodsParams {
+	return PendingPodsParams{CriticalCount: 5, HighCount: 2}
+}
+
+func (p *PendingPodsParams) Validate() error {
+	return firstError(
+		atLeast("highCount", p.HighCount, 1),
+		notBelow("criticalCount", p.CriticalCount, "highCount", p.HighCount),
+	)
+}
+
+func (r *PendingPodsRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *PendingPodsRule) SetParams(p Params) { r.params = p.(*PendingPodsParams) }
+
+func (r *PendingPodsRule) current() PendingPodsParams {
+	if r.params == nil {
+		return DefaultPendingPodsParams()
+	}
+	return *r.params
+}
+
 type schedulingReason struct {
 	Category string
 	Keywords []string
 }
 
+// The network category comes first: a pod whose sandbox cannot get an
+// address has been scheduled, and the CNI error may mention cpu or memory.
 var schedulingReasons = []schedulingReason{
+	{Category: "network", Keywords: []string{"FailedCreatePodSandBox", "failed to setup network", "failed to set up sandbox", "cni plugin"}},
 	{Category: "insufficient-cpu", Keywords: []string{"Insufficient cpu", "cpu"}},
 	{Category: "insufficient-memory", Keywords: []string{"Insufficient memory", "memory"}},
 	{Category: "taint", Keywords: []string{"had taint", "untolerated taint", "NoSchedule", "NoExecute"}},
@@ -42,7 +95,7 @@ func (r *PendingPodsRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 			continue
 		}
 
-		events := findPodEvents(snap.Events, pod.Namespace, pod.Name)
+		events := findPodEvents(snap.Events, pod)
 		cat := classifySchedulingReason(pod, events)
 
 		buckets[cat] = append(buckets[cat], podReason{
@@ -62,30 +115,38 @@ func (r *PendingPodsRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 		evidence := make([]model.Evidence, 0, len(pods)*2)
 
 		for _, pr := range pods {
-			evidence = append(evidence, model.Evidence{
-				Type:    model.EvidenceResource,
-				Ref:     fmt.Sprintf("pod/%s/%s", pr.pod.Namespace, pr.pod.Name),
-				Message: fmt.Sprintf("Pod is Pending (reason: %s)", cat),
-				Data: map[string]string{
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				model.PodRef(pr.pod.Namespace, pr.pod.Name, pr.pod.UID),
+				fmt.Sprintf("Pod is Pending (reason: %s)", cat),
+				map[string]string{
 					"namespace": pr.pod.Namespace,
 					"nodeName":  pr.pod.NodeName,
 				},
-			})
+			))
 			for _, ev := range pr.events {
-				evidence = append(evidence, model.Evidence{
-					Type:    model.EvidenceEvent,
-					Ref:     ev.InvolvedObject,
-					Message: ev.Message,
-					Data: map[string]string{
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceEvent,
+					ev.InvolvedObject,
+					ev.Message,
+					map[string]string{
 						"reason": ev.Reason,
 						"count":  fmt.Sprintf("%d", ev.Count),
 					},
-				})
+				))
 			}
 		}
 
+		if cat == "network" {
+			var stuck []collector.PodInfo
+			for _, pr := range pods {
+				stuck = append(stuck, pr.pod)
+			}
+			evidence = append(evidence, cniLogEvidence(snap, stuck)...)
+		}
+
 		confidence := pendingConfidence(len(pods), len(snap.Pods))
-		severity := pendingSeverity(len(pods), cat)
+		severity := pendingSeverity(len(pods), cat, r.current())
 
 		findings = append(findings, model.Finding{
 			SchemaVersion: model.SchemaVersion,
@@ -104,11 +165,39 @@ func (r *PendingPodsRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 	return findings
 }
 
-func findPodEvents(events []collector.EventInfo, namespace, name string) []collector.EventInfo {
-	ref := fmt.Sprintf("Pod/%s/%s", namespace, name)
+// cniLogEvidence returns IP allocation and CNI setup failures from the logs of
+// the CNI agents on the nodes the stuck pods were scheduled to, or of every
+// CNI agent when those nodes are unknown.
+func cniLogEvidence(snap *collector.Snapshot, stuck []collector.PodInfo) []model.Evidence {
+	nodes := make(map[string]bool)
+	for _, p := range stuck {
+		if p.NodeName != "" {
+			nodes[p.NodeName] = true
+		}
+	}
+	podNodes := make(map[string]string)
+	for _, pods := range [][]collector.PodInfo{snap.Pods, snap.KubeSystem.Pods} {
+		for _, p := range pods {
+			podNodes[p.Namespace+"/"+p.Name] = p.NodeName
+		}
+	}
+	return logPatternEvidence(snap.Logs, func(l collector.ContainerLog) bool {
+		if !collector.IsCNIPod(l.Pod) {
+			return false
+		}
+		node := podNodes[l.Namespace+"/"+l.Pod]
+		return len(nodes) == 0 || node == "" || nodes[node]
+	}, logCategoryNetwork)
+}
+
+func findPodEvents(events []collector.EventInfo, pod collector.PodInfo) []collector.EventInfo {
 	var matched []collector.EventInfo
 	for _, ev := range events {
-		if ev.InvolvedObject == ref || (ev.Namespace == namespace && strings.Contains(ev.Name, name)) {
+		if eventInvolvesPod(ev, pod) {
+			matched = append(matched, ev)
+			continue
+		}
+		if ev.InvolvedObject.IsZero() && ev.Namespace == pod.Namespace && strings.HasPrefix(ev.Name, pod.Name+".") {
 			matched = append(matched, ev)
 		}
 	}
@@ -165,13 +254,13 @@ func pendingConfidence(pendingCount, totalPods int) float64 {
 	return base
 }
 
-func pendingSeverity(count int, category string) model.Severity {
-	if count >= 5 {
+func pendingSeverity(count int, category string, params PendingPodsParams) model.Severity {
+	if count >= params.CriticalCount {
 		return model.SeverityCritical
 	}
 	switch category {
-	case "insufficient-cpu", "insufficient-memory":
-		if count >= 2 {
+	case "insufficient-cpu", "insufficient-memory", "network":
+		if count >= params.HighCount {
 			return model.SeverityHigh
 		}
 		return model.SeverityMedium
@@ -183,6 +272,9 @@ func pendingSeverity(count int, category string) model.Severity {
 }
 
 func buildPendingSummary(count int, category string) string {
+	if category == "network" {
+		return fmt.Sprintf("%d pod(s) stuck in Pending state. They were scheduled but their sandbox could not be given a network.", count)
+	}
 	return fmt.Sprintf("%d pod(s) stuck in Pending state. Most common scheduling failure: %s.", count, category)
 }
 
@@ -212,6 +304,12 @@ func pendingNextSteps(category string) []string {
 			"Check node labels match pod nodeSelector",
 			"Consider relaxing affinity constraints",
 		}
+	case "network":
+		return []string{
+			"Check the CNI agent pods on the affected nodes for errors and restarts",
+			"Check whether the pod subnet or IPAM pool has run out of addresses",
+			"Inspect pod events with kubectl describe for the sandbox error",
+		}
 	case "unschedulable":
 		return []string{
 			"Check if nodes are cordoned",
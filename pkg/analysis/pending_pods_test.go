@@ -62,7 +62,7 @@ func TestPendingPodsRule_InsufficientCPU(t *testing.T) {
 				Reason:         "FailedScheduling",
 				Message:        "0/3 nodes are available: 3 Insufficient cpu.",
 				Type:           "Warning",
-				InvolvedObject: "Pod/default/pending-1",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "pending-1"},
 				Count:          5,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
@@ -241,3 +241,37 @@ var _ Rule = (*PendingPodsRule)(nil)
 func init() {
 	_ = metav1.Now()
 }
+
+func TestPendingPodsRule_Network(t *testing.T) {
+	snap := &collector.Snapshot{
+		Pods: []collector.PodInfo{
+			{Name: "web-0", Namespace: "default", Phase: corev1.PodPending, NodeName: "w1"},
+		},
+		Events: []collector.EventInfo{{
+			Name: "web-0.1", Namespace: "default", Reason: "FailedCreatePodSandBox",
+			Message: `Failed to create pod sandbox: plugin type="aws-cni" failed (add): failed to assign an IP address to container`,
+		}},
+		KubeSystem: collector.KubeSystemHealth{Pods: []collector.PodInfo{
+			{Name: "aws-node-a", Namespace: "kube-system", NodeName: "w1"},
+			{Name: "aws-node-b", Namespace: "kube-system", NodeName: "w2"},
+		}},
+		Logs: []collector.ContainerLog{
+			{Namespace: "kube-system", Pod: "aws-node-a", Container: "aws-node", Lines: []string{"failed to allocate IP: no more free IP addresses available"}},
+			{Namespace: "kube-system", Pod: "aws-node-b", Container: "aws-node", Lines: []string{"failed to allocate IP: no more free IP addresses available"}},
+		},
+	}
+
+	findings := (&PendingPodsRule{}).Evaluate(snap)
+	if len(findings) != 1 || findings[0].ID != "pending-pods-network" {
+		t.Fatalf("expected pending-pods-network, got %+v", findings)
+	}
+	var logs []string
+	for _, e := range findings[0].Evidence {
+		if e.Type == model.EvidenceLog {
+			logs = append(logs, e.Ref+" "+e.Data["pattern"])
+		}
+	}
+	if len(logs) != 1 || logs[0] != "pod/kube-system/aws-node-a ip-exhausted" {
+		t.Errorf("expected the CNI agent log on w1 only, got %v", logs)
+	}
+}
@@ -0,0 +1,396 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type ResourceUsageRule struct {
+	params *ResourceUsageParams
+}
+
+func (r *ResourceUsageRule) Name() string { return "resource-usage" }
+
+func (r *ResourceUsageRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Noisy neighbours and nodes whose real usage diverges from their requests.",
+		Categories:  []string{"resource-pressure"},
+		Requires:    []string{"resource-metrics"},
+		Uses:        []string{"pods"},
+		MinSeverity: model.SeverityLow,
+		MaxSeverity: model.SeverityHigh,
+		DocURL:      docBaseURL + "resource-usage",
+	}
+}
+
+type ResourceUsageParams struct {
+	// NoisyUsageFactor is how many times its request a container must use
+	// to count as a noisy neighbour.
+	NoisyUsageFactor float64 `json:"noisyUsageFactor"`
+	// MinNoisyCPU and MinNoisyMemory are the usage below which a container
+	// is never noisy, however small its request.
+	MinNoisyCPU    resource.Quantity `json:"minNoisyCPU"`
+	MinNoisyMemory resource.Quantity `json:"minNoisyMemory"`
+	// NodeHotRatio is the fraction of allocatable in use from which a node
+	// is hot, raising the severity.
+	NodeHotRatio float64 `json:"nodeHotRatio"`
+	// DivergenceRatio is the gap between the used and requested fractions
+	// of allocatable that is reported.
+	DivergenceRatio float64 `json:"divergenceRatio"`
+	// IdleRequestRatio is the requested fraction of allocatable from which
+	// idle reservations are reported.
+	IdleRequestRatio float64 `json:"idleRequestRatio"`
+	// MaxNoisyContainers caps the containers listed as evidence.
+	MaxNoisyContainers int `json:"maxNoisyContainers"`
+}
+
+func DefaultResourceUsageParams() ResourceUsageParams {
+	return ResourceUsageParams{
+		NoisyUsageFactor:   2.0,
+		MinNoisyCPU:        resource.MustParse("200m"),
+		MinNoisyMemory:     resource.MustParse("256Mi"),
+		NodeHotRatio:       0.85,
+		DivergenceRatio:    0.30,
+		IdleRequestRatio:   0.70,
+		MaxNoisyContainers: 10,
+	}
+}
+
+func (p *ResourceUsageParams) Validate() error {
+	return firstError(
+		atLeast("noisyUsageFactor", p.NoisyUsageFactor, 1),
+		positive("minNoisyCPU", float64(p.MinNoisyCPU.MilliValue())),
+		positive("minNoisyMemory", float64(p.MinNoisyMemory.Value())),
+		fraction("nodeHotRatio", p.NodeHotRatio),
+		fraction("divergenceRatio", p.DivergenceRatio),
+		fraction("idleRequestRatio", p.IdleRequestRatio),
+		atLeast("maxNoisyContainers", p.MaxNoisyContainers, 1),
+	)
+}
+
+func (r *ResourceUsageRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *ResourceUsageRule) SetParams(p Params) { r.params = p.(*ResourceUsageParams) }
+
+func (r *ResourceUsageRule) current() ResourceUsageParams {
+	if r.params == nil {
+		return DefaultResourceUsageParams()
+	}
+	return *r.params
+}
+
+var usageResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+type noisyContainer struct {
+	pod       collector.PodInfo
+	container collector.ContainerInfo
+	resource  corev1.ResourceName
+	usage     int64
+	request   int64
+}
+
+func (r *ResourceUsageRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	params := r.current()
+	var findings []model.Finding
+
+	podsByNode := make(map[string][]collector.PodInfo)
+	for _, p := range snap.Pods {
+		if p.NodeName == "" || p.Phase == corev1.PodSucceeded || p.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNode[p.NodeName] = append(podsByNode[p.NodeName], p)
+	}
+
+	for _, node := range snap.Nodes {
+		pods := podsByNode[node.Name]
+
+		if f, ok := noisyNeighbourFinding(node, pods, params); ok {
+			findings = append(findings, f)
+		}
+		// Node usage counts every pod, so requests can only be compared
+		// with it when every pod on the node is in the snapshot.
+		if !snap.NamespaceScoped() {
+			findings = append(findings, usageDivergenceFindings(node, pods, params)...)
+		}
+	}
+
+	return findings
+}
+
+func noisyNeighbourFinding(node collector.NodeInfo, pods []collector.PodInfo, params ResourceUsageParams) (model.Finding, bool) {
+	var noisy []noisyContainer
+	for _, p := range pods {
+		for _, c := range p.Containers {
+			for _, res := range usageResources {
+				usage, ok := quantityValue(c.Usage, res)
+				if !ok || usage < minNoisyUsage(res, params) {
+					continue
+				}
+				request, _ := quantityValue(c.Resources.Requests, res)
+				if request > 0 && float64(usage) < params.NoisyUsageFactor*float64(request) {
+					continue
+				}
+				noisy = append(noisy, noisyContainer{pod: p, container: c, resource: res, usage: usage, request: request})
+			}
+		}
+	}
+	if len(noisy) == 0 {
+		return model.Finding{}, false
+	}
+
+	sort.SliceStable(noisy, func(i, j int) bool {
+		return excessRatio(noisy[i], params) > excessRatio(noisy[j], params)
+	})
+
+	hot := make(map[corev1.ResourceName]float64)
+	for _, res := range usageResources {
+		if ratio, ok := nodeUsageRatio(node, res); ok && ratio >= params.NodeHotRatio {
+			hot[res] = ratio
+		}
+	}
+
+	severity := model.SeverityMedium
+	evidence := []model.Evidence{
+		model.ObjectEvidence(
+			model.EvidenceResource,
+			model.NodeRef(node.Name, node.UID),
+			fmt.Sprintf("%d container(s) on %s use far more than they request", len(noisy), node.Name),
+			nil,
+		),
+	}
+	for _, res := range usageResources {
+		if ratio, ok := hot[res]; ok {
+			severity = model.SeverityHigh
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceMetric,
+				model.NodeRef(node.Name, node.UID),
+				fmt.Sprintf("Node %s %s usage is at %.0f%% of allocatable", node.Name, res, ratio*100),
+				map[string]string{
+					"resource": string(res),
+					"ratio":    fmt.Sprintf("%.3f", ratio),
+				},
+			))
+		}
+	}
+
+	shown := noisy
+	if len(shown) > params.MaxNoisyContainers {
+		shown = shown[:params.MaxNoisyContainers]
+	}
+	for _, n := range shown {
+		evidence = append(evidence, model.ObjectEvidence(
+			model.EvidenceMetric,
+			model.PodRef(n.pod.Namespace, n.pod.Name, n.pod.UID),
+			noisyMessage(n, params),
+			map[string]string{
+				"container": n.container.Name,
+				"resource":  string(n.resource),
+				"usage":     formatResource(n.resource, n.usage),
+				"request":   formatResource(n.resource, n.request),
+			},
+		))
+	}
+
+	return model.Finding{
+		SchemaVersion: model.SchemaVersion,
+		ID:            fmt.Sprintf("noisy-neighbours-%s", node.Name),
+		Title:         fmt.Sprintf("Noisy neighbours on node %s", node.Name),
+		Category:      "resource-pressure",
+		Severity:      severity,
+		Confidence:    noisyConfidence(len(hot)),
+		Summary:       noisySummary(node.Name, noisy, hot, params),
+		Evidence:      evidence,
+		NextSteps: []string{
+			"Raise requests on the listed containers to match their observed usage",
+			"Set limits on containers without requests so they cannot starve their neighbours",
+			"Check whether the noisy workloads should be spread with pod anti-affinity or topology spread constraints",
+		},
+		Timestamp: time.Now().UTC(),
+	}, true
+}
+
+func usageDivergenceFindings(node collector.NodeInfo, pods []collector.PodInfo, params ResourceUsageParams) []model.Finding {
+	var findings []model.Finding
+
+	for _, res := range usageResources {
+		usageRatio, ok := nodeUsageRatio(node, res)
+		if !ok {
+			continue
+		}
+		allocatable, _ := quantityValue(node.Allocatable, res)
+		requested := requestedOnNode(pods, res)
+		requestRatio := float64(requested) / float64(allocatable)
+
+		var (
+			severity model.Severity
+			title    string
+			summary  string
+			steps    []string
+		)
+		switch {
+		case usageRatio-requestRatio >= params.DivergenceRatio:
+			severity = model.SeverityMedium
+			if usageRatio >= params.NodeHotRatio {
+				severity = model.SeverityHigh
+			}
+			title = fmt.Sprintf("Node %s uses far more %s than is requested", node.Name, res)
+			summary = fmt.Sprintf("Node %s is using %.0f%% of its allocatable %s but pods only request %.0f%%. The scheduler believes the node has room and will keep placing pods on it.",
+				node.Name, usageRatio*100, res, requestRatio*100)
+			steps = []string{
+				"Find the pods whose usage exceeds their requests and raise those requests",
+				"Enforce default requests with a LimitRange in namespaces that omit them",
+			}
+		case requestRatio-usageRatio >= params.DivergenceRatio && requestRatio >= params.IdleRequestRatio:
+			severity = model.SeverityLow
+			title = fmt.Sprintf("Node %s has %s reserved but idle", node.Name, res)
+			summary = fmt.Sprintf("Pods on node %s request %.0f%% of its allocatable %s but only use %.0f%%. Inflated requests can leave pods Pending while capacity sits unused.",
+				node.Name, requestRatio*100, res, usageRatio*100)
+			steps = []string{
+				"Right-size requests on the largest pods on this node",
+				"Consider a VerticalPodAutoscaler in recommendation mode to size requests from real usage",
+			}
+		default:
+			continue
+		}
+
+		usage, _ := quantityValue(node.Usage, res)
+		findings = append(findings, model.Finding{
+			SchemaVersion: model.SchemaVersion,
+			ID:            fmt.Sprintf("node-usage-divergence-%s-%s", node.Name, res),
+			Title:         title,
+			Category:      "resource-pressure",
+			Severity:      severity,
+			Confidence:    0.6,
+			Summary:       summary,
+			Evidence: []model.Evidence{
+				model.ObjectEvidence(
+					model.EvidenceMetric,
+					model.NodeRef(node.Name, node.UID),
+					fmt.Sprintf("%s usage %s, requested %s, allocatable %s",
+						res, formatResource(res, usage), formatResource(res, requested), formatResource(res, allocatable)),
+					map[string]string{
+						"resource":     string(res),
+						"usageRatio":   fmt.Sprintf("%.3f", usageRatio),
+						"requestRatio": fmt.Sprintf("%.3f", requestRatio),
+					},
+				),
+			},
+			NextSteps: steps,
+			Timestamp: time.Now().UTC(),
+		})
+	}
+
+	return findings
+}
+
+func nodeUsageRatio(node collector.NodeInfo, res corev1.ResourceName) (float64, bool) {
+	usage, ok := quantityValue(node.Usage, res)
+	if !ok {
+		return 0, false
+	}
+	allocatable, ok := quantityValue(node.Allocatable, res)
+	if !ok || allocatable == 0 {
+		return 0, false
+	}
+	return float64(usage) / float64(allocatable), true
+}
+
+func requestedOnNode(pods []collector.PodInfo, res corev1.ResourceName) int64 {
+	var total int64
+	for _, p := range pods {
+		for _, c := range p.Containers {
+			if v, ok := quantityValue(c.Resources.Requests, res); ok {
+				total += v
+			}
+		}
+	}
+	return total
+}
+
+// quantityValue returns CPU in millicores and everything else in base units.
+func quantityValue(list corev1.ResourceList, res corev1.ResourceName) (int64, bool) {
+	q, ok := list[res]
+	if !ok {
+		return 0, false
+	}
+	if res == corev1.ResourceCPU {
+		return q.MilliValue(), true
+	}
+	return q.Value(), true
+}
+
+func minNoisyUsage(res corev1.ResourceName, params ResourceUsageParams) int64 {
+	if res == corev1.ResourceCPU {
+		return params.MinNoisyCPU.MilliValue()
+	}
+	return params.MinNoisyMemory.Value()
+}
+
+func excessRatio(n noisyContainer, params ResourceUsageParams) float64 {
+	if n.request == 0 {
+		return float64(n.usage) / float64(minNoisyUsage(n.resource, params))
+	}
+	return float64(n.usage) / float64(n.request)
+}
+
+func formatResource(res corev1.ResourceName, v int64) string {
+	if res == corev1.ResourceCPU {
+		if v < 1000 {
+			return fmt.Sprintf("%dm", v)
+		}
+		return fmt.Sprintf("%.2f cores", float64(v)/1000)
+	}
+	if v < 0 {
+		v = 0
+	}
+	return formatBytes(uint64(v))
+}
+
+func noisyMessage(n noisyContainer, params ResourceUsageParams) string {
+	if n.request == 0 {
+		return fmt.Sprintf("Container %s in pod %s/%s uses %s %s with no request",
+			n.container.Name, n.pod.Namespace, n.pod.Name, formatResource(n.resource, n.usage), n.resource)
+	}
+	return fmt.Sprintf("Container %s in pod %s/%s uses %s %s against a request of %s (%.1fx)",
+		n.container.Name, n.pod.Namespace, n.pod.Name, formatResource(n.resource, n.usage), n.resource,
+		formatResource(n.resource, n.request), excessRatio(n, params))
+}
+
+func noisyConfidence(hotResources int) float64 {
+	if hotResources > 0 {
+		return 0.8
+	}
+	return 0.6
+}
+
+func noisySummary(nodeName string, noisy []noisyContainer, hot map[corev1.ResourceName]float64, params ResourceUsageParams) string {
+	pods := make(map[string]bool)
+	for _, n := range noisy {
+		pods[n.pod.Namespace+"/"+n.pod.Name] = true
+	}
+	s := fmt.Sprintf("%d pod(s) on node %s use at least %.0fx their requests (or run without requests).",
+		len(pods), nodeName, params.NoisyUsageFactor)
+	if len(hot) > 0 {
+		var names []string
+		for _, res := range usageResources {
+			if _, ok := hot[res]; ok {
+				names = append(names, string(res))
+			}
+		}
+		s += fmt.Sprintf(" The node is running hot on %s, so co-located pods are likely being starved.", strings.Join(names, " and "))
+	}
+	return s
+}
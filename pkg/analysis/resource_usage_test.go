@@ -0,0 +1,209 @@
+package analysis
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func usageNode(name, cpu, mem, usedCPU, usedMem string) collector.NodeInfo {
+	n := collector.NodeInfo{
+		Name: name,
+		Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(mem),
+		},
+	}
+	if usedCPU != "" {
+		n.Usage = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(usedCPU),
+			corev1.ResourceMemory: resource.MustParse(usedMem),
+		}
+	}
+	return n
+}
+
+func usagePod(ns, name, node, reqCPU, reqMem, usedCPU, usedMem string) collector.PodInfo {
+	c := collector.ContainerInfo{Name: "app", Ready: true}
+	if reqCPU != "" {
+		c.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(reqCPU),
+			corev1.ResourceMemory: resource.MustParse(reqMem),
+		}
+	}
+	if usedCPU != "" {
+		c.Usage = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(usedCPU),
+			corev1.ResourceMemory: resource.MustParse(usedMem),
+		}
+	}
+	return collector.PodInfo{
+		Namespace:  ns,
+		Name:       name,
+		NodeName:   node,
+		Phase:      corev1.PodRunning,
+		Containers: []collector.ContainerInfo{c},
+	}
+}
+
+func TestResourceUsageRule_NoUsage(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{usageNode("node-1", "4", "16Gi", "", "")},
+		Pods:  []collector.PodInfo{usagePod("default", "web", "node-1", "500m", "1Gi", "", "")},
+	}
+
+	rule := &ResourceUsageRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings without metrics-server data, got %d", len(findings))
+	}
+}
+
+func TestResourceUsageRule_WithinRequests(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{usageNode("node-1", "4", "16Gi", "1500m", "6Gi")},
+		Pods: []collector.PodInfo{
+			usagePod("default", "web", "node-1", "1", "4Gi", "800m", "3Gi"),
+			usagePod("default", "api", "node-1", "1", "4Gi", "700m", "3Gi"),
+		},
+	}
+
+	rule := &ResourceUsageRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d: %+v", len(findings), findings)
+	}
+}
+
+func TestResourceUsageRule_NoisyNeighbourOnHotNode(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{usageNode("node-1", "4", "16Gi", "3800m", "8Gi")},
+		Pods: []collector.PodInfo{
+			usagePod("batch", "cruncher", "node-1", "200m", "2Gi", "3", "2Gi"),
+			usagePod("default", "web", "node-1", "2", "4Gi", "500m", "3Gi"),
+		},
+	}
+
+	rule := &ResourceUsageRule{}
+	findings := rule.Evaluate(snap)
+
+	var noisy, divergence *model.Finding
+	for i := range findings {
+		switch findings[i].ID {
+		case "noisy-neighbours-node-1":
+			noisy = &findings[i]
+		case "node-usage-divergence-node-1-cpu":
+			divergence = &findings[i]
+		}
+	}
+
+	if noisy == nil {
+		t.Fatalf("expected a noisy-neighbours finding, got %+v", findings)
+	}
+	if noisy.Severity != model.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", noisy.Severity, model.SeverityHigh)
+	}
+	if noisy.Category != "resource-pressure" {
+		t.Errorf("category: got %q", noisy.Category)
+	}
+	podEvidence := 0
+	for _, e := range noisy.Evidence {
+		if e.Ref == "pod/batch/cruncher" {
+			podEvidence++
+		}
+		if e.Ref == "pod/default/web" {
+			t.Errorf("pod within its requests should not be listed: %+v", e)
+		}
+	}
+	if podEvidence != 1 {
+		t.Errorf("cruncher evidence: got %d, want 1", podEvidence)
+	}
+
+	if divergence == nil {
+		t.Fatalf("expected a cpu divergence finding, got %+v", findings)
+	}
+	if divergence.Severity != model.SeverityHigh {
+		t.Errorf("divergence severity: got %q, want %q", divergence.Severity, model.SeverityHigh)
+	}
+}
+
+func TestResourceUsageRule_ReservedButIdle(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{usageNode("node-1", "4", "16Gi", "400m", "12Gi")},
+		Pods: []collector.PodInfo{
+			usagePod("default", "web", "node-1", "2", "6Gi", "200m", "6Gi"),
+			usagePod("default", "api", "node-1", "1500m", "6Gi", "200m", "6Gi"),
+		},
+	}
+
+	rule := &ResourceUsageRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d: %+v", len(findings), findings)
+	}
+	f := findings[0]
+	if f.ID != "node-usage-divergence-node-1-cpu" {
+		t.Errorf("id: got %q", f.ID)
+	}
+	if f.Severity != model.SeverityLow {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityLow)
+	}
+}
+
+func TestResourceUsageRule_NamespaceScopedSkipsDivergence(t *testing.T) {
+	// The node is busy with pods outside shop, which the snapshot lacks.
+	snap := func() *collector.Snapshot {
+		return &collector.Snapshot{
+			Nodes: []collector.NodeInfo{usageNode("node-1", "4", "16Gi", "3500m", "2Gi")},
+			Pods:  []collector.PodInfo{usagePod("shop", "web", "node-1", "500m", "1Gi", "400m", "1Gi")},
+		}
+	}
+	rule := &ResourceUsageRule{}
+
+	collected := snap()
+	collected.Collection = &collector.CollectionInfo{Options: collector.CollectionOptions{Namespace: "shop"}}
+	if findings := rule.Evaluate(collected); len(findings) != 0 {
+		t.Errorf("collected with a namespace: expected 0 findings, got %+v", findings)
+	}
+	if findings := rule.Evaluate(scopeSnapshot(snap(), []string{"shop"})); len(findings) != 0 {
+		t.Errorf("scoped by the config: expected 0 findings, got %+v", findings)
+	}
+	if findings := rule.Evaluate(snap()); len(findings) != 1 {
+		t.Errorf("unscoped: expected the divergence finding, got %+v", findings)
+	}
+}
+
+func TestResourceUsageRule_NoRequestsCountAsNoisy(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{usageNode("node-1", "8", "32Gi", "", "")},
+		Pods: []collector.PodInfo{
+			usagePod("default", "besteffort", "node-1", "", "", "1", "512Mi"),
+		},
+	}
+
+	rule := &ResourceUsageRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].Severity != model.SeverityMedium {
+		t.Errorf("severity: got %q, want %q", findings[0].Severity, model.SeverityMedium)
+	}
+}
+
+func TestResourceUsageRule_Name(t *testing.T) {
+	rule := &ResourceUsageRule{}
+	if rule.Name() != "resource-usage" {
+		t.Errorf("name: got %q, want %q", rule.Name(), "resource-usage")
+	}
+}
+
+var _ Rule = (*ResourceUsageRule)(nil)
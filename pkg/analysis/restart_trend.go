@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// RestartTrendRule flags containers whose restart count keeps rising between
+// frames of a recording. A single snapshot has no trend, so Evaluate finds
+// nothing.
+type RestartTrendRule struct {
+	params *RestartTrendParams
+}
+
+func (r *RestartTrendRule) Name() string { return "restart-trend" }
+
+func (r *RestartTrendRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Containers whose restart count keeps rising between frames of a recording.",
+		Categories:  []string{"pod-health"},
+		Uses:        []string{"pods", "kube-system", "logs"},
+		MinSeverity: model.SeverityMedium,
+		MaxSeverity: model.SeverityHigh,
+		Trend:       true,
+		DocURL:      docBaseURL + "restart-trend",
+	}
+}
+
+type RestartTrendParams struct {
+	// MinRestarts is the rise in restart count between two frames that is
+	// reported, and HighRestarts the rise that makes the finding high.
+	MinRestarts  int32 `json:"minRestarts"`
+	HighRestarts int32 `json:"highRestarts"`
+}
+
+func DefaultRestartTrendParams() RestartTrendParams {
+	return RestartTrendParams{MinRestarts: 2, HighRestarts: 5}
+}
+
+func (p *RestartTrendParams) Validate() error {
+	return firstError(
+		atLeast("minRestarts", p.MinRestarts, 1),
+		notBelow("highRestarts", p.HighRestarts, "minRestarts", p.MinRestarts),
+	)
+}
+
+func (r *RestartTrendRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *RestartTrendRule) SetParams(p Params) { r.params = p.(*RestartTrendParams) }
+
+func (r *RestartTrendRule) current() RestartTrendParams {
+	if r.params == nil {
+		return DefaultRestartTrendParams()
+	}
+	return *r.params
+}
+
+func (r *RestartTrendRule) Evaluate(snap *collector.Snapshot) []model.Finding { return nil }
+
+func (r *RestartTrendRule) EvaluateTrend(prev, cur *collector.Snapshot) []model.Finding {
+	params := r.current()
+	before := make(map[string]map[string]int32)
+	for _, pods := range [][]collector.PodInfo{prev.Pods, prev.KubeSystem.Pods} {
+		for _, p := range pods {
+			counts := make(map[string]int32, len(p.Containers))
+			for _, c := range p.Containers {
+				counts[c.Name] = c.RestartCount
+			}
+			before[restartTrendKey(p)] = counts
+		}
+	}
+
+	elapsed := cur.CollectedAt.Sub(prev.CollectedAt).Round(time.Second)
+	var findings []model.Finding
+	seen := make(map[string]bool)
+	for _, pods := range [][]collector.PodInfo{cur.Pods, cur.KubeSystem.Pods} {
+		for _, p := range pods {
+			key := restartTrendKey(p)
+			counts, ok := before[key]
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			for _, c := range p.Containers {
+				was, ok := counts[c.Name]
+				if !ok || c.RestartCount-was < params.MinRestarts {
+					continue
+				}
+				delta := c.RestartCount - was
+				severity := model.SeverityMedium
+				if delta >= params.HighRestarts {
+					severity = model.SeverityHigh
+				}
+				evidence := []model.Evidence{model.ObjectEvidence(
+					model.EvidenceResource,
+					model.PodRef(p.Namespace, p.Name, p.UID),
+					fmt.Sprintf("Container %s restarted %d time(s) between %s and %s",
+						c.Name, delta, prev.CollectedAt.UTC().Format(time.RFC3339), cur.CollectedAt.UTC().Format(time.RFC3339)),
+					map[string]string{"container": c.Name, "from": fmt.Sprint(was), "to": fmt.Sprint(c.RestartCount)},
+				)}
+				// Panics, OOMs and segfaults in the container's logs say why.
+				evidence = append(evidence, logPatternEvidence(cur.Logs, func(l collector.ContainerLog) bool {
+					return l.Namespace == p.Namespace && l.Pod == p.Name && l.Container == c.Name
+				}, logCategoryCrash)...)
+				findings = append(findings, model.Finding{
+					SchemaVersion: model.SchemaVersion,
+					ID:            fmt.Sprintf("restart-trend-%s-%s-%s", p.Namespace, p.Name, c.Name),
+					Title:         fmt.Sprintf("Container %s in pod %s/%s keeps restarting", c.Name, p.Namespace, p.Name),
+					Category:      "pod-health",
+					Severity:      severity,
+					Confidence:    0.8,
+					Summary:       fmt.Sprintf("Restart count rose by %d in %s, from %d to %d.", delta, elapsed, was, c.RestartCount),
+					Evidence:      evidence,
+					NextSteps: []string{
+						fmt.Sprintf("Check the previous container logs: kubectl logs -n %s %s -c %s --previous", p.Namespace, p.Name, c.Name),
+						"Look for OOMKilled or failing probes in the pod's last state",
+						"Compare with recent rollouts or config changes",
+					},
+					Timestamp: cur.CollectedAt.UTC(),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// restartTrendKey matches a pod across frames. A recreated pod has a new UID
+// and its restart count starts again, so it is not compared with the old one.
+func restartTrendKey(p collector.PodInfo) string {
+	return p.Namespace + "/" + p.Name + "/" + p.UID
+}
@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func restartFrame(at time.Time, uid string, restarts int32) *collector.Snapshot {
+	return &collector.Snapshot{
+		CollectedAt: at,
+		Pods: []collector.PodInfo{{
+			Namespace: "default", Name: "web-0", UID: uid,
+			Containers: []collector.ContainerInfo{{Name: "app", RestartCount: restarts}},
+		}},
+	}
+}
+
+func TestRestartTrendRule(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	rule := &RestartTrendRule{}
+
+	if got := rule.Evaluate(restartFrame(start, "uid-1", 40)); len(got) != 0 {
+		t.Errorf("a single snapshot has no trend, got %+v", got)
+	}
+
+	tests := []struct {
+		name     string
+		prev     *collector.Snapshot
+		cur      *collector.Snapshot
+		severity model.Severity
+	}{
+		{"steady", restartFrame(start, "uid-1", 3), restartFrame(start.Add(time.Minute), "uid-1", 4), ""},
+		{"rising", restartFrame(start, "uid-1", 3), restartFrame(start.Add(time.Minute), "uid-1", 6), model.SeverityMedium},
+		{"fast", restartFrame(start, "uid-1", 3), restartFrame(start.Add(time.Minute), "uid-1", 9), model.SeverityHigh},
+		{"recreated", restartFrame(start, "uid-1", 3), restartFrame(start.Add(time.Minute), "uid-2", 9), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := rule.EvaluateTrend(tt.prev, tt.cur)
+			if tt.severity == "" {
+				if len(findings) != 0 {
+					t.Errorf("expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("expected 1 finding, got %+v", findings)
+			}
+			f := findings[0]
+			if f.ID != "restart-trend-default-web-0-app" || f.Severity != tt.severity {
+				t.Errorf("got %s %s", f.ID, f.Severity)
+			}
+		})
+	}
+}
+
+func TestRestartTrendRule_CrashLogs(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	prev, cur := restartFrame(start, "uid-1", 3), restartFrame(start.Add(time.Minute), "uid-1", 6)
+	cur.Logs = []collector.ContainerLog{
+		{Namespace: "default", Pod: "web-0", Container: "app", Previous: true, Lines: []string{"starting", "panic: runtime error: invalid memory address"}},
+		{Namespace: "default", Pod: "web-0", Container: "sidecar", Lines: []string{"panic: unrelated"}},
+	}
+
+	findings := (&RestartTrendRule{}).EvaluateTrend(prev, cur)
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	var patterns []string
+	for _, e := range findings[0].Evidence {
+		if e.Type == model.EvidenceLog {
+			patterns = append(patterns, e.Data["container"]+" "+e.Data["pattern"])
+		}
+	}
+	if len(patterns) != 1 || patterns[0] != "app panic" {
+		t.Errorf("expected the panic from the app container, got %v", patterns)
+	}
+}
+
+func TestEngine_AnalyzeFrame(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	engine := NewEngine(&RestartTrendRule{})
+	prev, cur := restartFrame(start, "uid-1", 0), restartFrame(start.Add(time.Minute), "uid-1", 5)
+
+	if got := engine.Analyze(cur).Findings; len(got) != 0 {
+		t.Errorf("Analyze has no previous frame, got %+v", got)
+	}
+	got := engine.AnalyzeFrame(prev, cur).Findings
+	if len(got) != 1 || got[0].Rule != "restart-trend" {
+		t.Errorf("AnalyzeFrame: got %+v", got)
+	}
+}
@@ -9,3 +9,43 @@ type Rule interface {
 	Name() string
 	Evaluate(snap *collector.Snapshot) []model.Finding
 }
+
+// TrendRule is a Rule that can also compare a snapshot with the frame before
+// it in a recording. Engine.AnalyzeFrame calls EvaluateTrend instead of
+// Evaluate when a previous frame is available.
+type TrendRule interface {
+	Rule
+	EvaluateTrend(prev, cur *collector.Snapshot) []model.Finding
+}
+
+// ConfigurableRule is a Rule with tunable parameters. Params returns a
+// pointer to a copy of the current parameters, the defaults unless SetParams
+// was called; Engine.Configure decodes the config file over it, validates it
+// and passes it back to SetParams.
+type ConfigurableRule interface {
+	Rule
+	Params() Params
+	SetParams(p Params)
+}
+
+// Params is the parameter struct of a ConfigurableRule. Its JSON field names
+// are the keys of the rule's params in the config file.
+type Params interface {
+	Validate() error
+}
+
+// DescribedRule is a Rule that describes itself. Engine.AnalyzeFrame skips
+// it, and lists it in the report, when a collector it requires did not
+// collect anything.
+type DescribedRule interface {
+	Rule
+	Metadata() model.RuleMetadata
+}
+
+// FallibleRule is a Rule whose evaluation can fail, such as an exec plugin.
+// Engine.AnalyzeFrame calls Run instead of Evaluate and lists the error in
+// the report, keeping whatever findings Run returned with it.
+type FallibleRule interface {
+	Rule
+	Run(snap *collector.Snapshot) ([]model.Finding, error)
+}
@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// snapshotInfo summarises the snapshot header for the report.
+func snapshotInfo(snap *collector.Snapshot) *model.SnapshotInfo {
+	info := &model.SnapshotInfo{
+		CollectedAt:   snap.CollectedAt,
+		Since:         snap.Since,
+		SchemaVersion: snap.SchemaVersion,
+		NodeVersions:  nodeVersionCounts(snap.Nodes),
+	}
+	if c := snap.Cluster; c != nil {
+		info.ClusterID = c.ID
+		info.Context = c.Context
+		info.APIServerHost = c.APIServerHost
+		info.ServerVersion = c.ServerVersion
+		info.Platform = c.Platform
+	}
+	if c := snap.Collection; c != nil {
+		info.CollectorVersion = c.CollectorVersion
+		for _, e := range c.Errors {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("%s: %s", e.Collector, e.Message))
+		}
+		for _, d := range c.RBACDenials {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("%s: forbidden: %s", d.Collector, rbacDenialSummary(d)))
+		}
+	}
+	if snap.Redaction != nil {
+		info.Redaction = snap.Redaction.Profile
+	}
+	if snap.Signature != nil {
+		info.SignedBy = snap.Signature.KeyID
+	}
+	return info
+}
+
+func rbacDenialSummary(d collector.RBACDenial) string {
+	if d.Verb == "" || d.Resource == "" {
+		return d.Message
+	}
+	resource := d.Resource
+	if d.Group != "" {
+		resource += "." + d.Group
+	}
+	if d.Namespace != "" {
+		return fmt.Sprintf("cannot %s %s in namespace %s", d.Verb, resource, d.Namespace)
+	}
+	return fmt.Sprintf("cannot %s %s", d.Verb, resource)
+}
+
+// nodeVersionCounts groups nodes by their version combination, most common
+// first, so mixed-version clusters stand out in the report header.
+func nodeVersionCounts(nodes []collector.NodeInfo) []model.NodeVersionCount {
+	counts := make(map[model.NodeVersionCount]int)
+	for _, n := range nodes {
+		key := model.NodeVersionCount{
+			Kubelet:          n.Versions.Kubelet,
+			ContainerRuntime: n.Versions.ContainerRuntime,
+			OSImage:          n.Versions.OSImage,
+			Kernel:           n.Versions.Kernel,
+		}
+		if key == (model.NodeVersionCount{}) {
+			continue
+		}
+		counts[key]++
+	}
+
+	result := make([]model.NodeVersionCount, 0, len(counts))
+	for v, n := range counts {
+		v.Nodes = n
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Nodes != b.Nodes {
+			return a.Nodes > b.Nodes
+		}
+		if a.Kubelet != b.Kubelet {
+			return a.Kubelet < b.Kubelet
+		}
+		if a.ContainerRuntime != b.ContainerRuntime {
+			return a.ContainerRuntime < b.ContainerRuntime
+		}
+		if a.OSImage != b.OSImage {
+			return a.OSImage < b.OSImage
+		}
+		return a.Kernel < b.Kernel
+	})
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+func TestSnapshotInfo(t *testing.T) {
+	v129 := collector.NodeVersions{Kubelet: "v1.29.1", ContainerRuntime: "containerd://1.7.2", OSImage: "Ubuntu 22.04.3 LTS", Kernel: "5.15.0-1051-aws"}
+	v128 := v129
+	v128.Kubelet = "v1.28.5"
+
+	snap := &collector.Snapshot{
+		SchemaVersion: collector.SnapshotSchemaVersion,
+		CollectedAt:   time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC),
+		Since:         "30m0s",
+		Cluster:       &collector.ClusterInfo{ID: "uid-ks", Context: "prod-eu", ServerVersion: "v1.29.1"},
+		Collection: &collector.CollectionInfo{
+			CollectorVersion: "v0.9.0",
+			Errors:           []collector.CollectorError{{Collector: "webhooks", Message: "timeout"}},
+			RBACDenials: []collector.RBACDenial{
+				{Collector: "pvs", Verb: "list", Resource: "persistentvolumes", Message: "forbidden"},
+			},
+		},
+		Nodes: []collector.NodeInfo{
+			{Name: "a", Versions: v129},
+			{Name: "b", Versions: v128},
+			{Name: "c", Versions: v129},
+			{Name: "d"},
+		},
+	}
+
+	report := NewEngine().Analyze(snap)
+	info := report.Snapshot
+	if info == nil {
+		t.Fatal("report has no snapshot info")
+	}
+	if info.ClusterID != "uid-ks" || info.Context != "prod-eu" || info.CollectorVersion != "v0.9.0" {
+		t.Errorf("header: got %+v", info)
+	}
+	if len(info.NodeVersions) != 2 || info.NodeVersions[0].Kubelet != "v1.29.1" || info.NodeVersions[0].Nodes != 2 {
+		t.Errorf("node versions: got %+v", info.NodeVersions)
+	}
+	want := []string{"webhooks: timeout", "pvs: forbidden: cannot list persistentvolumes"}
+	if len(info.Warnings) != len(want) {
+		t.Fatalf("warnings: got %q, want %q", info.Warnings, want)
+	}
+	for i := range want {
+		if info.Warnings[i] != want[i] {
+			t.Errorf("warning %d: got %q, want %q", i, info.Warnings[i], want[i])
+		}
+	}
+}
@@ -11,10 +11,57 @@ import (
 	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
-type StorageRule struct{}
+type StorageRule struct {
+	params *StorageParams
+}
 
 func (r *StorageRule) Name() string { return "storage-issues" }
 
+func (r *StorageRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Pending PVCs, failed PVs, and attach, mount and CSI errors in events and CSI driver logs.",
+		Categories:  []string{"storage"},
+		Uses:        []string{"pvcs", "pvs", "events", "logs"},
+		MinSeverity: model.SeverityLow,
+		MaxSeverity: model.SeverityCritical,
+		DocURL:      docBaseURL + "storage-issues",
+	}
+}
+
+type StorageParams struct {
+	// CriticalPendingPVCs and CriticalEvents are the number of Pending PVCs
+	// or storage Warning events that make the finding critical.
+	CriticalPendingPVCs int `json:"criticalPendingPVCs"`
+	CriticalEvents      int `json:"criticalEvents"`
+}
+
+func DefaultStorageParams() StorageParams {
+	return StorageParams{CriticalPendingPVCs: 3, CriticalEvents: 5}
+}
+
+func (p *StorageParams) Validate() error {
+	return firstError(
+		atLeast("criticalPendingPVCs", p.CriticalPendingPVCs, 1),
+		atLeast("criticalEvents", p.CriticalEvents, 1),
+	)
+}
+
+func (r *StorageRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *StorageRule) SetParams(p Params) { r.params = p.(*StorageParams) }
+
+func (r *StorageRule) current() StorageParams {
+	if r.params == nil {
+		return DefaultStorageParams()
+	}
+	return *r.params
+}
+
 var storageEventReasons = []string{
 	"FailedAttachVolume",
 	"FailedMount",
@@ -42,53 +89,58 @@ func (r *StorageRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 	for _, pvc := range snap.PVCs {
 		if pvc.Phase == corev1.ClaimPending {
 			pendingPVCs++
-			evidence = append(evidence, model.Evidence{
-				Type:    model.EvidenceResource,
-				Ref:     fmt.Sprintf("pvc/%s/%s", pvc.Namespace, pvc.Name),
-				Message: fmt.Sprintf("PVC is Pending (storageClass: %s)", storageClassOrNone(pvc.StorageClassName)),
-				Data: map[string]string{
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				model.PVCRef(pvc.Namespace, pvc.Name, pvc.UID),
+				fmt.Sprintf("PVC is Pending (storageClass: %s)", storageClassOrNone(pvc.StorageClassName)),
+				map[string]string{
 					"namespace":    pvc.Namespace,
 					"storageClass": pvc.StorageClassName,
 					"volumeName":   pvc.VolumeName,
 				},
-			})
+			))
 		}
 	}
 
 	for _, pv := range snap.PVs {
 		if pv.Phase == corev1.VolumeFailed {
-			evidence = append(evidence, model.Evidence{
-				Type:    model.EvidenceResource,
-				Ref:     fmt.Sprintf("pv/%s", pv.Name),
-				Message: fmt.Sprintf("PV is in Failed phase (storageClass: %s)", storageClassOrNone(pv.StorageClassName)),
-				Data: map[string]string{
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				model.PVRef(pv.Name, pv.UID),
+				fmt.Sprintf("PV is in Failed phase (storageClass: %s)", storageClassOrNone(pv.StorageClassName)),
+				map[string]string{
 					"storageClass": pv.StorageClassName,
-					"claimRef":     pv.ClaimRef,
+					"claimRef":     claimRefString(pv.ClaimRef),
 				},
-			})
+			))
 		}
 	}
 
 	storageEvents := findStorageEvents(snap.Events)
 	for _, ev := range storageEvents {
-		evidence = append(evidence, model.Evidence{
-			Type:    model.EvidenceEvent,
-			Ref:     ev.InvolvedObject,
-			Message: truncateStorage(ev.Message, maxLogLineLen),
-			Data: map[string]string{
+		evidence = append(evidence, model.ObjectEvidence(
+			model.EvidenceEvent,
+			ev.InvolvedObject,
+			collector.TruncateLogLine(ev.Message),
+			map[string]string{
 				"reason":    ev.Reason,
 				"count":     fmt.Sprintf("%d", ev.Count),
 				"namespace": ev.Namespace,
 			},
-		})
+		))
 	}
 
+	logEvidence := logPatternEvidence(snap.Logs, func(l collector.ContainerLog) bool {
+		return strings.Contains(strings.ToLower(l.Pod), "csi")
+	}, logCategoryStorage)
+	evidence = append(evidence, logEvidence...)
+
 	if len(evidence) == 0 {
 		return nil
 	}
 
-	confidence := storageConfidence(pendingPVCs, len(storageEvents))
-	severity := storageSeverity(pendingPVCs, len(storageEvents))
+	confidence := storageConfidence(pendingPVCs, len(storageEvents), len(logEvidence))
+	severity := storageSeverity(pendingPVCs, len(storageEvents), r.current())
 
 	return []model.Finding{
 		{
@@ -98,7 +150,7 @@ func (r *StorageRule) Evaluate(snap *collector.Snapshot) []model.Finding {
 			Category:      "storage",
 			Severity:      severity,
 			Confidence:    confidence,
-			Summary:       storageSummary(pendingPVCs, len(storageEvents)),
+			Summary:       storageSummary(pendingPVCs, len(storageEvents), len(logEvidence)),
 			Evidence:      evidence,
 			NextSteps:     storageNextSteps(),
 			Timestamp:     time.Now().UTC(),
@@ -138,6 +190,13 @@ func isStorageEvent(ev collector.EventInfo) bool {
 	return false
 }
 
+func claimRefString(ref *model.ObjectRef) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.String()
+}
+
 func storageClassOrNone(sc string) string {
 	if sc == "" {
 		return "<none>"
@@ -145,14 +204,7 @@ func storageClassOrNone(sc string) string {
 	return sc
 }
 
-func truncateStorage(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
-	return s[:max-3] + "..."
-}
-
-func storageConfidence(pendingPVCs, eventCount int) float64 {
+func storageConfidence(pendingPVCs, eventCount, logPatterns int) float64 {
 	base := 0.5
 	if pendingPVCs > 0 {
 		base += 0.20
@@ -166,14 +218,17 @@ func storageConfidence(pendingPVCs, eventCount int) float64 {
 	if eventCount > 3 {
 		base += 0.10
 	}
+	if logPatterns > 0 {
+		base += 0.10
+	}
 	if base > 1.0 {
 		base = 1.0
 	}
 	return base
 }
 
-func storageSeverity(pendingPVCs, eventCount int) model.Severity {
-	if pendingPVCs >= 3 || eventCount >= 5 {
+func storageSeverity(pendingPVCs, eventCount int, params StorageParams) model.Severity {
+	if pendingPVCs >= params.CriticalPendingPVCs || eventCount >= params.CriticalEvents {
 		return model.SeverityCritical
 	}
 	if pendingPVCs > 0 && eventCount > 0 {
@@ -185,7 +240,7 @@ func storageSeverity(pendingPVCs, eventCount int) model.Severity {
 	return model.SeverityLow
 }
 
-func storageSummary(pendingPVCs, eventCount int) string {
+func storageSummary(pendingPVCs, eventCount, logLines int) string {
 	parts := []string{}
 	if pendingPVCs > 0 {
 		parts = append(parts, fmt.Sprintf("%d PVC(s) stuck in Pending.", pendingPVCs))
@@ -193,6 +248,9 @@ func storageSummary(pendingPVCs, eventCount int) string {
 	if eventCount > 0 {
 		parts = append(parts, fmt.Sprintf("%d storage-related warning event(s).", eventCount))
 	}
+	if logLines > 0 {
+		parts = append(parts, fmt.Sprintf("%d error pattern(s) in CSI driver logs.", logLines))
+	}
 	return strings.Join(parts, " ")
 }
 
@@ -62,8 +62,8 @@ func TestStorageRule_PendingPVC(t *testing.T) {
 	if len(f.Evidence) < 1 {
 		t.Fatal("expected at least 1 evidence item")
 	}
-	if f.Evidence[0].Ref != "pvc/default/stuck-pvc" {
-		t.Errorf("evidence ref: got %q, want %q", f.Evidence[0].Ref, "pvc/default/stuck-pvc")
+	if f.Evidence[0].Ref != "persistentvolumeclaim/default/stuck-pvc" {
+		t.Errorf("evidence ref: got %q, want %q", f.Evidence[0].Ref, "persistentvolumeclaim/default/stuck-pvc")
 	}
 }
 
@@ -84,7 +84,7 @@ func TestStorageRule_PendingPVCWithEvents(t *testing.T) {
 				Reason:         "ProvisioningFailed",
 				Message:        "Failed to provision volume with StorageClass ebs-sc: insufficient capacity",
 				Type:           "Warning",
-				InvolvedObject: "PersistentVolumeClaim/prod/db-pvc",
+				InvolvedObject: model.ObjectRef{Kind: "PersistentVolumeClaim", Namespace: "prod", Name: "db-pvc"},
 				Count:          5,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
@@ -123,13 +123,14 @@ func TestStorageRule_PendingPVCWithEvents(t *testing.T) {
 }
 
 func TestStorageRule_FailedPV(t *testing.T) {
+	claim := model.PVCRef("default", "my-claim", "")
 	snap := &collector.Snapshot{
 		PVs: []collector.PVInfo{
 			{
 				Name:             "pv-broken",
 				Phase:            corev1.VolumeFailed,
 				StorageClassName: "local-storage",
-				ClaimRef:         "default/my-claim",
+				ClaimRef:         &claim,
 			},
 		},
 	}
@@ -143,7 +144,7 @@ func TestStorageRule_FailedPV(t *testing.T) {
 
 	hasFailedPV := false
 	for _, e := range findings[0].Evidence {
-		if e.Ref == "pv/pv-broken" {
+		if e.Ref == "persistentvolume/pv-broken" {
 			hasFailedPV = true
 		}
 	}
@@ -161,7 +162,7 @@ func TestStorageRule_CSIEvents(t *testing.T) {
 				Reason:         "FailedMount",
 				Message:        "MountVolume.SetUp failed for volume pvc-123: CSI driver timeout",
 				Type:           "Warning",
-				InvolvedObject: "Pod/default/app-pod",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "app-pod"},
 				Count:          3,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC),
@@ -172,7 +173,7 @@ func TestStorageRule_CSIEvents(t *testing.T) {
 				Reason:         "FailedAttachVolume",
 				Message:        "AttachVolume.Attach failed: volume not found",
 				Type:           "Warning",
-				InvolvedObject: "Pod/default/app-pod",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "app-pod"},
 				Count:          2,
 				FirstTimestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
 				LastTimestamp:  time.Date(2024, 1, 1, 10, 10, 0, 0, time.UTC),
@@ -224,7 +225,7 @@ func TestStorageRule_OnlyEventsNoStorage(t *testing.T) {
 				Reason:         "FailedAttachVolume",
 				Message:        "AttachVolume.Attach failed for volume pvc-abc",
 				Type:           "Warning",
-				InvolvedObject: "Pod/default/my-pod",
+				InvolvedObject: model.ObjectRef{Kind: "Pod", Namespace: "default", Name: "my-pod"},
 				Count:          1,
 			},
 		},
@@ -241,6 +242,47 @@ func TestStorageRule_OnlyEventsNoStorage(t *testing.T) {
 	}
 }
 
+func TestStorageRule_CSIDriverLogs(t *testing.T) {
+	snap := &collector.Snapshot{
+		PVCs: []collector.PVCInfo{
+			{Name: "data", Namespace: "default", Phase: corev1.ClaimPending, StorageClassName: "ebs-sc"},
+		},
+		Logs: []collector.ContainerLog{
+			{
+				Namespace: "kube-system", Pod: "ebs-csi-controller-7f9c", Container: "ebs-plugin",
+				Lines: []string{
+					"I0615 10:30:00.000000 1 controller.go:104] CreateVolume: called",
+					"E0615 10:30:05.000000 1 driver.go:119] GRPC error: rpc error: code = DeadlineExceeded desc = context deadline exceeded",
+				},
+			},
+			{
+				Namespace: "default", Pod: "web-0", Container: "web",
+				Lines: []string{"mount failed: permission denied"},
+			},
+		},
+	}
+
+	rule := &StorageRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+
+	logEvidence := 0
+	for _, e := range findings[0].Evidence {
+		if e.Type == model.EvidenceLog {
+			logEvidence++
+			if e.Data["pattern"] != "csi-rpc-error" {
+				t.Errorf("pattern: got %q", e.Data["pattern"])
+			}
+		}
+	}
+	if logEvidence != 1 {
+		t.Errorf("log evidence: got %d, want 1", logEvidence)
+	}
+}
+
 func TestStorageRule_Name(t *testing.T) {
 	rule := &StorageRule{}
 	if rule.Name() != "storage-issues" {
@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// FrameAnalyzer analyses one frame of a recording given the frame before it,
+// which is nil for the first frame.
+type FrameAnalyzer func(prev, cur *collector.Snapshot) model.Report
+
+// Replay analyses the frames returned by next, which returns io.EOF after the
+// last one, and follows every finding across them by its fingerprint, so a
+// finding whose ID or title changes between frames stays one entry. Only two
+// frames are held at a time. If next fails, the timeline of the frames read so far is
+// returned with the error.
+func Replay(next func() (*collector.Snapshot, error), analyze FrameAnalyzer) (model.Timeline, error) {
+	t := model.Timeline{SchemaVersion: model.SchemaVersion, Frames: []model.TimelineFrame{}}
+	byFP := make(map[string]*model.TimelineFinding)
+	present := make(map[string]bool)
+
+	var prev *collector.Snapshot
+	for {
+		cur, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return finishTimeline(t, byFP), err
+		}
+
+		at := cur.CollectedAt
+		report := analyze(prev, cur)
+		t.Frames = append(t.Frames, model.TimelineFrame{CollectedAt: at, Findings: len(report.Findings)})
+
+		now := make(map[string]bool, len(report.Findings))
+		for _, f := range report.Findings {
+			fp := f.Fingerprint
+			if fp == "" {
+				fp = Fingerprint(f)
+			}
+			now[fp] = true
+			tf, ok := byFP[fp]
+			if !ok {
+				tf = &model.TimelineFinding{ID: f.ID, Fingerprint: fp, Rule: f.Rule, Category: f.Category, Title: f.Title, FirstSeen: at, PeakSeverity: f.Severity, PeakAt: at}
+				byFP[fp] = tf
+			}
+			if !present[fp] {
+				tf.Appearances++
+				tf.ClearedAt = nil
+			}
+			if severityRank(f.Severity) > severityRank(tf.PeakSeverity) {
+				tf.PeakSeverity, tf.PeakAt = f.Severity, at
+			}
+			tf.LastSeen = at
+			tf.Frames++
+		}
+		for fp := range present {
+			if !now[fp] {
+				cleared := at
+				byFP[fp].ClearedAt = &cleared
+			}
+		}
+		present = now
+		prev = cur
+	}
+	return finishTimeline(t, byFP), nil
+}
+
+func finishTimeline(t model.Timeline, byFP map[string]*model.TimelineFinding) model.Timeline {
+	t.Findings = make([]model.TimelineFinding, 0, len(byFP))
+	for _, tf := range byFP {
+		t.Findings = append(t.Findings, *tf)
+	}
+	sort.Slice(t.Findings, func(i, j int) bool {
+		a, b := t.Findings[i], t.Findings[j]
+		if !a.FirstSeen.Equal(b.FirstSeen) {
+			return a.FirstSeen.Before(b.FirstSeen)
+		}
+		if ra, rb := severityRank(a.PeakSeverity), severityRank(b.PeakSeverity); ra != rb {
+			return ra > rb
+		}
+		return a.ID < b.ID
+	})
+	return t
+}
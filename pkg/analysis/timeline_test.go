@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	frame := func(i int) time.Time { return start.Add(time.Duration(i) * 30 * time.Second) }
+
+	// Findings per frame: a node that comes under pressure, escalates and
+	// recovers, and DNS trouble that comes and goes.
+	perFrame := [][]model.Finding{
+		{},
+		{nodeFinding("a", model.SeverityMedium)},
+		{nodeFinding("a", model.SeverityCritical), {ID: "dns-instability", Severity: model.SeverityHigh}},
+		{nodeFinding("a", model.SeverityHigh)},
+		{{ID: "dns-instability", Severity: model.SeverityHigh}},
+	}
+
+	i := 0
+	next := func() (*collector.Snapshot, error) {
+		if i == len(perFrame) {
+			return nil, io.EOF
+		}
+		i++
+		return &collector.Snapshot{CollectedAt: frame(i - 1)}, nil
+	}
+	calls := 0
+	analyze := func(prev, cur *collector.Snapshot) model.Report {
+		if (prev == nil) != (calls == 0) {
+			t.Errorf("frame %d: previous frame %v", calls, prev)
+		}
+		calls++
+		return model.NewReport(perFrame[calls-1])
+	}
+
+	tl, err := Replay(next, analyze)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tl.Frames) != 5 || tl.Frames[2].Findings != 2 {
+		t.Errorf("frames: got %+v", tl.Frames)
+	}
+	if len(tl.Findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", tl.Findings)
+	}
+
+	node := tl.Findings[0]
+	if node.ID != "node-pressure-a-memorypressure" || !node.FirstSeen.Equal(frame(1)) ||
+		node.PeakSeverity != model.SeverityCritical || !node.PeakAt.Equal(frame(2)) ||
+		!node.LastSeen.Equal(frame(3)) || node.ClearedAt == nil || !node.ClearedAt.Equal(frame(4)) ||
+		node.Frames != 3 || node.Appearances != 1 {
+		t.Errorf("node finding: got %+v", node)
+	}
+
+	dns := tl.Findings[1]
+	if dns.ID != "dns-instability" || !dns.FirstSeen.Equal(frame(2)) || dns.ClearedAt != nil ||
+		dns.Frames != 2 || dns.Appearances != 2 {
+		t.Errorf("dns finding: got %+v", dns)
+	}
+}
+
+func TestReplay_Fingerprints(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	// The first finding is renamed between frames; the other two share an ID
+	// but are about different objects.
+	perFrame := [][]model.Finding{
+		{{ID: "webhook-old", Fingerprint: "aaaa", Severity: model.SeverityLow}},
+		{
+			{ID: "webhook-new", Fingerprint: "aaaa", Severity: model.SeverityHigh},
+			{ID: "shared", Fingerprint: "bbbb", Severity: model.SeverityLow},
+			{ID: "shared", Fingerprint: "cccc", Severity: model.SeverityLow},
+		},
+	}
+	i := 0
+	next := func() (*collector.Snapshot, error) {
+		if i == len(perFrame) {
+			return nil, io.EOF
+		}
+		i++
+		return &collector.Snapshot{CollectedAt: start.Add(time.Duration(i) * time.Minute)}, nil
+	}
+	tl, err := Replay(next, func(prev, cur *collector.Snapshot) model.Report {
+		return model.NewReport(perFrame[i-1])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tl.Findings) != 3 {
+		t.Fatalf("expected 3 findings, got %+v", tl.Findings)
+	}
+	renamed := tl.Findings[0]
+	if renamed.ID != "webhook-old" || renamed.Fingerprint != "aaaa" || renamed.Frames != 2 || renamed.PeakSeverity != model.SeverityHigh {
+		t.Errorf("renamed finding: got %+v", renamed)
+	}
+}
+
+func TestReplay_ReadError(t *testing.T) {
+	read := 0
+	next := func() (*collector.Snapshot, error) {
+		read++
+		if read > 2 {
+			return nil, errors.New("unexpected EOF")
+		}
+		return &collector.Snapshot{}, nil
+	}
+	tl, err := Replay(next, func(prev, cur *collector.Snapshot) model.Report {
+		return model.NewReport([]model.Finding{{ID: "x", Severity: model.SeverityLow}})
+	})
+	if err == nil {
+		t.Fatal("expected the read error")
+	}
+	if len(tl.Frames) != 2 || len(tl.Findings) != 1 {
+		t.Errorf("expected the frames read before the error, got %+v", tl)
+	}
+}
@@ -0,0 +1,390 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type WebhookRule struct {
+	params *WebhookParams
+}
+
+func (r *WebhookRule) Name() string { return "admission-webhooks" }
+
+func (r *WebhookRule) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        r.Name(),
+		Version:     "1",
+		Description: "Admission webhooks with unreachable backends, failing or slow calls, risky timeouts, or that intercept kube-system.",
+		Categories:  []string{"admission"},
+		Requires:    []string{"webhooks"},
+		Uses:        []string{"endpoints", "events", "kube-system", "apiserver-metrics"},
+		MinSeverity: model.SeverityLow,
+		MaxSeverity: model.SeverityCritical,
+		DocURL:      docBaseURL + "admission-webhooks",
+	}
+}
+
+type WebhookParams struct {
+	// LongTimeoutSeconds is the timeoutSeconds from which a webhook with
+	// failurePolicy=Fail is reported, since it can stall every matching
+	// request for that long.
+	LongTimeoutSeconds int32 `json:"longTimeoutSeconds"`
+	// SlowSeconds is the p99 admission latency above which a webhook is slow.
+	SlowSeconds float64 `json:"slowSeconds"`
+	// MinSamples is the number of calls the latency series needs before its
+	// p99 is trusted.
+	MinSamples uint64 `json:"minSamples"`
+}
+
+func DefaultWebhookParams() WebhookParams {
+	return WebhookParams{LongTimeoutSeconds: 15, SlowSeconds: 1.0, MinSamples: 20}
+}
+
+func (p *WebhookParams) Validate() error {
+	return firstError(
+		atLeast("longTimeoutSeconds", p.LongTimeoutSeconds, 1),
+		positive("slowSeconds", p.SlowSeconds),
+		atLeast("minSamples", p.MinSamples, 1),
+	)
+}
+
+func (r *WebhookRule) Params() Params {
+	p := r.current()
+	return &p
+}
+
+func (r *WebhookRule) SetParams(p Params) { r.params = p.(*WebhookParams) }
+
+func (r *WebhookRule) current() WebhookParams {
+	if r.params == nil {
+		return DefaultWebhookParams()
+	}
+	return *r.params
+}
+
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+var webhookFailurePattern = regexp.MustCompile(`failed calling webhook "([^"]+)"`)
+
+type webhookIssues struct {
+	unreachable      bool
+	failureEvents    int
+	slowP99          float64
+	callErrors       uint64
+	longTimeout      bool
+	interceptsSystem bool
+}
+
+func (r *WebhookRule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	if len(snap.Webhooks) == 0 {
+		return nil
+	}
+
+	params := r.current()
+	failures := findWebhookFailureEvents(snap.Events)
+	sameName := make(map[string]int)
+	for _, wh := range snap.Webhooks {
+		sameName[wh.Name]++
+	}
+
+	var findings []model.Finding
+	for _, wh := range snap.Webhooks {
+		failPolicy := wh.FailurePolicy == string(admissionregistrationv1.Fail)
+
+		var issues webhookIssues
+		var evidence []model.Evidence
+
+		whRef := webhookRef(wh)
+
+		if wh.Service != nil {
+			if ep, ok := findServiceEndpoints(snap.Endpoints, wh.Service.Namespace, wh.Service.Name); ok && ep.ReadyAddresses == 0 {
+				issues.unreachable = true
+				msg := fmt.Sprintf("Backing service %s/%s has no ready endpoints", wh.Service.Namespace, wh.Service.Name)
+				if !ep.Found {
+					msg = fmt.Sprintf("Backing service %s/%s has no endpoints object", wh.Service.Namespace, wh.Service.Name)
+				}
+				evidence = append(evidence, model.ObjectEvidence(
+					model.EvidenceResource,
+					model.ServiceRef(wh.Service.Namespace, wh.Service.Name),
+					msg,
+					map[string]string{
+						"webhook":           wh.Name,
+						"notReadyAddresses": fmt.Sprintf("%d", ep.NotReadyAddresses),
+					},
+				))
+			}
+		}
+
+		for _, ev := range failures[wh.Name] {
+			// Events only name the webhook. When several configurations use
+			// the name, the endpoint in the error decides.
+			if sameName[wh.Name] > 1 && !mentionsWebhookEndpoint(ev.Message, wh) {
+				continue
+			}
+			issues.failureEvents += int(ev.Count)
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceEvent,
+				ev.InvolvedObject,
+				collector.TruncateLogLine(ev.Message),
+				map[string]string{
+					"reason": ev.Reason,
+					"count":  fmt.Sprintf("%d", ev.Count),
+				},
+			))
+		}
+
+		if snap.APIServer != nil {
+			for _, wl := range snap.APIServer.WebhookLatencies {
+				if wl.Name != wh.Name || wl.Type != wh.Type {
+					continue
+				}
+				if wl.CallErrors > 0 {
+					issues.callErrors += wl.CallErrors
+					evidence = append(evidence, model.Evidence{
+						Type:    model.EvidenceMetric,
+						Ref:     seriesRef("apiserver_admission_webhook_rejection_count", "name", wl.Name, "type", wl.Type),
+						Message: fmt.Sprintf("%d call(s) to the webhook failed", wl.CallErrors),
+					})
+				}
+				if wl.Latency.Count < params.MinSamples {
+					continue
+				}
+				p99 := histogramQuantile(0.99, wl.Latency)
+				if p99 > params.SlowSeconds {
+					issues.slowP99 = p99
+					evidence = append(evidence, model.Evidence{
+						Type:    model.EvidenceMetric,
+						Ref:     seriesRef("apiserver_admission_webhook_admission_duration_seconds", "name", wl.Name, "type", wl.Type),
+						Message: fmt.Sprintf("Webhook p99 admission latency is %s", formatSeconds(p99)),
+						Data: map[string]string{
+							"p99":   fmt.Sprintf("%.3f", p99),
+							"count": fmt.Sprintf("%d", wl.Latency.Count),
+						},
+					})
+				}
+			}
+		}
+
+		if failPolicy && wh.TimeoutSeconds >= params.LongTimeoutSeconds {
+			issues.longTimeout = true
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				whRef,
+				fmt.Sprintf("Webhook %s has timeoutSeconds=%d with failurePolicy=Fail", wh.Name, wh.TimeoutSeconds),
+				map[string]string{
+					"timeoutSeconds": fmt.Sprintf("%d", wh.TimeoutSeconds),
+					"failurePolicy":  wh.FailurePolicy,
+				},
+			))
+		}
+
+		if selectorMatchesNamespace(wh.NamespaceSelector, "kube-system", snap.KubeSystem.NamespaceLabels) {
+			issues.interceptsSystem = true
+			evidence = append(evidence, model.ObjectEvidence(
+				model.EvidenceResource,
+				whRef,
+				fmt.Sprintf("Webhook %s intercepts kube-system with failurePolicy=%s", wh.Name, wh.FailurePolicy),
+				map[string]string{
+					"failurePolicy": wh.FailurePolicy,
+				},
+			))
+		}
+
+		if len(evidence) == 0 {
+			continue
+		}
+		// Every finding names its webhook, so that findings about different
+		// webhooks are never merged or share a fingerprint.
+		if !issues.longTimeout && !issues.interceptsSystem {
+			evidence = append([]model.Evidence{model.ObjectEvidence(
+				model.EvidenceResource,
+				whRef,
+				fmt.Sprintf("Webhook %s has failurePolicy=%s and timeoutSeconds=%d", wh.Name, wh.FailurePolicy, wh.TimeoutSeconds),
+				map[string]string{
+					"timeoutSeconds": fmt.Sprintf("%d", wh.TimeoutSeconds),
+					"failurePolicy":  wh.FailurePolicy,
+				},
+			)}, evidence...)
+		}
+
+		// Webhook names are only unique within their configuration.
+		findings = append(findings, model.Finding{
+			SchemaVersion: model.SchemaVersion,
+			ID:            fmt.Sprintf("admission-webhook-%s-%s-%s", wh.Type, wh.Configuration, wh.Name),
+			Title:         webhookTitle(wh.Name, issues, failPolicy),
+			Category:      "admission",
+			Severity:      webhookSeverity(issues, failPolicy),
+			Confidence:    webhookConfidence(issues),
+			Summary:       webhookSummary(wh, issues, failPolicy),
+			Evidence:      evidence,
+			NextSteps:     webhookNextSteps(wh, issues),
+			Timestamp:     time.Now().UTC(),
+		})
+	}
+
+	return findings
+}
+
+func findWebhookFailureEvents(events []collector.EventInfo) map[string][]collector.EventInfo {
+	byWebhook := make(map[string][]collector.EventInfo)
+	for _, ev := range events {
+		m := webhookFailurePattern.FindStringSubmatch(ev.Message)
+		if m == nil {
+			continue
+		}
+		byWebhook[m[1]] = append(byWebhook[m[1]], ev)
+	}
+	return byWebhook
+}
+
+// mentionsWebhookEndpoint reports whether an apiserver error, which quotes the
+// URL it called, is about wh's endpoint.
+func mentionsWebhookEndpoint(msg string, wh collector.WebhookInfo) bool {
+	if wh.Service == nil {
+		return wh.URL != "" && strings.Contains(msg, wh.URL)
+	}
+	port := wh.Service.Port
+	if port == 0 {
+		port = 443
+	}
+	host := fmt.Sprintf("//%s.%s.svc", wh.Service.Name, wh.Service.Namespace)
+	return strings.Contains(msg, host+wh.Service.Path) ||
+		strings.Contains(msg, fmt.Sprintf("%s:%d%s", host, port, wh.Service.Path))
+}
+
+func findServiceEndpoints(endpoints []collector.ServiceEndpointsInfo, namespace, name string) (collector.ServiceEndpointsInfo, bool) {
+	for _, ep := range endpoints {
+		if ep.Namespace == namespace && ep.Name == name {
+			return ep, true
+		}
+	}
+	return collector.ServiceEndpointsInfo{}, false
+}
+
+func selectorMatchesNamespace(sel *metav1.LabelSelector, namespace string, nsLabels map[string]string) bool {
+	if sel == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(sel)
+	if err != nil {
+		return false
+	}
+	set := labels.Set{namespaceNameLabel: namespace}
+	for k, v := range nsLabels {
+		set[k] = v
+	}
+	return selector.Matches(set)
+}
+
+func webhookTitle(name string, issues webhookIssues, failPolicy bool) string {
+	switch {
+	case issues.unreachable:
+		return fmt.Sprintf("Admission webhook %s is unreachable", name)
+	case issues.failureEvents > 0 || issues.callErrors > 0:
+		return fmt.Sprintf("Admission webhook %s is failing", name)
+	case issues.slowP99 > 0:
+		return fmt.Sprintf("Admission webhook %s is slow", name)
+	case !failPolicy:
+		return fmt.Sprintf("Admission webhook %s intercepts kube-system", name)
+	default:
+		return fmt.Sprintf("Admission webhook %s can block API requests", name)
+	}
+}
+
+func webhookSeverity(issues webhookIssues, failPolicy bool) model.Severity {
+	switch {
+	case issues.unreachable && failPolicy:
+		return model.SeverityCritical
+	case issues.unreachable, (issues.failureEvents > 0 || issues.callErrors > 0) && failPolicy:
+		return model.SeverityHigh
+	case issues.failureEvents > 0, issues.callErrors > 0, issues.slowP99 > 0, issues.interceptsSystem && failPolicy:
+		return model.SeverityMedium
+	default:
+		return model.SeverityLow
+	}
+}
+
+func webhookConfidence(issues webhookIssues) float64 {
+	base := 0.5
+	if issues.unreachable {
+		base += 0.20
+	}
+	if issues.failureEvents > 0 {
+		base += 0.15
+	}
+	if issues.callErrors > 0 || issues.slowP99 > 0 {
+		base += 0.10
+	}
+	if base > 1.0 {
+		base = 1.0
+	}
+	return base
+}
+
+func webhookSummary(wh collector.WebhookInfo, issues webhookIssues, failPolicy bool) string {
+	parts := []string{fmt.Sprintf("Webhook %s (%s, configuration %s, failurePolicy=%s, timeout %ds).",
+		wh.Name, wh.Type, wh.Configuration, wh.FailurePolicy, wh.TimeoutSeconds)}
+	if issues.unreachable {
+		parts = append(parts, "Its backing service has no ready endpoints.")
+	}
+	if issues.failureEvents > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed webhook call(s) reported in events.", issues.failureEvents))
+	}
+	if issues.callErrors > 0 {
+		parts = append(parts, fmt.Sprintf("apiserver recorded %d call error(s).", issues.callErrors))
+	}
+	if issues.slowP99 > 0 {
+		parts = append(parts, fmt.Sprintf("p99 admission latency is %s.", formatSeconds(issues.slowP99)))
+	}
+	if issues.interceptsSystem && failPolicy {
+		parts = append(parts, "It intercepts kube-system, so an outage can block control plane components.")
+	} else if issues.interceptsSystem {
+		parts = append(parts, "It intercepts kube-system, so control plane components wait on it and an outage lets their requests through unchecked.")
+	}
+	if issues.longTimeout {
+		parts = append(parts, "Every matching request can wait for the full timeout when the webhook is down.")
+	}
+	return strings.Join(parts, " ")
+}
+
+func webhookNextSteps(wh collector.WebhookInfo, issues webhookIssues) []string {
+	var steps []string
+	if wh.Service != nil && (issues.unreachable || issues.failureEvents > 0) {
+		steps = append(steps, fmt.Sprintf("Check the pods behind service %s/%s", wh.Service.Namespace, wh.Service.Name))
+	}
+	if issues.slowP99 > 0 {
+		steps = append(steps, "Check webhook server resource limits and replica count")
+	}
+	if issues.longTimeout {
+		steps = append(steps, "Lower timeoutSeconds so a failing webhook does not stall API requests")
+	}
+	if issues.interceptsSystem {
+		steps = append(steps, "Exclude kube-system with a namespaceSelector on kubernetes.io/metadata.name")
+	}
+	if wh.FailurePolicy == string(admissionregistrationv1.Fail) {
+		steps = append(steps, "Consider failurePolicy=Ignore for non-critical webhooks")
+	}
+	return steps
+}
+
+// webhookRef points at wh within its configuration.
+func webhookRef(wh collector.WebhookInfo) model.ObjectRef {
+	kind := "ValidatingWebhookConfiguration"
+	if wh.Type == collector.WebhookMutating {
+		kind = "MutatingWebhookConfiguration"
+	}
+	return model.ObjectRef{
+		Group: "admissionregistration.k8s.io", Version: "v1", Kind: kind, Name: wh.Configuration,
+		FieldPath: "webhooks{" + wh.Name + "}",
+	}
+}
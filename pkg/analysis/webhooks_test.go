@@ -0,0 +1,335 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func excludeKubeSystem() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+		},
+	}
+}
+
+func TestWebhookRule_NoWebhooks(t *testing.T) {
+	rule := &WebhookRule{}
+	findings := rule.Evaluate(&collector.Snapshot{})
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d", len(findings))
+	}
+}
+
+func TestWebhookRule_HealthyWebhook(t *testing.T) {
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			{
+				Configuration: "policy", Name: "validate.policy.example.com", Type: collector.WebhookValidating,
+				Service:           &collector.WebhookService{Namespace: "policy", Name: "policy-webhook", Port: 443},
+				TimeoutSeconds:    5,
+				FailurePolicy:     "Fail",
+				NamespaceSelector: excludeKubeSystem(),
+			},
+		},
+		Endpoints: []collector.ServiceEndpointsInfo{
+			{Namespace: "policy", Name: "policy-webhook", Found: true, ReadyAddresses: 2},
+		},
+	}
+
+	rule := &WebhookRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 0 {
+		t.Errorf("expected 0 findings, got %d", len(findings))
+	}
+}
+
+func TestWebhookRule_UnreachableFailPolicy(t *testing.T) {
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			{
+				Configuration: "policy", Name: "validate.policy.example.com", Type: collector.WebhookValidating,
+				Service:           &collector.WebhookService{Namespace: "policy", Name: "policy-webhook", Port: 443},
+				TimeoutSeconds:    10,
+				FailurePolicy:     "Fail",
+				NamespaceSelector: excludeKubeSystem(),
+			},
+		},
+		Endpoints: []collector.ServiceEndpointsInfo{
+			{Namespace: "policy", Name: "policy-webhook", Found: true, NotReadyAddresses: 2},
+		},
+		Events: []collector.EventInfo{
+			{
+				Namespace:      "default",
+				Name:           "web-6d4f.create",
+				Reason:         "FailedCreate",
+				Message:        `Error creating: Internal error occurred: failed calling webhook "validate.policy.example.com": context deadline exceeded`,
+				Type:           "Warning",
+				InvolvedObject: model.ObjectRef{Kind: "ReplicaSet", Namespace: "default", Name: "web-6d4f"},
+				Count:          14,
+			},
+		},
+	}
+
+	rule := &WebhookRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+
+	f := findings[0]
+	if f.ID != "admission-webhook-validating-policy-validate.policy.example.com" {
+		t.Errorf("id: got %q", f.ID)
+	}
+	if f.Category != "admission" {
+		t.Errorf("category: got %q, want %q", f.Category, "admission")
+	}
+	if f.Severity != model.SeverityCritical {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityCritical)
+	}
+
+	resourceEvidence := 0
+	eventEvidence := 0
+	for _, e := range f.Evidence {
+		switch e.Type {
+		case model.EvidenceResource:
+			resourceEvidence++
+		case model.EvidenceEvent:
+			eventEvidence++
+		}
+	}
+	if resourceEvidence != 2 {
+		t.Errorf("resource evidence: got %d, want 2 (webhook + service)", resourceEvidence)
+	}
+	if eventEvidence != 1 {
+		t.Errorf("event evidence: got %d, want 1", eventEvidence)
+	}
+}
+
+func TestWebhookRule_UnreachableIgnorePolicy(t *testing.T) {
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			{
+				Configuration: "sidecar", Name: "inject.sidecar.example.com", Type: collector.WebhookMutating,
+				Service:        &collector.WebhookService{Namespace: "mesh", Name: "injector", Port: 443},
+				TimeoutSeconds: 10,
+				FailurePolicy:  "Ignore",
+			},
+		},
+		Endpoints: []collector.ServiceEndpointsInfo{
+			{Namespace: "mesh", Name: "injector", Found: false},
+		},
+	}
+
+	rule := &WebhookRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].Severity != model.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", findings[0].Severity, model.SeverityHigh)
+	}
+}
+
+func TestWebhookRule_InterceptsKubeSystem(t *testing.T) {
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			{
+				Configuration: "policy", Name: "validate.policy.example.com", Type: collector.WebhookValidating,
+				URL:            "https://policy.example.com/validate",
+				TimeoutSeconds: 30,
+				FailurePolicy:  "Fail",
+			},
+		},
+	}
+
+	rule := &WebhookRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.Severity != model.SeverityMedium {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityMedium)
+	}
+	if len(f.Evidence) != 2 {
+		t.Errorf("evidence count: got %d, want 2 (long timeout + kube-system)", len(f.Evidence))
+	}
+}
+
+func TestWebhookRule_SameNameInTwoConfigurations(t *testing.T) {
+	webhook := func(cfg, typ string) collector.WebhookInfo {
+		return collector.WebhookInfo{
+			Configuration: cfg, Name: "pods.example.com", Type: typ,
+			URL: "https://policy.example.com/" + cfg, TimeoutSeconds: 30, FailurePolicy: "Fail",
+		}
+	}
+	snap := &collector.Snapshot{Webhooks: []collector.WebhookInfo{
+		webhook("policy", collector.WebhookValidating),
+		webhook("defaults", collector.WebhookMutating),
+		webhook("audit", collector.WebhookValidating),
+	}}
+
+	findings := (&WebhookRule{}).Evaluate(snap)
+	ids := make(map[string]bool)
+	for _, f := range findings {
+		ids[f.ID] = true
+	}
+	for _, want := range []string{
+		"admission-webhook-validating-policy-pods.example.com",
+		"admission-webhook-mutating-defaults-pods.example.com",
+		"admission-webhook-validating-audit-pods.example.com",
+	} {
+		if !ids[want] {
+			t.Errorf("missing finding %s, got %v", want, ids)
+		}
+	}
+}
+
+func TestWebhookRule_WebhooksOfOneConfigurationStayApart(t *testing.T) {
+	webhook := func(name string) collector.WebhookInfo {
+		return collector.WebhookInfo{
+			Configuration: "policy", Name: name, Type: collector.WebhookValidating,
+			URL: "https://policy.example.com/" + name, TimeoutSeconds: 30, FailurePolicy: "Fail",
+			NamespaceSelector: excludeKubeSystem(),
+		}
+	}
+	snap := &collector.Snapshot{Webhooks: []collector.WebhookInfo{webhook("pods.example.com"), webhook("services.example.com")}}
+
+	findings := (&WebhookRule{}).Evaluate(snap)
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d", len(findings))
+	}
+	if Fingerprint(findings[0]) == Fingerprint(findings[1]) {
+		t.Error("webhooks of one configuration share a fingerprint")
+	}
+	if got := NewCorrelator().Correlate(findings); len(got) != 2 {
+		t.Errorf("correlator merged webhooks of one configuration: %+v", got)
+	}
+}
+
+func TestWebhookRule_InterceptsKubeSystemIgnorePolicy(t *testing.T) {
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			{
+				Configuration: "labels", Name: "labels.example.com", Type: collector.WebhookMutating,
+				URL: "https://labels.example.com/mutate", TimeoutSeconds: 30, FailurePolicy: "Ignore",
+			},
+		},
+	}
+
+	findings := (&WebhookRule{}).Evaluate(snap)
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.Severity != model.SeverityLow || f.Title != "Admission webhook labels.example.com intercepts kube-system" {
+		t.Errorf("got %s %q", f.Severity, f.Title)
+	}
+	if len(f.Evidence) != 1 || !strings.Contains(f.Evidence[0].Message, "failurePolicy=Ignore") {
+		t.Errorf("expected the kube-system evidence only, got %+v", f.Evidence)
+	}
+}
+
+func TestWebhookRule_FailureEventsForSharedName(t *testing.T) {
+	webhook := func(cfg, typ, svc string) collector.WebhookInfo {
+		return collector.WebhookInfo{
+			Configuration: cfg, Name: "pods.example.com", Type: typ,
+			Service:        &collector.WebhookService{Namespace: "policy", Name: svc, Path: "/" + typ, Port: 443},
+			TimeoutSeconds: 5, FailurePolicy: "Ignore", NamespaceSelector: excludeKubeSystem(),
+		}
+	}
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			webhook("policy", collector.WebhookValidating, "policy-webhook"),
+			webhook("defaults", collector.WebhookMutating, "defaults-webhook"),
+		},
+		Events: []collector.EventInfo{{
+			Namespace: "default", Name: "web.create", Reason: "FailedCreate", Count: 4,
+			Message: `Internal error occurred: failed calling webhook "pods.example.com": failed to call webhook: ` +
+				`Post "https://defaults-webhook.policy.svc:443/mutating?timeout=5s": context deadline exceeded`,
+			InvolvedObject: model.ObjectRef{Kind: "ReplicaSet", Namespace: "default", Name: "web"},
+		}},
+	}
+
+	findings := (&WebhookRule{}).Evaluate(snap)
+	if len(findings) != 1 || findings[0].ID != "admission-webhook-mutating-defaults-pods.example.com" {
+		t.Fatalf("expected the event to be attributed to the mutating webhook only, got %+v", findings)
+	}
+}
+
+func TestWebhookRule_SlowWebhookMetrics(t *testing.T) {
+	snap := &collector.Snapshot{
+		Webhooks: []collector.WebhookInfo{
+			{
+				Configuration: "policy", Name: "validate.policy.example.com", Type: collector.WebhookValidating,
+				Service:           &collector.WebhookService{Namespace: "policy", Name: "policy-webhook", Port: 443},
+				TimeoutSeconds:    5,
+				FailurePolicy:     "Ignore",
+				NamespaceSelector: excludeKubeSystem(),
+			},
+		},
+		Endpoints: []collector.ServiceEndpointsInfo{
+			{Namespace: "policy", Name: "policy-webhook", Found: true, ReadyAddresses: 1},
+		},
+		APIServer: &collector.APIServerMetrics{
+			WebhookLatencies: []collector.WebhookLatency{
+				{
+					Name: "validate.policy.example.com", Type: collector.WebhookValidating,
+					Latency: latencyHistogram(100, []float64{0.5, 2.5, 5}, []uint64{10, 50, 100}),
+				},
+			},
+		},
+	}
+
+	rule := &WebhookRule{}
+	findings := rule.Evaluate(snap)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.Severity != model.SeverityMedium {
+		t.Errorf("severity: got %q, want %q", f.Severity, model.SeverityMedium)
+	}
+	if len(f.Evidence) != 2 || f.Evidence[0].Type != model.EvidenceResource || f.Evidence[1].Type != model.EvidenceMetric {
+		t.Errorf("expected the webhook and a single metric evidence, got %+v", f.Evidence)
+	}
+}
+
+func TestWebhookRule_MetricOnlyWebhooksStayApart(t *testing.T) {
+	var webhooks []collector.WebhookInfo
+	var latencies []collector.WebhookLatency
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		webhooks = append(webhooks, collector.WebhookInfo{
+			Configuration: "policy-" + name, Name: name, Type: collector.WebhookValidating,
+			URL: "https://" + name, TimeoutSeconds: 5, FailurePolicy: "Ignore", NamespaceSelector: excludeKubeSystem(),
+		})
+		latencies = append(latencies, collector.WebhookLatency{Name: name, Type: collector.WebhookValidating, CallErrors: 3})
+	}
+	snap := &collector.Snapshot{Webhooks: webhooks, APIServer: &collector.APIServerMetrics{WebhookLatencies: latencies}}
+
+	findings := (&WebhookRule{}).Evaluate(snap)
+	if got := NewCorrelator().Correlate(findings); len(got) != 2 {
+		t.Errorf("expected a finding per failing webhook, got %+v", got)
+	}
+}
+
+func TestWebhookRule_Name(t *testing.T) {
+	rule := &WebhookRule{}
+	if rule.Name() != "admission-webhooks" {
+		t.Errorf("name: got %q, want %q", rule.Name(), "admission-webhooks")
+	}
+}
+
+var _ Rule = (*WebhookRule)(nil)
@@ -0,0 +1,503 @@
+// Package celrule loads declarative analysis rules from YAML. A CEL
+// expression selects objects from the snapshot, optional CEL expressions
+// compute the severity and confidence, and text/template templates build the
+// finding text.
+package celrule
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	"sigs.k8s.io/yaml"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// File is a list of rules:
+//
+//	rules:
+//	  - name: gpu-node-cordoned
+//	    category: node-health
+//	    for: nodes
+//	    match: object.name.startsWith("gpu-") && object.unschedulable
+//	    severity: '"high"'
+//	    title: GPU node {{ .object.name }} is cordoned
+//	    summary: GPU node {{ .object.name }} accepts no new pods.
+//	    nextSteps:
+//	      - Uncordon {{ .object.name }} once maintenance is over
+type File struct {
+	Rules []Spec `json:"rules"`
+}
+
+// Spec is one rule as written in a rule file. Match, Severity and
+// Confidence are CEL expressions over object, the matched object, and
+// snapshot, which holds the nodes, pods, events and pvcs of the snapshot.
+// Both are typed with the field names of the snapshot JSON, and fields the
+// JSON omits read as zero values. Title, Summary, the
+// evidence messages and NextSteps are templates over object, severity and
+// confidence.
+type Spec struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category"`
+	DocURL      string `json:"docURL,omitempty"`
+	// For is the kind of object Match is evaluated against: nodes, pods,
+	// events or pvcs. Each matching object becomes one finding.
+	For   string `json:"for"`
+	Match string `json:"match"`
+	// Severity must evaluate to critical, high, medium or low; it defaults
+	// to medium. Confidence must evaluate to a number in [0, 1]; it
+	// defaults to 0.7.
+	Severity   string         `json:"severity,omitempty"`
+	Confidence string         `json:"confidence,omitempty"`
+	Title      string         `json:"title"`
+	Summary    string         `json:"summary"`
+	Evidence   []EvidenceSpec `json:"evidence,omitempty"`
+	NextSteps  []string       `json:"nextSteps,omitempty"`
+}
+
+// EvidenceSpec is evidence about the matched object. Type defaults to event
+// for events and resource otherwise. A finding without resource evidence gets
+// one for the object, the involved object for events.
+type EvidenceSpec struct {
+	Type    model.EvidenceType `json:"type,omitempty"`
+	Message string             `json:"message"`
+}
+
+const (
+	defaultSeverity   = `"medium"`
+	defaultConfidence = "0.7"
+	costLimit         = 1000000
+)
+
+var nameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// object is a snapshot object as CEL and the templates see it, with the
+// reference its evidence points at.
+type object struct {
+	ref   model.ObjectRef
+	value map[string]any
+}
+
+// kind says where a rule's objects come from, what type they have and how
+// to reference them.
+type kind struct {
+	evidence model.EvidenceType
+	item     reflect.Type
+	objects  func(snap *collector.Snapshot) []object
+}
+
+var kinds = map[string]kind{
+	"nodes": {model.EvidenceResource, reflect.TypeOf(collector.NodeInfo{}), func(snap *collector.Snapshot) []object {
+		return objects(snap.Nodes, func(n collector.NodeInfo) model.ObjectRef { return model.NodeRef(n.Name, n.UID) })
+	}},
+	"pods": {model.EvidenceResource, reflect.TypeOf(collector.PodInfo{}), func(snap *collector.Snapshot) []object {
+		return objects(snap.Pods, func(p collector.PodInfo) model.ObjectRef { return model.PodRef(p.Namespace, p.Name, p.UID) })
+	}},
+	"events": {model.EvidenceEvent, reflect.TypeOf(collector.EventInfo{}), func(snap *collector.Snapshot) []object {
+		return objects(snap.Events, func(e collector.EventInfo) model.ObjectRef { return e.InvolvedObject })
+	}},
+	"pvcs": {model.EvidenceResource, reflect.TypeOf(collector.PVCInfo{}), func(snap *collector.Snapshot) []object {
+		return objects(snap.PVCs, func(p collector.PVCInfo) model.ObjectRef { return model.PVCRef(p.Namespace, p.Name, p.UID) })
+	}},
+}
+
+func objects[T any](items []T, ref func(T) model.ObjectRef) []object {
+	objs := make([]object, 0, len(items))
+	for _, item := range items {
+		objs = append(objs, object{ref: ref(item), value: toValue(reflect.ValueOf(item)).(map[string]any)})
+	}
+	return objs
+}
+
+// Rule is a compiled Spec. It implements analysis.FallibleRule and
+// analysis.DescribedRule.
+type Rule struct {
+	spec         Spec
+	kind         kind
+	match        cel.Program
+	severity     cel.Program
+	confidence   cel.Program
+	usesSnapshot bool
+	// fixedSeverity is set when the severity does not depend on the object.
+	fixedSeverity model.Severity
+	title         *template.Template
+	summary       *template.Template
+	evidence      []*template.Template
+	nextSteps     []*template.Template
+}
+
+// Load reads and compiles the rule file at path.
+func Load(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rule file: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse compiles the rules in data, naming source in errors. Every
+// expression is type-checked against the snapshot types and every template
+// parsed, so a rule that loads can only fail on the data, such as by
+// indexing past the end of a list.
+func Parse(data []byte, source string) ([]*Rule, error) {
+	var f File
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("parse rule file %s: %w", source, err)
+	}
+	envs, err := newEnvs()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	rules := make([]*Rule, 0, len(f.Rules))
+	for i, spec := range f.Rules {
+		if !nameRe.MatchString(spec.Name) {
+			return nil, fmt.Errorf("rule file %s: rule %d: name %q must be lowercase letters, digits and dashes", source, i+1, spec.Name)
+		}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("rule file %s: rule %s is defined twice", source, spec.Name)
+		}
+		seen[spec.Name] = true
+		r, err := compile(envs, spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule file %s: rule %s: %w", source, spec.Name, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// newEnvs returns a CEL environment for each kind, in which object has the
+// type of the kind's objects.
+func newEnvs() (map[string]*cel.Env, error) {
+	provider, err := newTypeProvider()
+	if err != nil {
+		return nil, err
+	}
+	// Declare every type before the environments use the provider.
+	snapshotType := provider.celType(reflect.TypeOf(snapshotView{}))
+	objectTypes := make(map[string]*types.Type, len(kinds))
+	for name, k := range kinds {
+		objectTypes[name] = provider.celType(k.item)
+	}
+
+	envs := make(map[string]*cel.Env, len(kinds))
+	for name, t := range objectTypes {
+		env, err := cel.NewEnv(
+			cel.CustomTypeProvider(provider),
+			cel.Variable("object", t),
+			cel.Variable("snapshot", snapshotType),
+			cel.CrossTypeNumericComparisons(true),
+			ext.Strings(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		envs[name] = env
+	}
+	return envs, nil
+}
+
+func compile(envs map[string]*cel.Env, spec Spec) (*Rule, error) {
+	k, ok := kinds[spec.For]
+	if !ok {
+		return nil, fmt.Errorf("for: %q is not one of nodes, pods, events, pvcs", spec.For)
+	}
+	env := envs[spec.For]
+	if spec.Category == "" {
+		return nil, fmt.Errorf("category is required")
+	}
+	if spec.Match == "" {
+		return nil, fmt.Errorf("match is required")
+	}
+	r := &Rule{spec: spec, kind: k}
+
+	var err error
+	if r.match, _, err = r.program(env, "match", spec.Match, types.BoolType); err != nil {
+		return nil, err
+	}
+	var refs bool
+	if r.severity, refs, err = r.program(env, "severity", orDefault(spec.Severity, defaultSeverity), types.StringType); err != nil {
+		return nil, err
+	}
+	if !refs {
+		if r.fixedSeverity, err = r.evalSeverity(map[string]any{}); err != nil {
+			return nil, fmt.Errorf("severity: %w", err)
+		}
+	}
+	if r.confidence, refs, err = r.program(env, "confidence", orDefault(spec.Confidence, defaultConfidence), types.DoubleType, types.IntType, types.UintType); err != nil {
+		return nil, err
+	}
+	if !refs {
+		if _, err := r.evalConfidence(map[string]any{}); err != nil {
+			return nil, fmt.Errorf("confidence: %w", err)
+		}
+	}
+
+	if r.title, err = parseTemplate("title", spec.Title); err != nil {
+		return nil, err
+	}
+	if r.summary, err = parseTemplate("summary", spec.Summary); err != nil {
+		return nil, err
+	}
+	for i, e := range spec.Evidence {
+		switch e.Type {
+		case "", model.EvidenceMetric, model.EvidenceEvent, model.EvidenceLog, model.EvidenceResource:
+		default:
+			return nil, fmt.Errorf("evidence %d: type %q is not one of metric, event, log, resource", i+1, e.Type)
+		}
+		t, err := parseTemplate(fmt.Sprintf("evidence %d", i+1), e.Message)
+		if err != nil {
+			return nil, err
+		}
+		r.evidence = append(r.evidence, t)
+	}
+	for i, s := range spec.NextSteps {
+		t, err := parseTemplate(fmt.Sprintf("nextSteps %d", i+1), s)
+		if err != nil {
+			return nil, err
+		}
+		r.nextSteps = append(r.nextSteps, t)
+	}
+	return r, nil
+}
+
+// program compiles expr, which must type-check to one of want or to dyn,
+// and reports whether it reads any variable.
+func (r *Rule) program(env *cel.Env, field, expr string, want ...*types.Type) (cel.Program, bool, error) {
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, false, fmt.Errorf("%s: %w", field, iss.Err())
+	}
+	out := ast.OutputType()
+	ok := out.IsExactType(types.DynType)
+	var names []string
+	for _, t := range want {
+		ok = ok || out.IsExactType(t)
+		names = append(names, t.String())
+	}
+	if !ok {
+		return nil, false, fmt.Errorf("%s: %q evaluates to %s, want %s", field, expr, out, strings.Join(names, " or "))
+	}
+	refs := false
+	if checked, err := cel.AstToCheckedExpr(ast); err == nil {
+		for _, ref := range checked.GetReferenceMap() {
+			switch ref.GetName() {
+			case "snapshot":
+				r.usesSnapshot = true
+				refs = true
+			case "object":
+				refs = true
+			}
+		}
+	}
+	prg, err := env.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", field, err)
+	}
+	return prg, refs, nil
+}
+
+func (r *Rule) evalSeverity(vars map[string]any) (model.Severity, error) {
+	v, _, err := r.severity.Eval(vars)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("got %v, want a string", v.Value())
+	}
+	return parseSeverity(s)
+}
+
+func (r *Rule) evalConfidence(vars map[string]any) (float64, error) {
+	v, _, err := r.confidence.Eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	var c float64
+	switch n := v.Value().(type) {
+	case float64:
+		c = n
+	case int64:
+		c = float64(n)
+	case uint64:
+		c = float64(n)
+	default:
+		return 0, fmt.Errorf("got %v, want a number", v.Value())
+	}
+	if c < 0 || c > 1 {
+		return 0, fmt.Errorf("%v is not between 0 and 1", c)
+	}
+	return c, nil
+}
+
+func parseTemplate(field, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%s is required", field)
+	}
+	t, err := template.New(field).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	return t, nil
+}
+
+func parseSeverity(s string) (model.Severity, error) {
+	switch sev := model.Severity(s); sev {
+	case model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow:
+		return sev, nil
+	}
+	return "", fmt.Errorf("%q is not one of critical, high, medium, low", s)
+}
+
+func orDefault(s, def string) string {
+	if strings.TrimSpace(s) == "" {
+		return def
+	}
+	return s
+}
+
+func (r *Rule) Name() string { return r.spec.Name }
+
+func (r *Rule) Metadata() model.RuleMetadata {
+	m := model.RuleMetadata{
+		Name:        r.spec.Name,
+		Version:     orDefault(r.spec.Version, "1"),
+		Description: r.spec.Description,
+		Categories:  []string{r.spec.Category},
+		Requires:    []string{r.spec.For},
+		DocURL:      r.spec.DocURL,
+	}
+	m.MinSeverity, m.MaxSeverity = r.fixedSeverity, r.fixedSeverity
+	return m
+}
+
+// Evaluate returns a finding for every object Match selects, leaving out
+// the objects an expression fails on; the engine calls Run.
+func (r *Rule) Evaluate(snap *collector.Snapshot) []model.Finding {
+	findings, _ := r.Run(snap)
+	return findings
+}
+
+// Run returns a finding for every object Match selects. If an expression
+// fails on an object, for example by indexing past the end of a list, it
+// returns the findings for the other objects with an error naming the first
+// object that failed.
+func (r *Rule) Run(snap *collector.Snapshot) ([]model.Finding, error) {
+	vars := map[string]any{"snapshot": map[string]any{}}
+	if r.usesSnapshot {
+		vars["snapshot"] = toValue(reflect.ValueOf(snapshotView{snap.Nodes, snap.Pods, snap.Events, snap.PVCs}))
+	}
+
+	var findings []model.Finding
+	var firstErr error
+	failed := 0
+	for _, obj := range r.kind.objects(snap) {
+		vars["object"] = obj.value
+		f, ok, err := r.finding(obj, vars)
+		switch {
+		case err != nil:
+			if failed == 0 {
+				firstErr = fmt.Errorf("%s: %w", obj.ref, err)
+			}
+			failed++
+		case ok:
+			findings = append(findings, f)
+		}
+	}
+	if failed > 1 {
+		return findings, fmt.Errorf("%w (and %d more objects)", firstErr, failed-1)
+	}
+	return findings, firstErr
+}
+
+func (r *Rule) finding(obj object, vars map[string]any) (model.Finding, bool, error) {
+	matched, _, err := r.match.Eval(vars)
+	if err != nil {
+		return model.Finding{}, false, fmt.Errorf("match: %w", err)
+	}
+	if matched != types.True {
+		return model.Finding{}, false, nil
+	}
+	severity, err := r.evalSeverity(vars)
+	if err != nil {
+		return model.Finding{}, false, fmt.Errorf("severity: %w", err)
+	}
+	confidence, err := r.evalConfidence(vars)
+	if err != nil {
+		return model.Finding{}, false, fmt.Errorf("confidence: %w", err)
+	}
+
+	data := map[string]any{"object": obj.value, "severity": string(severity), "confidence": confidence}
+	f := model.Finding{
+		SchemaVersion: model.SchemaVersion,
+		ID:            findingID(r.spec.Name, obj.ref),
+		Rule:          r.spec.Name,
+		Title:         execute(r.title, data),
+		Category:      r.spec.Category,
+		Severity:      severity,
+		Confidence:    confidence,
+		Summary:       execute(r.summary, data),
+		NextSteps:     []string{},
+		Timestamp:     time.Now().UTC(),
+	}
+	for i, t := range r.evidence {
+		typ := r.spec.Evidence[i].Type
+		if typ == "" {
+			typ = r.kind.evidence
+		}
+		f.Evidence = append(f.Evidence, model.ObjectEvidence(typ, obj.ref, execute(t, data), nil))
+	}
+	if len(f.Evidence) == 0 {
+		f.Evidence = []model.Evidence{model.ObjectEvidence(r.kind.evidence, obj.ref, f.Title, nil)}
+	}
+	// The correlator and fingerprints tell findings apart by the objects
+	// of their resource evidence, which event evidence does not count as.
+	if !hasResourceEvidence(f.Evidence) && !obj.ref.IsZero() {
+		f.Evidence = append([]model.Evidence{model.ObjectEvidence(model.EvidenceResource, obj.ref, f.Title, nil)}, f.Evidence...)
+	}
+	for _, t := range r.nextSteps {
+		f.NextSteps = append(f.NextSteps, execute(t, data))
+	}
+	return f, true, nil
+}
+
+func hasResourceEvidence(evidence []model.Evidence) bool {
+	for _, e := range evidence {
+		if e.Type == model.EvidenceResource {
+			return true
+		}
+	}
+	return false
+}
+
+func execute(t *template.Template, data any) string {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return fmt.Sprintf("<%s: %v>", t.Name(), err)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func findingID(rule string, ref model.ObjectRef) string {
+	parts := []string{rule, strings.ToLower(ref.Kind)}
+	if ref.Namespace != "" {
+		parts = append(parts, ref.Namespace)
+	}
+	parts = append(parts, ref.Name)
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,208 @@
+package celrule
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const rulesYAML = `
+rules:
+  - name: gpu-node-cordoned
+    description: GPU nodes that accept no new pods
+    category: node-health
+    for: nodes
+    match: object.name.startsWith("gpu-") && object.unschedulable
+    severity: '"high"'
+    title: GPU node {{ .object.name }} is cordoned
+    summary: GPU node {{ .object.name }} accepts no new pods.
+    nextSteps:
+      - kubectl uncordon {{ .object.name }}
+  - name: crashing-batch-pod
+    category: pod-health
+    for: pods
+    match: >
+      object.namespace == "batch" &&
+      object.containers.exists(c, c.restartCount >= 3)
+    severity: >
+      object.containers.exists(c, c.restartCount >= 10) ? "critical" : "medium"
+    confidence: 'size(snapshot.pods) > 1 ? 0.9 : 0.5'
+    title: Pod {{ .object.namespace }}/{{ .object.name }} keeps restarting
+    summary: Severity {{ .severity }}, confidence {{ .confidence }}.
+    evidence:
+      - message: Pod runs on {{ .object.nodeName }}
+`
+
+func testSnapshot() *collector.Snapshot {
+	return &collector.Snapshot{
+		Nodes: []collector.NodeInfo{
+			{Name: "gpu-1", UID: "n1", Unschedulable: true},
+			{Name: "gpu-2", UID: "n2"},
+			{Name: "cpu-1", UID: "n3", Unschedulable: true},
+		},
+		Pods: []collector.PodInfo{
+			{Namespace: "batch", Name: "job-a", NodeName: "cpu-1", Phase: corev1.PodRunning,
+				Containers: []collector.ContainerInfo{{Name: "app", RestartCount: 12}}},
+			{Namespace: "batch", Name: "job-b", Phase: corev1.PodRunning,
+				Containers: []collector.ContainerInfo{{Name: "app", RestartCount: 1}}},
+			{Namespace: "web", Name: "api", Phase: corev1.PodRunning,
+				Containers: []collector.ContainerInfo{{Name: "app", RestartCount: 50}}},
+		},
+	}
+}
+
+func TestParseAndEvaluate(t *testing.T) {
+	rules, err := Parse([]byte(rulesYAML), "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules", len(rules))
+	}
+
+	findings := rules[0].Evaluate(testSnapshot())
+	if len(findings) != 1 {
+		t.Fatalf("gpu-node-cordoned: got %+v", findings)
+	}
+	f := findings[0]
+	if f.ID != "gpu-node-cordoned-node-gpu-1" || f.Rule != "gpu-node-cordoned" || f.Severity != model.SeverityHigh ||
+		f.Confidence != 0.7 || f.Title != "GPU node gpu-1 is cordoned" || len(f.NextSteps) != 1 || f.NextSteps[0] != "kubectl uncordon gpu-1" {
+		t.Errorf("gpu-node-cordoned: got %+v", f)
+	}
+	if len(f.Evidence) != 1 || f.Evidence[0].Ref != "node/gpu-1" || f.Evidence[0].Type != model.EvidenceResource {
+		t.Errorf("default evidence: got %+v", f.Evidence)
+	}
+	if m := rules[0].Metadata(); m.MinSeverity != model.SeverityHigh || len(m.Requires) != 1 || m.Requires[0] != "nodes" {
+		t.Errorf("metadata: got %+v", m)
+	}
+
+	findings = rules[1].Evaluate(testSnapshot())
+	if len(findings) != 1 {
+		t.Fatalf("crashing-batch-pod: got %+v", findings)
+	}
+	f = findings[0]
+	if f.Severity != model.SeverityCritical || f.Confidence != 0.9 || f.Summary != "Severity critical, confidence 0.9." {
+		t.Errorf("crashing-batch-pod: got %+v", f)
+	}
+	if len(f.Evidence) != 1 || f.Evidence[0].Ref != "pod/batch/job-a" || f.Evidence[0].Message != "Pod runs on cpu-1" {
+		t.Errorf("evidence: got %+v", f.Evidence)
+	}
+	if m := rules[1].Metadata(); m.MinSeverity != "" {
+		t.Errorf("severity depends on the object, got %+v", m)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	rules, err := Parse([]byte(`
+rules:
+  - name: second-container
+    category: test
+    for: pods
+    match: object.containers[1].name == "sidecar"
+    title: t
+    summary: s
+`), "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := testSnapshot()
+	snap.Pods[0].Containers = append(snap.Pods[0].Containers, collector.ContainerInfo{Name: "sidecar"})
+
+	findings, err := rules[0].Run(snap)
+	if err == nil || !strings.Contains(err.Error(), "pod/batch/job-b: match:") || !strings.Contains(err.Error(), "and 1 more objects") {
+		t.Errorf("expected an error naming the first failing pod, got %v", err)
+	}
+	if len(findings) != 1 || findings[0].ID != "second-container-pod-batch-job-a" {
+		t.Errorf("findings: got %+v", findings)
+	}
+	if findings := rules[0].Evaluate(snap); len(findings) != 1 {
+		t.Errorf("Evaluate should keep the matching objects, got %+v", findings)
+	}
+}
+
+func TestRun_OmittedFieldsAreZero(t *testing.T) {
+	// unschedulable is omitted from the snapshot JSON when false.
+	rules, err := Parse([]byte(`
+rules:
+  - name: schedulable
+    category: test
+    for: nodes
+    match: '!object.unschedulable && size(object.conditions) == 0'
+    title: t
+    summary: s
+`), "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := rules[0].Run(testSnapshot())
+	if err != nil || len(findings) != 1 || findings[0].ID != "schedulable-node-gpu-2" {
+		t.Errorf("got %+v, %v", findings, err)
+	}
+}
+
+func TestRun_EventFindingsReferenceInvolvedObject(t *testing.T) {
+	rules, err := Parse([]byte(`
+rules:
+  - name: image-pull
+    category: pod-health
+    for: events
+    match: object.reason == "Failed"
+    title: Image pull failed
+    summary: s
+`), "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := testSnapshot()
+	for _, pod := range []string{"job-a", "job-b"} {
+		snap.Events = append(snap.Events, collector.EventInfo{
+			Namespace: "batch", Name: pod + ".1", Reason: "Failed", Message: "ErrImagePull",
+			InvolvedObject: model.PodRef("batch", pod, ""),
+		})
+	}
+
+	findings, err := rules[0].Run(snap)
+	if err != nil || len(findings) != 2 {
+		t.Fatalf("got %+v, %v", findings, err)
+	}
+	for i, pod := range []string{"pod/batch/job-a", "pod/batch/job-b"} {
+		ev := findings[i].Evidence
+		if len(ev) != 2 || ev[0].Type != model.EvidenceResource || ev[0].Ref != pod || ev[1].Type != model.EvidenceEvent {
+			t.Errorf("finding %d: expected resource and event evidence for %s, got %+v", i, pod, ev)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	rule := func(fields string) string {
+		return "rules:\n  - name: r\n    category: c\n    for: pods\n    title: t\n    summary: s\n" + fields
+	}
+	for _, tc := range []struct {
+		name, yaml, want string
+	}{
+		{"syntax", rule("    match: object.name ==\n"), "match: ERROR: <input>:1:15: Syntax error"},
+		{"undeclared", rule("    match: objct.name == 'x'\n"), "undeclared reference to 'objct'"},
+		{"field", rule("    match: object.nme == 'x'\n"), "match: ERROR: <input>:1:7: undefined field 'nme'"},
+		{"nested field", rule("    match: object.containers.exists(c, c.restartCont > 3)\n"), "undefined field 'restartCont'"},
+		{"snapshot field", rule("    match: size(snapshot.nodez) > 0\n"), "undefined field 'nodez'"},
+		{"field type", rule("    match: object.phase > 3\n"), "found no matching overload for '_>_' applied to '(string, int)'"},
+		{"not bool", rule("    match: '1 + 2'\n"), `match: "1 + 2" evaluates to int, want bool`},
+		{"bad severity", rule("    match: 'true'\n    severity: '\"urgent\"'\n"), `severity: "urgent" is not one of`},
+		{"severity type", rule("    match: 'true'\n    severity: '3'\n"), "severity: \"3\" evaluates to int, want string"},
+		{"confidence range", rule("    match: 'true'\n    confidence: '1.5'\n"), "confidence: 1.5 is not between 0 and 1"},
+		{"template", rule("    match: 'true'\n    nextSteps: ['{{ .object.name']\n"), "nextSteps 1: template"},
+		{"kind", strings.Replace(rule("    match: 'true'\n"), "for: pods", "for: secrets", 1), `for: "secrets" is not one of`},
+		{"name", strings.Replace(rule("    match: 'true'\n"), "name: r", "name: Bad_Name", 1), "must be lowercase"},
+		{"unknown field", rule("    match: 'true'\n    sevirity: high\n"), "unknown field"},
+		{"evidence type", rule("    match: 'true'\n    evidence: [{type: trace, message: m}]\n"), `evidence 1: type "trace"`},
+	} {
+		_, err := Parse([]byte(tc.yaml), "test.yaml")
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
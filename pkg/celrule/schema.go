@@ -0,0 +1,237 @@
+package celrule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// snapshotView is the snapshot variable.
+type snapshotView struct {
+	Nodes  []collector.NodeInfo  `json:"nodes"`
+	Pods   []collector.PodInfo   `json:"pods"`
+	Events []collector.EventInfo `json:"events"`
+	PVCs   []collector.PVCInfo   `json:"pvcs"`
+}
+
+// Types with custom JSON encodings. They are strings to CEL, except
+// IntOrString, which can be either.
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	metaTimeType  = reflect.TypeOf(metav1.Time{})
+	microTimeType = reflect.TypeOf(metav1.MicroTime{})
+	quantityType  = reflect.TypeOf(resource.Quantity{})
+	intOrStrType  = reflect.TypeOf(intstr.IntOrString{})
+)
+
+// typeProvider declares the snapshot types to the CEL type checker with
+// their JSON field names, so that an expression reading a field that does
+// not exist fails to compile. At runtime objects are maps built by toValue;
+// the declared fields have no getters, so CEL reads them as map keys.
+type typeProvider struct {
+	*types.Registry
+	fields map[string]map[string]*types.Type
+	names  map[reflect.Type]string
+}
+
+func newTypeProvider() (*typeProvider, error) {
+	reg, err := types.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return &typeProvider{
+		Registry: reg,
+		fields:   make(map[string]map[string]*types.Type),
+		names:    make(map[reflect.Type]string),
+	}, nil
+}
+
+// celType returns the CEL type of values of t, declaring the struct types
+// it refers to.
+func (p *typeProvider) celType(t reflect.Type) *types.Type {
+	switch t {
+	case timeType, metaTimeType, microTimeType, quantityType:
+		return types.StringType
+	case intOrStrType:
+		return types.DynType
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return p.celType(t.Elem())
+	case reflect.Bool:
+		return types.BoolType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return types.IntType
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return types.UintType
+	case reflect.Float32, reflect.Float64:
+		return types.DoubleType
+	case reflect.String:
+		return types.StringType
+	case reflect.Slice, reflect.Array:
+		return types.NewListType(p.celType(t.Elem()))
+	case reflect.Map:
+		return types.NewMapType(types.StringType, p.celType(t.Elem()))
+	case reflect.Struct:
+		name, ok := p.names[t]
+		if !ok {
+			name = typeName(t)
+			p.names[t] = name
+			fields := make(map[string]*types.Type)
+			p.fields[name] = fields // break cycles
+			eachField(t, func(jsonName string, f reflect.StructField) {
+				fields[jsonName] = p.celType(f.Type)
+			})
+		}
+		return types.NewObjectType(name)
+	}
+	return types.DynType
+}
+
+func (p *typeProvider) FindStructType(name string) (*types.Type, bool) {
+	if _, ok := p.fields[name]; ok {
+		return types.NewTypeTypeWithParam(types.NewObjectType(name)), true
+	}
+	return p.Registry.FindStructType(name)
+}
+
+func (p *typeProvider) FindStructFieldType(name, field string) (*types.FieldType, bool) {
+	if fields, ok := p.fields[name]; ok {
+		t, ok := fields[field]
+		if !ok {
+			return nil, false
+		}
+		return &types.FieldType{Type: t}, true
+	}
+	return p.Registry.FindStructFieldType(name, field)
+}
+
+func (p *typeProvider) NewValue(name string, fields map[string]ref.Val) ref.Val {
+	if _, ok := p.fields[name]; ok {
+		return types.NewErr("%s objects cannot be created in expressions", name)
+	}
+	return p.Registry.NewValue(name, fields)
+}
+
+// typeName names a struct type, e.g. "slowwhy.NodeInfo" or
+// "slowwhy.v1.NodeCondition".
+func typeName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if strings.HasPrefix(pkg, "github.com/marek-kar/kube-slowwhy/") {
+		return "slowwhy." + t.Name()
+	}
+	return "slowwhy." + pkg[strings.LastIndex(pkg, "/")+1:] + "." + t.Name()
+}
+
+// eachField calls fn for the JSON fields of struct type t, flattening
+// embedded structs as encoding/json does.
+func eachField(t reflect.Type, fn func(jsonName string, f reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				eachField(ft, func(jsonName string, inner reflect.StructField) {
+					inner.Index = append([]int{i}, inner.Index...)
+					fn(jsonName, inner)
+				})
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fn(name, f)
+	}
+}
+
+// toValue converts v to the value CEL and the templates see, matching the
+// types declared by celType. Every declared field is present: nil pointers,
+// lists and maps, and fields omitted from the snapshot JSON, read as zero
+// values, so expressions need no has() checks.
+func toValue(v reflect.Value) any {
+	switch v.Type() {
+	case timeType, metaTimeType, microTimeType, quantityType:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		var s string
+		json.Unmarshal(data, &s)
+		return s
+	case intOrStrType:
+		ios := v.Interface().(intstr.IntOrString)
+		if ios.Type == intstr.Int {
+			return int64(ios.IntVal)
+		}
+		return ios.StrVal
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return toValue(reflect.Zero(v.Type().Elem()))
+		}
+		return toValue(v.Elem())
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = toValue(v.Index(i))
+		}
+		return list
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = toValue(iter.Value())
+		}
+		return m
+	case reflect.Struct:
+		m := make(map[string]any)
+		eachField(v.Type(), func(jsonName string, f reflect.StructField) {
+			fv, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				fv = reflect.Zero(f.Type)
+			}
+			m[jsonName] = toValue(fv)
+		})
+		return m
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toValue(v.Elem())
+	}
+	return nil
+}
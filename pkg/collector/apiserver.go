@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	metricRequestDuration       = "apiserver_request_duration_seconds"
+	metricEtcdRequestDuration   = "etcd_request_duration_seconds"
+	metricWebhookDuration       = "apiserver_admission_webhook_admission_duration_seconds"
+	metricWebhookRejections     = "apiserver_admission_webhook_rejection_count"
+	metricFlowControlRejected   = "apiserver_flowcontrol_rejected_requests_total"
+	metricCurrentInflight       = "apiserver_current_inflight_requests"
+	metricFlowControlExecuting  = "apiserver_flowcontrol_current_executing_requests"
+	metricFlowControlExecSeats  = "apiserver_flowcontrol_current_executing_seats"
+	metricFlowControlInQueue    = "apiserver_flowcontrol_current_inqueue_requests"
+	metricFlowControlLimitSeats = "apiserver_flowcontrol_current_limit_seats"
+	metricFlowControlNominal    = "apiserver_flowcontrol_nominal_limit_seats"
+	metricFlowControlLegacyCap  = "apiserver_flowcontrol_request_concurrency_limit"
+)
+
+var apiServerMetricNames = []string{
+	metricRequestDuration,
+	metricEtcdRequestDuration,
+	metricWebhookDuration,
+	metricWebhookRejections,
+	metricFlowControlRejected,
+	metricCurrentInflight,
+	metricFlowControlExecuting,
+	metricFlowControlExecSeats,
+	metricFlowControlInQueue,
+	metricFlowControlLimitSeats,
+	metricFlowControlNominal,
+	metricFlowControlLegacyCap,
+}
+
+func collectAPIServerMetrics(ctx context.Context, client kubernetes.Interface) (*APIServerMetrics, error) {
+	body, err := client.CoreV1().RESTClient().Get().AbsPath("/metrics").Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scrape apiserver metrics: %w", err)
+	}
+	defer body.Close()
+
+	samples, err := parsePromText(body, isAPIServerMetric)
+	if err != nil {
+		return nil, fmt.Errorf("parse apiserver metrics: %w", err)
+	}
+	return buildAPIServerMetrics(samples), nil
+}
+
+func isAPIServerMetric(name string) bool {
+	for _, m := range apiServerMetricNames {
+		if name == m || isHistogramSuffix(strings.TrimPrefix(name, m)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHistogramSuffix(s string) bool {
+	return s == "_bucket" || s == "_sum" || s == "_count"
+}
+
+func buildAPIServerMetrics(samples []promSample) *APIServerMetrics {
+	requests := newHistogramAccumulator()
+	etcd := newHistogramAccumulator()
+	webhooks := newHistogramAccumulator()
+
+	rejections := make(map[[3]string]float64)
+	webhookErrors := make(map[[2]string]float64)
+	inflight := make(map[string]float64)
+	levels := make(map[string]*PriorityLevelInfo)
+	limits := make(map[string]map[string]float64)
+	execSeats := make(map[string]float64)
+
+	level := func(name string) *PriorityLevelInfo {
+		pl, ok := levels[name]
+		if !ok {
+			pl = &PriorityLevelInfo{Name: name}
+			levels[name] = pl
+		}
+		return pl
+	}
+
+	for _, s := range samples {
+		switch {
+		case strings.HasPrefix(s.Name, metricRequestDuration):
+			requests.add(metricRequestDuration, s, "verb", "resource", "subresource")
+		case strings.HasPrefix(s.Name, metricEtcdRequestDuration):
+			etcd.add(metricEtcdRequestDuration, s, "operation", "type")
+		case strings.HasPrefix(s.Name, metricWebhookDuration):
+			webhooks.add(metricWebhookDuration, s, "name", "type")
+		case s.Name == metricWebhookRejections:
+			if et := s.Labels["error_type"]; et != "" && et != "no_error" {
+				webhookErrors[[2]string{s.Labels["name"], s.Labels["type"]}] += s.Value
+			}
+		case s.Name == metricFlowControlRejected:
+			key := [3]string{s.Labels["priority_level"], s.Labels["flow_schema"], s.Labels["reason"]}
+			rejections[key] += s.Value
+		case s.Name == metricCurrentInflight:
+			inflight[s.Labels["request_kind"]] += s.Value
+		case s.Name == metricFlowControlExecuting:
+			level(s.Labels["priority_level"]).Executing += s.Value
+		case s.Name == metricFlowControlExecSeats:
+			execSeats[s.Labels["priority_level"]] += s.Value
+		case s.Name == metricFlowControlInQueue:
+			level(s.Labels["priority_level"]).InQueue += s.Value
+		case s.Name == metricFlowControlLimitSeats, s.Name == metricFlowControlNominal, s.Name == metricFlowControlLegacyCap:
+			pl := s.Labels["priority_level"]
+			if limits[pl] == nil {
+				limits[pl] = make(map[string]float64)
+			}
+			limits[pl][s.Name] = s.Value
+		}
+	}
+
+	m := &APIServerMetrics{}
+
+	requests.each(func(l map[string]string, h Histogram) {
+		m.RequestLatencies = append(m.RequestLatencies, RequestLatency{
+			Verb:        l["verb"],
+			Resource:    l["resource"],
+			Subresource: l["subresource"],
+			Latency:     h,
+		})
+	})
+	etcd.each(func(l map[string]string, h Histogram) {
+		m.EtcdLatencies = append(m.EtcdLatencies, EtcdLatency{
+			Operation: l["operation"],
+			Type:      l["type"],
+			Latency:   h,
+		})
+	})
+	webhooks.each(func(l map[string]string, h Histogram) {
+		m.WebhookLatencies = append(m.WebhookLatencies, WebhookLatency{
+			Name:       l["name"],
+			Type:       l["type"],
+			Latency:    h,
+			CallErrors: uint64(webhookErrors[[2]string{l["name"], l["type"]}]),
+		})
+	})
+
+	for key, v := range rejections {
+		m.FlowControlRejections = append(m.FlowControlRejections, FlowControlRejection{
+			PriorityLevel: key[0],
+			FlowSchema:    key[1],
+			Reason:        key[2],
+			Count:         uint64(v),
+		})
+	}
+	sort.Slice(m.FlowControlRejections, func(i, j int) bool {
+		a, b := m.FlowControlRejections[i], m.FlowControlRejections[j]
+		if a.PriorityLevel != b.PriorityLevel {
+			return a.PriorityLevel < b.PriorityLevel
+		}
+		if a.FlowSchema != b.FlowSchema {
+			return a.FlowSchema < b.FlowSchema
+		}
+		return a.Reason < b.Reason
+	})
+
+	for kind, v := range inflight {
+		m.InflightRequests = append(m.InflightRequests, InflightRequests{RequestKind: kind, Current: v})
+	}
+	sort.Slice(m.InflightRequests, func(i, j int) bool {
+		return m.InflightRequests[i].RequestKind < m.InflightRequests[j].RequestKind
+	})
+
+	for name, seats := range execSeats {
+		level(name).Executing = seats
+	}
+	for name, byMetric := range limits {
+		pl := level(name)
+		for _, metric := range []string{metricFlowControlLimitSeats, metricFlowControlNominal, metricFlowControlLegacyCap} {
+			if v, ok := byMetric[metric]; ok {
+				pl.ConcurrencyLimit = v
+				break
+			}
+		}
+	}
+	for _, pl := range levels {
+		m.PriorityLevels = append(m.PriorityLevels, *pl)
+	}
+	sort.Slice(m.PriorityLevels, func(i, j int) bool {
+		return m.PriorityLevels[i].Name < m.PriorityLevels[j].Name
+	})
+
+	return m
+}
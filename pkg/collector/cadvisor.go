@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	metricCFSPeriods          = "container_cpu_cfs_periods_total"
+	metricCFSThrottledPeriods = "container_cpu_cfs_throttled_periods_total"
+	metricCFSThrottledSeconds = "container_cpu_cfs_throttled_seconds_total"
+)
+
+func collectCadvisorCPU(ctx context.Context, client kubernetes.Interface, nodes []NodeInfo, opts Options) ([]ContainerCPUStats, error) {
+	results := make([][]ContainerCPUStats, len(nodes))
+
+	errs := forEachNode(ctx, nodes, opts.NodeConcurrency, opts.NodeTimeout, func(ctx context.Context, i int, node string) error {
+		body, err := nodeProxyGet(ctx, client, node, "metrics/cadvisor")
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		stats, err := parseCadvisorCPU(node, body, opts.Namespace)
+		if err != nil {
+			return fmt.Errorf("parse cadvisor metrics: %w", err)
+		}
+		results[i] = stats
+		return nil
+	})
+
+	var stats []ContainerCPUStats
+	for _, s := range results {
+		stats = append(stats, s...)
+	}
+
+	if len(errs) > 0 {
+		return stats, fmt.Errorf("cadvisor metrics: %d of %d node(s) failed; first: %w", len(errs), len(nodes), errs[0])
+	}
+	return stats, nil
+}
+
+func parseCadvisorCPU(node string, r io.Reader, namespace string) ([]ContainerCPUStats, error) {
+	samples, err := parsePromText(r, func(name string) bool {
+		return name == metricCFSPeriods || name == metricCFSThrottledPeriods || name == metricCFSThrottledSeconds
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byContainer := make(map[string]*ContainerCPUStats)
+	var order []string
+	for _, s := range samples {
+		ns := firstLabel(s.Labels, "namespace")
+		pod := firstLabel(s.Labels, "pod", "pod_name")
+		container := firstLabel(s.Labels, "container", "container_name")
+		if ns == "" || pod == "" || container == "" || container == "POD" {
+			continue
+		}
+		if namespace != "" && ns != namespace {
+			continue
+		}
+
+		key := ns + "/" + pod + "/" + container
+		st, ok := byContainer[key]
+		if !ok {
+			st = &ContainerCPUStats{NodeName: node, Namespace: ns, Pod: pod, Container: container}
+			byContainer[key] = st
+			order = append(order, key)
+		}
+
+		switch s.Name {
+		case metricCFSPeriods:
+			st.Periods += uint64(s.Value)
+		case metricCFSThrottledPeriods:
+			st.ThrottledPeriods += uint64(s.Value)
+		case metricCFSThrottledSeconds:
+			st.ThrottledSeconds += s.Value
+		}
+	}
+
+	stats := make([]ContainerCPUStats, 0, len(order))
+	for _, key := range order {
+		if st := byContainer[key]; st.Periods > 0 {
+			stats = append(stats, *st)
+		}
+	}
+	return stats, nil
+}
+
+// firstLabel returns the first non-empty label among keys. Older kubelets
+// expose pod_name and container_name instead of pod and container.
+func firstLabel(labels map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(labels[k]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
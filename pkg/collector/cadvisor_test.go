@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+const cadvisorFixture = `# HELP container_cpu_cfs_periods_total Number of elapsed enforcement period intervals.
+# TYPE container_cpu_cfs_periods_total counter
+container_cpu_cfs_periods_total{container="web",id="/kubepods/burstable/pod1/abc",image="nginx",name="abc",namespace="default",pod="frontend-xyz"} 1000 1700000000000
+container_cpu_cfs_periods_total{container="POD",namespace="default",pod="frontend-xyz"} 50
+container_cpu_cfs_periods_total{container="",namespace="",pod=""} 9000
+container_cpu_cfs_periods_total{container_name="api",namespace="shop",pod_name="api-0"} 400
+# TYPE container_cpu_cfs_throttled_periods_total counter
+container_cpu_cfs_throttled_periods_total{container="web",namespace="default",pod="frontend-xyz"} 450
+container_cpu_cfs_throttled_periods_total{container_name="api",namespace="shop",pod_name="api-0"} 10
+# TYPE container_cpu_cfs_throttled_seconds_total counter
+container_cpu_cfs_throttled_seconds_total{container="web",namespace="default",pod="frontend-xyz"} 37.5
+container_cpu_usage_seconds_total{container="web",namespace="default",pod="frontend-xyz"} 900
+`
+
+func TestParseCadvisorCPU(t *testing.T) {
+	stats, err := parseCadvisorCPU("worker-1", strings.NewReader(cadvisorFixture), "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 containers, got %d: %+v", len(stats), stats)
+	}
+
+	web := stats[0]
+	if web.NodeName != "worker-1" || web.Namespace != "default" || web.Pod != "frontend-xyz" || web.Container != "web" {
+		t.Errorf("unexpected identity: %+v", web)
+	}
+	if web.Periods != 1000 || web.ThrottledPeriods != 450 {
+		t.Errorf("periods: got %d/%d, want 450/1000", web.ThrottledPeriods, web.Periods)
+	}
+	if web.ThrottledSeconds != 37.5 {
+		t.Errorf("throttled seconds: got %v", web.ThrottledSeconds)
+	}
+
+	api := stats[1]
+	if api.Pod != "api-0" || api.Container != "api" || api.Periods != 400 || api.ThrottledPeriods != 10 {
+		t.Errorf("legacy labels not handled: %+v", api)
+	}
+}
+
+func TestParseCadvisorCPU_NamespaceFilter(t *testing.T) {
+	stats, err := parseCadvisorCPU("worker-1", strings.NewReader(cadvisorFixture), "shop")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Namespace != "shop" {
+		t.Errorf("expected only the shop container, got %+v", stats)
+	}
+}
@@ -9,50 +9,118 @@ import (
 )
 
 func Collect(ctx context.Context, client kubernetes.Interface, opts Options) (*Snapshot, error) {
+	start := time.Now()
 	snap := &Snapshot{
 		SchemaVersion: SnapshotSchemaVersion,
-		CollectedAt:   time.Now().UTC(),
+		CollectedAt:   start.UTC(),
 		Since:         opts.Since.String(),
+		Collection:    &CollectionInfo{Options: opts.info()},
 	}
 
 	var errs []error
+	record := func(collector string, err error) {
+		errs = append(errs, err)
+		snap.Collection.record(collector, err)
+	}
+
+	cluster, err := collectClusterInfo(ctx, client)
+	if err != nil {
+		record("cluster", err)
+	}
+	snap.Cluster = cluster
 
 	nodes, err := collectNodes(ctx, client)
 	if err != nil {
-		errs = append(errs, err)
+		record("nodes", err)
 	}
 	snap.Nodes = nodes
 
 	pods, err := collectPods(ctx, client, opts.Namespace)
 	if err != nil {
-		errs = append(errs, err)
+		record("pods", err)
 	}
 	snap.Pods = pods
 
 	events, err := collectEvents(ctx, client, opts.Since)
 	if err != nil {
-		errs = append(errs, err)
+		record("events", err)
 	}
 	snap.Events = events
 
 	pvcs, err := collectPVCs(ctx, client, opts.Namespace)
 	if err != nil {
-		errs = append(errs, err)
+		record("pvcs", err)
 	}
 	snap.PVCs = pvcs
 
 	pvs, err := collectPVs(ctx, client)
 	if err != nil {
-		errs = append(errs, err)
+		record("pvs", err)
 	}
 	snap.PVs = pvs
 
-	ksHealth, err := collectKubeSystemHealth(ctx, client)
+	selectors := opts.SystemPodSelectors
+	if len(selectors) == 0 {
+		selectors = defaultSystemPodSelectors
+	}
+	ksHealth, err := collectKubeSystemHealth(ctx, client, selectors)
 	if err != nil {
-		errs = append(errs, err)
+		record("kube-system", err)
 	}
 	snap.KubeSystem = ksHealth
 
+	webhooks, err := collectWebhooks(ctx, client)
+	if err != nil {
+		record("webhooks", err)
+	}
+	snap.Webhooks = webhooks
+
+	endpoints, err := collectWebhookEndpoints(ctx, client, webhooks)
+	if err != nil {
+		record("endpoints", err)
+	}
+	snap.Endpoints = endpoints
+
+	if opts.APIServerMetrics {
+		apiMetrics, err := collectAPIServerMetrics(ctx, client)
+		if err != nil {
+			record("apiserver-metrics", err)
+		}
+		snap.APIServer = apiMetrics
+	}
+
+	if opts.KubeletStats {
+		stats, err := collectKubeletStats(ctx, client, nodes, opts)
+		if err != nil {
+			record("kubelet-stats", err)
+		}
+		snap.KubeletStats = stats
+	}
+
+	if opts.CadvisorMetrics {
+		cpu, err := collectCadvisorCPU(ctx, client, nodes, opts)
+		if err != nil {
+			record("cadvisor-metrics", err)
+		}
+		snap.ContainerCPU = cpu
+	}
+
+	if opts.ResourceMetrics {
+		if err := collectResourceMetrics(ctx, client, snap, opts.Namespace); err != nil {
+			record("resource-metrics", err)
+		}
+	}
+
+	if opts.Logs {
+		logs, err := collectContainerLogs(ctx, client, snap, opts)
+		if err != nil {
+			record("logs", err)
+		}
+		snap.Logs = logs
+	}
+
+	snap.Collection.Duration = time.Since(start).Round(time.Millisecond).String()
+
 	if len(errs) > 0 {
 		return snap, fmt.Errorf("collection had %d errors; first: %w", len(errs), errs[0])
 	}
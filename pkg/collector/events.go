@@ -5,39 +5,165 @@ import (
 	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
+const eventPageSize = 500
+
+// collectEvents prefers events.k8s.io/v1, which carries series and reporting
+// metadata, and falls back to core/v1 on clusters that do not serve it or
+// when RBAC only grants the core/v1 resource.
 func collectEvents(ctx context.Context, client kubernetes.Interface, since time.Duration) ([]EventInfo, error) {
 	cutoff := time.Now().Add(-since)
 
-	list, err := client.CoreV1().Events("").List(ctx, metav1.ListOptions{})
+	events, err := collectEventsV1(ctx, client, cutoff)
+	if err == nil {
+		return events, nil
+	}
+	if !apierrors.IsNotFound(err) && !apierrors.IsForbidden(err) {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	events, err = collectCoreEvents(ctx, client, cutoff)
 	if err != nil {
 		return nil, fmt.Errorf("list events: %w", err)
 	}
+	return events, nil
+}
 
+func collectEventsV1(ctx context.Context, client kubernetes.Interface, cutoff time.Time) ([]EventInfo, error) {
 	events := make([]EventInfo, 0)
-	for _, e := range list.Items {
-		ts := e.LastTimestamp.Time
-		if ts.IsZero() {
-			ts = e.EventTime.Time
-		}
-		if ts.Before(cutoff) {
-			continue
-		}
-
-		events = append(events, EventInfo{
-			Namespace:      e.Namespace,
-			Name:           e.Name,
-			Reason:         e.Reason,
-			Message:        e.Message,
-			Type:           e.Type,
-			InvolvedObject: fmt.Sprintf("%s/%s/%s", e.InvolvedObject.Kind, e.InvolvedObject.Namespace, e.InvolvedObject.Name),
-			Count:          e.Count,
-			FirstTimestamp: e.FirstTimestamp.Time,
-			LastTimestamp:  ts,
-		})
+	opts := metav1.ListOptions{Limit: eventPageSize}
+	for {
+		list, err := client.EventsV1().Events("").List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list.Items {
+			info := NewEventInfo(&e)
+			if info.LastTimestamp.Before(cutoff) {
+				continue
+			}
+			events = append(events, info)
+		}
+		if list.Continue == "" {
+			return events, nil
+		}
+		opts.Continue = list.Continue
 	}
-	return events, nil
+}
+
+func collectCoreEvents(ctx context.Context, client kubernetes.Interface, cutoff time.Time) ([]EventInfo, error) {
+	events := make([]EventInfo, 0)
+	opts := metav1.ListOptions{Limit: eventPageSize}
+	for {
+		list, err := client.CoreV1().Events("").List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list.Items {
+			info := NewCoreEventInfo(&e)
+			if info.LastTimestamp.Before(cutoff) {
+				continue
+			}
+			events = append(events, info)
+		}
+		if list.Continue == "" {
+			return events, nil
+		}
+		opts.Continue = list.Continue
+	}
+}
+
+func NewEventInfo(e *eventsv1.Event) EventInfo {
+	info := EventInfo{
+		Namespace:           e.Namespace,
+		Name:                e.Name,
+		Reason:              e.Reason,
+		Message:             e.Note,
+		Type:                e.Type,
+		InvolvedObject:      objectRef(e.Regarding),
+		ReportingController: e.ReportingController,
+		ReportingInstance:   e.ReportingInstance,
+		Action:              e.Action,
+		Count:               e.DeprecatedCount,
+		FirstTimestamp:      e.DeprecatedFirstTimestamp.Time,
+		LastTimestamp:       latest(e.DeprecatedLastTimestamp.Time, e.EventTime.Time),
+	}
+	if e.Related != nil {
+		related := objectRef(*e.Related)
+		info.Related = &related
+	}
+	if e.Series != nil {
+		info.LastTimestamp = latest(info.LastTimestamp, e.Series.LastObservedTime.Time)
+		if e.Series.Count > info.Count {
+			info.Count = e.Series.Count
+		}
+	}
+	finishEventInfo(&info, e.EventTime.Time)
+	return info
+}
+
+func NewCoreEventInfo(e *corev1.Event) EventInfo {
+	info := EventInfo{
+		Namespace:           e.Namespace,
+		Name:                e.Name,
+		Reason:              e.Reason,
+		Message:             e.Message,
+		Type:                e.Type,
+		InvolvedObject:      objectRef(e.InvolvedObject),
+		ReportingController: e.ReportingController,
+		ReportingInstance:   e.ReportingInstance,
+		Action:              e.Action,
+		Count:               e.Count,
+		FirstTimestamp:      e.FirstTimestamp.Time,
+		LastTimestamp:       latest(e.LastTimestamp.Time, e.EventTime.Time),
+	}
+	if e.Related != nil {
+		related := objectRef(*e.Related)
+		info.Related = &related
+	}
+	if e.Series != nil {
+		info.LastTimestamp = latest(info.LastTimestamp, e.Series.LastObservedTime.Time)
+		if e.Series.Count > info.Count {
+			info.Count = e.Series.Count
+		}
+	}
+	finishEventInfo(&info, e.EventTime.Time)
+	return info
+}
+
+// finishEventInfo fills in what older and newer reporters leave unset: a
+// missing first timestamp falls back to the event time, and an event always
+// counts at least once.
+func finishEventInfo(info *EventInfo, eventTime time.Time) {
+	if info.FirstTimestamp.IsZero() {
+		info.FirstTimestamp = eventTime
+	}
+	if info.FirstTimestamp.IsZero() {
+		info.FirstTimestamp = info.LastTimestamp
+	}
+	if info.Count < 1 {
+		info.Count = 1
+	}
+}
+
+func objectRef(ref corev1.ObjectReference) model.ObjectRef {
+	return model.RefFromAPIVersion(ref.APIVersion, ref.Kind, ref.Namespace, ref.Name, string(ref.UID))
+}
+
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, c := range times {
+		if c.After(t) {
+			t = c
+		}
+	}
+	return t
 }
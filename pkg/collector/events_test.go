@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func TestEventInfoFromV1_Series(t *testing.T) {
+	first := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC)
+
+	e := eventsv1.Event{
+		ObjectMeta:          metav1.ObjectMeta{Namespace: "default", Name: "web-0.17a"},
+		EventTime:           metav1.NewMicroTime(first),
+		Reason:              "BackOff",
+		Note:                "Back-off restarting failed container",
+		Type:                "Warning",
+		Action:              "Restarting",
+		ReportingController: "kubelet",
+		ReportingInstance:   "worker-1",
+		Regarding:           corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-0", UID: "pod-uid", APIVersion: "v1"},
+		Related:             &corev1.ObjectReference{Kind: "Node", Name: "worker-1"},
+		Series:              &eventsv1.EventSeries{Count: 42, LastObservedTime: metav1.NewMicroTime(last)},
+	}
+
+	info := NewEventInfo(&e)
+
+	if info.Count != 42 {
+		t.Errorf("count: got %d, want 42", info.Count)
+	}
+	if !info.FirstTimestamp.Equal(first) {
+		t.Errorf("first seen: got %v, want %v", info.FirstTimestamp, first)
+	}
+	if !info.LastTimestamp.Equal(last) {
+		t.Errorf("last seen: got %v, want %v", info.LastTimestamp, last)
+	}
+	if info.Message != "Back-off restarting failed container" {
+		t.Errorf("message: got %q", info.Message)
+	}
+	wantRef := model.ObjectRef{Version: "v1", Kind: "Pod", Namespace: "default", Name: "web-0", UID: "pod-uid"}
+	if info.InvolvedObject != wantRef {
+		t.Errorf("involved object: got %+v, want %+v", info.InvolvedObject, wantRef)
+	}
+	if info.Related == nil || info.Related.String() != "node/worker-1" {
+		t.Errorf("related: got %+v", info.Related)
+	}
+	if info.ReportingController != "kubelet" || info.ReportingInstance != "worker-1" || info.Action != "Restarting" {
+		t.Errorf("reporting: got %q/%q/%q", info.ReportingController, info.ReportingInstance, info.Action)
+	}
+}
+
+func TestEventInfoFromCore_EventTimeOnly(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	e := corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "x"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "x"},
+		EventTime:      metav1.NewMicroTime(ts),
+	}
+
+	info := NewCoreEventInfo(&e)
+
+	if info.Count != 1 {
+		t.Errorf("count: got %d, want 1", info.Count)
+	}
+	if !info.FirstTimestamp.Equal(ts) || !info.LastTimestamp.Equal(ts) {
+		t.Errorf("timestamps: got %v..%v, want %v", info.FirstTimestamp, info.LastTimestamp, ts)
+	}
+}
+
+func TestCollectEvents_FallsBackToCoreV1(t *testing.T) {
+	gr := schema.GroupResource{Group: "events.k8s.io", Resource: "events"}
+	for name, apiErr := range map[string]error{
+		"not served": apierrors.NewNotFound(gr, ""),
+		"forbidden":  apierrors.NewForbidden(gr, "", errors.New("no access")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			client := fake.NewSimpleClientset(
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "recent"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-0"},
+					Reason:         "BackOff",
+					Count:          3,
+					LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+				},
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "old"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-0"},
+					Reason:         "BackOff",
+					LastTimestamp:  metav1.NewTime(now.Add(-2 * time.Hour)),
+				},
+			)
+			client.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetResource().Group == "events.k8s.io" {
+					return true, nil, apiErr
+				}
+				return false, nil, nil
+			})
+
+			events, err := collectEvents(context.Background(), client, 30*time.Minute)
+			if err != nil {
+				t.Fatalf("collect: %v", err)
+			}
+			if len(events) != 1 || events[0].Name != "recent" {
+				t.Errorf("expected only the recent core/v1 event, got %+v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+type statsSummary struct {
+	Node struct {
+		NodeName string         `json:"nodeName"`
+		CPU      *summaryCPU    `json:"cpu"`
+		Memory   *summaryMemory `json:"memory"`
+		Fs       *summaryFs     `json:"fs"`
+		Runtime  *struct {
+			ImageFs *summaryFs `json:"imageFs"`
+		} `json:"runtime"`
+		Rlimit *struct {
+			MaxPID  *int64 `json:"maxpid"`
+			CurProc *int64 `json:"curproc"`
+		} `json:"rlimit"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		CPU              *summaryCPU    `json:"cpu"`
+		Memory           *summaryMemory `json:"memory"`
+		EphemeralStorage *summaryFs     `json:"ephemeral-storage"`
+		ProcessStats     *struct {
+			ProcessCount *uint64 `json:"process_count"`
+		} `json:"process_stats"`
+	} `json:"pods"`
+}
+
+type summaryCPU struct {
+	UsageNanoCores *uint64 `json:"usageNanoCores"`
+}
+
+type summaryMemory struct {
+	AvailableBytes  *uint64 `json:"availableBytes"`
+	WorkingSetBytes *uint64 `json:"workingSetBytes"`
+}
+
+type summaryFs struct {
+	AvailableBytes *uint64 `json:"availableBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	UsedBytes      *uint64 `json:"usedBytes"`
+	InodesFree     *uint64 `json:"inodesFree"`
+	Inodes         *uint64 `json:"inodes"`
+}
+
+func collectKubeletStats(ctx context.Context, client kubernetes.Interface, nodes []NodeInfo, opts Options) ([]NodeStats, error) {
+	results := make([]*NodeStats, len(nodes))
+
+	errs := forEachNode(ctx, nodes, opts.NodeConcurrency, opts.NodeTimeout, func(ctx context.Context, i int, node string) error {
+		body, err := nodeProxyGet(ctx, client, node, "stats/summary")
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		var summary statsSummary
+		if err := json.NewDecoder(body).Decode(&summary); err != nil {
+			return fmt.Errorf("decode stats summary: %w", err)
+		}
+		stats := nodeStatsFromSummary(node, summary)
+		results[i] = &stats
+		return nil
+	})
+
+	stats := make([]NodeStats, 0, len(nodes))
+	for _, s := range results {
+		if s != nil {
+			stats = append(stats, *s)
+		}
+	}
+
+	if len(errs) > 0 {
+		return stats, fmt.Errorf("kubelet stats: %d of %d node(s) failed; first: %w", len(errs), len(nodes), errs[0])
+	}
+	return stats, nil
+}
+
+func nodeStatsFromSummary(node string, s statsSummary) NodeStats {
+	stats := NodeStats{NodeName: node}
+
+	if s.Node.CPU != nil {
+		stats.CPUUsageNanoCores = deref(s.Node.CPU.UsageNanoCores)
+	}
+	if s.Node.Memory != nil {
+		stats.MemoryWorkingSetBytes = deref(s.Node.Memory.WorkingSetBytes)
+		stats.MemoryAvailableBytes = deref(s.Node.Memory.AvailableBytes)
+	}
+	stats.FS = fsStats(s.Node.Fs)
+	if s.Node.Runtime != nil {
+		stats.ImageFS = fsStats(s.Node.Runtime.ImageFs)
+	}
+	if s.Node.Rlimit != nil {
+		if s.Node.Rlimit.MaxPID != nil {
+			stats.MaxPID = *s.Node.Rlimit.MaxPID
+		}
+		if s.Node.Rlimit.CurProc != nil {
+			stats.RunningProcesses = *s.Node.Rlimit.CurProc
+		}
+	}
+
+	for _, p := range s.Pods {
+		ps := PodStats{
+			Namespace: p.PodRef.Namespace,
+			Name:      p.PodRef.Name,
+		}
+		if p.CPU != nil {
+			ps.CPUUsageNanoCores = deref(p.CPU.UsageNanoCores)
+		}
+		if p.Memory != nil {
+			ps.MemoryWorkingSetBytes = deref(p.Memory.WorkingSetBytes)
+		}
+		if p.EphemeralStorage != nil {
+			ps.EphemeralStorageBytes = deref(p.EphemeralStorage.UsedBytes)
+		}
+		if p.ProcessStats != nil {
+			ps.Processes = deref(p.ProcessStats.ProcessCount)
+		}
+		stats.Pods = append(stats.Pods, ps)
+	}
+
+	return stats
+}
+
+func fsStats(fs *summaryFs) *FSStats {
+	if fs == nil || fs.CapacityBytes == nil {
+		return nil
+	}
+	return &FSStats{
+		AvailableBytes: deref(fs.AvailableBytes),
+		CapacityBytes:  deref(fs.CapacityBytes),
+		UsedBytes:      deref(fs.UsedBytes),
+		InodesFree:     deref(fs.InodesFree),
+		Inodes:         deref(fs.Inodes),
+	}
+}
+
+func deref(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
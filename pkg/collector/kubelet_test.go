@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const statsSummaryFixture = `{
+  "node": {
+    "nodeName": "worker-1",
+    "cpu": {"usageNanoCores": 1500000000},
+    "memory": {"availableBytes": 524288000, "workingSetBytes": 7516192768},
+    "fs": {"availableBytes": 10737418240, "capacityBytes": 107374182400, "usedBytes": 96636764160},
+    "runtime": {"imageFs": {"availableBytes": 5368709120, "capacityBytes": 53687091200, "usedBytes": 48318382080}},
+    "rlimit": {"maxpid": 4194304, "curproc": 812}
+  },
+  "pods": [
+    {
+      "podRef": {"name": "web-0", "namespace": "default"},
+      "cpu": {"usageNanoCores": 250000000},
+      "memory": {"workingSetBytes": 268435456},
+      "ephemeral-storage": {"usedBytes": 1048576},
+      "process_stats": {"process_count": 12}
+    }
+  ]
+}`
+
+func TestNodeStatsFromSummary(t *testing.T) {
+	var summary statsSummary
+	if err := json.Unmarshal([]byte(statsSummaryFixture), &summary); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	stats := nodeStatsFromSummary("worker-1", summary)
+
+	if stats.MemoryAvailableBytes != 524288000 {
+		t.Errorf("memory available: got %d", stats.MemoryAvailableBytes)
+	}
+	if stats.FS == nil || stats.FS.CapacityBytes != 107374182400 {
+		t.Errorf("fs: got %+v", stats.FS)
+	}
+	if stats.ImageFS == nil || stats.ImageFS.AvailableBytes != 5368709120 {
+		t.Errorf("imagefs: got %+v", stats.ImageFS)
+	}
+	if stats.MaxPID != 4194304 || stats.RunningProcesses != 812 {
+		t.Errorf("pids: got %d/%d", stats.RunningProcesses, stats.MaxPID)
+	}
+	if len(stats.Pods) != 1 {
+		t.Fatalf("pods: got %d, want 1", len(stats.Pods))
+	}
+	p := stats.Pods[0]
+	if p.Namespace != "default" || p.Name != "web-0" || p.Processes != 12 || p.EphemeralStorageBytes != 1048576 {
+		t.Errorf("pod stats: got %+v", p)
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 
+	appsv1 "k8s.io/api/apps/v1"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 )
 
 const kubeSystemNS = "kube-system"
 
-var criticalLabels = []string{
+// defaultSystemPodSelectors pick the kube-system pods whose health is
+// collected: DNS, kube-proxy, the common CNIs and CSI node drivers.
+var defaultSystemPodSelectors = []string{
 	"k8s-app=kube-dns",
 	"k8s-app=kube-proxy",
 	"k8s-app=calico-node",
@@ -21,7 +26,24 @@ var criticalLabels = []string{
 	"app.kubernetes.io/name=aws-node",
 }
 
-func collectKubeSystemHealth(ctx context.Context, client kubernetes.Interface) (KubeSystemHealth, error) {
+// DefaultSystemPodSelectors returns the label selectors kube-system pods are
+// collected for when Options.SystemPodSelectors is empty.
+func DefaultSystemPodSelectors() []string {
+	return append([]string(nil), defaultSystemPodSelectors...)
+}
+
+// ValidateSystemPodSelectors reports the first selector that is not a valid
+// label selector.
+func ValidateSystemPodSelectors(selectors []string) error {
+	for _, sel := range selectors {
+		if _, err := labels.Parse(sel); err != nil {
+			return fmt.Errorf("system pod selector %q: %w", sel, err)
+		}
+	}
+	return nil
+}
+
+func collectKubeSystemHealth(ctx context.Context, client kubernetes.Interface, selectors []string) (KubeSystemHealth, error) {
 	var health KubeSystemHealth
 
 	dsList, err := client.AppsV1().DaemonSets(kubeSystemNS).List(ctx, metav1.ListOptions{})
@@ -29,56 +51,54 @@ func collectKubeSystemHealth(ctx context.Context, client kubernetes.Interface) (
 		return health, fmt.Errorf("list kube-system daemonsets: %w", err)
 	}
 
-	for _, ds := range dsList.Items {
-		health.DaemonSets = append(health.DaemonSets, DaemonSetInfo{
-			Name:                   ds.Name,
-			DesiredNumberScheduled: ds.Status.DesiredNumberScheduled,
-			CurrentNumberScheduled: ds.Status.CurrentNumberScheduled,
-			NumberReady:            ds.Status.NumberReady,
-			NumberMisscheduled:     ds.Status.NumberMisscheduled,
-			NumberUnavailable:      ds.Status.NumberUnavailable,
-		})
+	for i := range dsList.Items {
+		health.DaemonSets = append(health.DaemonSets, NewDaemonSetInfo(&dsList.Items[i]))
+	}
+
+	if ns, err := client.CoreV1().Namespaces().Get(ctx, kubeSystemNS, metav1.GetOptions{}); err == nil {
+		health.NamespaceLabels = ns.Labels
 	}
 
 	seen := make(map[string]bool)
-	for _, sel := range criticalLabels {
+	for _, sel := range selectors {
 		pods, err := client.CoreV1().Pods(kubeSystemNS).List(ctx, metav1.ListOptions{
 			LabelSelector: sel,
 		})
 		if err != nil {
 			continue
 		}
-		for _, p := range pods.Items {
+		for i, p := range pods.Items {
 			if seen[p.Name] {
 				continue
 			}
 			seen[p.Name] = true
 
-			containers := make([]ContainerInfo, 0, len(p.Spec.Containers))
-			for i, c := range p.Spec.Containers {
-				ci := ContainerInfo{
-					Name:      c.Name,
-					Resources: c.Resources,
-				}
-				if i < len(p.Status.ContainerStatuses) {
-					ci.Ready = p.Status.ContainerStatuses[i].Ready
-					ci.RestartCount = p.Status.ContainerStatuses[i].RestartCount
-					ci.State = p.Status.ContainerStatuses[i].State
-				}
-				containers = append(containers, ci)
-			}
-
-			health.Pods = append(health.Pods, PodInfo{
-				Name:       p.Name,
-				Namespace:  p.Namespace,
-				Phase:      p.Status.Phase,
-				Conditions: p.Status.Conditions,
-				Containers: containers,
-				NodeName:   p.Spec.NodeName,
-				QOSClass:   p.Status.QOSClass,
-			})
+			health.Pods = append(health.Pods, NewPodInfo(&pods.Items[i]))
 		}
 	}
 
 	return health, nil
 }
+
+func NewDaemonSetInfo(ds *appsv1.DaemonSet) DaemonSetInfo {
+	return DaemonSetInfo{
+		Name:                   ds.Name,
+		DesiredNumberScheduled: ds.Status.DesiredNumberScheduled,
+		CurrentNumberScheduled: ds.Status.CurrentNumberScheduled,
+		NumberReady:            ds.Status.NumberReady,
+		NumberMisscheduled:     ds.Status.NumberMisscheduled,
+		NumberUnavailable:      ds.Status.NumberUnavailable,
+	}
+}
+
+// IsCriticalSystemPod reports whether a kube-system pod's labels match one of
+// the selectors kube-system health is collected for. Invalid selectors match
+// nothing.
+func IsCriticalSystemPod(podLabels map[string]string, selectors []string) bool {
+	for _, sel := range selectors {
+		if s, err := labels.Parse(sel); err == nil && s.Matches(labels.Set(podLabels)) {
+			return true
+		}
+	}
+	return false
+}
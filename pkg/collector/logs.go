@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MaxLogLineLen is the most characters kept of a log line or event message.
+const MaxLogLineLen = 256
+
+const maxLogContainers = 50
+
+// CNIPodKeywords name the CNI agent pods whose logs explain network setup
+// failures.
+var CNIPodKeywords = []string{"calico", "cilium", "flannel", "aws-node", "weave", "antrea", "kube-ovn"}
+
+// logPodKeywords name the DNS, CNI and CSI pods whose logs are collected.
+var logPodKeywords = append([]string{"coredns", "kube-dns", "csi"}, CNIPodKeywords...)
+
+type logTarget struct {
+	namespace string
+	pod       string
+	container string
+	previous  bool
+}
+
+func collectContainerLogs(ctx context.Context, client kubernetes.Interface, snap *Snapshot, opts Options) ([]ContainerLog, error) {
+	targets := selectLogTargets(snap)
+
+	var (
+		logs []ContainerLog
+		errs []error
+	)
+	for _, t := range targets {
+		log, err := fetchContainerLog(ctx, client, t, opts)
+		if err != nil {
+			// Previous logs are routinely missing after a node restart or
+			// container GC; that is not worth reporting.
+			if t.previous && (apierrors.IsBadRequest(err) || apierrors.IsNotFound(err)) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s/%s/%s: %w", t.namespace, t.pod, t.container, err))
+			continue
+		}
+		logs = append(logs, log)
+	}
+
+	if len(errs) > 0 {
+		return logs, fmt.Errorf("container logs: %d of %d stream(s) failed; first: %w", len(errs), len(targets), errs[0])
+	}
+	return logs, nil
+}
+
+// selectLogTargets picks the DNS, CNI and CSI pods plus any crashlooping
+// container, fetching previous logs for containers that have restarted.
+func selectLogTargets(snap *Snapshot) []logTarget {
+	var targets []logTarget
+	seen := make(map[string]bool)
+	containers := 0
+
+	add := func(p PodInfo, all bool) {
+		for _, c := range p.Containers {
+			if containers >= maxLogContainers {
+				return
+			}
+			if !all && !isCrashLooping(c) {
+				continue
+			}
+			key := p.Namespace + "/" + p.Name + "/" + c.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			containers++
+
+			targets = append(targets, logTarget{namespace: p.Namespace, pod: p.Name, container: c.Name})
+			if c.RestartCount > 0 {
+				targets = append(targets, logTarget{namespace: p.Namespace, pod: p.Name, container: c.Name, previous: true})
+			}
+		}
+	}
+
+	for _, p := range snap.KubeSystem.Pods {
+		add(p, isLogPod(p.Name))
+	}
+	for _, p := range snap.Pods {
+		add(p, isLogPod(p.Name))
+	}
+	return targets
+}
+
+func isLogPod(name string) bool {
+	return nameContainsAny(name, logPodKeywords)
+}
+
+// IsCNIPod reports whether name looks like a CNI agent pod.
+func IsCNIPod(name string) bool {
+	return nameContainsAny(name, CNIPodKeywords)
+}
+
+func nameContainsAny(name string, keywords []string) bool {
+	lower := strings.ToLower(name)
+	for _, kw := range keywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCrashLooping(c ContainerInfo) bool {
+	return c.State.Waiting != nil && c.State.Waiting.Reason == "CrashLoopBackOff"
+}
+
+func fetchContainerLog(ctx context.Context, client kubernetes.Interface, t logTarget, opts Options) (ContainerLog, error) {
+	log := ContainerLog{
+		Namespace: t.namespace,
+		Pod:       t.pod,
+		Container: t.container,
+		Previous:  t.previous,
+	}
+
+	tail := opts.LogTailLines
+	limit := opts.LogLimitBytes
+	podLogOpts := &corev1.PodLogOptions{
+		Container: t.container,
+		Previous:  t.previous,
+	}
+	if tail > 0 {
+		podLogOpts.TailLines = &tail
+	}
+	if limit > 0 {
+		podLogOpts.LimitBytes = &limit
+	}
+
+	body, err := client.CoreV1().Pods(t.namespace).GetLogs(t.pod, podLogOpts).Stream(ctx)
+	if err != nil {
+		return log, err
+	}
+	defer body.Close()
+
+	lines, read, err := readLogLines(body)
+	if err != nil {
+		return log, fmt.Errorf("read logs: %w", err)
+	}
+	log.Lines = lines
+	log.Truncated = limit > 0 && read >= limit
+	return log, nil
+}
+
+// readLogLines splits a log stream into lines of at most MaxLogLineLen
+// characters and reports how many bytes were read.
+func readLogLines(r io.Reader) ([]string, int64, error) {
+	var (
+		lines []string
+		read  int64
+	)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		read += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			lines = append(lines, TruncateLogLine(line))
+		}
+		if err == io.EOF {
+			return lines, read, nil
+		}
+		if err != nil {
+			return lines, read, err
+		}
+	}
+}
+
+// TruncateLogLine cuts s to MaxLogLineLen characters, never inside a
+// multi-byte character.
+func TruncateLogLine(s string) string {
+	r := []rune(s)
+	if len(r) <= MaxLogLineLen {
+		return s
+	}
+	return string(r[:MaxLogLineLen-3]) + "..."
+}
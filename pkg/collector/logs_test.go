@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestReadLogLines(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	input := "first\r\n\n" + long + "\nlast without newline"
+
+	lines, read, err := readLogLines(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if read != int64(len(input)) {
+		t.Errorf("bytes read: got %d, want %d", read, len(input))
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), lines)
+	}
+	if lines[0] != "first" || lines[2] != "last without newline" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+	if len(lines[1]) != MaxLogLineLen || !strings.HasSuffix(lines[1], "...") {
+		t.Errorf("long line not truncated to %d chars: got %d", MaxLogLineLen, len(lines[1]))
+	}
+}
+
+func TestTruncateLogLine(t *testing.T) {
+	long := strings.Repeat("ü", 300)
+	got := TruncateLogLine(long)
+	if n := utf8.RuneCountInString(got); n != MaxLogLineLen {
+		t.Errorf("got %d characters, want %d", n, MaxLogLineLen)
+	}
+	if !utf8.ValidString(got) || !strings.HasSuffix(got, "...") {
+		t.Errorf("got %q", got)
+	}
+	if short := strings.Repeat("ü", MaxLogLineLen); TruncateLogLine(short) != short {
+		t.Errorf("a line of %d multi-byte characters should be kept", MaxLogLineLen)
+	}
+}
+
+func TestSelectLogTargets(t *testing.T) {
+	crashloop := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	snap := &Snapshot{
+		KubeSystem: KubeSystemHealth{
+			Pods: []PodInfo{
+				{Namespace: "kube-system", Name: "coredns-abc", Containers: []ContainerInfo{{Name: "coredns", RestartCount: 2}}},
+				{Namespace: "kube-system", Name: "kube-proxy-xyz", Containers: []ContainerInfo{{Name: "kube-proxy"}}},
+			},
+		},
+		Pods: []PodInfo{
+			{Namespace: "kube-system", Name: "coredns-abc", Containers: []ContainerInfo{{Name: "coredns", RestartCount: 2}}},
+			{Namespace: "storage", Name: "ebs-csi-node-1", Containers: []ContainerInfo{{Name: "ebs-plugin"}, {Name: "liveness-probe"}}},
+			{Namespace: "default", Name: "web-0", Containers: []ContainerInfo{{Name: "web", RestartCount: 7, State: crashloop}, {Name: "sidecar"}}},
+			{Namespace: "default", Name: "api-0", Containers: []ContainerInfo{{Name: "api"}}},
+		},
+	}
+
+	targets := selectLogTargets(snap)
+
+	got := make([]string, 0, len(targets))
+	for _, tg := range targets {
+		s := tg.namespace + "/" + tg.pod + "/" + tg.container
+		if tg.previous {
+			s += " (previous)"
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"kube-system/coredns-abc/coredns",
+		"kube-system/coredns-abc/coredns (previous)",
+		"storage/ebs-csi-node-1/ebs-plugin",
+		"storage/ebs-csi-node-1/liveness-probe",
+		"default/web-0/web",
+		"default/web-0/web (previous)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("targets:\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
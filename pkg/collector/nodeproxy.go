@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+func nodeProxyGet(ctx context.Context, client kubernetes.Interface, node, path string) (io.ReadCloser, error) {
+	return client.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(node).
+		SubResource("proxy").
+		Suffix(path).
+		Stream(ctx)
+}
+
+// forEachNode runs fn for every node with at most concurrency calls in flight,
+// each bounded by its own timeout. It returns one error per failed node.
+func forEachNode(ctx context.Context, nodes []NodeInfo, concurrency int, timeout time.Duration, fn func(ctx context.Context, i int, node string) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, concurrency)
+
+	for i, n := range nodes {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("node %s: %w", n.Name, ctx.Err()))
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			nodeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			if err := fn(nodeCtx, i, name); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("node %s: %w", name, err))
+				mu.Unlock()
+			}
+		}(i, n.Name)
+	}
+
+	wg.Wait()
+	return errs
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -15,14 +16,27 @@ func collectNodes(ctx context.Context, client kubernetes.Interface) ([]NodeInfo,
 	}
 
 	nodes := make([]NodeInfo, 0, len(list.Items))
-	for _, n := range list.Items {
-		nodes = append(nodes, NodeInfo{
-			Name:          n.Name,
-			Conditions:    n.Status.Conditions,
-			Allocatable:   n.Status.Allocatable,
-			Capacity:      n.Status.Capacity,
-			Unschedulable: n.Spec.Unschedulable,
-		})
+	for i := range list.Items {
+		nodes = append(nodes, NewNodeInfo(&list.Items[i]))
 	}
 	return nodes, nil
 }
+
+func NewNodeInfo(n *corev1.Node) NodeInfo {
+	return NodeInfo{
+		Name:          n.Name,
+		UID:           string(n.UID),
+		Conditions:    n.Status.Conditions,
+		Allocatable:   n.Status.Allocatable,
+		Capacity:      n.Status.Capacity,
+		Unschedulable: n.Spec.Unschedulable,
+		Versions: NodeVersions{
+			Kubelet:          n.Status.NodeInfo.KubeletVersion,
+			ContainerRuntime: n.Status.NodeInfo.ContainerRuntimeVersion,
+			OSImage:          n.Status.NodeInfo.OSImage,
+			Kernel:           n.Status.NodeInfo.KernelVersion,
+			OperatingSystem:  n.Status.NodeInfo.OperatingSystem,
+			Architecture:     n.Status.NodeInfo.Architecture,
+		},
+	}
+}
@@ -6,6 +6,22 @@ type Options struct {
 	Since     time.Duration
 	Namespace string
 	Output    string
+
+	APIServerMetrics bool
+	KubeletStats     bool
+	ResourceMetrics  bool
+	CadvisorMetrics  bool
+	Logs             bool
+
+	NodeConcurrency int
+	NodeTimeout     time.Duration
+
+	LogTailLines  int64
+	LogLimitBytes int64
+
+	// SystemPodSelectors are the label selectors of the kube-system pods
+	// whose health is collected. Empty means DefaultSystemPodSelectors.
+	SystemPodSelectors []string
 }
 
 func DefaultOptions() Options {
@@ -13,5 +29,11 @@ func DefaultOptions() Options {
 		Since:     30 * time.Minute,
 		Namespace: "",
 		Output:    "snapshot.json",
+
+		NodeConcurrency: 10,
+		NodeTimeout:     10 * time.Second,
+
+		LogTailLines:  200,
+		LogLimitBytes: 64 * 1024,
 	}
 }
@@ -0,0 +1,84 @@
+package collector
+
+// Permission is one API access a collector needs. Resource permissions are
+// checked cluster-wide unless Namespace is set; NonResourceURL permissions
+// have no group or resource.
+type Permission struct {
+	Collector      string `json:"collector"`
+	Verb           string `json:"verb"`
+	Group          string `json:"group,omitempty"`
+	Resource       string `json:"resource,omitempty"`
+	Subresource    string `json:"subresource,omitempty"`
+	Namespace      string `json:"namespace,omitempty"`
+	NonResourceURL string `json:"nonResourceURL,omitempty"`
+}
+
+// String formats the permission like "list pods", "get nodes/proxy" or
+// "get /metrics".
+func (p Permission) String() string {
+	if p.NonResourceURL != "" {
+		return p.Verb + " " + p.NonResourceURL
+	}
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	return p.Verb + " " + resource
+}
+
+// RequiredPermissions lists every API access Collect makes with opts. It must
+// be kept in step with the collectors; TestRequiredPermissions_CoverCollect
+// fails when a collector starts making a request that is not listed here.
+func RequiredPermissions(opts Options) []Permission {
+	ns := opts.Namespace
+	perms := []Permission{
+		{Collector: "cluster", Verb: "get", Resource: "namespaces"},
+		{Collector: "cluster", Verb: "get", NonResourceURL: "/version"},
+		{Collector: "nodes", Verb: "list", Resource: "nodes"},
+		{Collector: "pods", Verb: "list", Resource: "pods", Namespace: ns},
+		{Collector: "events", Verb: "list", Group: "events.k8s.io", Resource: "events"},
+		// Fallback for clusters that do not serve events.k8s.io/v1.
+		{Collector: "events", Verb: "list", Resource: "events"},
+		{Collector: "pvcs", Verb: "list", Resource: "persistentvolumeclaims", Namespace: ns},
+		{Collector: "pvs", Verb: "list", Resource: "persistentvolumes"},
+		{Collector: "kube-system", Verb: "list", Group: "apps", Resource: "daemonsets", Namespace: kubeSystemNS},
+		{Collector: "kube-system", Verb: "list", Resource: "pods", Namespace: kubeSystemNS},
+		{Collector: "webhooks", Verb: "list", Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"},
+		{Collector: "webhooks", Verb: "list", Group: "admissionregistration.k8s.io", Resource: "validatingwebhookconfigurations"},
+		// Webhook backends can live in any namespace.
+		{Collector: "endpoints", Verb: "get", Resource: "endpoints"},
+	}
+	if opts.APIServerMetrics {
+		perms = append(perms, Permission{Collector: "apiserver-metrics", Verb: "get", NonResourceURL: "/metrics"})
+	}
+	if opts.KubeletStats {
+		perms = append(perms, Permission{Collector: "kubelet-stats", Verb: "get", Resource: "nodes", Subresource: "proxy"})
+	}
+	if opts.CadvisorMetrics {
+		perms = append(perms, Permission{Collector: "cadvisor-metrics", Verb: "get", Resource: "nodes", Subresource: "proxy"})
+	}
+	if opts.ResourceMetrics {
+		perms = append(perms,
+			Permission{Collector: "resource-metrics", Verb: "list", Group: "metrics.k8s.io", Resource: "nodes"},
+			Permission{Collector: "resource-metrics", Verb: "list", Group: "metrics.k8s.io", Resource: "pods", Namespace: ns},
+		)
+	}
+	if opts.Logs {
+		// Crashlooping pods anywhere in scope, plus system pods.
+		perms = append(perms, Permission{Collector: "logs", Verb: "get", Resource: "pods", Subresource: "log"})
+	}
+	return perms
+}
+
+// covers reports whether p grants the access q asks for.
+func (p Permission) covers(q Permission) bool {
+	if p.NonResourceURL != "" || q.NonResourceURL != "" {
+		return p.Verb == q.Verb && p.NonResourceURL == q.NonResourceURL
+	}
+	return p.Verb == q.Verb && p.Group == q.Group && p.Resource == q.Resource &&
+		p.Subresource == q.Subresource &&
+		(p.Namespace == "" || p.Namespace == q.Namespace)
+}
@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestRequiredPermissions_CoverCollect runs Collect against a fake clientset
+// and checks that every request it made is listed by RequiredPermissions.
+// Opt-in collectors that go through the raw REST client cannot run against
+// the fake and are not covered here.
+func TestRequiredPermissions_CoverCollect(t *testing.T) {
+	for _, ns := range []string{"", "payments"} {
+		client := fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "coredns-abc", Labels: map[string]string{"k8s-app": "kube-dns"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "coredns"}}},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "coredns", RestartCount: 5, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+				}},
+			},
+		)
+
+		opts := DefaultOptions()
+		opts.Namespace = ns
+		opts.Logs = true
+		if _, err := Collect(context.Background(), client, opts); err != nil {
+			t.Fatalf("collect: %v", err)
+		}
+
+		perms := RequiredPermissions(opts)
+		for _, action := range client.Actions() {
+			q := actionPermission(action)
+			covered := false
+			for _, p := range perms {
+				if p.covers(q) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				t.Errorf("namespace %q: Collect made %s in namespace %q, which RequiredPermissions does not list", ns, q, q.Namespace)
+			}
+		}
+	}
+}
+
+func actionPermission(a k8stesting.Action) Permission {
+	gvr := a.GetResource()
+	if gvr.Resource == "version" && gvr.Group == "" {
+		return Permission{Verb: a.GetVerb(), NonResourceURL: "/version"}
+	}
+	return Permission{
+		Verb:        a.GetVerb(),
+		Group:       gvr.Group,
+		Resource:    gvr.Resource,
+		Subresource: a.GetSubresource(),
+		Namespace:   a.GetNamespace(),
+	}
+}
+
+func TestRequiredPermissions_OptIn(t *testing.T) {
+	base := len(RequiredPermissions(DefaultOptions()))
+
+	opts := DefaultOptions()
+	opts.APIServerMetrics = true
+	opts.KubeletStats = true
+	opts.CadvisorMetrics = true
+	opts.ResourceMetrics = true
+	opts.Logs = true
+	perms := RequiredPermissions(opts)
+	if len(perms) != base+6 {
+		t.Errorf("expected 6 opt-in permissions on top of %d, got %d", base, len(perms))
+	}
+
+	want := map[string]bool{"get /metrics": false, "get nodes/proxy": false, "list pods.metrics.k8s.io": false, "get pods/log": false}
+	for _, p := range perms {
+		if _, ok := want[p.String()]; ok {
+			want[p.String()] = true
+		}
+	}
+	for s, seen := range want {
+		if !seen {
+			t.Errorf("missing %s", s)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -15,31 +16,36 @@ func collectPods(ctx context.Context, client kubernetes.Interface, namespace str
 	}
 
 	pods := make([]PodInfo, 0, len(list.Items))
-	for _, p := range list.Items {
-		containers := make([]ContainerInfo, 0, len(p.Spec.Containers))
-		for i, c := range p.Spec.Containers {
-			var state = p.Status.ContainerStatuses
-			ci := ContainerInfo{
-				Name:      c.Name,
-				Resources: c.Resources,
-			}
-			if i < len(state) {
-				ci.Ready = state[i].Ready
-				ci.RestartCount = state[i].RestartCount
-				ci.State = state[i].State
-			}
-			containers = append(containers, ci)
+	for i := range list.Items {
+		pods = append(pods, NewPodInfo(&list.Items[i]))
+	}
+	return pods, nil
+}
+
+func NewPodInfo(p *corev1.Pod) PodInfo {
+	containers := make([]ContainerInfo, 0, len(p.Spec.Containers))
+	for i, c := range p.Spec.Containers {
+		var state = p.Status.ContainerStatuses
+		ci := ContainerInfo{
+			Name:      c.Name,
+			Resources: c.Resources,
 		}
+		if i < len(state) {
+			ci.Ready = state[i].Ready
+			ci.RestartCount = state[i].RestartCount
+			ci.State = state[i].State
+		}
+		containers = append(containers, ci)
+	}
 
-		pods = append(pods, PodInfo{
-			Name:       p.Name,
-			Namespace:  p.Namespace,
-			Phase:      p.Status.Phase,
-			Conditions: p.Status.Conditions,
-			Containers: containers,
-			NodeName:   p.Spec.NodeName,
-			QOSClass:   p.Status.QOSClass,
-		})
+	return PodInfo{
+		Name:       p.Name,
+		Namespace:  p.Namespace,
+		UID:        string(p.UID),
+		Phase:      p.Status.Phase,
+		Conditions: p.Status.Conditions,
+		Containers: containers,
+		NodeName:   p.Spec.NodeName,
+		QOSClass:   p.Status.QOSClass,
 	}
-	return pods, nil
 }
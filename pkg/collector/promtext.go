@@ -0,0 +1,191 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxPromLineLen = 1 << 20
+
+type promSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+func parsePromText(r io.Reader, keep func(name string) bool) ([]promSample, error) {
+	var samples []promSample
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPromLineLen)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		nameEnd := strings.IndexAny(line, "{ \t")
+		if nameEnd < 0 {
+			return nil, fmt.Errorf("line %d: missing value", lineNo)
+		}
+		name := line[:nameEnd]
+		if keep != nil && !keep(name) {
+			continue
+		}
+
+		rest := line[nameEnd:]
+		labels := map[string]string{}
+		if strings.HasPrefix(rest, "{") {
+			parsed, n, err := parsePromLabels(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			labels = parsed
+			rest = rest[n:]
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: missing value", lineNo)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", lineNo, fields[0])
+		}
+
+		samples = append(samples, promSample{Name: name, Labels: labels, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+func parsePromLabels(s string) (map[string]string, int, error) {
+	labels := map[string]string{}
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, 0, fmt.Errorf("label without value")
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label %q: value must be quoted", key)
+		}
+		i++
+
+		var b strings.Builder
+		closed := false
+		for i < len(s) {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				switch s[i+1] {
+				case 'n':
+					b.WriteByte('\n')
+				default:
+					b.WriteByte(s[i+1])
+				}
+				i += 2
+				continue
+			}
+			if c == '"' {
+				closed = true
+				i++
+				break
+			}
+			b.WriteByte(c)
+			i++
+		}
+		if !closed {
+			return nil, 0, fmt.Errorf("label %q: unterminated value", key)
+		}
+		labels[key] = b.String()
+	}
+}
+
+type histogramAccumulator struct {
+	order   []string
+	entries map[string]*histogramEntry
+}
+
+type histogramEntry struct {
+	labels  map[string]string
+	sum     float64
+	count   float64
+	buckets map[float64]float64
+}
+
+func newHistogramAccumulator() *histogramAccumulator {
+	return &histogramAccumulator{entries: make(map[string]*histogramEntry)}
+}
+
+// add folds a _bucket, _sum or _count sample into the histogram identified by
+// the given label keys, summing across all other labels.
+func (a *histogramAccumulator) add(base string, s promSample, keys ...string) {
+	group := make(map[string]string, len(keys))
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		group[k] = s.Labels[k]
+		parts = append(parts, s.Labels[k])
+	}
+	id := strings.Join(parts, "\x00")
+
+	e, ok := a.entries[id]
+	if !ok {
+		e = &histogramEntry{labels: group, buckets: make(map[float64]float64)}
+		a.entries[id] = e
+		a.order = append(a.order, id)
+	}
+
+	switch s.Name {
+	case base + "_sum":
+		e.sum += s.Value
+	case base + "_count":
+		e.count += s.Value
+	case base + "_bucket":
+		le, err := strconv.ParseFloat(s.Labels["le"], 64)
+		if err != nil || math.IsInf(le, 1) {
+			return
+		}
+		e.buckets[le] += s.Value
+	}
+}
+
+func (a *histogramAccumulator) each(fn func(labels map[string]string, h Histogram)) {
+	for _, id := range a.order {
+		e := a.entries[id]
+		h := Histogram{
+			Count: uint64(e.count),
+			Sum:   e.sum,
+		}
+		bounds := make([]float64, 0, len(e.buckets))
+		for le := range e.buckets {
+			bounds = append(bounds, le)
+		}
+		sort.Float64s(bounds)
+		for _, le := range bounds {
+			h.Buckets = append(h.Buckets, HistogramBucket{UpperBound: le, Count: uint64(e.buckets[le])})
+		}
+		fn(e.labels, h)
+	}
+}
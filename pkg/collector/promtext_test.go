@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+const apiServerMetricsFixture = `# HELP apiserver_request_duration_seconds Response latency distribution.
+# TYPE apiserver_request_duration_seconds histogram
+apiserver_request_duration_seconds_bucket{component="apiserver",resource="pods",scope="cluster",verb="LIST",le="1"} 40
+apiserver_request_duration_seconds_bucket{component="apiserver",resource="pods",scope="cluster",verb="LIST",le="5"} 90
+apiserver_request_duration_seconds_bucket{component="apiserver",resource="pods",scope="cluster",verb="LIST",le="+Inf"} 100
+apiserver_request_duration_seconds_sum{component="apiserver",resource="pods",scope="cluster",verb="LIST"} 250.5
+apiserver_request_duration_seconds_count{component="apiserver",resource="pods",scope="cluster",verb="LIST"} 100
+apiserver_request_duration_seconds_bucket{component="apiserver",resource="pods",scope="namespace",verb="LIST",le="1"} 10
+apiserver_request_duration_seconds_bucket{component="apiserver",resource="pods",scope="namespace",verb="LIST",le="5"} 10
+apiserver_request_duration_seconds_bucket{component="apiserver",resource="pods",scope="namespace",verb="LIST",le="+Inf"} 10
+apiserver_request_duration_seconds_sum{component="apiserver",resource="pods",scope="namespace",verb="LIST"} 1.5
+apiserver_request_duration_seconds_count{component="apiserver",resource="pods",scope="namespace",verb="LIST"} 10
+apiserver_flowcontrol_rejected_requests_total{flow_schema="service-accounts",priority_level="workload-low",reason="queue-full"} 12
+apiserver_flowcontrol_current_executing_seats{priority_level="workload-low"} 245
+apiserver_flowcontrol_current_executing_requests{flow_schema="service-accounts",priority_level="workload-low"} 200
+apiserver_flowcontrol_current_inqueue_requests{flow_schema="service-accounts",priority_level="workload-low"} 31
+apiserver_flowcontrol_nominal_limit_seats{priority_level="workload-low"} 245
+apiserver_flowcontrol_request_concurrency_limit{priority_level="workload-low"} 100
+apiserver_current_inflight_requests{request_kind="readOnly"} 260
+apiserver_admission_webhook_rejection_count{error_type="calling_webhook_error",name="policy.example.com",operation="CREATE",rejection_code="500",type="validating"} 7
+apiserver_admission_webhook_admission_duration_seconds_bucket{name="policy.example.com",operation="CREATE",rejected="false",type="validating",le="1"} 3
+apiserver_admission_webhook_admission_duration_seconds_bucket{name="policy.example.com",operation="CREATE",rejected="false",type="validating",le="+Inf"} 4
+apiserver_admission_webhook_admission_duration_seconds_count{name="policy.example.com",operation="CREATE",rejected="false",type="validating"} 4
+process_cpu_seconds_total 1234.5
+`
+
+func TestParsePromText(t *testing.T) {
+	input := `# comment
+metric_a 1
+metric_b{path="/a\"b",code="200"} 2.5 1700000000000
+metric_c{} +Inf
+`
+	samples, err := parsePromText(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("samples: got %d, want 3", len(samples))
+	}
+	if samples[1].Labels["path"] != `/a"b` {
+		t.Errorf("escaped label: got %q", samples[1].Labels["path"])
+	}
+	if samples[1].Value != 2.5 {
+		t.Errorf("value: got %f, want 2.5", samples[1].Value)
+	}
+}
+
+func TestParsePromText_Malformed(t *testing.T) {
+	if _, err := parsePromText(strings.NewReader(`metric{a="b} 1`), nil); err == nil {
+		t.Error("expected error for unterminated label value")
+	}
+	if _, err := parsePromText(strings.NewReader(`metric abc`), nil); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+func TestBuildAPIServerMetrics(t *testing.T) {
+	samples, err := parsePromText(strings.NewReader(apiServerMetricsFixture), isAPIServerMetric)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	m := buildAPIServerMetrics(samples)
+
+	if len(m.RequestLatencies) != 1 {
+		t.Fatalf("request latencies: got %d, want 1 (scopes aggregated)", len(m.RequestLatencies))
+	}
+	rl := m.RequestLatencies[0]
+	if rl.Verb != "LIST" || rl.Resource != "pods" {
+		t.Errorf("series: got %s %s", rl.Verb, rl.Resource)
+	}
+	if rl.Latency.Count != 110 {
+		t.Errorf("count: got %d, want 110", rl.Latency.Count)
+	}
+	if len(rl.Latency.Buckets) != 2 || rl.Latency.Buckets[0].Count != 50 {
+		t.Errorf("buckets: got %+v", rl.Latency.Buckets)
+	}
+
+	if len(m.FlowControlRejections) != 1 || m.FlowControlRejections[0].Count != 12 {
+		t.Errorf("rejections: got %+v", m.FlowControlRejections)
+	}
+
+	if len(m.PriorityLevels) != 1 {
+		t.Fatalf("priority levels: got %d, want 1", len(m.PriorityLevels))
+	}
+	pl := m.PriorityLevels[0]
+	if pl.Executing != 245 || pl.InQueue != 31 || pl.ConcurrencyLimit != 245 {
+		t.Errorf("priority level: got %+v", pl)
+	}
+
+	if len(m.InflightRequests) != 1 || m.InflightRequests[0].Current != 260 {
+		t.Errorf("inflight: got %+v", m.InflightRequests)
+	}
+
+	if len(m.WebhookLatencies) != 1 || m.WebhookLatencies[0].CallErrors != 7 {
+		t.Errorf("webhooks: got %+v", m.WebhookLatencies)
+	}
+}
@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClusterInfo identifies the cluster a snapshot came from. Context and
+// APIServerHost come from the kubeconfig and are filled in by the caller.
+type ClusterInfo struct {
+	// ID is the UID of the kube-system namespace, which is stable for the
+	// life of a cluster.
+	ID            string `json:"id,omitempty"`
+	Context       string `json:"context,omitempty"`
+	APIServerHost string `json:"apiServerHost,omitempty"`
+	ServerVersion string `json:"serverVersion,omitempty"`
+	Platform      string `json:"platform,omitempty"`
+}
+
+// CollectionInfo records how a snapshot was collected and what went wrong.
+type CollectionInfo struct {
+	CollectorVersion string            `json:"collectorVersion,omitempty"`
+	Duration         string            `json:"duration,omitempty"`
+	Options          CollectionOptions `json:"options"`
+	Errors           []CollectorError  `json:"errors,omitempty"`
+	RBACDenials      []RBACDenial      `json:"rbacDenials,omitempty"`
+}
+
+// CollectionOptions is the serialisable subset of Options.
+type CollectionOptions struct {
+	Since            string `json:"since"`
+	Namespace        string `json:"namespace,omitempty"`
+	APIServerMetrics bool   `json:"apiServerMetrics,omitempty"`
+	KubeletStats     bool   `json:"kubeletStats,omitempty"`
+	ResourceMetrics  bool   `json:"resourceMetrics,omitempty"`
+	CadvisorMetrics  bool   `json:"cadvisorMetrics,omitempty"`
+	Logs             bool   `json:"logs,omitempty"`
+	NodeConcurrency  int    `json:"nodeConcurrency,omitempty"`
+	NodeTimeout      string `json:"nodeTimeout,omitempty"`
+	LogTailLines     int64  `json:"logTailLines,omitempty"`
+	LogLimitBytes    int64  `json:"logLimitBytes,omitempty"`
+}
+
+// CollectorError is a failure of one collector. The rest of the snapshot is
+// still usable, but rules depending on this collector may be silent.
+type CollectorError struct {
+	Collector string `json:"collector"`
+	Message   string `json:"message"`
+}
+
+// RBACDenial is a request the apiserver refused with 403 Forbidden.
+type RBACDenial struct {
+	Collector string `json:"collector"`
+	Verb      string `json:"verb,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Resource  string `json:"resource,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (o Options) info() CollectionOptions {
+	info := CollectionOptions{
+		Since:            o.Since.String(),
+		Namespace:        o.Namespace,
+		APIServerMetrics: o.APIServerMetrics,
+		KubeletStats:     o.KubeletStats,
+		ResourceMetrics:  o.ResourceMetrics,
+		CadvisorMetrics:  o.CadvisorMetrics,
+		Logs:             o.Logs,
+	}
+	if o.KubeletStats || o.CadvisorMetrics {
+		info.NodeConcurrency = o.NodeConcurrency
+		info.NodeTimeout = o.NodeTimeout.String()
+	}
+	if o.Logs {
+		info.LogTailLines = o.LogTailLines
+		info.LogLimitBytes = o.LogLimitBytes
+	}
+	return info
+}
+
+func collectClusterInfo(ctx context.Context, client kubernetes.Interface) (*ClusterInfo, error) {
+	info := &ClusterInfo{}
+
+	ns, err := client.CoreV1().Namespaces().Get(ctx, kubeSystemNS, metav1.GetOptions{})
+	if err != nil {
+		return info, fmt.Errorf("get kube-system namespace: %w", err)
+	}
+	info.ID = string(ns.UID)
+
+	v, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return info, fmt.Errorf("get server version: %w", err)
+	}
+	info.ServerVersion = v.GitVersion
+	info.Platform = v.Platform
+	return info, nil
+}
+
+// record notes a collector failure, separating RBAC denials from other errors.
+func (c *CollectionInfo) record(collector string, err error) {
+	if d, ok := rbacDenial(collector, err); ok {
+		c.RBACDenials = append(c.RBACDenials, d)
+		return
+	}
+	c.Errors = append(c.Errors, CollectorError{Collector: collector, Message: err.Error()})
+}
+
+var forbiddenRe = regexp.MustCompile(`cannot (\w+) resource "([^"]+)" in API group "([^"]*)"(?: in the namespace "([^"]+)")?`)
+
+func rbacDenial(collector string, err error) (RBACDenial, bool) {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || !apierrors.IsForbidden(err) {
+		return RBACDenial{}, false
+	}
+
+	msg := status.Status().Message
+	d := RBACDenial{Collector: collector, Message: msg}
+	if m := forbiddenRe.FindStringSubmatch(msg); m != nil {
+		d.Verb, d.Resource, d.Group, d.Namespace = m[1], m[2], m[3], m[4]
+	} else if details := status.Status().Details; details != nil {
+		d.Group, d.Resource = details.Group, details.Kind
+	}
+	return d, true
+}
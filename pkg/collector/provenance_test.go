@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCollect_RecordsProvenance(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uid-ks"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+			Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{
+				KubeletVersion:          "v1.29.1",
+				ContainerRuntimeVersion: "containerd://1.7.2",
+				OSImage:                 "Ubuntu 22.04.3 LTS",
+				KernelVersion:           "5.15.0-1051-aws",
+			}},
+		},
+	)
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.29.1", Platform: "linux/amd64"}
+	client.PrependReactor("list", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "persistentvolumes"}, "",
+			errors.New(`User "dev" cannot list resource "persistentvolumes" in API group "" at the cluster scope`))
+	})
+
+	opts := DefaultOptions()
+	opts.Since = 30 * time.Minute
+	snap, err := Collect(context.Background(), client, opts)
+	if err == nil {
+		t.Fatal("expected the forbidden PV list to be reported")
+	}
+
+	if snap.Cluster == nil || snap.Cluster.ID != "uid-ks" || snap.Cluster.ServerVersion != "v1.29.1" {
+		t.Errorf("cluster: got %+v", snap.Cluster)
+	}
+	if v := snap.Nodes[0].Versions; v.Kubelet != "v1.29.1" || v.Kernel != "5.15.0-1051-aws" {
+		t.Errorf("node versions: got %+v", v)
+	}
+
+	coll := snap.Collection
+	if coll.Options.Since != "30m0s" || coll.Duration == "" {
+		t.Errorf("collection: got %+v", coll)
+	}
+	if len(coll.Errors) != 0 {
+		t.Errorf("forbidden error should not be a plain collector error: %+v", coll.Errors)
+	}
+	want := RBACDenial{Collector: "pvs", Verb: "list", Resource: "persistentvolumes"}
+	if len(coll.RBACDenials) != 1 {
+		t.Fatalf("rbac denials: got %+v", coll.RBACDenials)
+	}
+	got := coll.RBACDenials[0]
+	got.Message = ""
+	if got != want {
+		t.Errorf("rbac denial: got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+const metricsGroupVersion = "metrics.k8s.io/v1beta1"
+
+type nodeMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Usage corev1.ResourceList `json:"usage"`
+	} `json:"items"`
+}
+
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// collectResourceMetrics attaches metrics-server usage to the nodes and pods
+// already in the snapshot. When the metrics.k8s.io API group is not served it
+// leaves usage empty and returns nil.
+func collectResourceMetrics(ctx context.Context, client kubernetes.Interface, snap *Snapshot, namespace string) error {
+	if _, err := client.Discovery().ServerResourcesForGroupVersion(metricsGroupVersion); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("discover %s: %w", metricsGroupVersion, err)
+	}
+
+	var nodes nodeMetricsList
+	if err := getMetricsList(ctx, client, "/apis/"+metricsGroupVersion+"/nodes", &nodes); err != nil {
+		return fmt.Errorf("list node metrics: %w", err)
+	}
+	nodeUsage := make(map[string]corev1.ResourceList, len(nodes.Items))
+	for _, n := range nodes.Items {
+		nodeUsage[n.Metadata.Name] = n.Usage
+	}
+	for i := range snap.Nodes {
+		snap.Nodes[i].Usage = nodeUsage[snap.Nodes[i].Name]
+	}
+
+	podsPath := "/apis/" + metricsGroupVersion + "/pods"
+	if namespace != "" {
+		podsPath = "/apis/" + metricsGroupVersion + "/namespaces/" + namespace + "/pods"
+	}
+	var pods podMetricsList
+	if err := getMetricsList(ctx, client, podsPath, &pods); err != nil {
+		return fmt.Errorf("list pod metrics: %w", err)
+	}
+	containerUsage := make(map[string]corev1.ResourceList)
+	for _, p := range pods.Items {
+		for _, c := range p.Containers {
+			containerUsage[p.Metadata.Namespace+"/"+p.Metadata.Name+"/"+c.Name] = c.Usage
+		}
+	}
+	attachContainerUsage(snap.Pods, containerUsage)
+	attachContainerUsage(snap.KubeSystem.Pods, containerUsage)
+
+	return nil
+}
+
+func getMetricsList(ctx context.Context, client kubernetes.Interface, path string, into interface{}) error {
+	body, err := client.CoreV1().RESTClient().Get().AbsPath(path).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(into)
+}
+
+func attachContainerUsage(pods []PodInfo, usage map[string]corev1.ResourceList) {
+	for i := range pods {
+		p := &pods[i]
+		for j := range p.Containers {
+			c := &p.Containers[j]
+			c.Usage = usage[p.Namespace+"/"+p.Name+"/"+c.Name]
+		}
+	}
+}
@@ -4,63 +4,132 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
-const SnapshotSchemaVersion = "v1"
+const SnapshotSchemaVersion = "v2"
 
 type Snapshot struct {
-	SchemaVersion string            `json:"schemaVersion"`
-	CollectedAt   time.Time         `json:"collectedAt"`
-	Since         string            `json:"since"`
-	Nodes         []NodeInfo        `json:"nodes"`
-	Pods          []PodInfo         `json:"pods"`
-	Events        []EventInfo       `json:"events"`
-	PVCs          []PVCInfo         `json:"pvcs"`
-	PVs           []PVInfo          `json:"pvs"`
-	KubeSystem    KubeSystemHealth  `json:"kubeSystem"`
+	SchemaVersion string                 `json:"schemaVersion"`
+	CollectedAt   time.Time              `json:"collectedAt"`
+	Since         string                 `json:"since"`
+	Cluster       *ClusterInfo           `json:"cluster,omitempty"`
+	Collection    *CollectionInfo        `json:"collection,omitempty"`
+	Redaction     *RedactionInfo         `json:"redaction,omitempty"`
+	Signature     *SignatureInfo         `json:"signature,omitempty"`
+	Nodes         []NodeInfo             `json:"nodes"`
+	Pods          []PodInfo              `json:"pods"`
+	Events        []EventInfo            `json:"events"`
+	PVCs          []PVCInfo              `json:"pvcs"`
+	PVs           []PVInfo               `json:"pvs"`
+	KubeSystem    KubeSystemHealth       `json:"kubeSystem"`
+	Webhooks      []WebhookInfo          `json:"webhooks,omitempty"`
+	Endpoints     []ServiceEndpointsInfo `json:"endpoints,omitempty"`
+	APIServer     *APIServerMetrics      `json:"apiServer,omitempty"`
+	KubeletStats  []NodeStats            `json:"kubeletStats,omitempty"`
+	ContainerCPU  []ContainerCPUStats    `json:"containerCPU,omitempty"`
+	Logs          []ContainerLog         `json:"logs,omitempty"`
+
+	// ScopedNamespaces is set on the view of a snapshot given to a rule
+	// limited to these namespaces. It is never saved.
+	ScopedNamespaces []string `json:"-"`
+}
+
+// NamespaceScoped reports whether s holds the namespaced objects of only
+// some namespaces, because it was collected with a namespace or is a rule's
+// scoped view.
+func (s *Snapshot) NamespaceScoped() bool {
+	return len(s.ScopedNamespaces) > 0 || s.Collection != nil && s.Collection.Options.Namespace != ""
+}
+
+// RedactionInfo records how a snapshot was redacted. Names hashed with the same
+// KeyID can be correlated across snapshots.
+type RedactionInfo struct {
+	Profile        string   `json:"profile"`
+	KeyID          string   `json:"keyId,omitempty"`
+	HashedNames    bool     `json:"hashedNames"`
+	KeptNamespaces []string `json:"keptNamespaces,omitempty"`
+	Scrubbed       []string `json:"scrubbed,omitempty"`
+	DroppedLogs    bool     `json:"droppedLogs,omitempty"`
+}
+
+// SignatureInfo is an ed25519 signature over the snapshot's content digest.
+// The digest covers the whole snapshot, including every field here except
+// Digest and Value.
+type SignatureInfo struct {
+	Algorithm        string    `json:"algorithm"`
+	KeyID            string    `json:"keyId"`
+	PublicKey        string    `json:"publicKey"`
+	SignedAt         time.Time `json:"signedAt"`
+	CollectorVersion string    `json:"collectorVersion,omitempty"`
+	ClusterID        string    `json:"clusterId,omitempty"`
+	Context          string    `json:"context,omitempty"`
+	Digest           string    `json:"digest"`
+	Value            string    `json:"value"`
 }
 
 type NodeInfo struct {
-	Name         string                        `json:"name"`
-	Conditions   []corev1.NodeCondition        `json:"conditions"`
-	Allocatable  corev1.ResourceList           `json:"allocatable"`
-	Capacity     corev1.ResourceList           `json:"capacity"`
-	Unschedulable bool                         `json:"unschedulable"`
+	Name          string                 `json:"name"`
+	UID           string                 `json:"uid,omitempty"`
+	Conditions    []corev1.NodeCondition `json:"conditions"`
+	Allocatable   corev1.ResourceList    `json:"allocatable"`
+	Capacity      corev1.ResourceList    `json:"capacity"`
+	Unschedulable bool                   `json:"unschedulable"`
+	Usage         corev1.ResourceList    `json:"usage,omitempty"`
+	Versions      NodeVersions           `json:"versions"`
+}
+
+type NodeVersions struct {
+	Kubelet          string `json:"kubelet,omitempty"`
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	OSImage          string `json:"osImage,omitempty"`
+	Kernel           string `json:"kernel,omitempty"`
+	OperatingSystem  string `json:"operatingSystem,omitempty"`
+	Architecture     string `json:"architecture,omitempty"`
 }
 
 type PodInfo struct {
-	Name       string                 `json:"name"`
-	Namespace  string                 `json:"namespace"`
-	Phase      corev1.PodPhase        `json:"phase"`
-	Conditions []corev1.PodCondition  `json:"conditions,omitempty"`
-	Containers []ContainerInfo        `json:"containers"`
-	NodeName   string                 `json:"nodeName"`
-	QOSClass   corev1.PodQOSClass     `json:"qosClass"`
+	Name       string                `json:"name"`
+	Namespace  string                `json:"namespace"`
+	UID        string                `json:"uid,omitempty"`
+	Phase      corev1.PodPhase       `json:"phase"`
+	Conditions []corev1.PodCondition `json:"conditions,omitempty"`
+	Containers []ContainerInfo       `json:"containers"`
+	NodeName   string                `json:"nodeName"`
+	QOSClass   corev1.PodQOSClass    `json:"qosClass"`
 }
 
 type ContainerInfo struct {
-	Name         string                       `json:"name"`
-	Ready        bool                         `json:"ready"`
-	RestartCount int32                        `json:"restartCount"`
-	State        corev1.ContainerState        `json:"state"`
-	Resources    corev1.ResourceRequirements  `json:"resources"`
+	Name         string                      `json:"name"`
+	Ready        bool                        `json:"ready"`
+	RestartCount int32                       `json:"restartCount"`
+	State        corev1.ContainerState       `json:"state"`
+	Resources    corev1.ResourceRequirements `json:"resources"`
+	Usage        corev1.ResourceList         `json:"usage,omitempty"`
 }
 
 type EventInfo struct {
-	Namespace      string    `json:"namespace"`
-	Name           string    `json:"name"`
-	Reason         string    `json:"reason"`
-	Message        string    `json:"message"`
-	Type           string    `json:"type"`
-	InvolvedObject string    `json:"involvedObject"`
-	Count          int32     `json:"count"`
-	FirstTimestamp time.Time `json:"firstTimestamp"`
-	LastTimestamp   time.Time `json:"lastTimestamp"`
+	Namespace           string           `json:"namespace"`
+	Name                string           `json:"name"`
+	Reason              string           `json:"reason"`
+	Message             string           `json:"message"`
+	Type                string           `json:"type"`
+	InvolvedObject      model.ObjectRef  `json:"involvedObject"`
+	Related             *model.ObjectRef `json:"related,omitempty"`
+	ReportingController string           `json:"reportingController,omitempty"`
+	ReportingInstance   string           `json:"reportingInstance,omitempty"`
+	Action              string           `json:"action,omitempty"`
+	Count               int32            `json:"count"`
+	FirstTimestamp      time.Time        `json:"firstTimestamp"`
+	LastTimestamp       time.Time        `json:"lastTimestamp"`
 }
 
 type PVCInfo struct {
 	Name             string                            `json:"name"`
 	Namespace        string                            `json:"namespace"`
+	UID              string                            `json:"uid,omitempty"`
 	Phase            corev1.PersistentVolumeClaimPhase `json:"phase"`
 	VolumeName       string                            `json:"volumeName"`
 	StorageClassName string                            `json:"storageClassName,omitempty"`
@@ -68,16 +137,18 @@ type PVCInfo struct {
 }
 
 type PVInfo struct {
-	Name             string                      `json:"name"`
+	Name             string                       `json:"name"`
 	Phase            corev1.PersistentVolumePhase `json:"phase"`
 	StorageClassName string                       `json:"storageClassName,omitempty"`
 	Capacity         corev1.ResourceList          `json:"capacity,omitempty"`
-	ClaimRef         string                       `json:"claimRef,omitempty"`
+	UID              string                       `json:"uid,omitempty"`
+	ClaimRef         *model.ObjectRef             `json:"claimRef,omitempty"`
 }
 
 type KubeSystemHealth struct {
-	DaemonSets []DaemonSetInfo `json:"daemonSets"`
-	Pods       []PodInfo       `json:"pods"`
+	DaemonSets      []DaemonSetInfo   `json:"daemonSets"`
+	Pods            []PodInfo         `json:"pods"`
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
 }
 
 type DaemonSetInfo struct {
@@ -89,3 +160,136 @@ type DaemonSetInfo struct {
 	NumberUnavailable      int32  `json:"numberUnavailable"`
 }
 
+type WebhookInfo struct {
+	Configuration     string                `json:"configuration"`
+	Name              string                `json:"name"`
+	Type              string                `json:"type"`
+	Service           *WebhookService       `json:"service,omitempty"`
+	URL               string                `json:"url,omitempty"`
+	TimeoutSeconds    int32                 `json:"timeoutSeconds"`
+	FailurePolicy     string                `json:"failurePolicy"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	Rules             []string              `json:"rules,omitempty"`
+}
+
+type WebhookService struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Path      string `json:"path,omitempty"`
+	Port      int32  `json:"port"`
+}
+
+type ServiceEndpointsInfo struct {
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	Found             bool   `json:"found"`
+	ReadyAddresses    int    `json:"readyAddresses"`
+	NotReadyAddresses int    `json:"notReadyAddresses"`
+}
+
+type APIServerMetrics struct {
+	RequestLatencies      []RequestLatency       `json:"requestLatencies,omitempty"`
+	EtcdLatencies         []EtcdLatency          `json:"etcdLatencies,omitempty"`
+	WebhookLatencies      []WebhookLatency       `json:"webhookLatencies,omitempty"`
+	FlowControlRejections []FlowControlRejection `json:"flowControlRejections,omitempty"`
+	PriorityLevels        []PriorityLevelInfo    `json:"priorityLevels,omitempty"`
+	InflightRequests      []InflightRequests     `json:"inflightRequests,omitempty"`
+}
+
+type Histogram struct {
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+	Buckets []HistogramBucket `json:"buckets,omitempty"`
+}
+
+type HistogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+type RequestLatency struct {
+	Verb        string    `json:"verb"`
+	Resource    string    `json:"resource"`
+	Subresource string    `json:"subresource,omitempty"`
+	Latency     Histogram `json:"latency"`
+}
+
+type EtcdLatency struct {
+	Operation string    `json:"operation"`
+	Type      string    `json:"type"`
+	Latency   Histogram `json:"latency"`
+}
+
+type WebhookLatency struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Latency    Histogram `json:"latency"`
+	CallErrors uint64    `json:"callErrors,omitempty"`
+}
+
+type FlowControlRejection struct {
+	PriorityLevel string `json:"priorityLevel"`
+	FlowSchema    string `json:"flowSchema"`
+	Reason        string `json:"reason"`
+	Count         uint64 `json:"count"`
+}
+
+type PriorityLevelInfo struct {
+	Name             string  `json:"name"`
+	Executing        float64 `json:"executing"`
+	InQueue          float64 `json:"inQueue"`
+	ConcurrencyLimit float64 `json:"concurrencyLimit"`
+}
+
+type InflightRequests struct {
+	RequestKind string  `json:"requestKind"`
+	Current     float64 `json:"current"`
+}
+
+type NodeStats struct {
+	NodeName              string     `json:"nodeName"`
+	CPUUsageNanoCores     uint64     `json:"cpuUsageNanoCores,omitempty"`
+	MemoryWorkingSetBytes uint64     `json:"memoryWorkingSetBytes,omitempty"`
+	MemoryAvailableBytes  uint64     `json:"memoryAvailableBytes,omitempty"`
+	FS                    *FSStats   `json:"fs,omitempty"`
+	ImageFS               *FSStats   `json:"imageFs,omitempty"`
+	RunningProcesses      int64      `json:"runningProcesses,omitempty"`
+	MaxPID                int64      `json:"maxPID,omitempty"`
+	Pods                  []PodStats `json:"pods,omitempty"`
+}
+
+type FSStats struct {
+	AvailableBytes uint64 `json:"availableBytes"`
+	CapacityBytes  uint64 `json:"capacityBytes"`
+	UsedBytes      uint64 `json:"usedBytes"`
+	InodesFree     uint64 `json:"inodesFree,omitempty"`
+	Inodes         uint64 `json:"inodes,omitempty"`
+}
+
+type PodStats struct {
+	Namespace             string `json:"namespace"`
+	Name                  string `json:"name"`
+	CPUUsageNanoCores     uint64 `json:"cpuUsageNanoCores,omitempty"`
+	MemoryWorkingSetBytes uint64 `json:"memoryWorkingSetBytes,omitempty"`
+	EphemeralStorageBytes uint64 `json:"ephemeralStorageBytes,omitempty"`
+	Processes             uint64 `json:"processes,omitempty"`
+}
+
+type ContainerCPUStats struct {
+	NodeName         string  `json:"nodeName"`
+	Namespace        string  `json:"namespace"`
+	Pod              string  `json:"pod"`
+	Container        string  `json:"container"`
+	Periods          uint64  `json:"periods"`
+	ThrottledPeriods uint64  `json:"throttledPeriods"`
+	ThrottledSeconds float64 `json:"throttledSeconds,omitempty"`
+}
+
+type ContainerLog struct {
+	Namespace string   `json:"namespace"`
+	Pod       string   `json:"pod"`
+	Container string   `json:"container"`
+	Previous  bool     `json:"previous,omitempty"`
+	Lines     []string `json:"lines,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
 
 func collectPVCs(ctx context.Context, client kubernetes.Interface, namespace string) ([]PVCInfo, error) {
@@ -15,23 +18,28 @@ func collectPVCs(ctx context.Context, client kubernetes.Interface, namespace str
 	}
 
 	pvcs := make([]PVCInfo, 0, len(list.Items))
-	for _, p := range list.Items {
-		sc := ""
-		if p.Spec.StorageClassName != nil {
-			sc = *p.Spec.StorageClassName
-		}
-		pvcs = append(pvcs, PVCInfo{
-			Name:             p.Name,
-			Namespace:        p.Namespace,
-			Phase:            p.Status.Phase,
-			VolumeName:       p.Spec.VolumeName,
-			StorageClassName: sc,
-			Capacity:         p.Status.Capacity,
-		})
+	for i := range list.Items {
+		pvcs = append(pvcs, NewPVCInfo(&list.Items[i]))
 	}
 	return pvcs, nil
 }
 
+func NewPVCInfo(p *corev1.PersistentVolumeClaim) PVCInfo {
+	sc := ""
+	if p.Spec.StorageClassName != nil {
+		sc = *p.Spec.StorageClassName
+	}
+	return PVCInfo{
+		Name:             p.Name,
+		Namespace:        p.Namespace,
+		UID:              string(p.UID),
+		Phase:            p.Status.Phase,
+		VolumeName:       p.Spec.VolumeName,
+		StorageClassName: sc,
+		Capacity:         p.Status.Capacity,
+	}
+}
+
 func collectPVs(ctx context.Context, client kubernetes.Interface) ([]PVInfo, error) {
 	list, err := client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
 	if err != nil {
@@ -39,18 +47,27 @@ func collectPVs(ctx context.Context, client kubernetes.Interface) ([]PVInfo, err
 	}
 
 	pvs := make([]PVInfo, 0, len(list.Items))
-	for _, p := range list.Items {
-		claimRef := ""
-		if p.Spec.ClaimRef != nil {
-			claimRef = fmt.Sprintf("%s/%s", p.Spec.ClaimRef.Namespace, p.Spec.ClaimRef.Name)
-		}
-		pvs = append(pvs, PVInfo{
-			Name:             p.Name,
-			Phase:            p.Status.Phase,
-			StorageClassName: p.Spec.StorageClassName,
-			Capacity:         p.Spec.Capacity,
-			ClaimRef:         claimRef,
-		})
+	for i := range list.Items {
+		pvs = append(pvs, NewPVInfo(&list.Items[i]))
 	}
 	return pvs, nil
 }
+
+func NewPVInfo(p *corev1.PersistentVolume) PVInfo {
+	var claimRef *model.ObjectRef
+	if p.Spec.ClaimRef != nil {
+		ref := objectRef(*p.Spec.ClaimRef)
+		if ref.Kind == "" {
+			ref.Kind, ref.Version = "PersistentVolumeClaim", "v1"
+		}
+		claimRef = &ref
+	}
+	return PVInfo{
+		Name:             p.Name,
+		UID:              string(p.UID),
+		Phase:            p.Status.Phase,
+		StorageClassName: p.Spec.StorageClassName,
+		Capacity:         p.Spec.Capacity,
+		ClaimRef:         claimRef,
+	}
+}
@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	WebhookMutating   = "mutating"
+	WebhookValidating = "validating"
+
+	defaultWebhookTimeoutSeconds = 10
+)
+
+func collectWebhooks(ctx context.Context, client kubernetes.Interface) ([]WebhookInfo, error) {
+	var webhooks []WebhookInfo
+
+	mutating, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list mutating webhook configurations: %w", err)
+	}
+	for _, cfg := range mutating.Items {
+		for _, wh := range cfg.Webhooks {
+			webhooks = append(webhooks, webhookInfo(cfg.Name, WebhookMutating, wh.Name, wh.ClientConfig,
+				wh.TimeoutSeconds, wh.FailurePolicy, wh.NamespaceSelector, wh.Rules))
+		}
+	}
+
+	validating, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return webhooks, fmt.Errorf("list validating webhook configurations: %w", err)
+	}
+	for _, cfg := range validating.Items {
+		for _, wh := range cfg.Webhooks {
+			webhooks = append(webhooks, webhookInfo(cfg.Name, WebhookValidating, wh.Name, wh.ClientConfig,
+				wh.TimeoutSeconds, wh.FailurePolicy, wh.NamespaceSelector, wh.Rules))
+		}
+	}
+
+	return webhooks, nil
+}
+
+func webhookInfo(
+	configuration, typ, name string,
+	cc admissionregistrationv1.WebhookClientConfig,
+	timeout *int32,
+	policy *admissionregistrationv1.FailurePolicyType,
+	nsSelector *metav1.LabelSelector,
+	rules []admissionregistrationv1.RuleWithOperations,
+) WebhookInfo {
+	info := WebhookInfo{
+		Configuration:     configuration,
+		Name:              name,
+		Type:              typ,
+		TimeoutSeconds:    defaultWebhookTimeoutSeconds,
+		FailurePolicy:     string(admissionregistrationv1.Fail),
+		NamespaceSelector: nsSelector,
+	}
+	if timeout != nil {
+		info.TimeoutSeconds = *timeout
+	}
+	if policy != nil {
+		info.FailurePolicy = string(*policy)
+	}
+	if cc.Service != nil {
+		svc := &WebhookService{
+			Namespace: cc.Service.Namespace,
+			Name:      cc.Service.Name,
+			Port:      443,
+		}
+		if cc.Service.Path != nil {
+			svc.Path = *cc.Service.Path
+		}
+		if cc.Service.Port != nil {
+			svc.Port = *cc.Service.Port
+		}
+		info.Service = svc
+	}
+	if cc.URL != nil {
+		info.URL = *cc.URL
+	}
+	for _, r := range rules {
+		ops := make([]string, 0, len(r.Operations))
+		for _, op := range r.Operations {
+			ops = append(ops, string(op))
+		}
+		info.Rules = append(info.Rules, fmt.Sprintf("%s %s", strings.Join(ops, ","), strings.Join(r.Resources, ",")))
+	}
+	return info
+}
+
+func collectWebhookEndpoints(ctx context.Context, client kubernetes.Interface, webhooks []WebhookInfo) ([]ServiceEndpointsInfo, error) {
+	var result []ServiceEndpointsInfo
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, wh := range webhooks {
+		if wh.Service == nil {
+			continue
+		}
+		key := wh.Service.Namespace + "/" + wh.Service.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		info := ServiceEndpointsInfo{
+			Namespace: wh.Service.Namespace,
+			Name:      wh.Service.Name,
+		}
+		ep, err := client.CoreV1().Endpoints(wh.Service.Namespace).Get(ctx, wh.Service.Name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("get endpoints %s: %w", key, err))
+				continue
+			}
+			result = append(result, info)
+			continue
+		}
+
+		info.Found = true
+		for _, subset := range ep.Subsets {
+			info.ReadyAddresses += len(subset.Addresses)
+			info.NotReadyAddresses += len(subset.NotReadyAddresses)
+		}
+		result = append(result, info)
+	}
+
+	if len(errs) > 0 {
+		return result, errs[0]
+	}
+	return result, nil
+}
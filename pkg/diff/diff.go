@@ -0,0 +1,309 @@
+// Package diff compares two snapshots of a cluster structurally.
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// Snapshots compares before with after. Objects are matched by name, so both
+// snapshots should come from the same cluster and, if redacted, with the same
+// key; a warning is added when they visibly do not.
+func Snapshots(before, after *collector.Snapshot) model.SnapshotDiff {
+	d := model.SnapshotDiff{
+		SchemaVersion: model.SchemaVersion,
+		Before:        side(before),
+		After:         side(after),
+		Nodes:         []model.NodeChange{},
+		Pods:          []model.PodChange{},
+		WarningEvents: []model.EventReasonChange{},
+		PVCs:          []model.PVCChange{},
+		DaemonSets:    []model.DaemonSetChange{},
+	}
+	d.Warnings = warnings(before, after)
+	d.Nodes = diffNodes(before.Nodes, after.Nodes)
+	d.Pods, d.PodsAdded, d.PodsRemoved = diffPods(allPods(before), allPods(after))
+	d.WarningEvents = newWarningReasons(before.Events, after.Events)
+	d.PVCs = diffPVCs(before.PVCs, after.PVCs)
+	d.DaemonSets = diffDaemonSets(before.KubeSystem.DaemonSets, after.KubeSystem.DaemonSets)
+	return d
+}
+
+func side(snap *collector.Snapshot) model.DiffSide {
+	s := model.DiffSide{CollectedAt: snap.CollectedAt}
+	if snap.Cluster != nil {
+		s.Context, s.ClusterID = snap.Cluster.Context, snap.Cluster.ID
+	}
+	return s
+}
+
+func warnings(before, after *collector.Snapshot) []string {
+	var w []string
+	if b, a := side(before), side(after); b.ClusterID != "" && a.ClusterID != "" && b.ClusterID != a.ClusterID {
+		w = append(w, fmt.Sprintf("snapshots are from different clusters (%s and %s)", b.ClusterID, a.ClusterID))
+	}
+	if redactionKey(before) != redactionKey(after) {
+		w = append(w, "snapshots were not redacted with the same key, so names may not match")
+	}
+	if after.CollectedAt.Before(before.CollectedAt) {
+		w = append(w, "the after snapshot was collected before the before snapshot")
+	}
+	return w
+}
+
+func redactionKey(snap *collector.Snapshot) string {
+	if snap.Redaction == nil || !snap.Redaction.HashedNames {
+		return ""
+	}
+	return snap.Redaction.KeyID
+}
+
+func diffNodes(before, after []collector.NodeInfo) []model.NodeChange {
+	old := make(map[string]collector.NodeInfo, len(before))
+	for _, n := range before {
+		old[n.Name] = n
+	}
+	changes := []model.NodeChange{}
+	seen := make(map[string]bool, len(after))
+	for _, n := range after {
+		seen[n.Name] = true
+		prev, ok := old[n.Name]
+		if !ok {
+			changes = append(changes, model.NodeChange{Name: n.Name, Change: model.ChangeAdded})
+			continue
+		}
+		c := model.NodeChange{Name: n.Name, Change: model.ChangeChanged}
+		c.Conditions = conditionTransitions(prev, n)
+		if prev.Unschedulable != n.Unschedulable {
+			c.Unschedulable = &model.BoolChange{From: prev.Unschedulable, To: n.Unschedulable}
+		}
+		if prev.Versions.Kubelet != n.Versions.Kubelet {
+			c.Kubelet = &model.StringChange{From: prev.Versions.Kubelet, To: n.Versions.Kubelet}
+		}
+		if len(c.Conditions) > 0 || c.Unschedulable != nil || c.Kubelet != nil {
+			changes = append(changes, c)
+		}
+	}
+	for _, n := range before {
+		if !seen[n.Name] {
+			changes = append(changes, model.NodeChange{Name: n.Name, Change: model.ChangeRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// conditionTransitions lists conditions whose status changed. A condition
+// missing on one side is reported as Unknown there.
+func conditionTransitions(before, after collector.NodeInfo) []model.ConditionTransition {
+	old := make(map[string]string, len(before.Conditions))
+	for _, c := range before.Conditions {
+		old[string(c.Type)] = string(c.Status)
+	}
+	var transitions []model.ConditionTransition
+	seen := make(map[string]bool, len(after.Conditions))
+	for _, c := range after.Conditions {
+		t := string(c.Type)
+		seen[t] = true
+		from, ok := old[t]
+		if !ok {
+			from = "Unknown"
+		}
+		if from != string(c.Status) {
+			transitions = append(transitions, model.ConditionTransition{Type: t, From: from, To: string(c.Status)})
+		}
+	}
+	for _, c := range before.Conditions {
+		if t := string(c.Type); !seen[t] && string(c.Status) != "Unknown" {
+			transitions = append(transitions, model.ConditionTransition{Type: t, From: string(c.Status), To: "Unknown"})
+		}
+	}
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].Type < transitions[j].Type })
+	return transitions
+}
+
+// allPods merges the namespace pods with the kube-system pods, which are
+// collected separately and may overlap.
+func allPods(snap *collector.Snapshot) []collector.PodInfo {
+	pods := make([]collector.PodInfo, 0, len(snap.Pods)+len(snap.KubeSystem.Pods))
+	seen := make(map[string]bool, len(snap.Pods))
+	for _, list := range [][]collector.PodInfo{snap.Pods, snap.KubeSystem.Pods} {
+		for _, p := range list {
+			key := p.Namespace + "/" + p.Name
+			if !seen[key] {
+				seen[key] = true
+				pods = append(pods, p)
+			}
+		}
+	}
+	return pods
+}
+
+func diffPods(before, after []collector.PodInfo) ([]model.PodChange, int, int) {
+	old := make(map[string]collector.PodInfo, len(before))
+	for _, p := range before {
+		old[p.Namespace+"/"+p.Name] = p
+	}
+	changes := []model.PodChange{}
+	added := 0
+	seen := make(map[string]bool, len(after))
+	for _, p := range after {
+		key := p.Namespace + "/" + p.Name
+		seen[key] = true
+		prev, ok := old[key]
+		if !ok {
+			added++
+			continue
+		}
+		c := model.PodChange{Namespace: p.Namespace, Name: p.Name}
+		c.Recreated = prev.UID != "" && p.UID != "" && prev.UID != p.UID
+		if prev.Phase != p.Phase {
+			c.Phase = &model.StringChange{From: string(prev.Phase), To: string(p.Phase)}
+		}
+		if !c.Recreated {
+			c.Restarts = restartChanges(prev, p)
+		}
+		if c.Recreated || c.Phase != nil || len(c.Restarts) > 0 {
+			changes = append(changes, c)
+		}
+	}
+	removed := 0
+	for _, p := range before {
+		if !seen[p.Namespace+"/"+p.Name] {
+			removed++
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Namespace != changes[j].Namespace {
+			return changes[i].Namespace < changes[j].Namespace
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, added, removed
+}
+
+func restartChanges(before, after collector.PodInfo) []model.RestartChange {
+	old := make(map[string]int32, len(before.Containers))
+	for _, c := range before.Containers {
+		old[c.Name] = c.RestartCount
+	}
+	var changes []model.RestartChange
+	for _, c := range after.Containers {
+		if from, ok := old[c.Name]; ok && c.RestartCount > from {
+			changes = append(changes, model.RestartChange{Container: c.Name, From: from, To: c.RestartCount})
+		}
+	}
+	return changes
+}
+
+// newWarningReasons groups the after snapshot's Warning events by reason and
+// keeps the reasons with no Warning event in the before snapshot, most
+// frequent first.
+func newWarningReasons(before, after []collector.EventInfo) []model.EventReasonChange {
+	known := make(map[string]bool)
+	for _, e := range before {
+		if e.Type == "Warning" {
+			known[e.Reason] = true
+		}
+	}
+	byReason := make(map[string]*model.EventReasonChange)
+	objects := make(map[string]map[string]bool)
+	for _, e := range after {
+		if e.Type != "Warning" || known[e.Reason] {
+			continue
+		}
+		c, ok := byReason[e.Reason]
+		if !ok {
+			c = &model.EventReasonChange{Reason: e.Reason, Example: e.Message}
+			byReason[e.Reason] = c
+			objects[e.Reason] = make(map[string]bool)
+		}
+		c.Count += e.Count
+		objects[e.Reason][e.InvolvedObject.String()] = true
+	}
+	changes := make([]model.EventReasonChange, 0, len(byReason))
+	for reason, c := range byReason {
+		c.Objects = len(objects[reason])
+		changes = append(changes, *c)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Count != changes[j].Count {
+			return changes[i].Count > changes[j].Count
+		}
+		return changes[i].Reason < changes[j].Reason
+	})
+	return changes
+}
+
+func diffPVCs(before, after []collector.PVCInfo) []model.PVCChange {
+	old := make(map[string]collector.PVCInfo, len(before))
+	for _, p := range before {
+		old[p.Namespace+"/"+p.Name] = p
+	}
+	changes := []model.PVCChange{}
+	seen := make(map[string]bool, len(after))
+	for _, p := range after {
+		key := p.Namespace + "/" + p.Name
+		seen[key] = true
+		prev, ok := old[key]
+		switch {
+		case !ok:
+			changes = append(changes, model.PVCChange{Namespace: p.Namespace, Name: p.Name, Change: model.ChangeAdded, Phase: &model.StringChange{To: string(p.Phase)}})
+		case prev.Phase != p.Phase:
+			changes = append(changes, model.PVCChange{Namespace: p.Namespace, Name: p.Name, Change: model.ChangeChanged, Phase: &model.StringChange{From: string(prev.Phase), To: string(p.Phase)}})
+		}
+	}
+	for _, p := range before {
+		if !seen[p.Namespace+"/"+p.Name] {
+			changes = append(changes, model.PVCChange{Namespace: p.Namespace, Name: p.Name, Change: model.ChangeRemoved, Phase: &model.StringChange{From: string(p.Phase)}})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Namespace != changes[j].Namespace {
+			return changes[i].Namespace < changes[j].Namespace
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+func diffDaemonSets(before, after []collector.DaemonSetInfo) []model.DaemonSetChange {
+	old := make(map[string]collector.DaemonSetInfo, len(before))
+	for _, ds := range before {
+		old[ds.Name] = ds
+	}
+	changes := []model.DaemonSetChange{}
+	seen := make(map[string]bool, len(after))
+	for _, ds := range after {
+		seen[ds.Name] = true
+		prev, ok := old[ds.Name]
+		if !ok {
+			changes = append(changes, model.DaemonSetChange{
+				Name: ds.Name, Change: model.ChangeAdded,
+				Desired: &model.Int32Change{To: ds.DesiredNumberScheduled},
+				Ready:   &model.Int32Change{To: ds.NumberReady},
+			})
+			continue
+		}
+		c := model.DaemonSetChange{Name: ds.Name, Change: model.ChangeChanged}
+		if prev.DesiredNumberScheduled != ds.DesiredNumberScheduled {
+			c.Desired = &model.Int32Change{From: prev.DesiredNumberScheduled, To: ds.DesiredNumberScheduled}
+		}
+		if prev.NumberReady != ds.NumberReady {
+			c.Ready = &model.Int32Change{From: prev.NumberReady, To: ds.NumberReady}
+		}
+		if c.Desired != nil || c.Ready != nil {
+			changes = append(changes, c)
+		}
+	}
+	for _, ds := range before {
+		if !seen[ds.Name] {
+			changes = append(changes, model.DaemonSetChange{Name: ds.Name, Change: model.ChangeRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,193 @@
+package diff
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func node(name, kubelet string, memoryPressure corev1.ConditionStatus) collector.NodeInfo {
+	return collector.NodeInfo{
+		Name: name,
+		Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			{Type: corev1.NodeMemoryPressure, Status: memoryPressure},
+		},
+		Versions: collector.NodeVersions{Kubelet: kubelet},
+	}
+}
+
+func pod(ns, name, uid string, phase corev1.PodPhase, restarts int32) collector.PodInfo {
+	return collector.PodInfo{
+		Namespace: ns, Name: name, UID: uid, Phase: phase,
+		Containers: []collector.ContainerInfo{{Name: "app", RestartCount: restarts}},
+	}
+}
+
+func warning(reason, pod, message string, count int32) collector.EventInfo {
+	return collector.EventInfo{
+		Type: "Warning", Reason: reason, Message: message, Count: count,
+		InvolvedObject: model.PodRef("default", pod, ""),
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	start := time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC)
+	before := &collector.Snapshot{
+		CollectedAt: start,
+		Cluster:     &collector.ClusterInfo{ID: "uid-1", Context: "prod-eu"},
+		Nodes: []collector.NodeInfo{
+			node("worker-1", "v1.28.5", corev1.ConditionFalse),
+			node("worker-2", "v1.28.5", corev1.ConditionFalse),
+			node("worker-3", "v1.28.5", corev1.ConditionFalse),
+		},
+		Pods: []collector.PodInfo{
+			pod("default", "web-0", "p1", corev1.PodRunning, 1),
+			pod("default", "web-1", "p2", corev1.PodRunning, 0),
+			pod("default", "batch", "p3", corev1.PodRunning, 0),
+			pod("default", "steady", "p4", corev1.PodRunning, 2),
+			pod("default", "gone", "p5", corev1.PodRunning, 0),
+		},
+		Events: []collector.EventInfo{warning("BackOff", "web-0", "Back-off restarting failed container", 3)},
+		PVCs: []collector.PVCInfo{
+			{Namespace: "default", Name: "data-web-0", Phase: corev1.ClaimBound},
+			{Namespace: "default", Name: "scratch", Phase: corev1.ClaimBound},
+		},
+		KubeSystem: collector.KubeSystemHealth{DaemonSets: []collector.DaemonSetInfo{
+			{Name: "kube-proxy", DesiredNumberScheduled: 3, NumberReady: 3},
+			{Name: "aws-node", DesiredNumberScheduled: 3, NumberReady: 3},
+		}},
+	}
+	after := &collector.Snapshot{
+		CollectedAt: start.Add(24 * time.Hour),
+		Cluster:     &collector.ClusterInfo{ID: "uid-1", Context: "prod-eu"},
+		Nodes: []collector.NodeInfo{
+			node("worker-1", "v1.29.1", corev1.ConditionTrue),
+			node("worker-3", "v1.28.5", corev1.ConditionFalse),
+			node("worker-4", "v1.29.1", corev1.ConditionFalse),
+		},
+		Pods: []collector.PodInfo{
+			pod("default", "web-0", "p1", corev1.PodRunning, 6),
+			pod("default", "web-1", "p9", corev1.PodPending, 0),
+			pod("default", "batch", "p3", corev1.PodSucceeded, 0),
+			pod("default", "steady", "p4", corev1.PodRunning, 2),
+			pod("default", "new", "p6", corev1.PodRunning, 0),
+		},
+		Events: []collector.EventInfo{
+			warning("BackOff", "web-0", "Back-off restarting failed container", 9),
+			warning("FailedMount", "web-1", "MountVolume.SetUp failed", 4),
+			warning("FailedMount", "web-0", "MountVolume.SetUp failed", 1),
+			warning("Evicted", "batch", "The node was low on resource: memory.", 1),
+			{Type: "Normal", Reason: "Scheduled", Count: 1},
+		},
+		PVCs: []collector.PVCInfo{
+			{Namespace: "default", Name: "data-web-0", Phase: corev1.ClaimPending},
+			{Namespace: "default", Name: "data-web-1", Phase: corev1.ClaimBound},
+		},
+		KubeSystem: collector.KubeSystemHealth{
+			DaemonSets: []collector.DaemonSetInfo{
+				{Name: "kube-proxy", DesiredNumberScheduled: 3, NumberReady: 2},
+				{Name: "aws-node", DesiredNumberScheduled: 3, NumberReady: 3},
+			},
+			Pods: []collector.PodInfo{pod("kube-system", "coredns-1", "k1", corev1.PodRunning, 0)},
+		},
+	}
+
+	d := Snapshots(before, after)
+
+	if len(d.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", d.Warnings)
+	}
+
+	if len(d.Nodes) != 3 {
+		t.Fatalf("nodes: got %+v", d.Nodes)
+	}
+	w1 := d.Nodes[0]
+	if w1.Name != "worker-1" || w1.Change != model.ChangeChanged || len(w1.Conditions) != 1 ||
+		w1.Conditions[0] != (model.ConditionTransition{Type: "MemoryPressure", From: "False", To: "True"}) ||
+		w1.Kubelet == nil || w1.Kubelet.To != "v1.29.1" {
+		t.Errorf("worker-1: got %+v", w1)
+	}
+	if d.Nodes[1].Name != "worker-2" || d.Nodes[1].Change != model.ChangeRemoved ||
+		d.Nodes[2].Name != "worker-4" || d.Nodes[2].Change != model.ChangeAdded {
+		t.Errorf("added and removed nodes: got %+v", d.Nodes[1:])
+	}
+
+	if d.PodsAdded != 2 || d.PodsRemoved != 1 {
+		t.Errorf("pods added/removed: got %d/%d, want 2/1", d.PodsAdded, d.PodsRemoved)
+	}
+	if len(d.Pods) != 3 {
+		t.Fatalf("pods: got %+v", d.Pods)
+	}
+	if p := d.Pods[0]; p.Name != "batch" || p.Phase == nil || p.Phase.To != "Succeeded" {
+		t.Errorf("batch: got %+v", p)
+	}
+	if p := d.Pods[1]; p.Name != "web-0" || p.Phase != nil || len(p.Restarts) != 1 || p.Restarts[0] != (model.RestartChange{Container: "app", From: 1, To: 6}) {
+		t.Errorf("web-0: got %+v", p)
+	}
+	if p := d.Pods[2]; p.Name != "web-1" || !p.Recreated || p.Phase == nil || p.Phase.To != "Pending" {
+		t.Errorf("web-1: got %+v", p)
+	}
+
+	if len(d.WarningEvents) != 2 {
+		t.Fatalf("warning events: got %+v", d.WarningEvents)
+	}
+	if e := d.WarningEvents[0]; e.Reason != "FailedMount" || e.Count != 5 || e.Objects != 2 || e.Example == "" {
+		t.Errorf("FailedMount: got %+v", e)
+	}
+	if d.WarningEvents[1].Reason != "Evicted" {
+		t.Errorf("expected Evicted second, got %+v", d.WarningEvents[1])
+	}
+
+	if len(d.PVCs) != 3 || d.PVCs[0].Name != "data-web-0" || d.PVCs[0].Phase.To != "Pending" ||
+		d.PVCs[1].Change != model.ChangeAdded || d.PVCs[2].Change != model.ChangeRemoved {
+		t.Errorf("pvcs: got %+v", d.PVCs)
+	}
+
+	if len(d.DaemonSets) != 1 || d.DaemonSets[0].Name != "kube-proxy" || d.DaemonSets[0].Ready == nil ||
+		*d.DaemonSets[0].Ready != (model.Int32Change{From: 3, To: 2}) || d.DaemonSets[0].Desired != nil {
+		t.Errorf("daemonsets: got %+v", d.DaemonSets)
+	}
+}
+
+func TestSnapshots_Identical(t *testing.T) {
+	snap := &collector.Snapshot{
+		Nodes: []collector.NodeInfo{node("worker-1", "v1.29.1", corev1.ConditionFalse)},
+		Pods:  []collector.PodInfo{pod("default", "web-0", "p1", corev1.PodRunning, 3)},
+	}
+	if d := Snapshots(snap, snap); !d.Empty() {
+		t.Errorf("expected no changes, got %+v", d)
+	}
+}
+
+func TestSnapshots_RecreatedPodWithoutOtherChanges(t *testing.T) {
+	// A StatefulSet pod replaced while Running keeps its name and phase.
+	before := &collector.Snapshot{Pods: []collector.PodInfo{pod("default", "db-0", "p1", corev1.PodRunning, 4)}}
+	after := &collector.Snapshot{Pods: []collector.PodInfo{pod("default", "db-0", "p2", corev1.PodRunning, 0)}}
+
+	d := Snapshots(before, after)
+	if len(d.Pods) != 1 || !d.Pods[0].Recreated || d.Pods[0].Phase != nil || len(d.Pods[0].Restarts) != 0 {
+		t.Errorf("expected db-0 as recreated only, got %+v", d.Pods)
+	}
+	if d.Empty() {
+		t.Error("a recreated pod is a change")
+	}
+}
+
+func TestSnapshots_Warnings(t *testing.T) {
+	before := &collector.Snapshot{
+		Cluster:   &collector.ClusterInfo{ID: "uid-1"},
+		Redaction: &collector.RedactionInfo{HashedNames: true, KeyID: "k1"},
+	}
+	after := &collector.Snapshot{
+		Cluster:   &collector.ClusterInfo{ID: "uid-2"},
+		Redaction: &collector.RedactionInfo{HashedNames: true, KeyID: "k2"},
+	}
+	if d := Snapshots(before, after); len(d.Warnings) != 2 {
+		t.Errorf("expected cluster and redaction key warnings, got %v", d.Warnings)
+	}
+}
@@ -0,0 +1,113 @@
+package model
+
+import "time"
+
+// ChangeKind says whether an object was added, removed or changed between two
+// snapshots.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// SnapshotDiff is a structural comparison of two snapshots of one cluster.
+type SnapshotDiff struct {
+	SchemaVersion string              `json:"schemaVersion"`
+	Before        DiffSide            `json:"before"`
+	After         DiffSide            `json:"after"`
+	Warnings      []string            `json:"warnings,omitempty"`
+	Nodes         []NodeChange        `json:"nodes"`
+	PodsAdded     int                 `json:"podsAdded"`
+	PodsRemoved   int                 `json:"podsRemoved"`
+	Pods          []PodChange         `json:"pods"`
+	WarningEvents []EventReasonChange `json:"warningEvents"`
+	PVCs          []PVCChange         `json:"pvcs"`
+	DaemonSets    []DaemonSetChange   `json:"daemonSets"`
+}
+
+type DiffSide struct {
+	CollectedAt time.Time `json:"collectedAt"`
+	Context     string    `json:"context,omitempty"`
+	ClusterID   string    `json:"clusterID,omitempty"`
+}
+
+// Empty reports whether the snapshots differ in none of the compared fields.
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Nodes) == 0 && d.PodsAdded == 0 && d.PodsRemoved == 0 && len(d.Pods) == 0 &&
+		len(d.WarningEvents) == 0 && len(d.PVCs) == 0 && len(d.DaemonSets) == 0
+}
+
+// NodeChange is a node that joined, left, or changed conditions,
+// schedulability or kubelet version.
+type NodeChange struct {
+	Name          string                `json:"name"`
+	Change        ChangeKind            `json:"change"`
+	Conditions    []ConditionTransition `json:"conditions,omitempty"`
+	Unschedulable *BoolChange           `json:"unschedulable,omitempty"`
+	Kubelet       *StringChange         `json:"kubelet,omitempty"`
+}
+
+type ConditionTransition struct {
+	Type string `json:"type"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type BoolChange struct {
+	From bool `json:"from"`
+	To   bool `json:"to"`
+}
+
+type StringChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PodChange is a pod present in both snapshots whose phase changed or whose
+// containers restarted. Recreated is set when the pod has a new UID, in which
+// case restart counts are not compared.
+type PodChange struct {
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Recreated bool            `json:"recreated,omitempty"`
+	Phase     *StringChange   `json:"phase,omitempty"`
+	Restarts  []RestartChange `json:"restarts,omitempty"`
+}
+
+type RestartChange struct {
+	Container string `json:"container"`
+	From      int32  `json:"from"`
+	To        int32  `json:"to"`
+}
+
+// EventReasonChange is a Warning event reason seen only in the later
+// snapshot.
+type EventReasonChange struct {
+	Reason  string `json:"reason"`
+	Count   int32  `json:"count"`
+	Objects int    `json:"objects"`
+	Example string `json:"example,omitempty"`
+}
+
+type PVCChange struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Change    ChangeKind    `json:"change"`
+	Phase     *StringChange `json:"phase,omitempty"`
+}
+
+// DaemonSetChange is a kube-system DaemonSet whose desired or ready pod count
+// changed.
+type DaemonSetChange struct {
+	Name    string       `json:"name"`
+	Change  ChangeKind   `json:"change"`
+	Desired *Int32Change `json:"desired,omitempty"`
+	Ready   *Int32Change `json:"ready,omitempty"`
+}
+
+type Int32Change struct {
+	From int32 `json:"from"`
+	To   int32 `json:"to"`
+}
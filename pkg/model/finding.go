@@ -25,13 +25,37 @@ const (
 type Evidence struct {
 	Type    EvidenceType      `json:"type"`
 	Ref     string            `json:"ref"`
+	Object  *ObjectRef        `json:"object,omitempty"`
 	Message string            `json:"message"`
 	Data    map[string]string `json:"data,omitempty"`
 }
 
+// ObjectEvidence builds evidence about a Kubernetes object, keeping Ref in
+// the canonical form of obj.
+func ObjectEvidence(t EvidenceType, obj ObjectRef, message string, data map[string]string) Evidence {
+	return Evidence{
+		Type:    t,
+		Ref:     obj.String(),
+		Object:  &obj,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// ObjectRef returns the object e is about, reading Ref when Object is unset
+// as in evidence written by older plugins.
+func (e Evidence) ObjectRef() (ObjectRef, bool) {
+	if e.Object != nil {
+		return *e.Object, true
+	}
+	return ParseObjectRef(e.Ref)
+}
+
 type Finding struct {
 	SchemaVersion string     `json:"schemaVersion"`
 	ID            string     `json:"id"`
+	Fingerprint   string     `json:"fingerprint,omitempty"`
+	Rule          string     `json:"rule,omitempty"`
 	Title         string     `json:"title"`
 	Category      string     `json:"category"`
 	Severity      Severity   `json:"severity"`
@@ -44,8 +68,27 @@ type Finding struct {
 }
 
 type Report struct {
-	SchemaVersion string    `json:"schemaVersion"`
-	Findings      []Finding `json:"findings"`
+	SchemaVersion string        `json:"schemaVersion"`
+	Snapshot      *SnapshotInfo `json:"snapshot,omitempty"`
+	Findings      []Finding     `json:"findings"`
+	// SuppressedCount is the number of findings hidden by a suppression
+	// file. Suppressed lists them when requested.
+	SuppressedCount int                 `json:"suppressedCount,omitempty"`
+	Suppressed      []SuppressedFinding `json:"suppressed,omitempty"`
+	// SkippedRules lists the rules that did not run because the snapshot
+	// lacks data they require.
+	SkippedRules []SkippedRule `json:"skippedRules,omitempty"`
+	// RuleErrors lists the rules that failed; their findings are missing or
+	// incomplete.
+	RuleErrors []RuleError `json:"ruleErrors,omitempty"`
+}
+
+// SuppressedFinding is a finding matched by a suppression, with the reason
+// given for accepting it.
+type SuppressedFinding struct {
+	Reason  string  `json:"reason"`
+	Expires string  `json:"expires,omitempty"`
+	Finding Finding `json:"finding"`
 }
 
 func NewReport(findings []Finding) Report {
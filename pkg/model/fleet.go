@@ -0,0 +1,50 @@
+package model
+
+// FleetScope says how widely a finding is spread across a fleet.
+type FleetScope string
+
+const (
+	// FleetScopeCommon findings appear in every analysed cluster.
+	FleetScopeCommon FleetScope = "common"
+	// FleetScopeShared findings appear in more than one, but not all, clusters.
+	FleetScopeShared FleetScope = "shared"
+	// FleetScopeUnique findings appear in a single cluster.
+	FleetScopeUnique FleetScope = "unique"
+)
+
+// FleetReport combines the reports of several clusters.
+type FleetReport struct {
+	SchemaVersion string         `json:"schemaVersion"`
+	Clusters      []FleetCluster `json:"clusters"`
+	Findings      []FleetFinding `json:"findings"`
+}
+
+// FleetCluster is one cluster of the fleet. Error is set when the cluster
+// could not be collected or analysed.
+type FleetCluster struct {
+	Context  string        `json:"context"`
+	Snapshot *SnapshotInfo `json:"snapshot,omitempty"`
+	Findings int           `json:"findings"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// FleetFinding groups findings of the same kind across clusters. Findings
+// match when they come from the same rule and their IDs are equal once the
+// names of the objects they are about are removed, so MemoryPressure on a
+// node in one cluster matches MemoryPressure on any node in another.
+type FleetFinding struct {
+	Key      string            `json:"key"`
+	Rule     string            `json:"rule,omitempty"`
+	Category string            `json:"category"`
+	Title    string            `json:"title"`
+	Severity Severity          `json:"severity"`
+	Scope    FleetScope        `json:"scope"`
+	Clusters []FleetOccurrence `json:"clusters"`
+}
+
+// FleetOccurrence is the presence of a FleetFinding in one cluster.
+type FleetOccurrence struct {
+	Context  string   `json:"context"`
+	Severity Severity `json:"severity"`
+	IDs      []string `json:"ids"`
+}
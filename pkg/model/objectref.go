@@ -0,0 +1,131 @@
+package model
+
+import "strings"
+
+// ObjectRef identifies a Kubernetes object. Its String form is canonical:
+// lowercase kind, qualified by group for non-core kinds, then namespace (when
+// namespaced) and name, e.g. "pod/default/web-0", "node/worker-1" or
+// "validatingwebhookconfiguration.admissionregistration.k8s.io/policy".
+type ObjectRef struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+	// FieldPath names a part of the object, as in Kubernetes object
+	// references, e.g. "webhooks{validate.policy.example.com}". It is not
+	// part of the String form.
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+func PodRef(namespace, name, uid string) ObjectRef {
+	return ObjectRef{Version: "v1", Kind: "Pod", Namespace: namespace, Name: name, UID: uid}
+}
+
+func NodeRef(name, uid string) ObjectRef {
+	return ObjectRef{Version: "v1", Kind: "Node", Name: name, UID: uid}
+}
+
+func PVCRef(namespace, name, uid string) ObjectRef {
+	return ObjectRef{Version: "v1", Kind: "PersistentVolumeClaim", Namespace: namespace, Name: name, UID: uid}
+}
+
+func PVRef(name, uid string) ObjectRef {
+	return ObjectRef{Version: "v1", Kind: "PersistentVolume", Name: name, UID: uid}
+}
+
+func ServiceRef(namespace, name string) ObjectRef {
+	return ObjectRef{Version: "v1", Kind: "Service", Namespace: namespace, Name: name}
+}
+
+// RefFromAPIVersion builds a reference from the apiVersion/kind pair found in
+// object references and events.
+func RefFromAPIVersion(apiVersion, kind, namespace, name, uid string) ObjectRef {
+	group, version := "", apiVersion
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		group, version = apiVersion[:i], apiVersion[i+1:]
+	}
+	return ObjectRef{Group: group, Version: version, Kind: kind, Namespace: namespace, Name: name, UID: uid}
+}
+
+func (r ObjectRef) IsZero() bool {
+	return r.Kind == "" && r.Name == ""
+}
+
+func (r ObjectRef) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	kind := strings.ToLower(r.Kind)
+	if r.Group != "" {
+		kind += "." + r.Group
+	}
+	if r.Namespace == "" {
+		return kind + "/" + r.Name
+	}
+	return kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// Matches reports whether two references name the same object. UIDs decide
+// when both sides carry one; otherwise the canonical forms are compared.
+func (r ObjectRef) Matches(other ObjectRef) bool {
+	if r.UID != "" && other.UID != "" {
+		return r.UID == other.UID
+	}
+	return r.String() == other.String()
+}
+
+var refKindAliases = map[string]ObjectRef{
+	"pod":                   {Version: "v1", Kind: "Pod"},
+	"node":                  {Version: "v1", Kind: "Node"},
+	"pvc":                   {Version: "v1", Kind: "PersistentVolumeClaim"},
+	"persistentvolumeclaim": {Version: "v1", Kind: "PersistentVolumeClaim"},
+	"pv":                    {Version: "v1", Kind: "PersistentVolume"},
+	"persistentvolume":      {Version: "v1", Kind: "PersistentVolume"},
+	"service":               {Version: "v1", Kind: "Service"},
+	"svc":                   {Version: "v1", Kind: "Service"},
+}
+
+var clusterScopedKinds = map[string]bool{
+	"node":                           true,
+	"persistentvolume":               true,
+	"namespace":                      true,
+	"storageclass":                   true,
+	"mutatingwebhookconfiguration":   true,
+	"validatingwebhookconfiguration": true,
+}
+
+// ParseObjectRef reads the ref strings written before ObjectRef existed:
+// "Kind/namespace/name", "Kind//name" and "kind/name", with short aliases
+// such as "pvc" and "pv". It reports false for anything else, such as metric
+// series names.
+func ParseObjectRef(s string) (ObjectRef, bool) {
+	parts := strings.Split(s, "/")
+	var kind, namespace, name string
+	switch len(parts) {
+	case 2:
+		kind, name = parts[0], parts[1]
+	case 3:
+		kind, namespace, name = parts[0], parts[1], parts[2]
+	default:
+		return ObjectRef{}, false
+	}
+	if kind == "" || name == "" || strings.ContainsAny(kind, "{} ") {
+		return ObjectRef{}, false
+	}
+
+	ref := ObjectRef{Kind: kind, Namespace: namespace, Name: name}
+	lower := strings.ToLower(kind)
+	if i := strings.Index(lower, "."); i >= 0 {
+		ref.Kind, ref.Group = kind[:i], kind[i+1:]
+		lower = lower[:i]
+	}
+	if alias, ok := refKindAliases[lower]; ok && ref.Group == "" {
+		ref.Kind, ref.Version = alias.Kind, alias.Version
+	}
+	if len(parts) == 2 && !clusterScopedKinds[strings.ToLower(ref.Kind)] {
+		return ObjectRef{}, false
+	}
+	return ref, true
+}
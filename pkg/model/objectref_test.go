@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+func TestObjectRef_String(t *testing.T) {
+	tests := []struct {
+		ref  ObjectRef
+		want string
+	}{
+		{PodRef("default", "web-0", ""), "pod/default/web-0"},
+		{NodeRef("worker-1", ""), "node/worker-1"},
+		{PVCRef("prod", "data", ""), "persistentvolumeclaim/prod/data"},
+		{RefFromAPIVersion("apps/v1", "ReplicaSet", "default", "web-6d4f", ""), "replicaset.apps/default/web-6d4f"},
+		{ObjectRef{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.ref.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestObjectRef_Matches(t *testing.T) {
+	a := PodRef("default", "web-0", "uid-1")
+	if !a.Matches(PodRef("default", "web-0", "")) {
+		t.Error("expected ref without UID to match by name")
+	}
+	if a.Matches(PodRef("default", "web-0", "uid-2")) {
+		t.Error("expected differing UIDs not to match")
+	}
+	if a.Matches(PodRef("default", "web-01", "")) {
+		t.Error("expected different names not to match")
+	}
+}
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"Pod/default/web-0", "pod/default/web-0", true},
+		{"pvc/default/data", "persistentvolumeclaim/default/data", true},
+		{"Node//worker-1", "node/worker-1", true},
+		{"node/worker-1", "node/worker-1", true},
+		{"pod/web-0", "", false},
+		{"container_cpu_cfs_throttled_periods_total", "", false},
+	}
+	for _, tt := range tests {
+		ref, ok := ParseObjectRef(tt.in)
+		if ok != tt.ok || ref.String() != tt.want {
+			t.Errorf("%q: got %q/%v, want %q/%v", tt.in, ref.String(), ok, tt.want, tt.ok)
+		}
+	}
+}
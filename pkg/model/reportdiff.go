@@ -0,0 +1,43 @@
+package model
+
+// FindingChangeType classifies a finding when two reports of the same
+// cluster are compared.
+type FindingChangeType string
+
+const (
+	FindingNew         FindingChangeType = "new"
+	FindingEscalated   FindingChangeType = "escalated"
+	FindingDeescalated FindingChangeType = "de-escalated"
+	FindingResolved    FindingChangeType = "resolved"
+	FindingUnchanged   FindingChangeType = "unchanged"
+)
+
+// FindingChange is a finding matched across two reports by fingerprint.
+// Finding is the later version, or for resolved findings the last version
+// seen. Previous is the earlier severity of escalated, de-escalated and
+// resolved findings.
+type FindingChange struct {
+	Type     FindingChangeType `json:"type"`
+	Previous Severity          `json:"previousSeverity,omitempty"`
+	Finding  Finding           `json:"finding"`
+}
+
+// ReportDiff compares the findings of two reports of one cluster.
+type ReportDiff struct {
+	SchemaVersion string          `json:"schemaVersion"`
+	Before        *SnapshotInfo   `json:"before,omitempty"`
+	After         *SnapshotInfo   `json:"after,omitempty"`
+	Warnings      []string        `json:"warnings,omitempty"`
+	Findings      []FindingChange `json:"findings"`
+}
+
+// Count returns the number of findings classified as t.
+func (d ReportDiff) Count(t FindingChangeType) int {
+	n := 0
+	for _, c := range d.Findings {
+		if c.Type == t {
+			n++
+		}
+	}
+	return n
+}
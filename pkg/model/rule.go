@@ -0,0 +1,48 @@
+package model
+
+import "encoding/json"
+
+// RuleMetadata describes an analysis rule.
+type RuleMetadata struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Description string   `json:"description"`
+	Categories  []string `json:"categories"`
+	// Requires lists the collectors without which the rule can find
+	// nothing; the rule is skipped when one of them is missing. Uses lists
+	// collectors that add evidence when present.
+	Requires    []string `json:"requires,omitempty"`
+	Uses        []string `json:"uses,omitempty"`
+	MinSeverity Severity `json:"minSeverity"`
+	MaxSeverity Severity `json:"maxSeverity"`
+	// Trend is set for rules that compare consecutive frames of a
+	// recording and find nothing in a single snapshot.
+	Trend  bool   `json:"trend,omitempty"`
+	DocURL string `json:"docURL,omitempty"`
+}
+
+// RuleInfo is a rule's metadata with its configuration and, when listed
+// against a snapshot, whether it would run.
+type RuleInfo struct {
+	RuleMetadata
+	Enabled    bool            `json:"enabled"`
+	Namespaces []string        `json:"namespaces,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	// Skipped is why the rule would be skipped for the snapshot it was
+	// listed against.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// SkippedRule is a rule that did not run because data it requires was not
+// collected.
+type SkippedRule struct {
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// RuleError is a rule that failed to run, such as an exec plugin that timed
+// out or returned invalid findings.
+type RuleError struct {
+	Rule  string `json:"rule"`
+	Error string `json:"error"`
+}
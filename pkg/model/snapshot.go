@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// SnapshotInfo describes the snapshot a report was produced from, so a
+// report read months later still says which cluster it is about and how
+// complete the underlying data was.
+type SnapshotInfo struct {
+	CollectedAt      time.Time          `json:"collectedAt"`
+	Since            string             `json:"since,omitempty"`
+	SchemaVersion    string             `json:"schemaVersion,omitempty"`
+	CollectorVersion string             `json:"collectorVersion,omitempty"`
+	ClusterID        string             `json:"clusterID,omitempty"`
+	Context          string             `json:"context,omitempty"`
+	APIServerHost    string             `json:"apiServerHost,omitempty"`
+	ServerVersion    string             `json:"serverVersion,omitempty"`
+	Platform         string             `json:"platform,omitempty"`
+	NodeVersions     []NodeVersionCount `json:"nodeVersions,omitempty"`
+	Redaction        string             `json:"redaction,omitempty"`
+	SignedBy         string             `json:"signedBy,omitempty"`
+	// Warnings lists collector errors and RBAC denials. Findings that depend
+	// on the affected collectors may be missing.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// NodeVersionCount is the number of nodes sharing one combination of
+// kubelet, container runtime, OS image and kernel versions.
+type NodeVersionCount struct {
+	Kubelet          string `json:"kubelet,omitempty"`
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	OSImage          string `json:"osImage,omitempty"`
+	Kernel           string `json:"kernel,omitempty"`
+	Nodes            int    `json:"nodes"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Timeline follows findings across the frames of a recording.
+type Timeline struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Context       string            `json:"context,omitempty"`
+	Interval      string            `json:"interval,omitempty"`
+	Frames        []TimelineFrame   `json:"frames"`
+	Findings      []TimelineFinding `json:"findings"`
+}
+
+// TimelineFrame summarises the analysis of one frame.
+type TimelineFrame struct {
+	CollectedAt time.Time `json:"collectedAt"`
+	Findings    int       `json:"findings"`
+}
+
+// TimelineFinding is one finding, matched by fingerprint, over the whole
+// recording; ID is the one it had when first seen. ClearedAt is the
+// first frame after LastSeen, and nil when the finding is still present in the
+// last frame. Appearances counts separate runs of frames, so a finding that
+// comes and goes has more than one.
+type TimelineFinding struct {
+	ID           string     `json:"id"`
+	Fingerprint  string     `json:"fingerprint,omitempty"`
+	Rule         string     `json:"rule,omitempty"`
+	Category     string     `json:"category"`
+	Title        string     `json:"title"`
+	FirstSeen    time.Time  `json:"firstSeen"`
+	PeakSeverity Severity   `json:"peakSeverity"`
+	PeakAt       time.Time  `json:"peakAt"`
+	LastSeen     time.Time  `json:"lastSeen"`
+	ClearedAt    *time.Time `json:"clearedAt,omitempty"`
+	Frames       int        `json:"frames"`
+	Appearances  int        `json:"appearances"`
+}
@@ -0,0 +1,283 @@
+// Package plugin runs analysis rules kept outside this repository as
+// executables. A plugin reads a snapshot as JSON on stdin and writes a JSON
+// array of findings to stdout.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+	"github.com/marek-kar/kube-slowwhy/pkg/snapshot"
+)
+
+// Prefix starts the file name of every plugin; the rest of the name, without
+// an extension, is the rule name.
+const Prefix = "kube-slowwhy-rule-"
+
+const (
+	DefaultTimeout   = 30 * time.Second
+	DefaultMaxOutput = 10 << 20
+
+	// maxStderr is how much of a failing plugin's stderr is kept for the
+	// error message.
+	maxStderr = 4 << 10
+)
+
+var nameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Options limit every plugin run.
+type Options struct {
+	Timeout time.Duration
+	// MaxOutput is the most bytes a plugin may write to stdout.
+	MaxOutput int64
+}
+
+// Plugin is an executable rule. It implements analysis.FallibleRule and
+// analysis.DescribedRule.
+type Plugin struct {
+	name string
+	path string
+	opts Options
+}
+
+// Discover finds the plugins in dirs and then in the directories of the
+// PATH list pathList. A plugin found earlier hides later ones of the same
+// name. A missing directory or a plugin with an invalid rule name is an
+// error in dirs; on PATH, which holds files put there for other reasons,
+// such plugins are skipped with a warning and missing directories ignored.
+func Discover(dirs []string, pathList string, opts Options) ([]*Plugin, []string, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxOutput <= 0 {
+		opts.MaxOutput = DefaultMaxOutput
+	}
+
+	var plugins []*Plugin
+	var warnings []string
+	seen := make(map[string]bool)
+	search := func(dir string, required bool) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if required {
+				return fmt.Errorf("plugin dir: %w", err)
+			}
+			return nil
+		}
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), Prefix) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if !executable(path) {
+				continue
+			}
+			name := strings.TrimPrefix(e.Name(), Prefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if !nameRe.MatchString(name) {
+				err := fmt.Errorf("plugin %s: rule name %q must be lowercase letters, digits and dashes", path, name)
+				if required {
+					return err
+				}
+				warnings = append(warnings, err.Error()+"; skipped")
+				continue
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, &Plugin{name: name, path: path, opts: opts})
+		}
+		return nil
+	}
+
+	for _, dir := range dirs {
+		if err := search(dir, true); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		search(dir, false)
+	}
+	return plugins, warnings, nil
+}
+
+func executable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
+func (p *Plugin) Name() string { return p.name }
+
+// Path is the plugin's executable.
+func (p *Plugin) Path() string { return p.path }
+
+func (p *Plugin) Metadata() model.RuleMetadata {
+	return model.RuleMetadata{
+		Name:        p.name,
+		Description: "Exec plugin " + p.path,
+	}
+}
+
+// Evaluate runs the plugin and drops its error; the engine calls Run.
+func (p *Plugin) Evaluate(snap *collector.Snapshot) []model.Finding {
+	findings, _ := p.Run(snap)
+	return findings
+}
+
+// Run passes snap to the plugin and returns its findings, attributed to the
+// plugin's rule. It fails if the plugin exits non-zero, runs longer than the
+// timeout, writes more than the output limit or returns an invalid finding.
+func (p *Plugin) Run(snap *collector.Snapshot) ([]model.Finding, error) {
+	var stdin bytes.Buffer
+	if err := snapshot.Encode(&stdin, snap); err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = &stdin
+	stderr := &tailBuffer{max: maxStderr}
+	cmd.Stderr = stderr
+	// A plugin that leaves children holding its stderr open must not
+	// outlive the timeout.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	stdout, readErr := io.ReadAll(io.LimitReader(out, p.opts.MaxOutput+1))
+	overflow := int64(len(stdout)) > p.opts.MaxOutput
+	if overflow {
+		cancel()
+	}
+	err = cmd.Wait()
+	switch {
+	case overflow:
+		return nil, fmt.Errorf("output exceeds %d bytes", p.opts.MaxOutput)
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("timed out after %s", p.opts.Timeout)
+	case err != nil:
+		if msg := lastLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	case readErr != nil:
+		return nil, fmt.Errorf("read output: %w", readErr)
+	}
+	return p.decode(stdout)
+}
+
+func (p *Plugin) decode(data []byte) ([]model.Finding, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var findings []model.Finding
+	if err := dec.Decode(&findings); err != nil {
+		return nil, fmt.Errorf("decode findings: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("decode findings: unexpected data after the findings array")
+	}
+	now := time.Now().UTC()
+	for i := range findings {
+		f := &findings[i]
+		if err := validate(f); err != nil {
+			if f.ID != "" {
+				return nil, fmt.Errorf("finding %d (%s): %w", i+1, f.ID, err)
+			}
+			return nil, fmt.Errorf("finding %d: %w", i+1, err)
+		}
+		f.Rule = p.name
+		for j := range f.Evidence {
+			if e := &f.Evidence[j]; e.Object != nil {
+				e.Ref = e.Object.String()
+			}
+		}
+		if f.SchemaVersion == "" {
+			f.SchemaVersion = model.SchemaVersion
+		}
+		if f.Timestamp.IsZero() {
+			f.Timestamp = now
+		}
+	}
+	return findings, nil
+}
+
+func validate(f *model.Finding) error {
+	switch {
+	case f.SchemaVersion != "" && f.SchemaVersion != model.SchemaVersion:
+		return fmt.Errorf("schemaVersion %q is not %s", f.SchemaVersion, model.SchemaVersion)
+	case f.ID == "":
+		return fmt.Errorf("id is required")
+	case f.Title == "":
+		return fmt.Errorf("title is required")
+	case f.Category == "":
+		return fmt.Errorf("category is required")
+	case f.Summary == "":
+		return fmt.Errorf("summary is required")
+	case f.Confidence < 0 || f.Confidence > 1:
+		return fmt.Errorf("confidence %v is not between 0 and 1", f.Confidence)
+	}
+	switch f.Severity {
+	case model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow:
+	default:
+		return fmt.Errorf("severity %q is not one of critical, high, medium, low", f.Severity)
+	}
+	for i, e := range f.Evidence {
+		switch e.Type {
+		case model.EvidenceMetric, model.EvidenceEvent, model.EvidenceLog, model.EvidenceResource:
+		default:
+			return fmt.Errorf("evidence %d: type %q is not one of metric, event, log, resource", i+1, e.Type)
+		}
+		if e.Message == "" {
+			return fmt.Errorf("evidence %d: message is required", i+1)
+		}
+	}
+	return nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.Buffer.Write(p)
+	if over := b.Len() - b.max; over > 0 {
+		b.Next(over)
+	}
+	return len(p), nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, Prefix+name), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testPlugin(t *testing.T, script string, opts Options) *Plugin {
+	t.Helper()
+	dir := t.TempDir()
+	writePlugin(t, dir, "test", script)
+	plugins, _, err := Discover([]string{dir}, "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 {
+		t.Fatalf("got %d plugins", len(plugins))
+	}
+	return plugins[0]
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	pluginDir, pathDir := t.TempDir(), t.TempDir()
+	writePlugin(t, pluginDir, "quota", "echo []")
+	writePlugin(t, pathDir, "quota", "echo []")
+	writePlugin(t, pathDir, "gpu", "echo []")
+	if err := os.WriteFile(filepath.Join(pathDir, Prefix+"notes"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pathDir, "kubectl"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	pathList := strings.Join([]string{pathDir, filepath.Join(pathDir, "missing")}, string(filepath.ListSeparator))
+	writePlugin(t, pathDir, "lint.sh", "echo []")
+	plugins, warnings, err := Discover([]string{pluginDir}, pathList, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "lint.sh") {
+		t.Errorf("expected a warning about the invalid name on PATH, got %v", warnings)
+	}
+	if len(plugins) != 2 || plugins[0].Name() != "quota" || plugins[1].Name() != "gpu" {
+		t.Fatalf("got %+v", plugins)
+	}
+	if filepath.Dir(plugins[0].Path()) != pluginDir {
+		t.Errorf("plugin dir should hide PATH, got %s", plugins[0].Path())
+	}
+
+	if _, _, err := Discover([]string{filepath.Join(pluginDir, "missing")}, "", Options{}); err == nil {
+		t.Error("expected an error for a missing plugin dir")
+	}
+	writePlugin(t, pluginDir, "Bad_Name", "echo []")
+	if _, _, err := Discover([]string{pluginDir}, "", Options{}); err == nil || !strings.Contains(err.Error(), "Bad_Name") {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	// The plugin reports the nodes it was given, proving it read stdin.
+	p := testPlugin(t, `
+nodes=$(grep -c '"name": "w' )
+cat <<EOF
+[{"id": "quota-exceeded", "rule": "other", "title": "Quota exceeded on $nodes node(s)", "category": "capacity",
+  "severity": "high", "confidence": 0.8, "summary": "s",
+  "evidence": [{"type": "resource", "object": {"kind": "Node", "version": "v1", "name": "w1"}, "message": "m"}]}]
+EOF`, Options{})
+
+	snap := &collector.Snapshot{Nodes: []collector.NodeInfo{{Name: "w1"}, {Name: "w2"}}}
+	findings, err := p.Run(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("got %+v", findings)
+	}
+	f := findings[0]
+	if f.Title != "Quota exceeded on 2 node(s)" || f.Rule != "test" || f.SchemaVersion != model.SchemaVersion || f.Timestamp.IsZero() {
+		t.Errorf("got %+v", f)
+	}
+	if f.Evidence[0].Ref != "node/w1" {
+		t.Errorf("evidence ref: got %q", f.Evidence[0].Ref)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	valid := `{"id": "x", "title": "t", "category": "c", "severity": "low", "confidence": 0.5, "summary": "s"}`
+	for _, tc := range []struct {
+		name   string
+		script string
+		opts   Options
+		want   string
+	}{
+		{"exit status", "echo 'cannot reach quota API' >&2; exit 3", Options{}, "exit status 3: cannot reach quota API"},
+		{"timeout", "sleep 5", Options{Timeout: 100 * time.Millisecond}, "timed out after 100ms"},
+		{"output limit", "yes '[]'", Options{MaxOutput: 1000}, "output exceeds 1000 bytes"},
+		{"not json", "echo done", Options{}, "decode findings"},
+		{"unknown field", `echo '[{"id": "x", "colour": "red"}]'`, Options{}, `unknown field "colour"`},
+		{"trailing data", "echo '[] []'", Options{}, "unexpected data"},
+		{"missing title", `echo '[{"id": "x", "category": "c", "severity": "low", "summary": "s"}]'`, Options{}, "finding 1 (x): title is required"},
+		{"severity", `echo '[{"id": "x", "title": "t", "category": "c", "severity": "urgent", "summary": "s"}]'`, Options{}, `severity "urgent"`},
+		{"confidence", `echo '[` + strings.Replace(valid, "0.5", "80", 1) + `]'`, Options{}, "confidence 80"},
+		{"evidence", `echo '[` + strings.Replace(valid, `"summary": "s"`, `"summary": "s", "evidence": [{"type": "metric"}]`, 1) + `]'`, Options{}, "evidence 1: message is required"},
+		{"schema", `echo '[` + strings.Replace(valid, `"summary": "s"`, `"summary": "s", "schemaVersion": "v9"`, 1) + `]'`, Options{}, `schemaVersion "v9"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := testPlugin(t, tc.script, tc.opts)
+			start := time.Now()
+			_, err := p.Run(&collector.Snapshot{})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+			if d := time.Since(start); d > 3*time.Second {
+				t.Errorf("took %s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// DefaultRoleName is the name of the generated ClusterRole.
+const DefaultRoleName = "kube-slowwhy"
+
+// Rules merges perms into the fewest policy rules: resources in the same API
+// group that need the same verbs share a rule. Namespaced permissions are
+// granted cluster-wide because a ClusterRole cannot be limited by namespace.
+func Rules(perms []collector.Permission) []rbacv1.PolicyRule {
+	resourceVerbs := make(map[string]map[string]map[string]bool) // group -> resource -> verbs
+	urlVerbs := make(map[string]map[string]bool)                 // url -> verbs
+	for _, p := range perms {
+		if p.NonResourceURL != "" {
+			addVerb(urlVerbs, p.NonResourceURL, p.Verb)
+			continue
+		}
+		if resourceVerbs[p.Group] == nil {
+			resourceVerbs[p.Group] = make(map[string]map[string]bool)
+		}
+		resource := p.Resource
+		if p.Subresource != "" {
+			resource += "/" + p.Subresource
+		}
+		addVerb(resourceVerbs[p.Group], resource, p.Verb)
+	}
+
+	groups := make([]string, 0, len(resourceVerbs))
+	for g := range resourceVerbs {
+		groups = append(groups, g)
+	}
+	// The core group sorts first as the empty string.
+	sort.Strings(groups)
+
+	var rules []rbacv1.PolicyRule
+	for _, g := range groups {
+		for _, set := range groupByVerbs(resourceVerbs[g]) {
+			rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{g}, Resources: set.names, Verbs: set.verbs})
+		}
+	}
+	for _, set := range groupByVerbs(urlVerbs) {
+		rules = append(rules, rbacv1.PolicyRule{NonResourceURLs: set.names, Verbs: set.verbs})
+	}
+	return rules
+}
+
+func addVerb(m map[string]map[string]bool, name, verb string) {
+	if m[name] == nil {
+		m[name] = make(map[string]bool)
+	}
+	m[name][verb] = true
+}
+
+type verbSet struct {
+	verbs []string
+	names []string
+}
+
+// groupByVerbs collects names needing identical verbs, ordered by verbs.
+func groupByVerbs(m map[string]map[string]bool) []verbSet {
+	byKey := make(map[string]*verbSet)
+	for name, verbs := range m {
+		vs := make([]string, 0, len(verbs))
+		for v := range verbs {
+			vs = append(vs, v)
+		}
+		sort.Strings(vs)
+		key := strings.Join(vs, ",")
+		if byKey[key] == nil {
+			byKey[key] = &verbSet{verbs: vs}
+		}
+		byKey[key].names = append(byKey[key].names, name)
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sets := make([]verbSet, 0, len(keys))
+	for _, k := range keys {
+		set := byKey[k]
+		sort.Strings(set.names)
+		sets = append(sets, *set)
+	}
+	return sets
+}
+
+// clusterRole is the YAML shape of a ClusterRole without the empty status
+// fields that rbacv1.ClusterRole would print.
+type clusterRole struct {
+	APIVersion string              `json:"apiVersion"`
+	Kind       string              `json:"kind"`
+	Metadata   clusterRoleMeta     `json:"metadata"`
+	Rules      []rbacv1.PolicyRule `json:"rules"`
+}
+
+type clusterRoleMeta struct {
+	Name string `json:"name"`
+}
+
+// ClusterRoleYAML renders a ClusterRole named name that grants perms.
+func ClusterRoleYAML(name string, perms []collector.Permission) ([]byte, error) {
+	role := clusterRole{
+		APIVersion: rbacv1.SchemeGroupVersion.String(),
+		Kind:       "ClusterRole",
+		Metadata:   clusterRoleMeta{Name: name},
+		Rules:      Rules(perms),
+	}
+	data, err := yaml.Marshal(role)
+	if err != nil {
+		return nil, fmt.Errorf("encode ClusterRole: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,69 @@
+// Package rbac checks the permissions the collectors need against a cluster
+// and generates a ClusterRole granting exactly those permissions.
+package rbac
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// Result is the outcome of checking one permission.
+type Result struct {
+	collector.Permission
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Check asks the apiserver, with one SelfSubjectAccessReview per permission,
+// whether the client's identity holds each of perms. An error means the
+// reviews themselves could not be made.
+func Check(ctx context.Context, client kubernetes.Interface, perms []collector.Permission) ([]Result, error) {
+	results := make([]Result, 0, len(perms))
+	for _, p := range perms {
+		review := &authorizationv1.SelfSubjectAccessReview{Spec: accessReviewSpec(p)}
+		resp, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return results, fmt.Errorf("check %s: %w", p, err)
+		}
+		r := Result{Permission: p, Allowed: resp.Status.Allowed, Reason: resp.Status.Reason}
+		if resp.Status.EvaluationError != "" && r.Reason == "" {
+			r.Reason = resp.Status.EvaluationError
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func accessReviewSpec(p collector.Permission) authorizationv1.SelfSubjectAccessReviewSpec {
+	if p.NonResourceURL != "" {
+		return authorizationv1.SelfSubjectAccessReviewSpec{
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: p.NonResourceURL, Verb: p.Verb},
+		}
+	}
+	return authorizationv1.SelfSubjectAccessReviewSpec{
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace:   p.Namespace,
+			Verb:        p.Verb,
+			Group:       p.Group,
+			Resource:    p.Resource,
+			Subresource: p.Subresource,
+		},
+	}
+}
+
+// Denied returns the results that were not allowed.
+func Denied(results []Result) []Result {
+	var denied []Result
+	for _, r := range results {
+		if !r.Allowed {
+			denied = append(denied, r)
+		}
+	}
+	return denied
+}
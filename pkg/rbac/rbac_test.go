@@ -0,0 +1,111 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func allCollectors() collector.Options {
+	opts := collector.DefaultOptions()
+	opts.APIServerMetrics = true
+	opts.KubeletStats = true
+	opts.CadvisorMetrics = true
+	opts.ResourceMetrics = true
+	opts.Logs = true
+	return opts
+}
+
+func TestCheck(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		if ra := review.Spec.ResourceAttributes; ra != nil && ra.Resource == "persistentvolumes" {
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Reason: "no RBAC policy matched"}
+		} else if nra := review.Spec.NonResourceAttributes; nra != nil && nra.Path == "/metrics" {
+			review.Status = authorizationv1.SubjectAccessReviewStatus{}
+		} else {
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true}
+		}
+		return true, review, nil
+	})
+
+	perms := collector.RequiredPermissions(allCollectors())
+	results, err := Check(context.Background(), client, perms)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(results) != len(perms) {
+		t.Fatalf("got %d results for %d permissions", len(results), len(perms))
+	}
+
+	denied := Denied(results)
+	if len(denied) != 2 {
+		t.Fatalf("expected 2 denials, got %+v", denied)
+	}
+	if denied[0].String() != "list persistentvolumes" || denied[0].Reason != "no RBAC policy matched" {
+		t.Errorf("first denial: got %+v", denied[0])
+	}
+	if denied[1].String() != "get /metrics" || denied[1].Collector != "apiserver-metrics" {
+		t.Errorf("second denial: got %+v", denied[1])
+	}
+}
+
+func TestRules_MergesByGroupAndVerbs(t *testing.T) {
+	rules := Rules([]collector.Permission{
+		{Verb: "list", Resource: "pods"},
+		{Verb: "list", Resource: "nodes"},
+		{Verb: "get", Resource: "nodes", Subresource: "proxy"},
+		{Verb: "get", Resource: "nodes", Subresource: "proxy"},
+		{Verb: "list", Group: "apps", Resource: "daemonsets", Namespace: "kube-system"},
+		{Verb: "get", NonResourceURL: "/metrics"},
+	})
+	if len(rules) != 4 {
+		t.Fatalf("expected 4 rules, got %+v", rules)
+	}
+	if r := rules[0]; r.APIGroups[0] != "" || r.Verbs[0] != "get" || r.Resources[0] != "nodes/proxy" {
+		t.Errorf("rule 0: got %+v", r)
+	}
+	if r := rules[1]; len(r.Resources) != 2 || r.Resources[0] != "nodes" || r.Resources[1] != "pods" {
+		t.Errorf("rule 1: got %+v", r)
+	}
+	if r := rules[3]; len(r.NonResourceURLs) != 1 || r.NonResourceURLs[0] != "/metrics" {
+		t.Errorf("rule 3: got %+v", r)
+	}
+}
+
+// The full role is published in docs/ so that the README can link to a file
+// that always matches what the collectors request.
+func TestClusterRoleYAML_MatchesPublished(t *testing.T) {
+	goldenPath := filepath.Join("..", "..", "docs", "clusterrole.yaml")
+	got, err := ClusterRoleYAML(DefaultRoleName, collector.RequiredPermissions(allCollectors()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+	if !bytes.Equal(got, golden) {
+		t.Errorf("%s is out of date; run go test ./pkg/rbac -update", goldenPath)
+	}
+}
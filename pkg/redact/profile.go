@@ -0,0 +1,136 @@
+// Package redact removes identifying data from snapshots so they can be shared
+// outside the team that collected them.
+package redact
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Profile selects what is redacted. Profiles are either built in (see
+// Profiles) or loaded from a YAML file that may start from a built-in one:
+//
+//	base: standard
+//	keepNamespaces: [kube-system, monitoring]
+//	patterns:
+//	  - '\bacme-[a-z0-9-]+\.corp\b'
+type Profile struct {
+	Name string `json:"name"`
+	Base string `json:"base,omitempty"`
+
+	// HashNames replaces namespace, pod, PVC and node names with keyed hashes,
+	// except for namespaces in KeepNamespaces and the objects inside them.
+	HashNames      bool     `json:"hashNames"`
+	KeepNamespaces []string `json:"keepNamespaces,omitempty"`
+
+	ScrubIPs       bool `json:"scrubIPs"`
+	ScrubEmails    bool `json:"scrubEmails"`
+	ScrubTokens    bool `json:"scrubTokens"`
+	ScrubHostnames bool `json:"scrubHostnames"`
+
+	// Patterns are extra regular expressions whose matches are replaced with
+	// "<redacted>".
+	Patterns []string `json:"patterns,omitempty"`
+
+	DropLogs bool `json:"dropLogs"`
+}
+
+// systemNamespaces are kept by the built-in profiles: they carry no customer
+// data and several rules recognise components by their names there. default
+// is not among them, since many clusters run workloads there; a profile file
+// can keep it.
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Profiles are the built-in redaction profiles.
+var Profiles = map[string]Profile{
+	"minimal": {
+		Name:        "minimal",
+		ScrubIPs:    true,
+		ScrubEmails: true,
+		ScrubTokens: true,
+	},
+	"standard": {
+		Name:           "standard",
+		HashNames:      true,
+		KeepNamespaces: systemNamespaces,
+		ScrubIPs:       true,
+		ScrubEmails:    true,
+		ScrubTokens:    true,
+	},
+	"strict": {
+		Name:           "strict",
+		HashNames:      true,
+		KeepNamespaces: systemNamespaces,
+		ScrubIPs:       true,
+		ScrubEmails:    true,
+		ScrubTokens:    true,
+		ScrubHostnames: true,
+		DropLogs:       true,
+	},
+}
+
+// DefaultProfile is used when redaction is requested without naming a profile.
+const DefaultProfile = "standard"
+
+// ProfileNames lists the built-in profiles in a stable order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfile returns the built-in profile with the given name, or reads one
+// from the YAML file at that path.
+func LoadProfile(nameOrPath string) (Profile, error) {
+	if p, ok := Profiles[nameOrPath]; ok {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		if os.IsNotExist(err) && !strings.ContainsAny(nameOrPath, `/\.`) {
+			return Profile{}, fmt.Errorf("unknown redaction profile %q (built-in: %s)", nameOrPath, strings.Join(ProfileNames(), ", "))
+		}
+		return Profile{}, fmt.Errorf("read redaction profile: %w", err)
+	}
+	return parseProfile(data, nameOrPath)
+}
+
+func parseProfile(data []byte, source string) (Profile, error) {
+	var header struct {
+		Base string `json:"base"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return Profile{}, fmt.Errorf("parse redaction profile %s: %w", source, err)
+	}
+
+	var p Profile
+	if header.Base != "" {
+		base, ok := Profiles[header.Base]
+		if !ok {
+			return Profile{}, fmt.Errorf("redaction profile %s: unknown base %q", source, header.Base)
+		}
+		p = base
+		p.KeepNamespaces = append([]string(nil), base.KeepNamespaces...)
+	}
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return Profile{}, fmt.Errorf("parse redaction profile %s: %w", source, err)
+	}
+	if p.Name == "" || (header.Base != "" && p.Name == header.Base) {
+		p.Name = source
+	}
+	for _, pat := range p.Patterns {
+		if _, err := regexp.Compile(pat); err != nil {
+			return Profile{}, fmt.Errorf("redaction profile %s: pattern %q: %w", source, pat, err)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,65 @@
+package redact
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProfile_BuiltIn(t *testing.T) {
+	p, err := LoadProfile("strict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.DropLogs || !p.HashNames {
+		t.Errorf("unexpected strict profile: %+v", p)
+	}
+
+	if _, err := LoadProfile("paranoid"); err == nil || !strings.Contains(err.Error(), "built-in") {
+		t.Errorf("expected unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadProfile_FileWithBase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acme.yaml")
+	data := `base: standard
+keepNamespaces: [kube-system, monitoring]
+patterns:
+  - '\bacme-[a-z0-9-]+\.corp\b'
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadProfile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if p.Name != path || !p.HashNames || !p.ScrubIPs {
+		t.Errorf("expected standard settings under the file's name, got %+v", p)
+	}
+	if len(p.KeepNamespaces) != 2 || p.KeepNamespaces[1] != "monitoring" {
+		t.Errorf("keepNamespaces: got %v", p.KeepNamespaces)
+	}
+	if len(Profiles["standard"].KeepNamespaces) != len(systemNamespaces) {
+		t.Error("loading a profile modified the built-in base")
+	}
+}
+
+func TestLoadProfile_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"typo.yaml":    "hashNamez: true\n",
+		"base.yaml":    "base: lenient\n",
+		"pattern.yaml": "patterns: ['(']\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadProfile(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
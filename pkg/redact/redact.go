@@ -0,0 +1,444 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const (
+	minKeyLen = 16
+	hashLen   = 10
+)
+
+// Redactor applies a Profile to snapshots. Names are hashed with an HMAC, so
+// the same name always maps to the same replacement under the same key and
+// findings still correlate across pods, nodes and events.
+type Redactor struct {
+	profile  Profile
+	key      []byte
+	keep     map[string]bool
+	scrubs   []scrub
+	replaced map[string]string
+}
+
+type scrub struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+var (
+	ipv4Re     = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Re     = regexp.MustCompile(`\b(?:[0-9a-fA-F]{1,4}:){3,7}[0-9a-fA-F]{1,4}\b|\b(?:[0-9a-fA-F]{1,4}:)+:(?:[0-9a-fA-F]{1,4}(?::[0-9a-fA-F]{1,4})*)?\b`)
+	emailRe    = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	bearerRe   = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtRe      = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	secretKVRe = regexp.MustCompile(`(?i)\b(password|passwd|pwd|token|secret|api[_-]?key|access[_-]?key)(["']?\s*[=:]\s*["']?)[^\s"',;]+`)
+	awsKeyRe   = regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	hostnameRe = regexp.MustCompile(`\b(?:[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.){2,}[A-Za-z]{2,63}\b`)
+	nameTokRe  = regexp.MustCompile(`[A-Za-z0-9][A-Za-z0-9._-]*`)
+)
+
+// New returns a Redactor for profile, hashing names with key.
+func New(profile Profile, key []byte) (*Redactor, error) {
+	if profile.HashNames && len(key) < minKeyLen {
+		return nil, fmt.Errorf("redaction key must be at least %d bytes", minKeyLen)
+	}
+
+	r := &Redactor{
+		profile:  profile,
+		key:      key,
+		keep:     make(map[string]bool),
+		replaced: make(map[string]string),
+	}
+	for _, ns := range profile.KeepNamespaces {
+		r.keep[ns] = true
+	}
+
+	if profile.ScrubTokens {
+		r.scrubs = append(r.scrubs,
+			scrub{jwtRe, "<token>"},
+			scrub{bearerRe, "$1 <token>"},
+			scrub{secretKVRe, "$1$2<redacted>"},
+			scrub{awsKeyRe, "<token>"},
+		)
+	}
+	if profile.ScrubEmails {
+		r.scrubs = append(r.scrubs, scrub{emailRe, "<email>"})
+	}
+	if profile.ScrubIPs {
+		r.scrubs = append(r.scrubs, scrub{ipv4Re, "<ip>"}, scrub{ipv6Re, "<ip>"})
+	}
+	if profile.ScrubHostnames {
+		r.scrubs = append(r.scrubs, scrub{hostnameRe, "<host>"})
+	}
+	for _, pat := range profile.Patterns {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pat, err)
+		}
+		r.scrubs = append(r.scrubs, scrub{re, "<redacted>"})
+	}
+	return r, nil
+}
+
+// NewKey returns a random key. Names hashed with it cannot be correlated with
+// any other snapshot.
+func NewKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate redaction key: %w", err)
+	}
+	return key, nil
+}
+
+// LoadKey reads a redaction key from a file. Sharing a key file between runs
+// keeps hashed names stable across snapshots.
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read redaction key: %w", err)
+	}
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) < minKeyLen {
+		return nil, fmt.Errorf("redaction key %s must be at least %d bytes", path, minKeyLen)
+	}
+	return key, nil
+}
+
+// KeyID identifies a key without revealing it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("kube-slowwhy-redact\x00"), key...))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Apply redacts snap in place and records the profile in its header.
+func (r *Redactor) Apply(snap *collector.Snapshot) error {
+	if snap.Redaction != nil {
+		return fmt.Errorf("snapshot is already redacted with profile %q", snap.Redaction.Profile)
+	}
+
+	// Structured names are hashed first so that the text pass can replace
+	// every known name wherever it appears in a message.
+	if r.profile.HashNames {
+		r.hashNames(snap)
+	}
+	r.scrubText(snap)
+	if r.profile.DropLogs {
+		snap.Logs = nil
+	}
+
+	snap.Redaction = r.info()
+	// A signature cannot survive redaction; the copy must be re-signed.
+	snap.Signature = nil
+	return nil
+}
+
+func (r *Redactor) info() *collector.RedactionInfo {
+	info := &collector.RedactionInfo{
+		Profile:     r.profile.Name,
+		HashedNames: r.profile.HashNames,
+		DroppedLogs: r.profile.DropLogs,
+	}
+	if r.profile.HashNames {
+		info.KeyID = KeyID(r.key)
+		info.KeptNamespaces = append([]string(nil), r.profile.KeepNamespaces...)
+	}
+	for _, s := range []struct {
+		on   bool
+		name string
+	}{
+		{r.profile.ScrubIPs, "ips"},
+		{r.profile.ScrubEmails, "emails"},
+		{r.profile.ScrubTokens, "tokens"},
+		{r.profile.ScrubHostnames, "hostnames"},
+		{len(r.profile.Patterns) > 0, "patterns"},
+	} {
+		if s.on {
+			info.Scrubbed = append(info.Scrubbed, s.name)
+		}
+	}
+	return info
+}
+
+func (r *Redactor) hash(prefix, name string) string {
+	if name == "" {
+		return ""
+	}
+	if h, ok := r.replaced[name]; ok && strings.HasPrefix(h, prefix+"-") {
+		return h
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(prefix + "\x00" + name))
+	h := prefix + "-" + hex.EncodeToString(mac.Sum(nil))[:hashLen]
+	r.replaced[name] = h
+	return h
+}
+
+func (r *Redactor) namespace(ns string) string {
+	if ns == "" || r.keep[ns] {
+		return ns
+	}
+	return r.hash("ns", ns)
+}
+
+// named hashes the name of a namespaced object unless its namespace is kept.
+func (r *Redactor) named(prefix, ns, name string) string {
+	if r.keep[ns] {
+		return name
+	}
+	return r.hash(prefix, name)
+}
+
+func (r *Redactor) node(name string) string {
+	return r.hash("node", name)
+}
+
+// webhook hashes an admission webhook name. The apiserver metrics and the
+// webhook configurations must agree on it for the two to be joined.
+func (r *Redactor) webhook(name string) string {
+	return r.hash("webhook", name)
+}
+
+func (r *Redactor) ref(ref model.ObjectRef) model.ObjectRef {
+	switch {
+	case ref.Kind == "Node" && ref.Group == "":
+		ref.Name = r.node(ref.Name)
+	case ref.Kind == "Namespace" && ref.Group == "":
+		ref.Name = r.namespace(ref.Name)
+	case ref.Kind == "MutatingWebhookConfiguration" || ref.Kind == "ValidatingWebhookConfiguration":
+		ref.Name = r.hash("whcfg", ref.Name)
+	case ref.Namespace != "":
+		ref.Name = r.named(refPrefix(ref.Kind), ref.Namespace, ref.Name)
+		ref.Namespace = r.namespace(ref.Namespace)
+	}
+	return ref
+}
+
+func refPrefix(kind string) string {
+	switch kind {
+	case "Pod":
+		return "pod"
+	case "PersistentVolumeClaim":
+		return "claim"
+	case "Service":
+		return "svc"
+	default:
+		return strings.ToLower(kind)
+	}
+}
+
+func (r *Redactor) pod(p *collector.PodInfo) {
+	p.Name = r.named("pod", p.Namespace, p.Name)
+	p.Namespace = r.namespace(p.Namespace)
+	p.NodeName = r.node(p.NodeName)
+}
+
+func (r *Redactor) hashNames(snap *collector.Snapshot) {
+	if snap.Cluster != nil {
+		snap.Cluster.Context = r.hash("ctx", snap.Cluster.Context)
+		snap.Cluster.APIServerHost = r.hash("host", snap.Cluster.APIServerHost)
+	}
+	if snap.Collection != nil {
+		snap.Collection.Options.Namespace = r.namespace(snap.Collection.Options.Namespace)
+		for i := range snap.Collection.RBACDenials {
+			d := &snap.Collection.RBACDenials[i]
+			d.Namespace = r.namespace(d.Namespace)
+		}
+	}
+	for i := range snap.Nodes {
+		snap.Nodes[i].Name = r.node(snap.Nodes[i].Name)
+	}
+	for i := range snap.Pods {
+		r.pod(&snap.Pods[i])
+	}
+	for i := range snap.KubeSystem.Pods {
+		r.pod(&snap.KubeSystem.Pods[i])
+	}
+	for i := range snap.PVCs {
+		pvc := &snap.PVCs[i]
+		pvc.Name = r.named("claim", pvc.Namespace, pvc.Name)
+		pvc.Namespace = r.namespace(pvc.Namespace)
+	}
+	for i := range snap.PVs {
+		if ref := snap.PVs[i].ClaimRef; ref != nil {
+			redacted := r.ref(*ref)
+			snap.PVs[i].ClaimRef = &redacted
+		}
+	}
+	for i := range snap.Events {
+		ev := &snap.Events[i]
+		ev.Namespace = r.namespace(ev.Namespace)
+		ev.InvolvedObject = r.ref(ev.InvolvedObject)
+		if ev.Related != nil {
+			related := r.ref(*ev.Related)
+			ev.Related = &related
+		}
+	}
+	for i := range snap.Webhooks {
+		wh := &snap.Webhooks[i]
+		wh.Configuration = r.hash("whcfg", wh.Configuration)
+		wh.Name = r.webhook(wh.Name)
+		if wh.Service != nil {
+			wh.Service.Name = r.named("svc", wh.Service.Namespace, wh.Service.Name)
+			wh.Service.Namespace = r.namespace(wh.Service.Namespace)
+		}
+		r.selector(wh.NamespaceSelector)
+	}
+	if snap.APIServer != nil {
+		for i := range snap.APIServer.WebhookLatencies {
+			wl := &snap.APIServer.WebhookLatencies[i]
+			wl.Name = r.webhook(wl.Name)
+		}
+	}
+	for i := range snap.Endpoints {
+		ep := &snap.Endpoints[i]
+		ep.Name = r.named("svc", ep.Namespace, ep.Name)
+		ep.Namespace = r.namespace(ep.Namespace)
+	}
+	for i := range snap.KubeletStats {
+		st := &snap.KubeletStats[i]
+		st.NodeName = r.node(st.NodeName)
+		for j := range st.Pods {
+			p := &st.Pods[j]
+			p.Name = r.named("pod", p.Namespace, p.Name)
+			p.Namespace = r.namespace(p.Namespace)
+		}
+	}
+	for i := range snap.ContainerCPU {
+		c := &snap.ContainerCPU[i]
+		c.NodeName = r.node(c.NodeName)
+		c.Pod = r.named("pod", c.Namespace, c.Pod)
+		c.Namespace = r.namespace(c.Namespace)
+	}
+	for i := range snap.Logs {
+		l := &snap.Logs[i]
+		l.Pod = r.named("pod", l.Namespace, l.Pod)
+		l.Namespace = r.namespace(l.Namespace)
+	}
+}
+
+// selector hashes namespace names that webhooks match on explicitly.
+func (r *Redactor) selector(sel *metav1.LabelSelector) {
+	if sel == nil {
+		return
+	}
+	const nameLabel = "kubernetes.io/metadata.name"
+	if v, ok := sel.MatchLabels[nameLabel]; ok {
+		sel.MatchLabels[nameLabel] = r.namespace(v)
+	}
+	for i := range sel.MatchExpressions {
+		expr := &sel.MatchExpressions[i]
+		if expr.Key != nameLabel {
+			continue
+		}
+		for j, v := range expr.Values {
+			expr.Values[j] = r.namespace(v)
+		}
+	}
+}
+
+func (r *Redactor) scrubText(snap *collector.Snapshot) {
+	if snap.Cluster != nil {
+		snap.Cluster.APIServerHost = r.text(snap.Cluster.APIServerHost)
+	}
+	if snap.Collection != nil {
+		for i := range snap.Collection.Errors {
+			snap.Collection.Errors[i].Message = r.text(snap.Collection.Errors[i].Message)
+		}
+		for i := range snap.Collection.RBACDenials {
+			snap.Collection.RBACDenials[i].Message = r.text(snap.Collection.RBACDenials[i].Message)
+		}
+	}
+	for i := range snap.Nodes {
+		r.nodeConditions(snap.Nodes[i].Conditions)
+	}
+	for i := range snap.Pods {
+		r.podText(&snap.Pods[i])
+	}
+	for i := range snap.KubeSystem.Pods {
+		r.podText(&snap.KubeSystem.Pods[i])
+	}
+	for i := range snap.Events {
+		ev := &snap.Events[i]
+		ev.Name = r.replaceNames(ev.Name)
+		ev.ReportingInstance = r.replaceNames(ev.ReportingInstance)
+		ev.Message = r.text(ev.Message)
+	}
+	for i := range snap.Webhooks {
+		snap.Webhooks[i].URL = r.text(snap.Webhooks[i].URL)
+	}
+	for i := range snap.Logs {
+		for j, line := range snap.Logs[i].Lines {
+			snap.Logs[i].Lines[j] = r.text(line)
+		}
+	}
+}
+
+func (r *Redactor) nodeConditions(conds []corev1.NodeCondition) {
+	for i := range conds {
+		conds[i].Message = r.text(conds[i].Message)
+	}
+}
+
+func (r *Redactor) podText(p *collector.PodInfo) {
+	for i := range p.Conditions {
+		p.Conditions[i].Message = r.text(p.Conditions[i].Message)
+	}
+	for i := range p.Containers {
+		st := &p.Containers[i].State
+		if st.Waiting != nil {
+			st.Waiting.Message = r.text(st.Waiting.Message)
+		}
+		if st.Terminated != nil {
+			st.Terminated.Message = r.text(st.Terminated.Message)
+		}
+	}
+}
+
+// text replaces hashed names and then applies the profile's scrub patterns.
+func (r *Redactor) text(s string) string {
+	if s == "" {
+		return s
+	}
+	s = r.replaceNames(s)
+	for _, sc := range r.scrubs {
+		s = sc.re.ReplaceAllString(s, sc.repl)
+	}
+	return s
+}
+
+// replaceNames swaps every whole token that is a hashed name for its hash.
+// Dotted tokens such as event names ("web-0.17a3") are also tried part by
+// part.
+func (r *Redactor) replaceNames(s string) string {
+	if len(r.replaced) == 0 {
+		return s
+	}
+	return nameTokRe.ReplaceAllStringFunc(s, func(tok string) string {
+		if h, ok := r.replaced[tok]; ok {
+			return h
+		}
+		if !strings.Contains(tok, ".") {
+			return tok
+		}
+		parts := strings.Split(tok, ".")
+		for i, p := range parts {
+			if h, ok := r.replaced[p]; ok {
+				parts[i] = h
+			}
+		}
+		return strings.Join(parts, ".")
+	})
+}
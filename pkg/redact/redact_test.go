@@ -0,0 +1,272 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func testSnapshot() *collector.Snapshot {
+	return &collector.Snapshot{
+		Nodes: []collector.NodeInfo{{
+			Name: "ip-10-0-1-2.ec2.internal",
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeMemoryPressure, Message: "kubelet on ip-10-0-1-2.ec2.internal has insufficient memory"},
+			},
+		}},
+		Pods: []collector.PodInfo{
+			{Namespace: "payments", Name: "ledger-0", NodeName: "ip-10-0-1-2.ec2.internal"},
+			{Namespace: "default", Name: "checkout-api-0", NodeName: "ip-10-0-1-2.ec2.internal"},
+		},
+		KubeSystem: collector.KubeSystemHealth{Pods: []collector.PodInfo{
+			{Namespace: "kube-system", Name: "coredns-abc", NodeName: "ip-10-0-1-2.ec2.internal"},
+		}},
+		Events: []collector.EventInfo{{
+			Namespace:         "payments",
+			Name:              "ledger-0.17a3",
+			Reason:            "Failed",
+			Message:           "Failed to pull image for payments/ledger-0 from 10.1.2.3: token=s3cr3t owner jane@example.com",
+			InvolvedObject:    model.PodRef("payments", "ledger-0", "uid-1"),
+			ReportingInstance: "ip-10-0-1-2.ec2.internal",
+		}},
+		Logs: []collector.ContainerLog{{
+			Namespace: "kube-system", Pod: "coredns-abc", Container: "coredns",
+			Lines: []string{"[ERROR] plugin/errors: 2 example.com. A: read udp 10.2.0.5:41234->10.0.0.10:53: i/o timeout"},
+		}},
+	}
+}
+
+func TestApply_Standard(t *testing.T) {
+	snap := testSnapshot()
+	r, err := New(Profiles["standard"], testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(snap); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	node := snap.Nodes[0].Name
+	if !strings.HasPrefix(node, "node-") {
+		t.Fatalf("node name not hashed: %q", node)
+	}
+	pod := snap.Pods[0]
+	if !strings.HasPrefix(pod.Namespace, "ns-") || !strings.HasPrefix(pod.Name, "pod-") || pod.NodeName != node {
+		t.Errorf("pod not hashed consistently: %+v", pod)
+	}
+
+	ev := snap.Events[0]
+	if ev.Namespace != pod.Namespace || ev.InvolvedObject.Name != pod.Name || ev.InvolvedObject.UID != "uid-1" {
+		t.Errorf("event ref does not match the hashed pod: %+v", ev)
+	}
+	if ev.Name != pod.Name+".17a3" {
+		t.Errorf("event name: got %q, want %q", ev.Name, pod.Name+".17a3")
+	}
+	if ev.ReportingInstance != node {
+		t.Errorf("reporting instance: got %q, want %q", ev.ReportingInstance, node)
+	}
+	for _, leak := range []string{"payments", "ledger-0", "10.1.2.3", "s3cr3t", "jane@example.com"} {
+		if strings.Contains(ev.Message, leak) {
+			t.Errorf("event message still contains %q: %s", leak, ev.Message)
+		}
+	}
+	if !strings.Contains(ev.Message, pod.Namespace+"/"+pod.Name) {
+		t.Errorf("event message should name the hashed pod: %s", ev.Message)
+	}
+	if strings.Contains(snap.Nodes[0].Conditions[0].Message, "ip-10-0-1-2") {
+		t.Errorf("node condition still names the node: %s", snap.Nodes[0].Conditions[0].Message)
+	}
+
+	if def := snap.Pods[1]; def.Namespace == "default" || def.Name == "checkout-api-0" {
+		t.Errorf("pods in default should be hashed: %+v", def)
+	}
+
+	ks := snap.KubeSystem.Pods[0]
+	if ks.Name != "coredns-abc" || ks.Namespace != "kube-system" || ks.NodeName != node {
+		t.Errorf("kube-system pod should keep its name but hash its node: %+v", ks)
+	}
+	if line := snap.Logs[0].Lines[0]; strings.Contains(line, "10.2.0.5") || !strings.Contains(line, "i/o timeout") {
+		t.Errorf("log line not scrubbed as expected: %s", line)
+	}
+
+	if snap.Redaction == nil || snap.Redaction.Profile != "standard" || snap.Redaction.KeyID != KeyID(testKey) {
+		t.Errorf("redaction header: got %+v", snap.Redaction)
+	}
+}
+
+func TestApply_Header(t *testing.T) {
+	snap := testSnapshot()
+	snap.Cluster = &collector.ClusterInfo{ID: "uid-ks", Context: "acme-prod", APIServerHost: "10.0.0.1:6443"}
+	snap.Collection = &collector.CollectionInfo{
+		Options: collector.CollectionOptions{Namespace: "payments"},
+		Errors:  []collector.CollectorError{{Collector: "logs", Message: "get logs for payments/ledger-0: timeout"}},
+		RBACDenials: []collector.RBACDenial{{
+			Collector: "pvcs", Verb: "list", Resource: "persistentvolumeclaims", Namespace: "payments",
+			Message: `persistentvolumeclaims is forbidden: cannot list resource "persistentvolumeclaims" in API group "" in the namespace "payments"`,
+		}},
+	}
+	r, err := New(Profiles["standard"], testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(snap); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if c := snap.Cluster; !strings.HasPrefix(c.Context, "ctx-") || c.ID != "uid-ks" || strings.Contains(c.APIServerHost, "10.0.0.1") {
+		t.Errorf("cluster header not redacted: %+v", c)
+	}
+	ns := snap.Pods[0].Namespace
+	coll := snap.Collection
+	if coll.Options.Namespace != ns || coll.RBACDenials[0].Namespace != ns {
+		t.Errorf("collection namespaces not hashed consistently: %+v", coll)
+	}
+	for _, msg := range []string{coll.Errors[0].Message, coll.RBACDenials[0].Message} {
+		if strings.Contains(msg, "payments") || strings.Contains(msg, "ledger-0") {
+			t.Errorf("collection message still names objects: %s", msg)
+		}
+	}
+}
+
+func TestApply_SameKeySameHashes(t *testing.T) {
+	a, b := testSnapshot(), testSnapshot()
+	for _, snap := range []*collector.Snapshot{a, b} {
+		r, err := New(Profiles["standard"], testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Apply(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Pods[0].Name != b.Pods[0].Name || a.Nodes[0].Name != b.Nodes[0].Name {
+		t.Error("expected identical hashes for the same key")
+	}
+
+	c := testSnapshot()
+	r, _ := New(Profiles["standard"], []byte("another-key-of-sufficient-length"))
+	if err := r.Apply(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Pods[0].Name == a.Pods[0].Name {
+		t.Error("expected different hashes for a different key")
+	}
+}
+
+func TestApply_MinimalKeepsNames(t *testing.T) {
+	snap := testSnapshot()
+	r, err := New(Profiles["minimal"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Pods[0].Name != "ledger-0" || snap.Events[0].Namespace != "payments" {
+		t.Errorf("minimal profile should keep names: %+v", snap.Pods[0])
+	}
+	if strings.Contains(snap.Events[0].Message, "10.1.2.3") {
+		t.Errorf("minimal profile should scrub IPs: %s", snap.Events[0].Message)
+	}
+	if snap.Redaction.KeyID != "" {
+		t.Errorf("no key is used without name hashing, got key ID %q", snap.Redaction.KeyID)
+	}
+}
+
+func TestApply_StrictDropsLogs(t *testing.T) {
+	snap := testSnapshot()
+	r, err := New(Profiles["strict"], testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Logs != nil {
+		t.Error("strict profile should drop logs")
+	}
+}
+
+func TestApply_RejectsRedactedSnapshot(t *testing.T) {
+	snap := testSnapshot()
+	snap.Redaction = &collector.RedactionInfo{Profile: "standard"}
+	r, _ := New(Profiles["minimal"], nil)
+	if err := r.Apply(snap); err == nil {
+		t.Error("expected an error for an already redacted snapshot")
+	}
+}
+
+func TestScrubPatterns(t *testing.T) {
+	r, err := New(Profile{ScrubIPs: true, ScrubEmails: true, ScrubTokens: true, ScrubHostnames: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ in, want string }{
+		{"dial tcp 10.0.0.1:443: connect: connection refused", "dial tcp <ip>:443: connect: connection refused"},
+		{"listen on fe80:0:0:0:1ff:fe23:4567:890a failed", "listen on <ip> failed"},
+		{"started at 10:30:00", "started at 10:30:00"},
+		{"Authorization: Bearer abc.def-123", "Authorization: Bearer <token>"},
+		{`password="hunter2" user=admin`, `password="<redacted>" user=admin`},
+		{"contact ops@acme.io", "contact <email>"},
+		{"pull registry.acme.corp/app:v1.2.3", "pull <host>/app:v1.2.3"},
+	}
+	for _, tt := range tests {
+		if got := r.text(tt.in); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApply_HashesWebhookNames(t *testing.T) {
+	snap := testSnapshot()
+	snap.Webhooks = []collector.WebhookInfo{{
+		Configuration: "acme-policy", Name: "validate.acme.io", Type: collector.WebhookValidating,
+		Service: &collector.WebhookService{Namespace: "acme-system", Name: "acme-webhook"},
+	}}
+	snap.APIServer = &collector.APIServerMetrics{WebhookLatencies: []collector.WebhookLatency{
+		{Name: "validate.acme.io", Type: collector.WebhookValidating},
+	}}
+	snap.Events = append(snap.Events, collector.EventInfo{
+		Namespace: "payments", Reason: "FailedCreate",
+		Message: `Error creating: Internal error occurred: failed calling webhook "validate.acme.io": context deadline exceeded`,
+	})
+	r, err := New(Profiles["standard"], testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(snap); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	wh := snap.Webhooks[0]
+	if !strings.HasPrefix(wh.Name, "webhook-") || !strings.HasPrefix(wh.Configuration, "whcfg-") {
+		t.Fatalf("webhook not hashed: %+v", wh)
+	}
+	if got := snap.APIServer.WebhookLatencies[0].Name; got != wh.Name {
+		t.Errorf("latency name %q does not match the hashed webhook %q", got, wh.Name)
+	}
+	if msg := snap.Events[1].Message; strings.Contains(msg, "acme") || !strings.Contains(msg, `"`+wh.Name+`"`) {
+		t.Errorf("event message should name the hashed webhook: %s", msg)
+	}
+}
+
+func TestApply_HashesAPIServerHost(t *testing.T) {
+	snap := testSnapshot()
+	snap.Cluster = &collector.ClusterInfo{Context: "acme-prod", APIServerHost: "acme-prod-api:6443"}
+	r, err := New(Profiles["standard"], testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(snap); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if h := snap.Cluster.APIServerHost; !strings.HasPrefix(h, "host-") {
+		t.Errorf("apiserver host not redacted: %q", h)
+	}
+}
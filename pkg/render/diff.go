@@ -0,0 +1,178 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const maxDiffExample = 80
+
+type DiffRenderer interface {
+	RenderDiff(w io.Writer, d model.SnapshotDiff) error
+}
+
+func NewDiff(f Format) DiffRenderer {
+	switch f {
+	case FormatJSON:
+		return &jsonRenderer{}
+	default:
+		return &tableRenderer{}
+	}
+}
+
+func (r *jsonRenderer) RenderDiff(w io.Writer, d model.SnapshotDiff) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func (r *tableRenderer) RenderDiff(w io.Writer, d model.SnapshotDiff) error {
+	fmt.Fprintf(w, "Before: %s\n", describeSide(d.Before))
+	fmt.Fprintf(w, "After:  %s\n", describeSide(d.After))
+	for _, warning := range d.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+	if d.Empty() {
+		_, err := fmt.Fprintln(w, "\nNo changes.")
+		return err
+	}
+
+	if len(d.Nodes) > 0 {
+		tw := section(w, "CHANGE\tNODE\tDETAILS")
+		for _, n := range d.Nodes {
+			var details []string
+			for _, c := range n.Conditions {
+				details = append(details, fmt.Sprintf("%s %s -> %s", c.Type, c.From, c.To))
+			}
+			if u := n.Unschedulable; u != nil {
+				if u.To {
+					details = append(details, "cordoned")
+				} else {
+					details = append(details, "uncordoned")
+				}
+			}
+			if k := n.Kubelet; k != nil {
+				details = append(details, fmt.Sprintf("kubelet %s -> %s", k.From, k.To))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", n.Change, n.Name, joinDetails(details))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if d.PodsAdded > 0 || d.PodsRemoved > 0 || len(d.Pods) > 0 {
+		fmt.Fprintf(w, "\nPods: %d added, %d removed, %d changed\n", d.PodsAdded, d.PodsRemoved, len(d.Pods))
+		if len(d.Pods) > 0 {
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "POD\tDETAILS")
+			for _, p := range d.Pods {
+				var details []string
+				if p.Recreated {
+					details = append(details, "recreated")
+				}
+				if p.Phase != nil {
+					details = append(details, fmt.Sprintf("phase %s -> %s", p.Phase.From, p.Phase.To))
+				}
+				for _, rc := range p.Restarts {
+					details = append(details, fmt.Sprintf("%s restarts %d -> %d (+%d)", rc.Container, rc.From, rc.To, rc.To-rc.From))
+				}
+				fmt.Fprintf(tw, "%s/%s\t%s\n", p.Namespace, p.Name, joinDetails(details))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(d.WarningEvents) > 0 {
+		tw := section(w, "NEW WARNING REASON\tCOUNT\tOBJECTS\tEXAMPLE")
+		for _, e := range d.WarningEvents {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", e.Reason, e.Count, e.Objects, truncate(e.Example, maxDiffExample))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(d.PVCs) > 0 {
+		tw := section(w, "CHANGE\tPVC\tPHASE")
+		for _, p := range d.PVCs {
+			fmt.Fprintf(tw, "%s\t%s/%s\t%s\n", p.Change, p.Namespace, p.Name, describeStringChange(p.Phase))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(d.DaemonSets) > 0 {
+		tw := section(w, "CHANGE\tDAEMONSET\tDETAILS")
+		for _, ds := range d.DaemonSets {
+			var details []string
+			if ds.Change == model.ChangeAdded {
+				details = append(details, fmt.Sprintf("%d/%d ready", ds.Ready.To, ds.Desired.To))
+			} else {
+				if c := ds.Desired; c != nil {
+					details = append(details, fmt.Sprintf("desired %d -> %d", c.From, c.To))
+				}
+				if c := ds.Ready; c != nil {
+					details = append(details, fmt.Sprintf("ready %d -> %d", c.From, c.To))
+				}
+			}
+			fmt.Fprintf(tw, "%s\tkube-system/%s\t%s\n", ds.Change, ds.Name, joinDetails(details))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// section starts a table after a blank line and writes its header.
+func section(w io.Writer, header string) *tabwriter.Writer {
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, header)
+	return tw
+}
+
+func joinDetails(details []string) string {
+	if len(details) == 0 {
+		return "-"
+	}
+	return strings.Join(details, "; ")
+}
+
+func describeSide(s model.DiffSide) string {
+	desc := formatTime(s.CollectedAt)
+	if s.Context != "" {
+		desc += ", context " + s.Context
+	}
+	return desc
+}
+
+func describeStringChange(c *model.StringChange) string {
+	switch {
+	case c == nil:
+		return ""
+	case c.From == "":
+		return c.To
+	case c.To == "":
+		return "was " + c.From
+	default:
+		return c.From + " -> " + c.To
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type FleetRenderer interface {
+	RenderFleet(w io.Writer, fleet model.FleetReport) error
+}
+
+func NewFleet(f Format) FleetRenderer {
+	switch f {
+	case FormatJSON:
+		return &jsonRenderer{}
+	default:
+		return &tableRenderer{}
+	}
+}
+
+func (r *jsonRenderer) RenderFleet(w io.Writer, fleet model.FleetReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fleet)
+}
+
+func (r *tableRenderer) RenderFleet(w io.Writer, fleet model.FleetReport) error {
+	analysed := 0
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "CONTEXT\tCLUSTER ID\tKUBERNETES\tFINDINGS\tSTATUS\n")
+	for _, c := range fleet.Clusters {
+		if c.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\terror: %s\n", c.Context, c.Error)
+			continue
+		}
+		analysed++
+		id, version := "-", "-"
+		if s := c.Snapshot; s != nil {
+			if s.ClusterID != "" {
+				id = s.ClusterID
+			}
+			if s.ServerVersion != "" {
+				version = s.ServerVersion
+			}
+		}
+		status := "ok"
+		if c.Snapshot != nil && len(c.Snapshot.Warnings) > 0 {
+			status = fmt.Sprintf("%d collection warning(s)", len(c.Snapshot.Warnings))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", c.Context, id, version, c.Findings, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "SCOPE\tCLUSTERS\tSEVERITY\tCATEGORY\tFINDING\n")
+	for _, f := range fleet.Findings {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%s\t%s\t%s\n",
+			f.Scope,
+			len(f.Clusters), analysed,
+			strings.ToUpper(string(f.Severity)),
+			f.Category,
+			f.Key,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, f := range fleet.Findings {
+		fmt.Fprintf(w, "\n--- %s ---\n", f.Key)
+		fmt.Fprintf(w, "Example: %s\n", f.Title)
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, c := range f.Clusters {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", c.Context, strings.ToUpper(string(c.Severity)), strings.Join(c.IDs, ", "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/marek-kar/kube-slowwhy/pkg/model"
 )
@@ -41,6 +42,12 @@ func (r *jsonRenderer) Render(w io.Writer, report model.Report) error {
 type tableRenderer struct{}
 
 func (r *tableRenderer) Render(w io.Writer, report model.Report) error {
+	if report.Snapshot != nil {
+		if err := renderSnapshotHeader(w, report.Snapshot); err != nil {
+			return err
+		}
+	}
+
 	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
 
 	fmt.Fprintf(tw, "SEVERITY\tID\tCATEGORY\tTITLE\tCONFIDENCE\n")
@@ -76,5 +83,132 @@ func (r *tableRenderer) Render(w io.Writer, report model.Report) error {
 			}
 		}
 	}
+
+	if len(report.SkippedRules) > 0 {
+		if err := renderSkippedRules(w, report.SkippedRules); err != nil {
+			return err
+		}
+	}
+	if len(report.RuleErrors) > 0 {
+		if err := renderRuleErrors(w, report.RuleErrors); err != nil {
+			return err
+		}
+	}
+	if report.SuppressedCount > 0 {
+		return renderSuppressed(w, report)
+	}
 	return nil
 }
+
+func renderSkippedRules(w io.Writer, skipped []model.SkippedRule) error {
+	fmt.Fprintf(w, "\n%d rule(s) skipped\n", len(skipped))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "RULE\tREASON\n")
+	for _, s := range skipped {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Rule, s.Reason)
+	}
+	return tw.Flush()
+}
+
+func renderRuleErrors(w io.Writer, failed []model.RuleError) error {
+	fmt.Fprintf(w, "\n%d rule(s) failed\n", len(failed))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "RULE\tERROR\n")
+	for _, e := range failed {
+		fmt.Fprintf(tw, "%s\t%s\n", e.Rule, e.Error)
+	}
+	return tw.Flush()
+}
+
+func renderSuppressed(w io.Writer, report model.Report) error {
+	fmt.Fprintf(w, "\n%d finding(s) suppressed\n", report.SuppressedCount)
+	if len(report.Suppressed) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "SEVERITY\tID\tREASON\tEXPIRES\n")
+	for _, s := range report.Suppressed {
+		expires := s.Expires
+		if expires == "" {
+			expires = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", strings.ToUpper(string(s.Finding.Severity)), s.Finding.ID, s.Reason, expires)
+	}
+	return tw.Flush()
+}
+
+func renderSnapshotHeader(w io.Writer, s *model.SnapshotInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', 0)
+
+	line := "collected " + s.CollectedAt.UTC().Format(time.RFC3339)
+	if s.Since != "" {
+		line += ", events since " + s.Since
+	}
+	if s.CollectorVersion != "" {
+		line += ", kube-slowwhy " + s.CollectorVersion
+	}
+	if s.SchemaVersion != "" {
+		line += ", schema " + s.SchemaVersion
+	}
+	fmt.Fprintf(tw, "Snapshot:\t%s\n", line)
+
+	var cluster []string
+	if s.Context != "" {
+		cluster = append(cluster, "context "+s.Context)
+	}
+	if s.APIServerHost != "" {
+		cluster = append(cluster, "apiserver "+s.APIServerHost)
+	}
+	if s.ServerVersion != "" {
+		v := "Kubernetes " + s.ServerVersion
+		if s.Platform != "" {
+			v += " " + s.Platform
+		}
+		cluster = append(cluster, v)
+	}
+	if s.ClusterID != "" {
+		cluster = append(cluster, "id "+s.ClusterID)
+	}
+	if len(cluster) > 0 {
+		fmt.Fprintf(tw, "Cluster:\t%s\n", strings.Join(cluster, ", "))
+	}
+
+	for i, v := range s.NodeVersions {
+		label := ""
+		if i == 0 {
+			label = "Nodes:"
+		}
+		var parts []string
+		if v.Kubelet != "" {
+			parts = append(parts, "kubelet "+v.Kubelet)
+		}
+		for _, p := range []string{v.ContainerRuntime, v.OSImage} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		if v.Kernel != "" {
+			parts = append(parts, "kernel "+v.Kernel)
+		}
+		fmt.Fprintf(tw, "%s\t%d x %s\n", label, v.Nodes, strings.Join(parts, ", "))
+	}
+
+	if s.Redaction != "" {
+		fmt.Fprintf(tw, "Redacted:\tprofile %s\n", s.Redaction)
+	}
+	if s.SignedBy != "" {
+		fmt.Fprintf(tw, "Signed:\tkey %s\n", s.SignedBy)
+	}
+	for i, warning := range s.Warnings {
+		label := ""
+		if i == 0 {
+			label = "Warnings:"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", label, warning)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -109,3 +109,380 @@ func TestJSONRenderer(t *testing.T) {
 		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
 	}
 }
+
+func TestTableRenderer_SnapshotHeader(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "snapshot_header.table.golden")
+	report := testReport()
+	report.Snapshot = &model.SnapshotInfo{
+		CollectedAt:      time.Date(2023, 6, 15, 10, 30, 0, 0, time.UTC),
+		Since:            "30m0s",
+		SchemaVersion:    "v2",
+		CollectorVersion: "v0.9.0",
+		ClusterID:        "8d6f4b2e-1c3a-4f5e-9b7d-2a1c3e5f7b9d",
+		Context:          "prod-eu",
+		APIServerHost:    "api.prod-eu.example.com:6443",
+		ServerVersion:    "v1.29.1",
+		Platform:         "linux/amd64",
+		NodeVersions: []model.NodeVersionCount{
+			{Kubelet: "v1.29.1", ContainerRuntime: "containerd://1.7.2", OSImage: "Ubuntu 22.04.3 LTS", Kernel: "5.15.0-1051-aws", Nodes: 5},
+			{Kubelet: "v1.28.5", ContainerRuntime: "containerd://1.7.2", OSImage: "Ubuntu 22.04.3 LTS", Kernel: "5.15.0-1051-aws", Nodes: 1},
+		},
+		SignedBy: "5c1e0b7a9d3f2468",
+		Warnings: []string{"pvs: forbidden: cannot list persistentvolumes"},
+	}
+
+	r := New(FormatTable)
+	var buf bytes.Buffer
+	if err := r.Render(&buf, report); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_Fleet(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "fleet.table.golden")
+	fleet := model.FleetReport{
+		SchemaVersion: model.SchemaVersion,
+		Clusters: []model.FleetCluster{
+			{Context: "eu-1", Snapshot: &model.SnapshotInfo{ClusterID: "uid-1", ServerVersion: "v1.29.1"}, Findings: 3},
+			{Context: "eu-2", Snapshot: &model.SnapshotInfo{ClusterID: "uid-2", ServerVersion: "v1.28.5", Warnings: []string{"pvs: forbidden"}}, Findings: 2},
+			{Context: "ap-1", Error: "load kubeconfig: context \"ap-1\" does not exist"},
+		},
+		Findings: []model.FleetFinding{
+			{
+				Key: "dns-instability", Rule: "dns-instability", Category: "dns", Title: "DNS instability detected",
+				Severity: model.SeverityHigh, Scope: model.FleetScopeCommon,
+				Clusters: []model.FleetOccurrence{
+					{Context: "eu-1", Severity: model.SeverityHigh, IDs: []string{"dns-instability"}},
+					{Context: "eu-2", Severity: model.SeverityMedium, IDs: []string{"dns-instability"}},
+				},
+			},
+			{
+				Key: "node-pressure-memorypressure", Rule: "node-pressure", Category: "node-health", Title: "Node worker-1 has MemoryPressure",
+				Severity: model.SeverityCritical, Scope: model.FleetScopeUnique,
+				Clusters: []model.FleetOccurrence{
+					{Context: "eu-1", Severity: model.SeverityCritical, IDs: []string{"node-pressure-worker-1-memorypressure", "node-pressure-worker-2-memorypressure"}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewFleet(FormatTable).RenderFleet(&buf, fleet); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_Timeline(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "timeline.table.golden")
+	start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * 30 * time.Second) }
+	cleared := at(3)
+	timeline := model.Timeline{
+		SchemaVersion: model.SchemaVersion,
+		Context:       "prod-eu",
+		Interval:      "30s",
+		Frames: []model.TimelineFrame{
+			{CollectedAt: at(0)}, {CollectedAt: at(1), Findings: 2}, {CollectedAt: at(2), Findings: 1}, {CollectedAt: at(3), Findings: 1},
+		},
+		Findings: []model.TimelineFinding{
+			{
+				ID: "node-pressure-worker-1-memorypressure", Rule: "node-pressure", Category: "node-health",
+				FirstSeen: at(1), PeakSeverity: model.SeverityCritical, PeakAt: at(2), LastSeen: at(2), ClearedAt: &cleared,
+				Frames: 2, Appearances: 1,
+			},
+			{
+				ID: "restart-trend-default-web-0-app", Rule: "restart-trend", Category: "pod-health",
+				FirstSeen: at(1), PeakSeverity: model.SeverityMedium, PeakAt: at(1), LastSeen: at(3),
+				Frames: 2, Appearances: 2,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewTimeline(FormatTable).RenderTimeline(&buf, timeline); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_Diff(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "diff.table.golden")
+	start := time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC)
+	d := model.SnapshotDiff{
+		SchemaVersion: model.SchemaVersion,
+		Before:        model.DiffSide{CollectedAt: start, Context: "prod-eu"},
+		After:         model.DiffSide{CollectedAt: start.Add(24 * time.Hour), Context: "prod-eu"},
+		Nodes: []model.NodeChange{
+			{
+				Name: "worker-1", Change: model.ChangeChanged,
+				Conditions: []model.ConditionTransition{{Type: "MemoryPressure", From: "False", To: "True"}},
+				Kubelet:    &model.StringChange{From: "v1.28.5", To: "v1.29.1"},
+			},
+			{Name: "worker-2", Change: model.ChangeRemoved},
+			{Name: "worker-3", Change: model.ChangeChanged, Unschedulable: &model.BoolChange{To: true}},
+		},
+		PodsAdded:   2,
+		PodsRemoved: 1,
+		Pods: []model.PodChange{
+			{Namespace: "default", Name: "web-0", Restarts: []model.RestartChange{{Container: "app", From: 1, To: 6}}},
+			{Namespace: "default", Name: "web-1", Recreated: true, Phase: &model.StringChange{From: "Running", To: "Pending"}},
+		},
+		WarningEvents: []model.EventReasonChange{
+			{Reason: "FailedMount", Count: 5, Objects: 2, Example: "MountVolume.SetUp failed for volume \"pvc-0a1b2c3d\" : rpc error: code = DeadlineExceeded desc = context deadline exceeded"},
+		},
+		PVCs: []model.PVCChange{
+			{Namespace: "default", Name: "data-web-0", Change: model.ChangeChanged, Phase: &model.StringChange{From: "Bound", To: "Pending"}},
+			{Namespace: "default", Name: "data-web-1", Change: model.ChangeAdded, Phase: &model.StringChange{To: "Bound"}},
+		},
+		DaemonSets: []model.DaemonSetChange{
+			{Name: "kube-proxy", Change: model.ChangeChanged, Ready: &model.Int32Change{From: 3, To: 2}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewDiff(FormatTable).RenderDiff(&buf, d); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_ReportDiff(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "reportdiff.table.golden")
+	start := time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC)
+	finding := func(id, category, title string, sev model.Severity) model.Finding {
+		return model.Finding{ID: id, Category: category, Title: title, Severity: sev}
+	}
+	d := model.ReportDiff{
+		SchemaVersion: model.SchemaVersion,
+		Before:        &model.SnapshotInfo{CollectedAt: start, Context: "prod-eu"},
+		After:         &model.SnapshotInfo{CollectedAt: start.Add(24 * time.Hour), Context: "prod-eu"},
+		Findings: []model.FindingChange{
+			{Type: model.FindingEscalated, Previous: model.SeverityMedium, Finding: finding("node-pressure-worker-1-memorypressure", "node-health", "Node worker-1 has MemoryPressure", model.SeverityCritical)},
+			{Type: model.FindingUnchanged, Finding: finding("dns-instability", "dns", "DNS instability detected", model.SeverityHigh)},
+			{Type: model.FindingNew, Finding: finding("pending-pods-insufficient-cpu", "scheduling", "3 Pending pod(s) due to insufficient-cpu", model.SeverityMedium)},
+			{Type: model.FindingDeescalated, Previous: model.SeverityHigh, Finding: finding("storage-issue", "storage", "1 PVC(s) stuck in Pending", model.SeverityLow)},
+			{Type: model.FindingResolved, Previous: model.SeverityLow, Finding: finding("cpu-throttling-default-web-0-app", "resource-usage", "Container default/web-0/app is CPU throttled", model.SeverityLow)},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewReportDiff(FormatTable).RenderReportDiff(&buf, d); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_Suppressed(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "suppressed.table.golden")
+	report := testReport()
+	report.Suppressed = []model.SuppressedFinding{
+		{Reason: "GPU pool is tainted for batch jobs only", Finding: model.Finding{ID: "pending-pods-taint", Severity: model.SeverityMedium}},
+		{Reason: "e2e fixtures crashloop on purpose", Expires: "2024-09-30", Finding: model.Finding{ID: "restart-trend-e2e-flaky-0-app", Severity: model.SeverityHigh}},
+	}
+	report.SuppressedCount = len(report.Suppressed)
+
+	var buf bytes.Buffer
+	if err := New(FormatTable).Render(&buf, report); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_SkippedRules(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "skipped_rules.table.golden")
+	report := testReport()
+	report.SkippedRules = []model.SkippedRule{
+		{Rule: "apiserver-latency", Reason: "apiserver-metrics not collected (collect with --apiserver-metrics)"},
+		{Rule: "admission-webhooks", Reason: "webhooks denied by RBAC: forbidden"},
+	}
+
+	var buf bytes.Buffer
+	if err := New(FormatTable).Render(&buf, report); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_RuleErrors(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "rule_errors.table.golden")
+	report := testReport()
+	report.RuleErrors = []model.RuleError{
+		{Rule: "gpu-quota", Error: "timed out after 30s"},
+		{Rule: "team-checks", Error: `finding 2 (disk-full): severity "urgent" is not one of critical, high, medium, low`},
+	}
+
+	var buf bytes.Buffer
+	if err := New(FormatTable).Render(&buf, report); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
+
+func TestTableRenderer_Rules(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "rules.table.golden")
+	rules := []model.RuleInfo{
+		{
+			RuleMetadata: model.RuleMetadata{
+				Name: "pending-pods", Version: "1", Description: "Pending pods grouped by why the scheduler cannot place them.",
+				Categories: []string{"scheduling"}, Requires: []string{"pods"}, Uses: []string{"events"},
+				MinSeverity: model.SeverityLow, MaxSeverity: model.SeverityCritical,
+				DocURL: "https://example.com/docs#pending-pods",
+			},
+			Enabled:    true,
+			Namespaces: []string{"shop", "checkout"},
+			Params:     []byte(`{"criticalCount":5,"highCount":2}`),
+		},
+		{
+			RuleMetadata: model.RuleMetadata{
+				Name: "cpu-throttling", Version: "1", Description: "Containers throttled by their CPU limit.",
+				Requires: []string{"cadvisor-metrics"}, MinSeverity: model.SeverityMedium, MaxSeverity: model.SeverityHigh,
+			},
+			Enabled: true,
+			Skipped: "cadvisor-metrics not collected (collect with --cadvisor-metrics)",
+		},
+		{
+			RuleMetadata: model.RuleMetadata{Name: "restart-trend", Description: "Rising restart counts.", Trend: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	r := NewRules(FormatTable)
+	if err := r.RenderRules(&buf, rules); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for _, rule := range rules {
+		buf.WriteString("\n")
+		if err := r.RenderRule(&buf, rule); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("output mismatch.\n--- got ---\n%s\n--- want ---\n%s", buf.String(), string(golden))
+	}
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// reportDiffSections is the order findings are listed in, most actionable
+// first.
+var reportDiffSections = []struct {
+	typ   model.FindingChangeType
+	title string
+}{
+	{model.FindingNew, "New"},
+	{model.FindingEscalated, "Escalated"},
+	{model.FindingDeescalated, "De-escalated"},
+	{model.FindingResolved, "Resolved"},
+	{model.FindingUnchanged, "Unchanged"},
+}
+
+type ReportDiffRenderer interface {
+	RenderReportDiff(w io.Writer, d model.ReportDiff) error
+}
+
+func NewReportDiff(f Format) ReportDiffRenderer {
+	switch f {
+	case FormatJSON:
+		return &jsonRenderer{}
+	default:
+		return &tableRenderer{}
+	}
+}
+
+func (r *jsonRenderer) RenderReportDiff(w io.Writer, d model.ReportDiff) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func (r *tableRenderer) RenderReportDiff(w io.Writer, d model.ReportDiff) error {
+	fmt.Fprintf(w, "Before: %s\n", describeReport(d.Before))
+	fmt.Fprintf(w, "After:  %s\n", describeReport(d.After))
+	for _, warning := range d.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+
+	counts := make([]string, 0, len(reportDiffSections))
+	for _, s := range reportDiffSections {
+		counts = append(counts, fmt.Sprintf("%d %s", d.Count(s.typ), s.typ))
+	}
+	fmt.Fprintf(w, "Findings: %s\n", strings.Join(counts, ", "))
+
+	for _, s := range reportDiffSections {
+		if d.Count(s.typ) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", s.title)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SEVERITY\tID\tCATEGORY\tTITLE")
+		for _, c := range d.Findings {
+			if c.Type != s.typ {
+				continue
+			}
+			severity := strings.ToUpper(string(c.Finding.Severity))
+			if c.Type == model.FindingEscalated || c.Type == model.FindingDeescalated {
+				severity = strings.ToUpper(string(c.Previous)) + " -> " + severity
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", severity, c.Finding.ID, c.Finding.Category, c.Finding.Title)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeReport(s *model.SnapshotInfo) string {
+	if s == nil {
+		return "unknown snapshot"
+	}
+	return describeSide(model.DiffSide{CollectedAt: s.CollectedAt, Context: s.Context})
+}
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type RulesRenderer interface {
+	RenderRules(w io.Writer, rules []model.RuleInfo) error
+	RenderRule(w io.Writer, rule model.RuleInfo) error
+}
+
+func NewRules(f Format) RulesRenderer {
+	switch f {
+	case FormatJSON:
+		return &jsonRenderer{}
+	default:
+		return &tableRenderer{}
+	}
+}
+
+func (r *jsonRenderer) RenderRules(w io.Writer, rules []model.RuleInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rules)
+}
+
+func (r *jsonRenderer) RenderRule(w io.Writer, rule model.RuleInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rule)
+}
+
+func (r *tableRenderer) RenderRules(w io.Writer, rules []model.RuleInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tSEVERITY\tREQUIRES\tDESCRIPTION")
+	var skipped []model.RuleInfo
+	for _, rule := range rules {
+		if rule.Skipped != "" {
+			skipped = append(skipped, rule)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			rule.Name, ruleStatus(rule), severityRange(rule.RuleMetadata), joinOrDash(rule.Requires), rule.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(skipped) == 0 {
+		return nil
+	}
+	tw = section(w, "SKIPPED\tREASON")
+	for _, rule := range skipped {
+		fmt.Fprintf(tw, "%s\t%s\n", rule.Name, rule.Skipped)
+	}
+	return tw.Flush()
+}
+
+func (r *tableRenderer) RenderRule(w io.Writer, rule model.RuleInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", rule.Name)
+	if rule.Version != "" {
+		fmt.Fprintf(tw, "Version:\t%s\n", rule.Version)
+	}
+	if rule.Description != "" {
+		fmt.Fprintf(tw, "Description:\t%s\n", rule.Description)
+	}
+	fmt.Fprintf(tw, "Categories:\t%s\n", joinOrDash(rule.Categories))
+	fmt.Fprintf(tw, "Severity:\t%s\n", severityRange(rule.RuleMetadata))
+	fmt.Fprintf(tw, "Requires:\t%s\n", joinOrDash(rule.Requires))
+	fmt.Fprintf(tw, "Uses:\t%s\n", joinOrDash(rule.Uses))
+	if rule.Trend {
+		fmt.Fprintf(tw, "Runs on:\trecordings only, comparing consecutive frames\n")
+	}
+	fmt.Fprintf(tw, "Status:\t%s\n", ruleStatus(rule))
+	if rule.Skipped != "" {
+		fmt.Fprintf(tw, "Skipped:\t%s\n", rule.Skipped)
+	}
+	namespaces := "all"
+	if len(rule.Namespaces) > 0 {
+		namespaces = strings.Join(rule.Namespaces, ", ")
+	}
+	fmt.Fprintf(tw, "Namespaces:\t%s\n", namespaces)
+	if rule.DocURL != "" {
+		fmt.Fprintf(tw, "Docs:\t%s\n", rule.DocURL)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(rule.Params) == 0 {
+		return nil
+	}
+	params, err := yaml.JSONToYAML(rule.Params)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "Parameters:")
+	for _, line := range strings.Split(strings.TrimRight(string(params), "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	return nil
+}
+
+func ruleStatus(rule model.RuleInfo) string {
+	switch {
+	case !rule.Enabled:
+		return "disabled"
+	case rule.Skipped != "":
+		return "skipped"
+	default:
+		return "enabled"
+	}
+}
+
+func severityRange(m model.RuleMetadata) string {
+	switch {
+	case m.MinSeverity == "" && m.MaxSeverity == "":
+		return "-"
+	case m.MinSeverity == m.MaxSeverity || m.MinSeverity == "":
+		return string(m.MaxSeverity)
+	case m.MaxSeverity == "":
+		return string(m.MinSeverity)
+	default:
+		return string(m.MinSeverity) + "-" + string(m.MaxSeverity)
+	}
+}
+
+func joinOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+type TimelineRenderer interface {
+	RenderTimeline(w io.Writer, t model.Timeline) error
+}
+
+func NewTimeline(f Format) TimelineRenderer {
+	switch f {
+	case FormatJSON:
+		return &jsonRenderer{}
+	default:
+		return &tableRenderer{}
+	}
+}
+
+func (r *jsonRenderer) RenderTimeline(w io.Writer, t model.Timeline) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+func (r *tableRenderer) RenderTimeline(w io.Writer, t model.Timeline) error {
+	if len(t.Frames) == 0 {
+		_, err := fmt.Fprintln(w, "Recording has no frames.")
+		return err
+	}
+
+	first, last := t.Frames[0].CollectedAt, t.Frames[len(t.Frames)-1].CollectedAt
+	header := fmt.Sprintf("Recording: %d frame(s) from %s to %s", len(t.Frames), formatTime(first), formatTime(last))
+	if t.Interval != "" {
+		header += ", every " + t.Interval
+	}
+	if t.Context != "" {
+		header += ", context " + t.Context
+	}
+	fmt.Fprintln(w, header)
+
+	if len(t.Findings) == 0 {
+		_, err := fmt.Fprintln(w, "\nNo findings in any frame.")
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "FIRST SEEN\tPEAK\tPEAK AT\tCLEARED\tFRAMES\tFINDING\n")
+	for _, f := range t.Findings {
+		cleared := "-"
+		if f.ClearedAt != nil {
+			cleared = formatTime(*f.ClearedAt)
+		}
+		frames := fmt.Sprintf("%d/%d", f.Frames, len(t.Frames))
+		if f.Appearances > 1 {
+			frames += fmt.Sprintf(" (%d runs)", f.Appearances)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatTime(f.FirstSeen),
+			strings.ToUpper(string(f.PeakSeverity)),
+			formatTime(f.PeakAt),
+			cleared,
+			frames,
+			f.ID,
+		)
+	}
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,84 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of the manifest inside a bundle directory.
+const ManifestFile = "bundle.json"
+
+// Manifest lists the snapshots of a multi-cluster bundle: a directory with
+// one snapshot file per kube context and this manifest.
+type Manifest struct {
+	CreatedAt time.Time     `json:"createdAt"`
+	Clusters  []BundleEntry `json:"clusters"`
+}
+
+// BundleEntry is one context of a bundle. File is relative to the bundle
+// directory and empty when Error is set.
+type BundleEntry struct {
+	Context   string `json:"context"`
+	File      string `json:"file,omitempty"`
+	ClusterID string `json:"clusterID,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// IsBundle reports whether path is a bundle directory.
+func IsBundle(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ManifestFile))
+	return err == nil
+}
+
+// BundleFileName returns the snapshot file name for a context, replacing
+// characters that are not safe in file names. ext is appended as is, e.g.
+// ".json.zst".
+func BundleFileName(context, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, context)
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		name = "context"
+	}
+	return name + ext
+}
+
+// WriteManifest writes the manifest into dir.
+func WriteManifest(dir string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode bundle manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write bundle manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest of the bundle in dir.
+func LoadManifest(dir string) (Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read bundle manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("parse bundle manifest %s: %w", dir, err)
+	}
+	for _, e := range m.Clusters {
+		if e.File != "" && (filepath.IsAbs(e.File) || strings.Contains(filepath.ToSlash(e.File), "/")) {
+			return Manifest{}, fmt.Errorf("bundle manifest %s: file %q for context %s must be a plain file name", dir, e.File, e.Context)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleFileName(t *testing.T) {
+	tests := map[string]string{
+		"prod-eu":                                "prod-eu.json",
+		"arn:aws:eks:eu-west-1:123:cluster/prod": "arn_aws_eks_eu-west-1_123_cluster_prod.json",
+		"../etc":                                 "_etc.json",
+		"":                                       "context.json",
+		"gke_project_europe-west1_cluster@admin 1": "gke_project_europe-west1_cluster_admin_1.json",
+	}
+	for context, want := range tests {
+		if got := BundleFileName(context, ".json"); got != want {
+			t.Errorf("BundleFileName(%q): got %q, want %q", context, got, want)
+		}
+	}
+}
+
+func TestManifest_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if IsBundle(dir) {
+		t.Fatal("empty directory reported as a bundle")
+	}
+
+	m := Manifest{
+		CreatedAt: time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC),
+		Clusters: []BundleEntry{
+			{Context: "eu-1", File: "eu-1.json.zst", ClusterID: "uid-1"},
+			{Context: "ap-1", Error: "load kubeconfig: context not found"},
+		},
+	}
+	if err := WriteManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	if !IsBundle(dir) {
+		t.Fatal("bundle not detected")
+	}
+	got, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Clusters) != 2 || got.Clusters[0] != m.Clusters[0] || got.Clusters[1].Error == "" {
+		t.Errorf("manifest: got %+v", got)
+	}
+}
+
+func TestLoadManifest_RejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"clusters": [{"context": "x", "file": "../x.json"}]}`
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(dir); err == nil || !strings.Contains(err.Error(), "plain file name") {
+		t.Errorf("expected path rejection, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// CompressionFor picks the compression for a snapshot written to path from its
+// extension: ".gz" for gzip, ".zst" for zstd, anything else uncompressed.
+func CompressionFor(path string) Compression {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip":
+		return CompressionGzip
+	case ".zst", ".zstd":
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// Open returns a reader for the snapshot at path. Compression is detected from
+// the file contents, so renamed files still read correctly.
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open snapshot: %w", err)
+	}
+	r, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &stackCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+// Create opens path for writing a snapshot, compressing according to its
+// extension. Closing the returned writer flushes the compressor and the file;
+// it also has a Flush method that writes out what the compressor holds.
+func Create(path string) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot: %w", err)
+	}
+
+	var w io.WriteCloser
+	switch CompressionFor(path) {
+	case CompressionGzip:
+		w = gzip.NewWriter(f)
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("create zstd writer: %w", err)
+		}
+		w = zw
+	default:
+		w = nopWriteCloser{f}
+	}
+	return &stackWriteCloser{Writer: w, closers: []io.Closer{w, f}}, nil
+}
+
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip stream: %w", err)
+		}
+		return gr, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open zstd stream: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// stackCloser closes its closers in order and reports the first error.
+type stackCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *stackCloser) Close() error {
+	return closeAll(s.closers)
+}
+
+type stackWriteCloser struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (s *stackWriteCloser) Close() error {
+	return closeAll(s.closers)
+}
+
+// Flush ends the compressor's current block, so that what was written so far
+// can be read back before the writer is closed.
+func (s *stackWriteCloser) Flush() error {
+	if f, ok := s.Writer.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func closeAll(closers []io.Closer) error {
+	var first error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
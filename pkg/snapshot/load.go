@@ -0,0 +1,192 @@
+// Package snapshot reads snapshot files written by any supported version of
+// the collector and describes the current snapshot format.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// Load reads the snapshot at path, which may be gzip or zstd compressed,
+// upgrading it to the current schema version.
+func Load(path string) (*collector.Snapshot, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	snap, err := Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// Decode reads an uncompressed snapshot from r, upgrading it to the current
+// schema version.
+func Decode(r io.Reader) (*collector.Snapshot, error) {
+	snap, _, err := decode(r, false)
+	return snap, err
+}
+
+// decode returns the schema version the input was written with alongside the
+// upgraded snapshot. With strict set, fields unknown to the current schema are
+// rejected.
+//
+// Top-level fields are buffered until schemaVersion is seen, which the
+// collector writes first. A snapshot at the current version is then decoded
+// one list element at a time; older versions are buffered whole and migrated.
+func decode(r io.Reader, strict bool) (*collector.Snapshot, string, error) {
+	dec := json.NewDecoder(r)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return decodeNext(dec, strict)
+}
+
+// decodeNext decodes the next snapshot from dec, leaving it positioned after
+// the snapshot so a stream of snapshots can be read with one decoder.
+func decodeNext(dec *json.Decoder, strict bool) (*collector.Snapshot, string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, "", fmt.Errorf("decode snapshot: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, "", fmt.Errorf("decode snapshot: not a JSON object")
+	}
+
+	sd := &streamDecoder{dec: dec, strict: strict}
+	raw := make(map[string]json.RawMessage)
+	var (
+		order     []string
+		version   string
+		streaming bool
+	)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, version, fmt.Errorf("decode snapshot: %w", err)
+		}
+		key, _ := tok.(string)
+
+		if streaming {
+			if err := sd.decodeField(key, nil); err != nil {
+				return nil, version, fmt.Errorf("decode snapshot %s: %w", key, err)
+			}
+			continue
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, version, fmt.Errorf("decode snapshot %s: %w", key, err)
+		}
+		if key == "schemaVersion" {
+			if err := json.Unmarshal(value, &version); err != nil {
+				return nil, "", fmt.Errorf("decode snapshot schemaVersion: %w", err)
+			}
+			if err := checkVersion(version); err != nil {
+				return nil, version, err
+			}
+			if version == collector.SnapshotSchemaVersion {
+				streaming = true
+				order = append(order, key)
+				raw[key] = value
+				for _, k := range order {
+					if err := sd.decodeField(k, raw[k]); err != nil {
+						return nil, version, fmt.Errorf("decode snapshot %s: %w", k, err)
+					}
+				}
+				raw = nil
+				continue
+			}
+		}
+		if _, seen := raw[key]; !seen {
+			order = append(order, key)
+		}
+		raw[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, version, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if streaming {
+		return &sd.snap, version, nil
+	}
+
+	if err := upgrade(raw, version); err != nil {
+		return nil, version, err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, version, fmt.Errorf("re-encode snapshot: %w", err)
+	}
+	var snap collector.Snapshot
+	if err := unmarshal(data, &snap, strict); err != nil {
+		return nil, version, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return &snap, version, nil
+}
+
+func unmarshal(data []byte, v interface{}, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// checkVersion rejects versions this build cannot read or migrate.
+func checkVersion(version string) error {
+	current := collector.SnapshotSchemaVersion
+	if version == "" {
+		return fmt.Errorf("snapshot has no schemaVersion; is this a kube-slowwhy snapshot?")
+	}
+	n, ok := versionNumber(version)
+	if !ok {
+		return fmt.Errorf("unsupported snapshot schemaVersion %q", version)
+	}
+	if cur, _ := versionNumber(current); n > cur {
+		return fmt.Errorf("unsupported snapshot schemaVersion %q: it is newer than %s, the latest this kube-slowwhy understands; upgrade kube-slowwhy to read it", version, current)
+	}
+	return nil
+}
+
+// upgrade applies registered migrations until raw is at the current version.
+func upgrade(raw map[string]json.RawMessage, version string) error {
+	if err := checkVersion(version); err != nil {
+		return err
+	}
+	current := collector.SnapshotSchemaVersion
+	for version != current {
+		m, ok := findMigration(version)
+		if !ok {
+			return fmt.Errorf("unsupported snapshot schemaVersion %q: no migration to %s", version, current)
+		}
+		if err := m.apply(raw); err != nil {
+			return fmt.Errorf("migrate snapshot %s to %s: %w", m.from, m.to, err)
+		}
+		version = m.to
+		if err := setJSON(raw, "schemaVersion", version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionNumber parses schema versions of the form "v<N>".
+func versionNumber(v string) (int, bool) {
+	if !strings.HasPrefix(v, "v") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v[1:])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
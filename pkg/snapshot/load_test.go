@@ -0,0 +1,107 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const snapshotV1Fixture = `{
+  "schemaVersion": "v1",
+  "collectedAt": "2024-01-01T10:00:00Z",
+  "since": "30m0s",
+  "nodes": [{"name": "worker-1", "conditions": null, "allocatable": null, "capacity": null, "unschedulable": false}],
+  "pods": [],
+  "events": [
+    {"namespace": "default", "name": "web-0.17a", "reason": "BackOff", "message": "Back-off", "type": "Warning",
+     "involvedObject": "Pod/default/web-0", "involvedObjectUID": "uid-1", "involvedObjectAPIVersion": "v1",
+     "related": "Node//worker-1", "count": 3, "firstTimestamp": "2024-01-01T09:50:00Z", "lastTimestamp": "2024-01-01T09:59:00Z"},
+    {"namespace": "default", "name": "worker-1.oom", "reason": "SystemOOM", "message": "oom", "type": "Warning",
+     "involvedObject": "Node//worker-1", "count": 1, "firstTimestamp": "2024-01-01T09:50:00Z", "lastTimestamp": "2024-01-01T09:50:00Z"},
+    {"namespace": "apps", "name": "web.1", "reason": "ScalingReplicaSet", "message": "scaled", "type": "Normal",
+     "involvedObject": "Deployment/apps/web", "involvedObjectAPIVersion": "apps/v1", "count": 1,
+     "firstTimestamp": "2024-01-01T09:50:00Z", "lastTimestamp": "2024-01-01T09:50:00Z"}
+  ],
+  "pvcs": [],
+  "pvs": [
+    {"name": "pv-1", "phase": "Bound", "claimRef": "default/data"},
+    {"name": "pv-2", "phase": "Available"}
+  ],
+  "kubeSystem": {"daemonSets": null, "pods": null}
+}`
+
+func TestLoad_MigratesV1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(snapshotV1Fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if snap.SchemaVersion != collector.SnapshotSchemaVersion {
+		t.Errorf("schemaVersion: got %q, want %q", snap.SchemaVersion, collector.SnapshotSchemaVersion)
+	}
+	if len(snap.Events) != 3 {
+		t.Fatalf("events: got %d, want 3", len(snap.Events))
+	}
+
+	pod := snap.Events[0].InvolvedObject
+	if pod != (model.ObjectRef{Version: "v1", Kind: "Pod", Namespace: "default", Name: "web-0", UID: "uid-1"}) {
+		t.Errorf("pod ref: got %+v", pod)
+	}
+	if snap.Events[0].Related == nil || snap.Events[0].Related.String() != "node/worker-1" {
+		t.Errorf("related: got %+v", snap.Events[0].Related)
+	}
+	if got := snap.Events[1].InvolvedObject.String(); got != "node/worker-1" {
+		t.Errorf("node ref: got %q", got)
+	}
+	if got := snap.Events[2].InvolvedObject.String(); got != "deployment.apps/apps/web" {
+		t.Errorf("deployment ref: got %q", got)
+	}
+
+	if snap.PVs[0].ClaimRef == nil || snap.PVs[0].ClaimRef.String() != "persistentvolumeclaim/default/data" {
+		t.Errorf("claimRef: got %+v", snap.PVs[0].ClaimRef)
+	}
+	if snap.PVs[1].ClaimRef != nil {
+		t.Errorf("unbound pv claimRef: got %+v", snap.PVs[1].ClaimRef)
+	}
+}
+
+func TestDecode_RejectsUnsupportedVersions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"future", `{"schemaVersion": "v9"}`, "upgrade kube-slowwhy"},
+		{"garbage", `{"schemaVersion": "2024-01"}`, `unsupported snapshot schemaVersion "2024-01"`},
+		{"missing", `{"nodes": []}`, "no schemaVersion"},
+		{"not an object", `[]`, "decode snapshot"},
+	}
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestMigrations_ReachCurrentVersion(t *testing.T) {
+	for _, m := range migrations {
+		version := m.from
+		for steps := 0; version != collector.SnapshotSchemaVersion; steps++ {
+			next, ok := findMigration(version)
+			if !ok || steps > len(migrations) {
+				t.Fatalf("no migration path from %s to %s", m.from, collector.SnapshotSchemaVersion)
+			}
+			version = next.to
+		}
+	}
+}
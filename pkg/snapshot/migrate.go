@@ -0,0 +1,138 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// migration upgrades the raw top-level fields of a snapshot from one schema
+// version to the next. Migrations work on raw JSON so that they do not depend
+// on Go types that only describe the current version.
+type migration struct {
+	from  string
+	to    string
+	apply func(raw map[string]json.RawMessage) error
+}
+
+// migrations must form a chain ending at collector.SnapshotSchemaVersion.
+// When the schema changes, bump the version and append a step here.
+var migrations = []migration{
+	{from: "v1", to: "v2", apply: migrateV1ToV2},
+}
+
+func findMigration(from string) (migration, bool) {
+	for _, m := range migrations {
+		if m.from == from {
+			return m, true
+		}
+	}
+	return migration{}, false
+}
+
+type v1Event struct {
+	InvolvedObject           string `json:"involvedObject"`
+	InvolvedObjectUID        string `json:"involvedObjectUID,omitempty"`
+	InvolvedObjectAPIVersion string `json:"involvedObjectAPIVersion,omitempty"`
+	Related                  string `json:"related,omitempty"`
+}
+
+// migrateV1ToV2 replaces the "Kind/namespace/name" strings of v1 events and
+// the "namespace/name" claimRef of v1 persistent volumes with ObjectRefs.
+func migrateV1ToV2(raw map[string]json.RawMessage) error {
+	if data, ok := raw["events"]; ok {
+		var events []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &events); err != nil {
+			return fmt.Errorf("events: %w", err)
+		}
+		for i, ev := range events {
+			var old v1Event
+			for key, dst := range map[string]*string{
+				"involvedObject":           &old.InvolvedObject,
+				"involvedObjectUID":        &old.InvolvedObjectUID,
+				"involvedObjectAPIVersion": &old.InvolvedObjectAPIVersion,
+				"related":                  &old.Related,
+			} {
+				if v, ok := ev[key]; ok {
+					if err := json.Unmarshal(v, dst); err != nil {
+						return fmt.Errorf("events[%d].%s: %w", i, key, err)
+					}
+				}
+			}
+			delete(ev, "involvedObjectUID")
+			delete(ev, "involvedObjectAPIVersion")
+
+			ref := legacyEventRef(old.InvolvedObject)
+			if old.InvolvedObjectAPIVersion != "" {
+				ref = model.RefFromAPIVersion(old.InvolvedObjectAPIVersion, ref.Kind, ref.Namespace, ref.Name, "")
+			}
+			ref.UID = old.InvolvedObjectUID
+			if err := setJSON(ev, "involvedObject", ref); err != nil {
+				return err
+			}
+
+			if old.Related != "" {
+				if err := setJSON(ev, "related", legacyEventRef(old.Related)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := setJSON(raw, "events", events); err != nil {
+			return err
+		}
+	}
+
+	if data, ok := raw["pvs"]; ok {
+		var pvs []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &pvs); err != nil {
+			return fmt.Errorf("pvs: %w", err)
+		}
+		for i, pv := range pvs {
+			v, ok := pv["claimRef"]
+			if !ok {
+				continue
+			}
+			var claim string
+			if err := json.Unmarshal(v, &claim); err != nil {
+				return fmt.Errorf("pvs[%d].claimRef: %w", i, err)
+			}
+			if claim == "" {
+				delete(pv, "claimRef")
+				continue
+			}
+			ns, name, _ := strings.Cut(claim, "/")
+			if err := setJSON(pv, "claimRef", model.PVCRef(ns, name, "")); err != nil {
+				return err
+			}
+		}
+		if err := setJSON(raw, "pvs", pvs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyEventRef splits the v1 "Kind/namespace/name" form, where cluster-scoped
+// objects have an empty namespace segment.
+func legacyEventRef(s string) model.ObjectRef {
+	if ref, ok := model.ParseObjectRef(s); ok {
+		return ref
+	}
+	parts := strings.SplitN(s, "/", 3)
+	if len(parts) != 3 {
+		return model.ObjectRef{Name: s}
+	}
+	return model.ObjectRef{Kind: parts[0], Namespace: parts[1], Name: parts[2]}
+}
+
+func setJSON(m map[string]json.RawMessage, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", key, err)
+	}
+	m[key] = data
+	return nil
+}
@@ -0,0 +1,145 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+const (
+	RecordingKind    = "Recording"
+	RecordingVersion = "v1"
+)
+
+// RecordingHeader starts a recording: a header followed by one full snapshot
+// per frame, as a stream of JSON objects in a single, optionally compressed,
+// file. Frames repeat most of their content, which compression removes.
+type RecordingHeader struct {
+	Kind             string    `json:"kind"`
+	RecordingVersion string    `json:"recordingVersion"`
+	StartedAt        time.Time `json:"startedAt"`
+	Interval         string    `json:"interval"`
+	Context          string    `json:"context,omitempty"`
+	CollectorVersion string    `json:"collectorVersion,omitempty"`
+}
+
+// RecordingWriter appends frames to a recording. Every frame is flushed
+// through the compressor to the file as it is added, so a recording stopped
+// early keeps its frames.
+type RecordingWriter struct {
+	w io.WriteCloser
+}
+
+// CreateRecording creates the recording at path, compressing according to the
+// file extension, and writes its header.
+func CreateRecording(path string, header RecordingHeader) (*RecordingWriter, error) {
+	header.Kind, header.RecordingVersion = RecordingKind, RecordingVersion
+	w, err := Create(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("encode recording header: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("write recording header: %w", err)
+	}
+	return &RecordingWriter{w: w}, nil
+}
+
+func (r *RecordingWriter) Add(snap *collector.Snapshot) error {
+	if err := Encode(r.w, snap); err != nil {
+		return fmt.Errorf("write recording frame: %w", err)
+	}
+	if f, ok := r.w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("write recording frame: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *RecordingWriter) Close() error {
+	if err := r.w.Close(); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	return nil
+}
+
+// RecordingReader reads the frames of a recording one at a time.
+type RecordingReader struct {
+	Header RecordingHeader
+
+	rc  io.ReadCloser
+	dec *json.Decoder
+}
+
+// OpenRecording opens the recording at path and reads its header.
+func OpenRecording(path string) (*RecordingReader, error) {
+	rc, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &RecordingReader{rc: rc, dec: json.NewDecoder(rc)}
+	if err := r.dec.Decode(&r.Header); err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("%s: decode recording header: %w", path, err)
+	}
+	if r.Header.Kind != RecordingKind {
+		rc.Close()
+		return nil, fmt.Errorf("%s: not a recording", path)
+	}
+	if r.Header.RecordingVersion != RecordingVersion {
+		rc.Close()
+		return nil, fmt.Errorf("%s: unsupported recording version %q", path, r.Header.RecordingVersion)
+	}
+	return r, nil
+}
+
+// Next returns the next frame, upgraded to the current snapshot schema, or
+// io.EOF after the last one.
+func (r *RecordingReader) Next() (*collector.Snapshot, error) {
+	if !r.dec.More() {
+		if _, err := r.dec.Token(); !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("decode recording: trailing data")
+		}
+		return nil, io.EOF
+	}
+	snap, _, err := decodeNext(r.dec, false)
+	if err != nil {
+		return nil, fmt.Errorf("decode recording frame: %w", err)
+	}
+	return snap, nil
+}
+
+func (r *RecordingReader) Close() error {
+	return r.rc.Close()
+}
+
+// IsRecording reports whether the file at path starts with a recording
+// header. Snapshots start with their schemaVersion instead.
+func IsRecording(path string) bool {
+	rc, err := Open(path)
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+
+	dec := json.NewDecoder(rc)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+	key, err := dec.Token()
+	if err != nil || key != "kind" {
+		return false
+	}
+	var kind string
+	return dec.Decode(&kind) == nil && kind == RecordingKind
+}
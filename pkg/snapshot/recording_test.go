@@ -0,0 +1,140 @@
+package snapshot
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+func TestRecording_RoundTrip(t *testing.T) {
+	for _, name := range []string{"rec.json", "rec.json.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			start := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+
+			w, err := CreateRecording(path, RecordingHeader{StartedAt: start, Interval: "30s", Context: "prod-eu"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				snap := &collector.Snapshot{
+					SchemaVersion: collector.SnapshotSchemaVersion,
+					CollectedAt:   start.Add(time.Duration(i) * 30 * time.Second),
+					Nodes:         []collector.NodeInfo{{Name: "worker-1"}},
+					Pods:          []collector.PodInfo{},
+					Events:        []collector.EventInfo{},
+				}
+				if err := w.Add(snap); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !IsRecording(path) {
+				t.Fatal("recording not detected")
+			}
+			r, err := OpenRecording(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if r.Header.Kind != RecordingKind || r.Header.Context != "prod-eu" || r.Header.Interval != "30s" {
+				t.Errorf("header: got %+v", r.Header)
+			}
+			var frames []*collector.Snapshot
+			for {
+				snap, err := r.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				frames = append(frames, snap)
+			}
+			if len(frames) != 3 || !frames[2].CollectedAt.Equal(start.Add(time.Minute)) || frames[1].Nodes[0].Name != "worker-1" {
+				t.Errorf("frames: got %d, last at %v", len(frames), frames[len(frames)-1].CollectedAt)
+			}
+		})
+	}
+}
+
+func TestIsRecording_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := Save(path, &collector.Snapshot{SchemaVersion: collector.SnapshotSchemaVersion}); err != nil {
+		t.Fatal(err)
+	}
+	if IsRecording(path) {
+		t.Error("snapshot reported as a recording")
+	}
+	if _, err := OpenRecording(path); err == nil {
+		t.Error("expected an error opening a snapshot as a recording")
+	}
+}
+
+func TestRecording_FramesReadableBeforeClose(t *testing.T) {
+	for _, name := range []string{"rec.json", "rec.json.gz", "rec.json.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			w, err := CreateRecording(path, RecordingHeader{Interval: "30s"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			if err := w.Add(&collector.Snapshot{SchemaVersion: collector.SnapshotSchemaVersion, Nodes: []collector.NodeInfo{{Name: "worker-1"}}}); err != nil {
+				t.Fatal(err)
+			}
+
+			// A recording interrupted here keeps the frame.
+			r, err := OpenRecording(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			snap, err := r.Next()
+			if err != nil {
+				t.Fatalf("first frame: %v", err)
+			}
+			if len(snap.Nodes) != 1 || snap.Nodes[0].Name != "worker-1" {
+				t.Errorf("got %+v", snap.Nodes)
+			}
+		})
+	}
+}
+
+func TestRecording_TruncatedFrame(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.json")
+	w, err := CreateRecording(path, RecordingHeader{Interval: "30s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(&collector.Snapshot{SchemaVersion: collector.SnapshotSchemaVersion}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"schemaVersion": "v2", "nodes": [`)
+	f.Close()
+
+	r, err := OpenRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("first frame: %v", err)
+	}
+	if _, err := r.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected an error for the truncated frame, got %v", err)
+	}
+}
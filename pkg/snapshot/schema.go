@@ -0,0 +1,164 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaID is the $id of the published snapshot schema.
+const SchemaID = "https://github.com/marek-kar/kube-slowwhy/snapshot/" + collector.SnapshotSchemaVersion + ".schema.json"
+
+// Schema returns a JSON Schema describing snapshots of the current schema
+// version. It is generated from the collector types, so it always matches
+// what the collector writes.
+func Schema() ([]byte, error) {
+	g := &schemaGen{defs: make(map[string]interface{})}
+	root := g.structSchema(reflect.TypeOf(collector.Snapshot{}))
+
+	props := root["properties"].(map[string]interface{})
+	props["schemaVersion"] = map[string]interface{}{"const": collector.SnapshotSchemaVersion}
+
+	doc := map[string]interface{}{
+		"$schema": schemaDialect,
+		"$id":     SchemaID,
+		"title":   "kube-slowwhy snapshot",
+	}
+	for k, v := range root {
+		doc[k] = v
+	}
+	doc["$defs"] = g.defs
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Types with custom JSON encodings, keyed by the schema they encode to.
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	metaTimeType  = reflect.TypeOf(metav1.Time{})
+	microTimeType = reflect.TypeOf(metav1.MicroTime{})
+	quantityType  = reflect.TypeOf(resource.Quantity{})
+	intOrStrType  = reflect.TypeOf(intstr.IntOrString{})
+)
+
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+func (g *schemaGen) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case metaTimeType, microTimeType:
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "date-time"}
+	case quantityType:
+		return map[string]interface{}{"type": "string", "description": "Kubernetes resource quantity, e.g. 500m or 1Gi"}
+	case intOrStrType:
+		return map[string]interface{}{"type": []string{"integer", "string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		name := defName(t)
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil // break cycles
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	g.addFields(t, props, &required)
+	sort.Strings(required)
+
+	s := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *schemaGen) addFields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(ft, props, required)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.typeSchema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// defName names a definition after its Go package the way the Kubernetes
+// OpenAPI documents do, e.g. "io.k8s.api.core.v1.NodeCondition". The
+// collector and model types keep their bare names.
+func defName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if strings.HasPrefix(pkg, "github.com/marek-kar/kube-slowwhy/") {
+		return t.Name()
+	}
+	host, rest, _ := strings.Cut(pkg, "/")
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".") + "." + strings.ReplaceAll(rest, "/", ".") + "." + t.Name()
+}
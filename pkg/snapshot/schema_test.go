@@ -0,0 +1,73 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// The published schema lives in docs/ so it can be linked and fetched without
+// building the tool.
+func TestSchema_MatchesPublished(t *testing.T) {
+	goldenPath := filepath.Join("..", "..", "docs", "snapshot.schema.json")
+	got, err := Schema()
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v (run with -update to create)", err)
+	}
+	if !bytes.Equal(got, golden) {
+		t.Errorf("%s is out of date; run go test ./pkg/snapshot -update", goldenPath)
+	}
+}
+
+func TestSchema_DescribesSnapshot(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	var doc struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	for _, field := range []string{"schemaVersion", "nodes", "events", "kubeSystem"} {
+		if _, ok := doc.Properties[field]; !ok {
+			t.Errorf("missing top-level property %q", field)
+		}
+	}
+	if _, ok := doc.Properties["webhooks"]; !ok {
+		t.Error("missing optional property webhooks")
+	}
+	for _, r := range doc.Required {
+		if r == "webhooks" {
+			t.Error("omitempty field webhooks should not be required")
+		}
+	}
+	if _, ok := doc.Defs["EventInfo"].Properties["involvedObject"]; !ok {
+		t.Error("EventInfo definition lacks involvedObject")
+	}
+	if _, ok := doc.Defs["io.k8s.api.core.v1.NodeCondition"]; !ok {
+		t.Error("missing definition for core/v1 NodeCondition")
+	}
+}
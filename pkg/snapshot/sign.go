@@ -0,0 +1,230 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+const (
+	signatureAlgorithm = "ed25519"
+	digestPrefix       = "sha256:"
+)
+
+// Sign embeds an ed25519 signature over the snapshot's content digest. Any
+// existing signature is replaced. The collector version, cluster ID and kube
+// context are copied from the snapshot header into the signature.
+func Sign(snap *collector.Snapshot, key ed25519.PrivateKey) error {
+	pub := key.Public().(ed25519.PublicKey)
+	sig := &collector.SignatureInfo{
+		Algorithm: signatureAlgorithm,
+		KeyID:     PublicKeyID(pub),
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		SignedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if snap.Collection != nil {
+		sig.CollectorVersion = snap.Collection.CollectorVersion
+	}
+	if snap.Cluster != nil {
+		sig.ClusterID = snap.Cluster.ID
+		sig.Context = snap.Cluster.Context
+	}
+	snap.Signature = sig
+
+	digest, err := Digest(snap)
+	if err != nil {
+		snap.Signature = nil
+		return err
+	}
+	sig.Digest = digest
+	sig.Value = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(digest)))
+	return nil
+}
+
+// Digest returns the SHA-256 of the snapshot's canonical encoding with the
+// signature's Digest and Value cleared. The snapshot is streamed into the hash,
+// so large snapshots are not encoded in memory.
+func Digest(snap *collector.Snapshot) (string, error) {
+	var saved collector.SignatureInfo
+	if snap.Signature != nil {
+		saved = *snap.Signature
+		snap.Signature.Digest, snap.Signature.Value = "", ""
+		defer func() { *snap.Signature = saved }()
+	}
+
+	h := sha256.New()
+	if err := Encode(h, snap); err != nil {
+		return "", fmt.Errorf("digest snapshot: %w", err)
+	}
+	return digestPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verification describes a successfully verified snapshot signature.
+type Verification struct {
+	Signature *collector.SignatureInfo
+	// Trusted is set when the signature was checked against a caller-supplied
+	// public key rather than only the one embedded in the snapshot.
+	Trusted bool
+}
+
+// Verify checks the snapshot's digest and signature. When trusted is non-nil
+// the snapshot must have been signed by that key; otherwise the embedded
+// public key is used, which proves integrity but not origin.
+func Verify(snap *collector.Snapshot, trusted ed25519.PublicKey) (Verification, error) {
+	sig := snap.Signature
+	if sig == nil {
+		return Verification{}, fmt.Errorf("snapshot is not signed")
+	}
+	if sig.Algorithm != signatureAlgorithm {
+		return Verification{}, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+
+	embedded, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+	if err != nil || len(embedded) != ed25519.PublicKeySize {
+		return Verification{}, fmt.Errorf("signature has a malformed public key")
+	}
+	pub := ed25519.PublicKey(embedded)
+	if trusted != nil {
+		if !bytes.Equal(trusted, pub) {
+			return Verification{}, fmt.Errorf("snapshot was signed by key %s, not the trusted key %s", PublicKeyID(pub), PublicKeyID(trusted))
+		}
+	}
+	if PublicKeyID(pub) != sig.KeyID {
+		return Verification{}, fmt.Errorf("signature key ID %s does not match its public key", sig.KeyID)
+	}
+
+	digest, err := Digest(snap)
+	if err != nil {
+		return Verification{}, err
+	}
+	if digest != sig.Digest {
+		return Verification{}, fmt.Errorf("content digest mismatch: snapshot was modified after signing")
+	}
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return Verification{}, fmt.Errorf("signature value is not base64: %w", err)
+	}
+	if !ed25519.Verify(pub, []byte(sig.Digest), value) {
+		return Verification{}, fmt.Errorf("signature does not match the content digest")
+	}
+	return Verification{Signature: sig, Trusted: trusted != nil}, nil
+}
+
+// VerifyFile loads and verifies the snapshot at path. Signatures cover the
+// schema version the snapshot was written with, so files that would need a
+// migration cannot be verified by this version.
+func VerifyFile(path string, trusted ed25519.PublicKey) (Verification, error) {
+	r, err := Open(path)
+	if err != nil {
+		return Verification{}, err
+	}
+	defer r.Close()
+
+	snap, version, err := decode(r, true)
+	if err != nil {
+		return Verification{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if snap.Signature != nil && version != collector.SnapshotSchemaVersion {
+		return Verification{}, fmt.Errorf("%s: signatures on schema %s snapshots can only be verified by a kube-slowwhy that writes %s", path, version, version)
+	}
+	v, err := Verify(snap, trusted)
+	if err != nil {
+		return Verification{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
+
+// PublicKeyID is a short fingerprint of an ed25519 public key.
+func PublicKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey writes a new ed25519 key pair to privPath (mode 0600) and
+// pubPath as PKCS#8 and PKIX PEM.
+func GenerateKey(privPath, pubPath string) (ed25519.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("encode private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("encode public key: %w", err)
+	}
+
+	f, err := os.OpenFile(privPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("write private key: %w", err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: privDER}); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write private key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("write private key: %w", err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		return nil, fmt.Errorf("write public key: %w", err)
+	}
+	return pub, nil
+}
+
+// LoadPrivateKey reads a PKCS#8 PEM ed25519 private key.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is %T, not ed25519", path, key)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a PKIX PEM ed25519 public key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is %T, not ed25519", path, key)
+	}
+	return pub, nil
+}
+
+func readPEM(path, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a PEM %s block", path, blockType)
+	}
+	return block, nil
+}
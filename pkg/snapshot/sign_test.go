@@ -0,0 +1,124 @@
+package snapshot
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+func testKeyPair(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestSignVerify_RoundTripThroughFile(t *testing.T) {
+	pub, priv := testKeyPair(t)
+	snap := testSnapshot()
+	snap.Cluster = &collector.ClusterInfo{ID: "cluster-uid", Context: "prod-eu"}
+	snap.Collection = &collector.CollectionInfo{CollectorVersion: "v1.2.3"}
+
+	if err := Sign(snap, priv); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if snap.Signature.ClusterID != "cluster-uid" || snap.Signature.Context != "prod-eu" {
+		t.Errorf("provenance not recorded: %+v", snap.Signature)
+	}
+
+	for _, file := range []string{"signed.json", "signed.json.zst"} {
+		path := filepath.Join(t.TempDir(), file)
+		if err := Save(path, snap); err != nil {
+			t.Fatal(err)
+		}
+		v, err := VerifyFile(path, pub)
+		if err != nil {
+			t.Fatalf("%s: verify: %v", file, err)
+		}
+		if !v.Trusted || v.Signature.CollectorVersion != "v1.2.3" {
+			t.Errorf("%s: unexpected verification %+v", file, v)
+		}
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	_, priv := testKeyPair(t)
+	snap := testSnapshot()
+	if err := Sign(snap, priv); err != nil {
+		t.Fatal(err)
+	}
+
+	snap.Events[0].Message = "nothing to see here"
+	if _, err := Verify(snap, nil); err == nil || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("expected digest mismatch, got %v", err)
+	}
+}
+
+func TestVerify_DetectsChangedProvenance(t *testing.T) {
+	_, priv := testKeyPair(t)
+	snap := testSnapshot()
+	snap.Cluster = &collector.ClusterInfo{Context: "staging"}
+	if err := Sign(snap, priv); err != nil {
+		t.Fatal(err)
+	}
+
+	snap.Cluster.Context = "prod"
+	if _, err := Verify(snap, nil); err == nil {
+		t.Error("expected changing the signed context to fail verification")
+	}
+}
+
+func TestVerify_UntrustedKey(t *testing.T) {
+	_, priv := testKeyPair(t)
+	other, _ := testKeyPair(t)
+	snap := testSnapshot()
+	if err := Sign(snap, priv); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := Verify(snap, nil); err != nil || v.Trusted {
+		t.Errorf("embedded key: got %+v, %v", v, err)
+	}
+	if _, err := Verify(snap, other); err == nil || !strings.Contains(err.Error(), "not the trusted key") {
+		t.Errorf("expected untrusted key error, got %v", err)
+	}
+}
+
+func TestVerify_Unsigned(t *testing.T) {
+	if _, err := Verify(testSnapshot(), nil); err == nil {
+		t.Error("expected an error for an unsigned snapshot")
+	}
+}
+
+func TestGenerateKey_LoadsBack(t *testing.T) {
+	dir := t.TempDir()
+	privPath, pubPath := filepath.Join(dir, "signing.key"), filepath.Join(dir, "signing.pub")
+	pub, err := GenerateKey(privPath, pubPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	priv, err := LoadPrivateKey(privPath)
+	if err != nil {
+		t.Fatalf("load private: %v", err)
+	}
+	loaded, err := LoadPublicKey(pubPath)
+	if err != nil {
+		t.Fatalf("load public: %v", err)
+	}
+	if !pub.Equal(loaded) || !pub.Equal(priv.Public()) {
+		t.Error("loaded keys do not match the generated pair")
+	}
+	if _, err := GenerateKey(privPath, pubPath); err == nil {
+		t.Error("expected GenerateKey to refuse to overwrite an existing key")
+	}
+	if _, err := LoadPrivateKey(pubPath); err == nil {
+		t.Error("expected loading a public key as private to fail")
+	}
+}
@@ -0,0 +1,192 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// snapshotField is a top-level field of collector.Snapshot as it appears in
+// JSON.
+type snapshotField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var snapshotFields, snapshotFieldsByName = func() ([]snapshotField, map[string]snapshotField) {
+	t := reflect.TypeOf(collector.Snapshot{})
+	var fields []snapshotField
+	byName := make(map[string]snapshotField)
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		f := snapshotField{name: name, index: i, omitEmpty: strings.Contains(opts, "omitempty")}
+		fields = append(fields, f)
+		byName[name] = f
+	}
+	return fields, byName
+}()
+
+// Save writes snap to path, compressing according to the file extension.
+func Save(path string, snap *collector.Snapshot) error {
+	w, err := Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Encode(w, snap); err != nil {
+		w.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Encode writes snap as indented JSON one list element at a time, so the
+// encoded text of a large snapshot is never held in memory. The output is the
+// same as json.MarshalIndent(snap, "", "  ") followed by a newline.
+func Encode(w io.Writer, snap *collector.Snapshot) error {
+	bw := bufio.NewWriter(w)
+	v := reflect.ValueOf(snap).Elem()
+
+	bw.WriteString("{")
+	first := true
+	for _, f := range snapshotFields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if !first {
+			bw.WriteString(",")
+		}
+		first = false
+		fmt.Fprintf(bw, "\n  %q: ", f.name)
+
+		if fv.Kind() == reflect.Slice && !fv.IsNil() {
+			if err := encodeList(bw, fv); err != nil {
+				return fmt.Errorf("encode %s: %w", f.name, err)
+			}
+			continue
+		}
+		data, err := json.MarshalIndent(fv.Interface(), "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", f.name, err)
+		}
+		bw.Write(data)
+	}
+	if !first {
+		bw.WriteString("\n")
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+func encodeList(bw *bufio.Writer, list reflect.Value) error {
+	if list.Len() == 0 {
+		_, err := bw.WriteString("[]")
+		return err
+	}
+	bw.WriteString("[")
+	for i := 0; i < list.Len(); i++ {
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		data, err := json.MarshalIndent(list.Index(i).Interface(), "    ", "  ")
+		if err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+		bw.WriteString("\n    ")
+		bw.Write(data)
+	}
+	_, err := bw.WriteString("\n  ]")
+	return err
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+	return false
+}
+
+// streamDecoder decodes top-level snapshot fields straight into a Snapshot,
+// one list element at a time.
+type streamDecoder struct {
+	dec    *json.Decoder
+	strict bool
+	snap   collector.Snapshot
+}
+
+func (d *streamDecoder) decodeField(name string, raw json.RawMessage) error {
+	f, ok := snapshotFieldsByName[name]
+	if !ok {
+		if d.strict {
+			return fmt.Errorf("json: unknown field %q", name)
+		}
+		if raw == nil {
+			var skip json.RawMessage
+			return d.dec.Decode(&skip)
+		}
+		return nil
+	}
+
+	fv := reflect.ValueOf(&d.snap).Elem().Field(f.index)
+	if raw != nil {
+		return unmarshal(raw, fv.Addr().Interface(), d.strict)
+	}
+	if fv.Kind() == reflect.Slice {
+		return d.decodeList(fv)
+	}
+	return d.dec.Decode(fv.Addr().Interface())
+}
+
+func (d *streamDecoder) decodeList(list reflect.Value) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		list.Set(reflect.Zero(list.Type()))
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+
+	out := reflect.MakeSlice(list.Type(), 0, 0)
+	for d.dec.More() {
+		elem := reflect.New(list.Type().Elem())
+		if err := d.dec.Decode(elem.Interface()); err != nil {
+			return fmt.Errorf("item %d: %w", out.Len(), err)
+		}
+		out = reflect.Append(out, elem.Elem())
+	}
+	if _, err := d.dec.Token(); err != nil {
+		return err
+	}
+	list.Set(out)
+	return nil
+}
@@ -0,0 +1,159 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func testSnapshot() *collector.Snapshot {
+	return &collector.Snapshot{
+		SchemaVersion: collector.SnapshotSchemaVersion,
+		CollectedAt:   time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Since:         "30m0s",
+		Nodes: []collector.NodeInfo{{
+			Name:        "worker-1",
+			UID:         "node-uid",
+			Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+		}},
+		Pods: []collector.PodInfo{
+			{Namespace: "default", Name: "web-0", Phase: corev1.PodRunning, NodeName: "worker-1"},
+			{Namespace: "default", Name: "web-1", Phase: corev1.PodPending},
+		},
+		Events: []collector.EventInfo{{
+			Namespace:      "default",
+			Name:           "web-0.17a",
+			Reason:         "BackOff",
+			Message:        "Back-off restarting <failed> container",
+			InvolvedObject: model.PodRef("default", "web-0", "pod-uid"),
+			Count:          3,
+		}},
+		PVCs:       []collector.PVCInfo{},
+		KubeSystem: collector.KubeSystemHealth{NamespaceLabels: map[string]string{"team": "platform"}},
+		Logs:       []collector.ContainerLog{{Namespace: "kube-system", Pod: "coredns-abc", Container: "coredns", Lines: []string{"SERVFAIL"}}},
+	}
+}
+
+func TestEncode_MatchesMarshalIndent(t *testing.T) {
+	for name, snap := range map[string]*collector.Snapshot{
+		"populated": testSnapshot(),
+		"empty":     {SchemaVersion: collector.SnapshotSchemaVersion},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, snap); err != nil {
+			t.Fatalf("%s: encode: %v", name, err)
+		}
+		want, err := json.MarshalIndent(snap, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != string(want)+"\n" {
+			t.Errorf("%s: output differs from json.MarshalIndent\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+		}
+	}
+}
+
+func TestSaveLoad_Compression(t *testing.T) {
+	want := testSnapshot()
+	for _, file := range []string{"snapshot.json", "snapshot.json.gz", "snapshot.json.zst"} {
+		path := filepath.Join(t.TempDir(), file)
+		if err := Save(path, want); err != nil {
+			t.Fatalf("%s: save: %v", file, err)
+		}
+
+		got, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", file, err)
+		}
+		if !reflect.DeepEqual(normalize(t, got), normalize(t, want)) {
+			t.Errorf("%s: round trip changed the snapshot", file)
+		}
+	}
+}
+
+func TestLoad_DetectsCompressionFromContent(t *testing.T) {
+	dir := t.TempDir()
+	compressed := filepath.Join(dir, "snapshot.json.zst")
+	if err := Save(compressed, testSnapshot()); err != nil {
+		t.Fatal(err)
+	}
+	renamed := filepath.Join(dir, "snapshot.json")
+	if err := os.Rename(compressed, renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, zstdMagic) {
+		t.Fatalf("expected zstd output, got %q", data[:4])
+	}
+	if _, err := Load(renamed); err != nil {
+		t.Errorf("load renamed zstd snapshot: %v", err)
+	}
+}
+
+func TestDecode_FieldsBeforeSchemaVersion(t *testing.T) {
+	input := `{"nodes": [{"name": "worker-1"}], "schemaVersion": "v2", "pods": [{"namespace": "default", "name": "web-0"}]}`
+	snap, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(snap.Nodes) != 1 || len(snap.Pods) != 1 {
+		t.Errorf("expected 1 node and 1 pod, got %d and %d", len(snap.Nodes), len(snap.Pods))
+	}
+}
+
+func TestDecode_StrictRejectsUnknownNestedField(t *testing.T) {
+	input := `{"schemaVersion": "v2", "pods": [{"namespace": "default", "name": "web-0", "nodeNmae": "worker-1"}]}`
+	if _, _, err := decode(strings.NewReader(input), true); err == nil || !strings.Contains(err.Error(), "nodeNmae") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	if _, err := Decode(strings.NewReader(input)); err != nil {
+		t.Errorf("lenient decode: %v", err)
+	}
+}
+
+func TestCompressionFor(t *testing.T) {
+	tests := map[string]Compression{
+		"snap.json":        CompressionNone,
+		"snap.json.gz":     CompressionGzip,
+		"snap.json.GZ":     CompressionGzip,
+		"snap.json.zst":    CompressionZstd,
+		"/tmp/snap.zstd":   CompressionZstd,
+		"snap.gz.json":     CompressionNone,
+		"no-extension-ext": CompressionNone,
+	}
+	for path, want := range tests {
+		if got := CompressionFor(path); got != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+}
+
+// normalize round-trips through JSON so that resource.Quantity caches and
+// nil-versus-empty differences that encode identically do not matter.
+func normalize(t *testing.T, snap *collector.Snapshot) interface{} {
+	t.Helper()
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+)
+
+// Problem is a single validation failure, located by a JSON-path-like string
+// such as "pods[3].namespace".
+type Problem struct {
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// ValidationResult describes a snapshot file checked by ValidateFile.
+type ValidationResult struct {
+	Path          string    `json:"path"`
+	SchemaVersion string    `json:"schemaVersion,omitempty"`
+	Migrated      bool      `json:"migrated"`
+	Problems      []Problem `json:"problems,omitempty"`
+}
+
+func (r ValidationResult) Valid() bool { return len(r.Problems) == 0 }
+
+// ValidateFile checks that the file at path is a snapshot this version can
+// read, contains no fields unknown to the current schema, and is internally
+// consistent. Only I/O failures are returned as errors; everything else is
+// reported as a Problem.
+func ValidateFile(path string) (ValidationResult, error) {
+	result := ValidationResult{Path: path}
+
+	f, err := Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	snap, version, err := decode(f, true)
+	result.SchemaVersion = version
+	if err != nil {
+		result.Problems = []Problem{{Message: err.Error()}}
+		return result, nil
+	}
+	result.Migrated = version != collector.SnapshotSchemaVersion
+	result.Problems = Validate(snap)
+	return result, nil
+}
+
+// Validate reports structural problems the JSON decoder cannot catch, such as
+// objects without names or duplicate entries.
+func Validate(snap *collector.Snapshot) []Problem {
+	var problems []Problem
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if snap.CollectedAt.IsZero() {
+		add("collectedAt", "missing collection time")
+	}
+	if snap.Since != "" {
+		if _, err := time.ParseDuration(snap.Since); err != nil {
+			add("since", "not a duration: %q", snap.Since)
+		}
+	}
+
+	nodes := make(map[string]bool)
+	for i, n := range snap.Nodes {
+		path := fmt.Sprintf("nodes[%d]", i)
+		switch {
+		case n.Name == "":
+			add(path+".name", "missing")
+		case nodes[n.Name]:
+			add(path+".name", "duplicate node %q", n.Name)
+		}
+		nodes[n.Name] = true
+	}
+
+	validatePods(snap.Pods, "pods", add)
+	validatePods(snap.KubeSystem.Pods, "kubeSystem.pods", add)
+
+	for i, ev := range snap.Events {
+		path := fmt.Sprintf("events[%d]", i)
+		if ev.InvolvedObject.Kind == "" || ev.InvolvedObject.Name == "" {
+			add(path+".involvedObject", "needs both kind and name")
+		}
+		if ev.Count < 0 {
+			add(path+".count", "negative count %d", ev.Count)
+		}
+		if !ev.FirstTimestamp.IsZero() && ev.LastTimestamp.Before(ev.FirstTimestamp) {
+			add(path+".lastTimestamp", "before firstTimestamp")
+		}
+	}
+
+	for i, pvc := range snap.PVCs {
+		path := fmt.Sprintf("pvcs[%d]", i)
+		if pvc.Name == "" || pvc.Namespace == "" {
+			add(path, "needs both name and namespace")
+		}
+	}
+	for i, pv := range snap.PVs {
+		path := fmt.Sprintf("pvs[%d]", i)
+		if pv.Name == "" {
+			add(path+".name", "missing")
+		}
+		if pv.ClaimRef != nil && pv.ClaimRef.Kind != "PersistentVolumeClaim" {
+			add(path+".claimRef", "refers to a %s, not a PersistentVolumeClaim", pv.ClaimRef.Kind)
+		}
+	}
+
+	for i, l := range snap.Logs {
+		if l.Pod == "" || l.Container == "" {
+			add(fmt.Sprintf("logs[%d]", i), "needs both pod and container")
+		}
+	}
+
+	return problems
+}
+
+func validatePods(pods []collector.PodInfo, field string, add func(path, format string, args ...interface{})) {
+	seen := make(map[string]bool)
+	for i, p := range pods {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if p.Name == "" || p.Namespace == "" {
+			add(path, "needs both name and namespace")
+			continue
+		}
+		key := p.Namespace + "/" + p.Name
+		if seen[key] {
+			add(path, "duplicate pod %s", key)
+		}
+		seen[key] = true
+	}
+}
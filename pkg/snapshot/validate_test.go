@@ -0,0 +1,93 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func TestValidate_Clean(t *testing.T) {
+	claim := model.PVCRef("default", "data", "")
+	snap := &collector.Snapshot{
+		SchemaVersion: collector.SnapshotSchemaVersion,
+		CollectedAt:   time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Since:         "30m0s",
+		Nodes:         []collector.NodeInfo{{Name: "worker-1"}},
+		Pods:          []collector.PodInfo{{Namespace: "default", Name: "web-0"}},
+		Events: []collector.EventInfo{
+			{InvolvedObject: model.PodRef("default", "web-0", ""), Count: 1},
+		},
+		PVs: []collector.PVInfo{{Name: "pv-1", ClaimRef: &claim}},
+	}
+
+	if problems := Validate(snap); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestValidate_Problems(t *testing.T) {
+	node := model.NodeRef("worker-1", "")
+	snap := &collector.Snapshot{
+		Since: "half an hour",
+		Nodes: []collector.NodeInfo{{Name: "worker-1"}, {Name: "worker-1"}},
+		Pods:  []collector.PodInfo{{Name: "web-0"}},
+		Events: []collector.EventInfo{
+			{Reason: "BackOff"},
+		},
+		PVs: []collector.PVInfo{{Name: "pv-1", ClaimRef: &node}},
+	}
+
+	problems := Validate(snap)
+	want := []string{
+		"collectedAt: missing collection time",
+		`since: not a duration: "half an hour"`,
+		`nodes[1].name: duplicate node "worker-1"`,
+		"pods[0]: needs both name and namespace",
+		"events[0].involvedObject: needs both kind and name",
+		"pvs[0].claimRef: refers to a Node, not a PersistentVolumeClaim",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems: got %v, want %d", problems, len(want))
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem %d: got %q, want %q", i, p.String(), want[i])
+		}
+	}
+}
+
+func TestValidateFile_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	data := `{"schemaVersion": "v2", "collectedAt": "2024-01-01T10:00:00Z", "nodez": []}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if result.Valid() || !strings.Contains(result.Problems[0].Message, `unknown field "nodez"`) {
+		t.Errorf("expected unknown field problem, got %+v", result)
+	}
+}
+
+func TestValidateFile_ReportsMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(snapshotV1Fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !result.Valid() || !result.Migrated || result.SchemaVersion != "v1" {
+		t.Errorf("expected a valid migrated v1 snapshot, got %+v", result)
+	}
+}
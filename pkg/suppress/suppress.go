@@ -0,0 +1,209 @@
+// Package suppress hides findings that are known and accepted, such as a GPU
+// node pool that is always tainted or a test namespace full of crashloops.
+package suppress
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+// DefaultFile is read from the working directory when no file is named.
+const DefaultFile = ".slowwhy-ignore.yaml"
+
+const dateLayout = "2006-01-02"
+
+// File is a list of suppressions:
+//
+//	suppressions:
+//	  - rule: pending-pods
+//	    ref: node/gpu-*
+//	    reason: GPU pool is tainted for batch jobs only
+//	  - namespace: e2e
+//	    reason: test fixtures crashloop on purpose
+//	    expires: 2024-09-30
+type File struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// Suppression hides the findings matching all of its selectors. Namespace
+// and Ref match a finding only if every object in its resource evidence is
+// in that namespace or matches that pattern, so a suppression never hides a
+// problem with an object it does not name.
+type Suppression struct {
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	// Ref is a path.Match pattern against canonical object refs such as
+	// "node/gpu-*" or "pod/e2e/*".
+	Ref    string `json:"ref,omitempty"`
+	Reason string `json:"reason"`
+	// Expires is a date, YYYY-MM-DD, or an RFC 3339 time. A date suppresses
+	// until the end of that day in UTC.
+	Expires string `json:"expires,omitempty"`
+
+	expiresAt time.Time
+}
+
+// Load reads the suppression file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read suppression file: %w", err)
+	}
+	return parse(data, path)
+}
+
+func parse(data []byte, source string) (*File, error) {
+	var f File
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("parse suppression file %s: %w", source, err)
+	}
+	for i := range f.Suppressions {
+		s := &f.Suppressions[i]
+		if s.Fingerprint == "" && s.Rule == "" && s.Category == "" && s.Namespace == "" && s.Ref == "" {
+			return nil, fmt.Errorf("suppression file %s: entry %d has no fingerprint, rule, category, namespace or ref", source, i+1)
+		}
+		if strings.TrimSpace(s.Reason) == "" {
+			return nil, fmt.Errorf("suppression file %s: entry %d (%s) has no reason", source, i+1, s)
+		}
+		if s.Ref != "" {
+			if _, err := path.Match(s.Ref, ""); err != nil {
+				return nil, fmt.Errorf("suppression file %s: entry %d: ref %q: %w", source, i+1, s.Ref, err)
+			}
+		}
+		if s.Expires != "" {
+			t, err := parseExpiry(s.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("suppression file %s: entry %d: %w", source, i+1, err)
+			}
+			s.expiresAt = t
+		}
+	}
+	return &f, nil
+}
+
+func parseExpiry(s string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expires %q: want YYYY-MM-DD or an RFC 3339 time", s)
+}
+
+// String describes the selectors of s, for warnings.
+func (s Suppression) String() string {
+	var parts []string
+	for _, kv := range [][2]string{
+		{"fingerprint", s.Fingerprint}, {"rule", s.Rule}, {"category", s.Category},
+		{"namespace", s.Namespace}, {"ref", s.Ref},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Expired reports whether s no longer applies at now.
+func (s Suppression) Expired(now time.Time) bool {
+	return !s.expiresAt.IsZero() && !now.Before(s.expiresAt)
+}
+
+// Matches reports whether f is selected by s, regardless of expiry.
+func (s Suppression) Matches(f model.Finding) bool {
+	if s.Fingerprint != "" {
+		fp := f.Fingerprint
+		if fp == "" {
+			fp = analysis.Fingerprint(f)
+		}
+		if fp != s.Fingerprint {
+			return false
+		}
+	}
+	if s.Rule != "" && s.Rule != f.Rule {
+		return false
+	}
+	if s.Category != "" && s.Category != f.Category {
+		return false
+	}
+	if s.Namespace == "" && s.Ref == "" {
+		return true
+	}
+
+	objects := 0
+	for _, e := range f.Evidence {
+		if e.Type != model.EvidenceResource {
+			continue
+		}
+		ref, ok := e.ObjectRef()
+		if !ok {
+			continue
+		}
+		objects++
+		if s.Namespace != "" && ref.Namespace != s.Namespace {
+			return false
+		}
+		if s.Ref != "" {
+			if ok, _ := path.Match(s.Ref, ref.String()); !ok {
+				return false
+			}
+		}
+	}
+	return objects > 0
+}
+
+// Expired lists the suppressions that no longer apply at now.
+func (f *File) Expired(now time.Time) []Suppression {
+	if f == nil {
+		return nil
+	}
+	var expired []Suppression
+	for _, s := range f.Suppressions {
+		if s.Expired(now) {
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
+
+// Apply moves the findings matched by an unexpired suppression from
+// report.Findings to report.Suppressed. A nil File suppresses nothing.
+func (f *File) Apply(report model.Report, now time.Time) model.Report {
+	if f == nil {
+		return report
+	}
+	kept := make([]model.Finding, 0, len(report.Findings))
+	for _, finding := range report.Findings {
+		s, ok := f.match(finding, now)
+		if !ok {
+			kept = append(kept, finding)
+			continue
+		}
+		report.Suppressed = append(report.Suppressed, model.SuppressedFinding{
+			Reason: s.Reason, Expires: s.Expires, Finding: finding,
+		})
+	}
+	report.Findings = kept
+	report.SuppressedCount = len(report.Suppressed)
+	return report
+}
+
+func (f *File) match(finding model.Finding, now time.Time) (Suppression, bool) {
+	for _, s := range f.Suppressions {
+		if !s.Expired(now) && s.Matches(finding) {
+			return s, true
+		}
+	}
+	return Suppression{}, false
+}
@@ -0,0 +1,111 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func pendingFinding(pods ...model.ObjectRef) model.Finding {
+	f := model.Finding{ID: "pending-pods-taint", Rule: "pending-pods", Category: "scheduling", Severity: model.SeverityMedium}
+	for _, p := range pods {
+		f.Evidence = append(f.Evidence, model.ObjectEvidence(model.EvidenceResource, p, "Pending", nil))
+	}
+	return f
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	data := `suppressions:
+  - rule: pending-pods
+    namespace: gpu-jobs
+    reason: GPU pool is tainted for batch jobs only
+  - namespace: e2e
+    reason: test fixtures crashloop on purpose
+    expires: 2024-09-30
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(f.Suppressions) != 2 {
+		t.Fatalf("expected 2 suppressions, got %+v", f.Suppressions)
+	}
+	if s := f.Suppressions[1]; s.Expired(time.Date(2024, 9, 30, 23, 0, 0, 0, time.UTC)) || !s.Expired(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("a date expiry should last until the end of that day")
+	}
+	if expired := f.Expired(time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)); len(expired) != 1 || expired[0].Namespace != "e2e" {
+		t.Errorf("expected the e2e suppression to be expired, got %+v", expired)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, tc := range map[string]struct{ data, want string }{
+		"no reason":    {"suppressions:\n  - rule: dns-instability\n", "no reason"},
+		"no selector":  {"suppressions:\n  - reason: noisy\n", "no fingerprint"},
+		"bad expiry":   {"suppressions:\n  - rule: dns-instability\n    reason: noisy\n    expires: next week\n", "invalid expires"},
+		"bad pattern":  {"suppressions:\n  - ref: 'node/[gpu'\n    reason: noisy\n", "ref"},
+		"unknown keys": {"suppressions:\n  - rules: dns-instability\n    reason: noisy\n", "unknown field"},
+	} {
+		if _, err := parse([]byte(tc.data), "test.yaml"); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	f, err := parse([]byte(`suppressions:
+  - rule: pending-pods
+    namespace: gpu-jobs
+    reason: GPU pool is tainted
+  - ref: node/gpu-*
+    reason: GPU nodes run hot
+  - category: dns
+    reason: old
+    expires: 2024-06-01
+`), "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gpuPending := pendingFinding(model.PodRef("gpu-jobs", "train-0", ""), model.PodRef("gpu-jobs", "train-1", ""))
+	mixedPending := pendingFinding(model.PodRef("gpu-jobs", "train-0", ""), model.PodRef("default", "web-0", ""))
+	gpuNode := model.Finding{ID: "node-pressure-gpu-1-memorypressure", Rule: "node-pressure", Category: "node-health",
+		Evidence: []model.Evidence{{Type: model.EvidenceResource, Ref: "Node//gpu-1"}}}
+	dns := model.Finding{ID: "dns-instability", Rule: "dns-instability", Category: "dns"}
+
+	report := f.Apply(model.Report{Findings: []model.Finding{gpuPending, mixedPending, gpuNode, dns}}, now)
+
+	if len(report.Findings) != 2 || report.Findings[0].ID != mixedPending.ID || report.Findings[1].ID != dns.ID {
+		t.Errorf("expected the mixed-namespace and expired-suppression findings to remain, got %+v", report.Findings)
+	}
+	if report.SuppressedCount != 2 || report.Suppressed[0].Reason != "GPU pool is tainted" || report.Suppressed[1].Finding.ID != gpuNode.ID {
+		t.Errorf("unexpected suppressed findings: %+v", report.Suppressed)
+	}
+
+	var none *File
+	if r := none.Apply(model.Report{Findings: []model.Finding{dns}}, now); len(r.Findings) != 1 || r.SuppressedCount != 0 {
+		t.Errorf("a nil file should suppress nothing, got %+v", r)
+	}
+}
+
+func TestMatches_Fingerprint(t *testing.T) {
+	dns := model.Finding{ID: "dns-instability", Rule: "dns-instability", Category: "dns"}
+	other := model.Finding{ID: "storage-issue", Rule: "storage", Category: "storage"}
+	// Reports written before fingerprints existed are fingerprinted on the
+	// fly, so dns has no Fingerprint set here.
+	s := Suppression{Fingerprint: analysis.Fingerprint(dns), Reason: "known"}
+	if !s.Matches(dns) || s.Matches(other) {
+		t.Error("fingerprint suppression should match only its finding")
+	}
+}
@@ -0,0 +1,356 @@
+// Package watch keeps a live snapshot of a cluster from shared informers and
+// re-analyses it as objects change.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+const kubeSystemNS = "kube-system"
+
+type Options struct {
+	Namespace string
+	Since     time.Duration
+	// Debounce is how long to wait after the first change before analysing,
+	// so a burst of updates is analysed once.
+	Debounce time.Duration
+	// Resync re-analyses even when nothing changed, so events that fall out
+	// of the Since window resolve their findings.
+	Resync time.Duration
+	// SystemPodSelectors are the kube-system pods tracked for health, as in
+	// collector.Options.
+	SystemPodSelectors []string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Since:    30 * time.Minute,
+		Debounce: 5 * time.Second,
+		Resync:   time.Minute,
+
+		SystemPodSelectors: collector.DefaultSystemPodSelectors(),
+	}
+}
+
+// Analyzer turns a snapshot into a report, normally the default engine
+// followed by the correlator.
+type Analyzer func(*collector.Snapshot) model.Report
+
+// Update is sent after every analysis that changed the set of findings, and
+// after the first one. The first update lists every current finding as new.
+type Update struct {
+	Time    time.Time
+	Initial bool
+	Report  model.Report
+	Changes []model.FindingChange
+}
+
+type Watcher struct {
+	opts    Options
+	analyze Analyzer
+
+	factories []informers.SharedInformerFactory
+	synced    []cache.InformerSynced
+	dirty     chan struct{}
+
+	nodes      func() []collector.NodeInfo
+	pods       func() []collector.PodInfo
+	systemPods func() []collector.PodInfo
+	daemonSets func() []collector.DaemonSetInfo
+	events     func() []collector.EventInfo
+	pvcs       func() []collector.PVCInfo
+	pvs        func() []collector.PVInfo
+}
+
+// New sets up the informers. Events are read from events.k8s.io/v1 when the
+// cluster serves it and it may be listed, and from core/v1 otherwise, as
+// collect does.
+func New(ctx context.Context, client kubernetes.Interface, opts Options, analyze Analyzer) (*Watcher, error) {
+	if opts.Debounce <= 0 || opts.Resync <= 0 {
+		return nil, fmt.Errorf("debounce and resync intervals must be positive")
+	}
+	if len(opts.SystemPodSelectors) == 0 {
+		opts.SystemPodSelectors = collector.DefaultSystemPodSelectors()
+	}
+	w := &Watcher{
+		opts:    opts,
+		analyze: analyze,
+		dirty:   make(chan struct{}, 1),
+	}
+
+	all := informers.NewSharedInformerFactory(client, 0)
+	scoped := all
+	if opts.Namespace != "" {
+		scoped = informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(opts.Namespace))
+	}
+	system := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(kubeSystemNS))
+	w.factories = []informers.SharedInformerFactory{all}
+	if scoped != all {
+		w.factories = append(w.factories, scoped)
+	}
+	w.factories = append(w.factories, system)
+
+	nodes := all.Core().V1().Nodes()
+	w.track(nodes.Informer())
+	w.nodes = func() []collector.NodeInfo {
+		list, _ := nodes.Lister().List(labels.Everything())
+		out := make([]collector.NodeInfo, 0, len(list))
+		for _, n := range list {
+			out = append(out, collector.NewNodeInfo(n))
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		return out
+	}
+
+	pods := scoped.Core().V1().Pods()
+	w.track(pods.Informer())
+	w.pods = func() []collector.PodInfo {
+		list, _ := pods.Lister().List(labels.Everything())
+		return podInfos(list, func(*corev1.Pod) bool { return true })
+	}
+
+	systemPods := system.Core().V1().Pods()
+	w.track(systemPods.Informer())
+	w.systemPods = func() []collector.PodInfo {
+		list, _ := systemPods.Lister().List(labels.Everything())
+		return podInfos(list, func(p *corev1.Pod) bool { return collector.IsCriticalSystemPod(p.Labels, opts.SystemPodSelectors) })
+	}
+
+	daemonSets := system.Apps().V1().DaemonSets()
+	w.track(daemonSets.Informer())
+	w.daemonSets = func() []collector.DaemonSetInfo {
+		list, _ := daemonSets.Lister().List(labels.Everything())
+		out := make([]collector.DaemonSetInfo, 0, len(list))
+		for _, ds := range list {
+			out = append(out, collector.NewDaemonSetInfo(ds))
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		return out
+	}
+
+	pvcs := scoped.Core().V1().PersistentVolumeClaims()
+	w.track(pvcs.Informer())
+	w.pvcs = func() []collector.PVCInfo {
+		list, _ := pvcs.Lister().List(labels.Everything())
+		out := make([]collector.PVCInfo, 0, len(list))
+		for _, p := range list {
+			out = append(out, collector.NewPVCInfo(p))
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].Namespace != out[j].Namespace {
+				return out[i].Namespace < out[j].Namespace
+			}
+			return out[i].Name < out[j].Name
+		})
+		return out
+	}
+
+	pvs := all.Core().V1().PersistentVolumes()
+	w.track(pvs.Informer())
+	w.pvs = func() []collector.PVInfo {
+		list, _ := pvs.Lister().List(labels.Everything())
+		out := make([]collector.PVInfo, 0, len(list))
+		for _, p := range list {
+			out = append(out, collector.NewPVInfo(p))
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		return out
+	}
+
+	_, err := client.EventsV1().Events("").List(ctx, metav1.ListOptions{Limit: 1})
+	switch {
+	case err == nil:
+		events := all.Events().V1().Events()
+		w.track(events.Informer())
+		w.events = func() []collector.EventInfo {
+			list, _ := events.Lister().List(labels.Everything())
+			out := make([]collector.EventInfo, 0, len(list))
+			for _, e := range list {
+				out = append(out, collector.NewEventInfo(e))
+			}
+			return out
+		}
+	case apierrors.IsNotFound(err) || apierrors.IsForbidden(err):
+		events := all.Core().V1().Events()
+		w.track(events.Informer())
+		w.events = func() []collector.EventInfo {
+			list, _ := events.Lister().List(labels.Everything())
+			out := make([]collector.EventInfo, 0, len(list))
+			for _, e := range list {
+				out = append(out, collector.NewCoreEventInfo(e))
+			}
+			return out
+		}
+	default:
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	return w, nil
+}
+
+func (w *Watcher) track(informer cache.SharedIndexInformer) {
+	w.synced = append(w.synced, informer.HasSynced)
+	// The handler registration can only fail once the informer has stopped.
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { w.notify() },
+		UpdateFunc: func(_, _ interface{}) { w.notify() },
+		DeleteFunc: func(interface{}) { w.notify() },
+	})
+}
+
+func (w *Watcher) notify() {
+	select {
+	case w.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// Snapshot builds a snapshot from the informer caches. Only events seen in the
+// Since window are included.
+func (w *Watcher) Snapshot() *collector.Snapshot {
+	now := time.Now()
+	cutoff := now.Add(-w.opts.Since)
+
+	events := make([]collector.EventInfo, 0)
+	for _, e := range w.events() {
+		if !e.LastTimestamp.Before(cutoff) {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].LastTimestamp.Equal(events[j].LastTimestamp) {
+			return events[i].LastTimestamp.Before(events[j].LastTimestamp)
+		}
+		return events[i].Namespace+"/"+events[i].Name < events[j].Namespace+"/"+events[j].Name
+	})
+
+	return &collector.Snapshot{
+		SchemaVersion: collector.SnapshotSchemaVersion,
+		CollectedAt:   now.UTC(),
+		Since:         w.opts.Since.String(),
+		Nodes:         w.nodes(),
+		Pods:          w.pods(),
+		Events:        events,
+		PVCs:          w.pvcs(),
+		PVs:           w.pvs(),
+		KubeSystem: collector.KubeSystemHealth{
+			DaemonSets: w.daemonSets(),
+			Pods:       w.systemPods(),
+		},
+	}
+}
+
+// Run starts the informers, waits for their caches and analyses the snapshot
+// once, then again after every burst of changes and at least every Resync.
+// It returns when ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context, send func(Update)) error {
+	for _, f := range w.factories {
+		f.Start(ctx.Done())
+	}
+	defer func() {
+		for _, f := range w.factories {
+			f.Shutdown()
+		}
+	}()
+	if !cache.WaitForCacheSync(ctx.Done(), w.synced...) {
+		if err := ctx.Err(); err != nil {
+			return nil
+		}
+		return fmt.Errorf("informer caches did not sync")
+	}
+
+	var findings []model.Finding
+	analyze := func(initial bool) {
+		report := w.analyze(w.Snapshot())
+		changes := analysis.Changes(findings, report.Findings)
+		findings = report.Findings
+		if initial || len(changes) > 0 {
+			send(Update{Time: time.Now(), Initial: initial, Report: report, Changes: changes})
+		}
+	}
+
+	// The initial list fills the caches, which is not a change.
+	select {
+	case <-w.dirty:
+	default:
+	}
+	analyze(true)
+
+	resync := time.NewTicker(w.opts.Resync)
+	defer resync.Stop()
+	var debounce *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return nil
+		case <-w.dirty:
+			if debounce == nil {
+				debounce = time.NewTimer(w.opts.Debounce)
+				fire = debounce.C
+			}
+		case <-fire:
+			debounce, fire = nil, nil
+			analyze(false)
+		case <-resync.C:
+			if debounce == nil {
+				analyze(false)
+			}
+		}
+	}
+}
+
+func podInfos(pods []*corev1.Pod, keep func(*corev1.Pod) bool) []collector.PodInfo {
+	out := make([]collector.PodInfo, 0, len(pods))
+	for _, p := range pods {
+		if keep(p) {
+			out = append(out, collector.NewPodInfo(p))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// RequiredPermissions lists the list and watch access the informers need, in
+// the same form as collector.RequiredPermissions.
+func RequiredPermissions(namespace string) []collector.Permission {
+	var perms []collector.Permission
+	add := func(group, resource, ns string) {
+		for _, verb := range []string{"list", "watch"} {
+			perms = append(perms, collector.Permission{Collector: "watch", Verb: verb, Group: group, Resource: resource, Namespace: ns})
+		}
+	}
+	add("", "nodes", "")
+	add("", "pods", namespace)
+	add("events.k8s.io", "events", "")
+	// Fallback for clusters that do not serve events.k8s.io/v1 or forbid it.
+	add("", "events", "")
+	add("", "persistentvolumeclaims", namespace)
+	add("", "persistentvolumes", "")
+	add("apps", "daemonsets", kubeSystemNS)
+	add("", "pods", kubeSystemNS)
+	return perms
+}
@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/marek-kar/kube-slowwhy/pkg/analysis"
+	"github.com/marek-kar/kube-slowwhy/pkg/collector"
+	"github.com/marek-kar/kube-slowwhy/pkg/model"
+)
+
+func pressureNode(name string, pressure corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			{Type: corev1.NodeMemoryPressure, Status: pressure, Reason: "KubeletHasInsufficientMemory"},
+		}},
+	}
+}
+
+func analyze(snap *collector.Snapshot) model.Report {
+	return analysis.DefaultEngine().Analyze(snap)
+}
+
+func next(t *testing.T, updates <-chan Update) Update {
+	t.Helper()
+	select {
+	case u := <-updates:
+		return u
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an update")
+		return Update{}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := fake.NewSimpleClientset(
+		pressureNode("worker-1", corev1.ConditionFalse),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "coredns-1", Namespace: "kube-system", Labels: map[string]string{"k8s-app": "kube-dns"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "coredns"}}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "coredns", Ready: true}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: "kube-system"},
+		},
+	)
+	opts := DefaultOptions()
+	opts.Debounce = 10 * time.Millisecond
+	w, err := New(ctx, client, opts, analyze)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(chan Update, 10)
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx, func(u Update) { updates <- u }) }()
+
+	first := next(t, updates)
+	if !first.Initial || len(first.Changes) != 0 {
+		t.Fatalf("expected an initial update without changes, got %+v", first)
+	}
+	snap := w.Snapshot()
+	if len(snap.Nodes) != 1 || len(snap.Pods) != 2 || len(snap.KubeSystem.Pods) != 1 || snap.KubeSystem.Pods[0].Name != "coredns-1" {
+		t.Errorf("snapshot from caches: nodes=%d pods=%d kube-system=%+v", len(snap.Nodes), len(snap.Pods), snap.KubeSystem.Pods)
+	}
+
+	if _, err := client.CoreV1().Nodes().Update(ctx, pressureNode("worker-1", corev1.ConditionTrue), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	u := next(t, updates)
+	if len(u.Changes) != 1 || u.Changes[0].Type != model.FindingNew || u.Changes[0].Finding.Rule != "node-pressure" {
+		t.Fatalf("expected a new node-pressure finding, got %+v", u.Changes)
+	}
+
+	if _, err := client.CoreV1().Nodes().Update(ctx, pressureNode("worker-1", corev1.ConditionFalse), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	u = next(t, updates)
+	if len(u.Changes) != 1 || u.Changes[0].Type != model.FindingResolved {
+		t.Fatalf("expected the finding to resolve, got %+v", u.Changes)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+
+	perms := RequiredPermissions("")
+	for _, a := range client.Actions() {
+		if a.GetVerb() != "list" && a.GetVerb() != "watch" {
+			continue
+		}
+		covered := false
+		for _, p := range perms {
+			if p.Verb == a.GetVerb() && p.Group == a.GetResource().Group && p.Resource == a.GetResource().Resource &&
+				(p.Namespace == "" || p.Namespace == a.GetNamespace()) {
+				covered = true
+			}
+		}
+		if !covered {
+			t.Errorf("%s %s in %q is not in RequiredPermissions", a.GetVerb(), a.GetResource(), a.GetNamespace())
+		}
+	}
+}
+
+func TestNew_RejectsZeroIntervals(t *testing.T) {
+	if _, err := New(context.Background(), fake.NewSimpleClientset(), Options{}, analyze); err == nil {
+		t.Error("expected an error for zero debounce and resync")
+	}
+}
+
+func TestNew_FallsBackToCoreEventsWhenForbidden(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := fake.NewSimpleClientset(&corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: "web-0.17a3"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-0"},
+		Reason:         "BackOff",
+		LastTimestamp:  metav1.Now(),
+	})
+	client.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Group == "events.k8s.io" {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "events.k8s.io", Resource: "events"}, "", errors.New("no access"))
+		}
+		return false, nil, nil
+	})
+	opts := DefaultOptions()
+	opts.Debounce = 10 * time.Millisecond
+	w, err := New(ctx, client, opts, analyze)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	updates := make(chan Update, 10)
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx, func(u Update) { updates <- u }) }()
+	next(t, updates)
+
+	if events := w.Snapshot().Events; len(events) != 1 || events[0].Name != "web-0.17a3" {
+		t.Errorf("expected the core/v1 event, got %+v", events)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+}